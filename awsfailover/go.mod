@@ -0,0 +1,11 @@
+module github.com/dadanrm/failover/awsfailover
+
+go 1.24.7
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.47.1
+	github.com/aws/smithy-go v1.28.2
+	github.com/dadanrm/failover v0.0.0
+)
+
+replace github.com/dadanrm/failover => ../
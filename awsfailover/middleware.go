@@ -0,0 +1,165 @@
+// Package awsfailover runs the calls of AWS SDK for Go v2 clients through
+// failover policies, in place of the SDK's own retryer, so that AWS calls
+// share retry budgets and breakers with everything else.
+package awsfailover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/dadanrm/failover"
+)
+
+// retryID is the ID of the SDK's retry middleware, which policyMiddleware
+// takes the place and the ID of.
+const retryID = "Retry"
+
+// Classifier reports whether an error returned by an AWS call is a failure
+// of the service, to be retried and counted by breakers. Other errors, such
+// as validation or access errors, are returned to the caller at once.
+type Classifier func(err error) bool
+
+var defaultRetryables = retry.IsErrorRetryables(retry.DefaultRetryables)
+
+// DefaultClassifier treats the errors the SDK's standard retryer retries as
+// failures: throttling, server errors, timeouts and broken connections.
+func DefaultClassifier(err error) bool {
+	return defaultRetryables.IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// policyMiddleware sends each attempt of an operation through a policy.
+type policyMiddleware struct {
+	policy   failover.Policy
+	classify Classifier
+}
+
+// Option configures optional middleware behavior.
+type Option func(*policyMiddleware)
+
+// WithClassifier replaces DefaultClassifier.
+func WithClassifier(c Classifier) Option {
+	return func(m *policyMiddleware) {
+		m.classify = c
+	}
+}
+
+// WithPolicy returns an API option that replaces the SDK's retry middleware
+// with one running every attempt through policy. Set it in the APIOptions of
+// a client, or of an aws.Config with Install. The policy does the retrying,
+// so it normally includes a failover.RetryPolicy; errors that are not
+// failures per the classifier are neither retried nor seen by the policy.
+//
+// A throttling response with an X-Amz-Retry-After header fails the attempt
+// with a failover.RetryAfterError, which RetryPolicy honors.
+func WithPolicy(policy failover.Policy, opts ...Option) func(*middleware.Stack) error {
+	m := &policyMiddleware{
+		policy:   policy,
+		classify: DefaultClassifier,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(stack *middleware.Stack) error {
+		if _, err := stack.Finalize.Swap(retryID, m); err == nil {
+			return nil
+		}
+		// No SDK retryer to replace: retry before signing, so that each
+		// attempt is signed afresh.
+		if err := stack.Finalize.Insert(m, "Signing", middleware.Before); err == nil {
+			return nil
+		}
+		return stack.Finalize.Add(m, middleware.Before)
+	}
+}
+
+// Install sets WithPolicy in the API options of cfg, so that every client
+// created from cfg uses policy.
+func Install(cfg *aws.Config, policy failover.Policy, opts ...Option) {
+	cfg.APIOptions = append(cfg.APIOptions, WithPolicy(policy, opts...))
+}
+
+// ID implements middleware.FinalizeMiddleware.
+func (m *policyMiddleware) ID() string {
+	return retryID
+}
+
+// HandleFinalize implements middleware.FinalizeMiddleware.
+func (m *policyMiddleware) HandleFinalize(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (
+	out middleware.FinalizeOutput, metadata middleware.Metadata, err error,
+) {
+	var (
+		attempt  int
+		final    error // Error that is not a failure, ending the call
+		deferred *failover.RetryAfterError
+	)
+
+	err = m.policy.Do(ctx, func(ctx context.Context) error {
+		attempt++
+		attemptIn := in
+		attemptIn.Request = smithyhttp.RequestCloner(in.Request)
+
+		// Later attempts must send the payload from its start.
+		if attempt > 1 {
+			if req, ok := attemptIn.Request.(*smithyhttp.Request); ok {
+				if err := req.RewindStream(); err != nil {
+					final = fmt.Errorf("rewind request stream for retry: %w", err)
+					return nil
+				}
+			}
+		}
+
+		var err error
+		out, metadata, err = next.HandleFinalize(ctx, attemptIn)
+		if err == nil {
+			return nil
+		}
+		if !m.classify(err) {
+			final = err
+			return nil
+		}
+
+		if delay, ok := retryAfter(err); ok {
+			deferred = &failover.RetryAfterError{Err: err, Delay: delay}
+			return deferred
+		}
+		return err
+	})
+
+	if final != nil {
+		return out, metadata, final
+	}
+	var exhausted *failover.MaxAttemptsError
+	if errors.As(err, &exhausted) {
+		err = exhausted.LastErr
+	}
+	if deferred != nil && err == error(deferred) {
+		err = deferred.Err
+	}
+
+	return out, metadata, err
+}
+
+// retryAfter returns the delay asked for by the X-Amz-Retry-After header, in
+// milliseconds, of the response that failed with err.
+func retryAfter(err error) (time.Duration, bool) {
+	var re *smithyhttp.ResponseError
+	if !errors.As(err, &re) || re.Response == nil || re.Response.Response == nil {
+		return 0, false
+	}
+
+	ms, perr := strconv.ParseInt(re.Response.Header.Get("X-Amz-Retry-After"), 10, 64)
+	if perr != nil || ms < 0 {
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
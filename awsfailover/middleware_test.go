@@ -0,0 +1,135 @@
+package awsfailover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/dadanrm/failover"
+)
+
+var errTest = errors.New("test error")
+
+// sdkRetry stands in for the SDK's retry middleware, failing the test if
+// it is still in the stack.
+type sdkRetry struct{ t *testing.T }
+
+func (sdkRetry) ID() string { return retryID }
+
+func (r sdkRetry) HandleFinalize(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	r.t.Error("Expected the SDK retryer to be replaced")
+	return next.HandleFinalize(ctx, in)
+}
+
+// serverError is an HTTP response error with the given status.
+func serverError(status int, header http.Header) error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status, Header: header}},
+		Err:      errTest,
+	}
+}
+
+// invoke runs one operation through a stack set up like an SDK client's,
+// with WithPolicy applied, answering each attempt with the next of errs and
+// recording the bodies sent.
+func invoke(t *testing.T, policy failover.Policy, errs []error, opts ...Option) ([]string, error) {
+	t.Helper()
+	stack := middleware.NewStack("op", smithyhttp.NewStackRequest)
+	stack.Finalize.Add(sdkRetry{t}, middleware.After)
+	if err := WithPolicy(policy, opts...)(stack); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	stack.Serialize.Add(middleware.SerializeMiddlewareFunc("body", func(ctx context.Context, in middleware.SerializeInput, next middleware.SerializeHandler) (middleware.SerializeOutput, middleware.Metadata, error) {
+		req, _ := in.Request.(*smithyhttp.Request).SetStream(bytes.NewReader([]byte("payload")))
+		in.Request = req
+		return next.HandleSerialize(ctx, in)
+	}), middleware.After)
+
+	var bodies []string
+	handler := middleware.HandlerFunc(func(_ context.Context, in any) (any, middleware.Metadata, error) {
+		body, _ := io.ReadAll(in.(*smithyhttp.Request).GetStream())
+		bodies = append(bodies, string(body))
+		var err error
+		if len(bodies) <= len(errs) {
+			err = errs[len(bodies)-1]
+		}
+		return nil, middleware.Metadata{}, err
+	})
+
+	_, _, err := middleware.DecorateHandler(handler, stack).Handle(context.Background(), nil)
+	return bodies, err
+}
+
+func TestWithPolicy_RetriesFailures(t *testing.T) {
+	t.Parallel()
+	policy := failover.NewRetryPolicy(3, time.Millisecond)
+
+	bodies, err := invoke(t, policy, []error{serverError(500, nil), serverError(503, nil)})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(bodies))
+	}
+	for i, body := range bodies {
+		if body != "payload" {
+			t.Fatalf("Expected attempt %d to send the whole payload, got %q", i+1, body)
+		}
+	}
+}
+
+func TestWithPolicy_ClientErrorNotRetried(t *testing.T) {
+	t.Parallel()
+	breaker := failover.NewCircuitBreaker(1, 1, time.Minute)
+	policy := failover.NewPipeline(
+		failover.WithRetry(failover.NewRetryPolicy(3, time.Millisecond)),
+		failover.WithBreaker(breaker))
+	clientErr := serverError(403, nil)
+
+	bodies, err := invoke(t, policy, []error{clientErr})
+
+	if !errors.Is(err, clientErr) {
+		t.Fatalf("Expected %v, got %v", clientErr, err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", len(bodies))
+	}
+	if breaker.State() != failover.Closed {
+		t.Fatalf("Expected a client error not to open the breaker, got %v", breaker.State())
+	}
+}
+
+func TestWithPolicy_RetryAfter(t *testing.T) {
+	t.Parallel()
+	policy := failover.NewRetryPolicy(2, time.Millisecond)
+	throttled := serverError(503, http.Header{"X-Amz-Retry-After": []string{"50"}})
+
+	start := time.Now()
+	_, err := invoke(t, policy, []error{throttled, throttled})
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Expected to wait the 50ms asked for, waited %v", elapsed)
+	}
+	if err != throttled {
+		t.Fatalf("Expected the SDK error %v, got %v", throttled, err)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	t.Parallel()
+	var cfg aws.Config
+
+	Install(&cfg, failover.NewRetryPolicy(1, time.Millisecond))
+
+	if len(cfg.APIOptions) != 1 {
+		t.Fatalf("Expected 1 API option, got %d", len(cfg.APIOptions))
+	}
+}
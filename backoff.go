@@ -0,0 +1,65 @@
+package failover
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes how long to wait before a retry.
+type Backoff interface {
+	// Delay returns the wait before retry number attempt, counting from 1
+	// for the first retry.
+	Delay(attempt int) time.Duration
+}
+
+// BackoffFunc adapts a function to a Backoff.
+type BackoffFunc func(attempt int) time.Duration
+
+// Delay calls f(attempt).
+func (f BackoffFunc) Delay(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// ConstantBackoff waits the same duration before every retry.
+type ConstantBackoff time.Duration
+
+// Delay implements Backoff.
+func (c ConstantBackoff) Delay(int) time.Duration {
+	return time.Duration(c)
+}
+
+// ExponentialBackoff multiplies the delay after every retry, optionally
+// capped and randomized.
+type ExponentialBackoff struct {
+	Initial    time.Duration // Delay before the first retry
+	Max        time.Duration // Upper bound on the delay, zero for none
+	Multiplier float64       // Growth factor per retry; 2 if unset
+	Jitter     float64       // Fraction of the delay to randomize, in [0, 1]
+}
+
+// Delay implements Backoff.
+func (e ExponentialBackoff) Delay(attempt int) time.Duration {
+	multiplier := e.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := float64(e.Initial)
+	for range attempt - 1 {
+		delay *= multiplier
+		if e.Max > 0 && delay >= float64(e.Max) {
+			break
+		}
+	}
+
+	if e.Max > 0 && delay > float64(e.Max) {
+		delay = float64(e.Max)
+	}
+
+	if e.Jitter > 0 {
+		// Spread the delay uniformly over [delay*(1-jitter), delay*(1+jitter)).
+		delay *= 1 - e.Jitter + 2*e.Jitter*rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
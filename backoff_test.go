@@ -0,0 +1,38 @@
+package failover
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+	b := ExponentialBackoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w*time.Millisecond {
+			t.Fatalf("Attempt %d: Expected %v, got %v", i+1, w*time.Millisecond, got)
+		}
+	}
+}
+
+func TestExponentialBackoff_Jitter(t *testing.T) {
+	t.Parallel()
+	b := ExponentialBackoff{Initial: 100 * time.Millisecond, Jitter: 0.5}
+
+	for range 100 {
+		if d := b.Delay(1); d < 50*time.Millisecond || d >= 150*time.Millisecond {
+			t.Fatalf("Expected delay within 50ms..150ms, got %v", d)
+		}
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	t.Parallel()
+	b := ConstantBackoff(time.Second)
+
+	if b.Delay(1) != time.Second || b.Delay(10) != time.Second {
+		t.Fatalf("Expected constant 1s delay, got %v and %v", b.Delay(1), b.Delay(10))
+	}
+}
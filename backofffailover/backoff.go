@@ -0,0 +1,72 @@
+// Package backofffailover converts between the backoff strategies of
+// github.com/cenkalti/backoff/v5 and failover.Backoff, so that existing
+// backoff configurations can drive failover retry policies, and failover
+// strategies can drive code still using backoff.Retry.
+package backofffailover
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/dadanrm/failover"
+)
+
+// fromBackOff is the failover.Backoff of a backoff.BackOff.
+type fromBackOff struct {
+	newBackOff func() backoff.BackOff
+}
+
+// FromBackOff returns a failover.Backoff that waits what a BackOff returned
+// by newBackOff would. A BackOff remembers where it is in its sequence, so
+// each delay replays the sequence of a fresh one up to the retry asked for;
+// the result is safe for concurrent use by any number of calls. Once the
+// BackOff stops, the delay stays at the last one it returned: bound the
+// retries with the attempts of the policy instead.
+//
+//	policy := failover.NewRetryPolicy(5, 0,
+//		failover.WithBackoff(backofffailover.FromBackOff(func() backoff.BackOff {
+//			return backoff.NewExponentialBackOff()
+//		})))
+func FromBackOff(newBackOff func() backoff.BackOff) failover.Backoff {
+	return fromBackOff{newBackOff: newBackOff}
+}
+
+// Delay implements failover.Backoff.
+func (f fromBackOff) Delay(attempt int) time.Duration {
+	b := f.newBackOff()
+
+	var delay time.Duration
+	for range attempt {
+		next := b.NextBackOff()
+		if next == backoff.Stop {
+			break
+		}
+		delay = next
+	}
+
+	return delay
+}
+
+// toBackOff is the backoff.BackOff of a failover.Backoff.
+type toBackOff struct {
+	backoff failover.Backoff
+	retries int // Retries the delay has been asked for since the last Reset
+}
+
+// ToBackOff returns a backoff.BackOff that waits what b does, counting the
+// retries since it was last reset. Like the BackOffs of package backoff, it
+// is not safe for concurrent use; it never returns backoff.Stop.
+func ToBackOff(b failover.Backoff) backoff.BackOff {
+	return &toBackOff{backoff: b}
+}
+
+// NextBackOff implements backoff.BackOff.
+func (t *toBackOff) NextBackOff() time.Duration {
+	t.retries++
+	return t.backoff.Delay(t.retries)
+}
+
+// Reset implements backoff.BackOff.
+func (t *toBackOff) Reset() {
+	t.retries = 0
+}
@@ -0,0 +1,82 @@
+package backofffailover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/dadanrm/failover"
+)
+
+var errTest = errors.New("test error")
+
+// steps is a BackOff that waits 1ms, 2ms, and so on, then stops.
+type steps struct{ n, max int }
+
+func (s *steps) NextBackOff() time.Duration {
+	if s.n == s.max {
+		return backoff.Stop
+	}
+	s.n++
+	return time.Duration(s.n) * time.Millisecond
+}
+
+func (s *steps) Reset() { s.n = 0 }
+
+func TestFromBackOff(t *testing.T) {
+	t.Parallel()
+	b := FromBackOff(func() backoff.BackOff { return &steps{max: 3} })
+
+	for attempt, want := range map[int]time.Duration{1: time.Millisecond, 3: 3 * time.Millisecond, 5: 3 * time.Millisecond} {
+		if got := b.Delay(attempt); got != want {
+			t.Errorf("Expected delay %v for attempt %d, got %v", want, attempt, got)
+		}
+	}
+}
+
+func TestFromBackOff_RetryPolicy(t *testing.T) {
+	t.Parallel()
+	var delays []time.Duration
+	policy := failover.NewRetryPolicy(3, 0,
+		failover.WithBackoff(FromBackOff(func() backoff.BackOff { return &steps{max: 10} })),
+		failover.WithRetryFunc(func(_ context.Context, _ int, _ error, delay time.Duration) {
+			delays = append(delays, delay)
+		}))
+
+	_ = policy.Do(context.Background(), func(context.Context) error { return errTest })
+
+	if len(delays) != 2 || delays[0] != time.Millisecond || delays[1] != 2*time.Millisecond {
+		t.Fatalf("Expected delays [1ms 2ms], got %v", delays)
+	}
+}
+
+func TestToBackOff(t *testing.T) {
+	t.Parallel()
+	b := ToBackOff(failover.ExponentialBackoff{Initial: time.Millisecond})
+
+	for _, want := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond} {
+		if got := b.NextBackOff(); got != want {
+			t.Fatalf("Expected delay %v, got %v", want, got)
+		}
+	}
+
+	b.Reset()
+	if got := b.NextBackOff(); got != time.Millisecond {
+		t.Fatalf("Expected delay %v after Reset, got %v", time.Millisecond, got)
+	}
+}
+
+func TestToBackOff_Retry(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	_, err := backoff.Retry(context.Background(), func() (int, error) {
+		calls++
+		return 0, errTest
+	}, backoff.WithBackOff(ToBackOff(failover.ConstantBackoff(time.Millisecond))), backoff.WithMaxTries(3))
+
+	if !errors.Is(err, errTest) || calls != 3 {
+		t.Fatalf("Expected 3 calls ending in %v, got %d and %v", errTest, calls, err)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/dadanrm/failover/backofffailover
+
+go 1.24.7
+
+require (
+	github.com/cenkalti/backoff/v5 v5.0.3
+	github.com/dadanrm/failover v0.0.0
+)
+
+replace github.com/dadanrm/failover => ../
@@ -0,0 +1,287 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Picker chooses the endpoint a Balancer sends a call to.
+type Picker interface {
+	// Pick returns one of candidates, which is never empty.
+	Pick(candidates []*Endpoint) *Endpoint
+}
+
+// Balancer spreads calls across endpoints according to its Picker,
+// excluding endpoints whose breaker is open, that its OutlierDetector
+// ejected or that its health source reports Down. Endpoints are
+// re-included as soon as their breaker lets calls through again in
+// HalfOpen, so a recovered endpoint gets traffic back without any extra
+// configuration; WithCanary makes that return gradual.
+type Balancer struct {
+	mu        sync.Mutex // Protects endpoints, excluded and canaries
+	endpoints []*Endpoint
+	picker    Picker
+	outliers  *OutlierDetector // Optional, records outcomes and ejects outliers
+	health    HealthSource     // Optional source of endpoint status
+
+	canaryFraction float64                 // Share of calls sent to recovering endpoints
+	canaryWindow   time.Duration           // How long a recovering endpoint must succeed, zero to disable
+	excluded       map[*Endpoint]bool      // Endpoints excluded when last seen
+	canaries       map[*Endpoint]time.Time // Recovering endpoints, by start of their canary window
+}
+
+// BalancerOption configures optional Balancer behavior.
+type BalancerOption func(*Balancer)
+
+// WithPicker sets how the balancer chooses among available endpoints. The
+// default is WeightedRoundRobin.
+func WithPicker(p Picker) BalancerOption {
+	return func(b *Balancer) {
+		b.picker = p
+	}
+}
+
+// WithOutlierDetection records the outcome of every call in o and excludes
+// the endpoints it ejects.
+func WithOutlierDetection(o *OutlierDetector) BalancerOption {
+	return func(b *Balancer) {
+		b.outliers = o
+	}
+}
+
+// WithHealthExclusion excludes the endpoints that s reports as Down,
+// looked up by name. Unknown endpoints are included.
+func WithHealthExclusion(s HealthSource) BalancerOption {
+	return func(b *Balancer) {
+		b.health = s
+	}
+}
+
+// WithCanary makes an endpoint that comes back after being excluded a
+// canary: it only gets fraction of the calls, shared with the other
+// canaries, until it has gone window without a failure. A failure restarts
+// the window. The endpoint then takes its full share again.
+func WithCanary(fraction float64, window time.Duration) BalancerOption {
+	return func(b *Balancer) {
+		b.canaryFraction = fraction
+		b.canaryWindow = window
+	}
+}
+
+// NewBalancer creates a Balancer over endpoints.
+func NewBalancer(endpoints []*Endpoint, opts ...BalancerOption) *Balancer {
+	b := &Balancer{
+		endpoints: endpoints,
+		picker:    WeightedRoundRobin(),
+		excluded:  make(map[*Endpoint]bool),
+		canaries:  make(map[*Endpoint]time.Time),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Do runs fn against an endpoint chosen by the picker. If the chosen
+// endpoint's breaker rejects the call, another endpoint is picked. It
+// returns ErrNoEndpoint if none could take the call.
+func (b *Balancer) Do(ctx context.Context, fn EndpointFunc) error {
+	candidates := b.available()
+	for len(candidates) > 0 {
+		ep := b.picker.Pick(b.route(candidates))
+
+		err := ep.call(ctx, fn)
+		if !errors.Is(err, ErrCircuitOpen) {
+			if b.outliers != nil {
+				b.outliers.Record(ep, err)
+			}
+			if err != nil {
+				b.restartCanary(ep)
+			}
+			return err
+		}
+		candidates = slices.DeleteFunc(candidates, func(c *Endpoint) bool { return c == ep })
+	}
+
+	return ErrNoEndpoint
+}
+
+// Endpoints returns the balancer's endpoints.
+func (b *Balancer) Endpoints() []*Endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*Endpoint(nil), b.endpoints...)
+}
+
+// SetEndpoints replaces the balancer's endpoints. Endpoints with the same
+// name and address as a current one keep the current one's state, and the
+// OutlierDetector forgets the removed ones.
+func (b *Balancer) SetEndpoints(endpoints []*Endpoint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.endpoints = mergeEndpoints(b.endpoints, endpoints)
+	if b.outliers != nil {
+		b.outliers.SetEndpoints(b.endpoints)
+	}
+	for ep := range b.excluded {
+		if !slices.Contains(b.endpoints, ep) {
+			delete(b.excluded, ep)
+			delete(b.canaries, ep)
+		}
+	}
+}
+
+// available returns the endpoints whose breaker is not open and that are
+// neither ejected nor Down, starting the canary window of the ones that
+// just came back.
+func (b *Balancer) available() []*Endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	candidates := make([]*Endpoint, 0, len(b.endpoints))
+	for _, ep := range b.endpoints {
+		excluded := ep.Breaker != nil && ep.Breaker.State() == Open ||
+			b.outliers != nil && b.outliers.Ejected(ep) ||
+			b.health != nil && b.health.Status(ep.Name) == Down
+
+		if b.canaryWindow > 0 {
+			if !excluded && b.excluded[ep] {
+				b.canaries[ep] = now
+			}
+			if start, ok := b.canaries[ep]; ok && (excluded || now.Sub(start) >= b.canaryWindow) {
+				delete(b.canaries, ep)
+			}
+			b.excluded[ep] = excluded
+		}
+
+		if !excluded {
+			candidates = append(candidates, ep)
+		}
+	}
+
+	return candidates
+}
+
+// route narrows candidates to the canaries for a fraction of the calls and
+// to the other endpoints for the rest.
+func (b *Balancer) route(candidates []*Endpoint) []*Endpoint {
+	if b.canaryWindow == 0 {
+		return candidates
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var canaries, regular []*Endpoint
+	for _, ep := range candidates {
+		if _, ok := b.canaries[ep]; ok {
+			canaries = append(canaries, ep)
+		} else {
+			regular = append(regular, ep)
+		}
+	}
+
+	if len(canaries) == 0 || len(regular) == 0 {
+		return candidates
+	}
+	if rand.Float64() < b.canaryFraction {
+		return canaries
+	}
+
+	return regular
+}
+
+// restartCanary restarts the canary window of ep if it is a canary.
+func (b *Balancer) restartCanary(ep *Endpoint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.canaries[ep]; ok {
+		b.canaries[ep] = time.Now()
+	}
+}
+
+// weightedRoundRobin is the smooth weighted round-robin used by nginx: each
+// pick adds every candidate's weight to its running score, picks the
+// highest score and subtracts the total from it. Picks follow the weights
+// exactly over a cycle while interleaving endpoints rather than bunching
+// them.
+type weightedRoundRobin struct {
+	mu     sync.Mutex
+	scores map[*Endpoint]int
+}
+
+// WeightedRoundRobin returns a Picker that distributes calls in proportion
+// to endpoint weights.
+func WeightedRoundRobin() Picker {
+	return &weightedRoundRobin{scores: make(map[*Endpoint]int)}
+}
+
+func (w *weightedRoundRobin) Pick(candidates []*Endpoint) *Endpoint {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var best *Endpoint
+	total := 0
+	for _, ep := range candidates {
+		w.scores[ep] += ep.weight()
+		total += ep.weight()
+		if best == nil || w.scores[ep] > w.scores[best] {
+			best = ep
+		}
+	}
+	w.scores[best] -= total
+
+	// Forget endpoints that are no longer candidates, so one that returns
+	// does not come back with a stale score.
+	if len(w.scores) > len(candidates) {
+		for ep := range w.scores {
+			if !slices.Contains(candidates, ep) {
+				delete(w.scores, ep)
+			}
+		}
+	}
+
+	return best
+}
+
+// powerOfTwoChoices picks the less loaded of two random candidates.
+type powerOfTwoChoices struct{}
+
+// PowerOfTwoChoices returns a Picker that samples two random endpoints and
+// picks the one with fewer in-flight calls relative to its weight. Load is
+// read from each endpoint's Bulkhead, so endpoints need one for the choice
+// to be informed. Compared to round-robin it keeps calls away from slow
+// endpoints, which sharply reduces tail latency under uneven load.
+func PowerOfTwoChoices() Picker {
+	return powerOfTwoChoices{}
+}
+
+func (powerOfTwoChoices) Pick(candidates []*Endpoint) *Endpoint {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	i := rand.IntN(len(candidates))
+	j := rand.IntN(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+
+	a, b := candidates[i], candidates[j]
+	// Compare inFlight/weight without dividing.
+	if b.inFlight()*a.weight() < a.inFlight()*b.weight() {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,208 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countCalls returns an EndpointFunc that counts the calls per endpoint.
+func countCalls(counts map[string]int) EndpointFunc {
+	return func(_ context.Context, ep *Endpoint) error {
+		counts[ep.Name]++
+		return nil
+	}
+}
+
+func TestBalancer_Weighted(t *testing.T) {
+	t.Parallel()
+	b := NewBalancer([]*Endpoint{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}, {Name: "c"}})
+
+	counts := make(map[string]int)
+	for range 50 {
+		if err := b.Do(context.Background(), countCalls(counts)); err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+
+	if counts["a"] != 30 || counts["b"] != 10 || counts["c"] != 10 {
+		t.Fatalf("Expected calls split 30/10/10, got %v", counts)
+	}
+}
+
+func TestBalancer_SmoothInterleaving(t *testing.T) {
+	t.Parallel()
+	p := WeightedRoundRobin()
+	a, b := &Endpoint{Name: "a", Weight: 2}, &Endpoint{Name: "b", Weight: 1}
+
+	var got string
+	for range 6 {
+		got += p.Pick([]*Endpoint{a, b}).Name
+	}
+	if got != "abaaba" {
+		t.Fatalf("Expected picks abaaba, got %s", got)
+	}
+}
+
+func TestBalancer_ExcludesOpenBreaker(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, 30*time.Millisecond)
+	b := NewBalancer([]*Endpoint{{Name: "a", Breaker: cb}, {Name: "b"}})
+	ctx := context.Background()
+
+	cb.Execute(func() error { return errTest })
+
+	counts := make(map[string]int)
+	for range 10 {
+		b.Do(ctx, countCalls(counts))
+	}
+	if counts["a"] != 0 || counts["b"] != 10 {
+		t.Fatalf("Expected all calls on b while a is open, got %v", counts)
+	}
+
+	// Through HalfOpen the endpoint takes its share again.
+	time.Sleep(40 * time.Millisecond)
+	counts = make(map[string]int)
+	for range 10 {
+		b.Do(ctx, countCalls(counts))
+	}
+	if counts["a"] != 5 || counts["b"] != 5 {
+		t.Fatalf("Expected calls split evenly after recovery, got %v", counts)
+	}
+}
+
+func TestBalancer_NoEndpoint(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	cb.Execute(func() error { return errTest })
+	b := NewBalancer([]*Endpoint{{Name: "a", Breaker: cb}})
+
+	if err := b.Do(context.Background(), countCalls(map[string]int{})); !errors.Is(err, ErrNoEndpoint) {
+		t.Fatalf("Expected ErrNoEndpoint, got %v", err)
+	}
+}
+
+func TestPowerOfTwoChoices_PrefersLessLoaded(t *testing.T) {
+	t.Parallel()
+	busy := &Endpoint{Name: "busy", Bulkhead: NewBulkhead(10, 0, 0)}
+	idle := &Endpoint{Name: "idle", Bulkhead: NewBulkhead(10, 0, 0)}
+	defer hold(t, busy.Bulkhead)()
+
+	p := PowerOfTwoChoices()
+	for range 20 {
+		if ep := p.Pick([]*Endpoint{busy, idle}); ep != idle {
+			t.Fatalf("Expected the idle endpoint, got %s", ep.Name)
+		}
+	}
+}
+
+func TestPowerOfTwoChoices_Balancer(t *testing.T) {
+	t.Parallel()
+	endpoints := []*Endpoint{
+		{Name: "a", Bulkhead: NewBulkhead(1, 0, 0)},
+		{Name: "b", Bulkhead: NewBulkhead(1, 0, 0)},
+	}
+	b := NewBalancer(endpoints, WithPicker(PowerOfTwoChoices()))
+
+	// With a held, every call must go to b.
+	release := hold(t, endpoints[0].Bulkhead)
+	defer release()
+
+	counts := make(map[string]int)
+	for range 10 {
+		if err := b.Do(context.Background(), countCalls(counts)); err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+	if counts["b"] != 10 {
+		t.Fatalf("Expected all calls on the idle endpoint, got %v", counts)
+	}
+}
+
+func TestBalancer_Canary(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, 10*time.Millisecond)
+	b := NewBalancer([]*Endpoint{{Name: "a", Breaker: cb}, {Name: "b"}}, WithCanary(0.1, 50*time.Millisecond))
+	ctx := context.Background()
+
+	b.Do(ctx, countCalls(map[string]int{})) // a seen available
+	cb.Execute(func() error { return errTest })
+	b.Do(ctx, countCalls(map[string]int{})) // a seen excluded
+	time.Sleep(15 * time.Millisecond)
+
+	// Back in HalfOpen, a only gets the canary share.
+	counts := make(map[string]int)
+	for range 1000 {
+		b.Do(ctx, countCalls(counts))
+	}
+	if counts["a"] == 0 || counts["a"] > 200 {
+		t.Fatalf("Expected about 10%% of calls on the canary, got %v", counts)
+	}
+
+	// Once the window passes without failure, a takes its full share.
+	time.Sleep(60 * time.Millisecond)
+	counts = make(map[string]int)
+	for range 100 {
+		b.Do(ctx, countCalls(counts))
+	}
+	if counts["a"] != 50 {
+		t.Fatalf("Expected calls split evenly after the canary window, got %v", counts)
+	}
+}
+
+// switchBreaker is a Breaker whose state is set by the test.
+type switchBreaker struct{ open atomic.Bool }
+
+func (s *switchBreaker) Execute(fn WorkFunc) error { return fn() }
+
+func (s *switchBreaker) State() State {
+	if s.open.Load() {
+		return Open
+	}
+	return Closed
+}
+
+func TestBalancer_CanaryFailureRestartsWindow(t *testing.T) {
+	t.Parallel()
+	sb := &switchBreaker{}
+	b := NewBalancer([]*Endpoint{{Name: "a", Breaker: sb}, {Name: "b"}}, WithCanary(1, 40*time.Millisecond))
+	ctx := context.Background()
+
+	sb.open.Store(true)
+	b.Do(ctx, countCalls(map[string]int{}))
+	sb.open.Store(false)
+
+	var got []string
+	b.Do(ctx, record(&got)) // starts the window
+	time.Sleep(30 * time.Millisecond)
+	b.Do(ctx, record(&got, "a")) // a canary failure restarts it
+	time.Sleep(30 * time.Millisecond)
+
+	got = nil
+	for range 5 {
+		b.Do(ctx, record(&got))
+	}
+	for _, name := range got {
+		if name != "a" {
+			t.Fatalf("Expected every call on the canary while its window runs, got %v", got)
+		}
+	}
+}
+
+func TestBalancer_HealthExclusion(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(time.Hour)
+	h.Add("a", func(context.Context) error { return errTest })
+	h.Check(context.Background(), "a")
+	b := NewBalancer([]*Endpoint{{Name: "a"}, {Name: "b"}}, WithHealthExclusion(h))
+
+	counts := make(map[string]int)
+	for range 4 {
+		b.Do(context.Background(), countCalls(counts))
+	}
+	if counts["a"] != 0 || counts["b"] != 4 {
+		t.Fatalf("Expected all calls on b while a is Down, got %v", counts)
+	}
+}
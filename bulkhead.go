@@ -0,0 +1,187 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned when the bulkhead has no free slot and its
+// wait queue is full or the wait timed out.
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// ErrDeadlineUnreachable is returned when a call is rejected up front
+// because its context deadline would expire before the call could be
+// admitted and served.
+var ErrDeadlineUnreachable = errors.New("deadline would expire before the call completes")
+
+// Bulkhead limits the number of concurrent executions of a protected
+// resource, so one slow dependency cannot exhaust the caller's goroutines.
+//
+// The bulkhead honors the Priority carried by the context: BestEffort calls
+// never wait in the queue, Critical calls may queue beyond maxQueue, and
+// slots set aside with WithCriticalReserve are only used by Critical calls.
+//
+// A caller that would have to queue is rejected with ErrDeadlineUnreachable
+// if its context deadline is sooner than the estimated wait plus the
+// average service time, instead of waiting only to fail.
+type Bulkhead struct {
+	sem          chan struct{} // Holds one token per running execution
+	shared       chan struct{} // Holds one token per non-critical execution, nil without a reserve
+	maxQueue     int           // How many callers may wait for a slot
+	queueTimeout time.Duration // How long a queued caller waits, zero for no limit
+
+	queued      atomic.Int64
+	serviceTime atomic.Int64 // Moving average of execution time, in nanoseconds
+}
+
+// BulkheadOption configures optional Bulkhead behavior.
+type BulkheadOption func(*Bulkhead)
+
+// WithCriticalReserve sets aside n of the bulkhead's slots for Critical
+// calls, so they keep flowing when other traffic saturates the rest.
+func WithCriticalReserve(n int) BulkheadOption {
+	return func(b *Bulkhead) {
+		b.shared = make(chan struct{}, max(cap(b.sem)-n, 0))
+	}
+}
+
+// NewBulkhead creates a Bulkhead allowing maxConcurrent executions at once.
+// When all slots are taken up to maxQueue callers wait for one, each for at
+// most queueTimeout (zero waits until the context is done). A maxQueue of
+// zero rejects immediately when saturated.
+func NewBulkhead(maxConcurrent, maxQueue int, queueTimeout time.Duration, opts ...BulkheadOption) *Bulkhead {
+	b := &Bulkhead{
+		sem:          make(chan struct{}, maxConcurrent),
+		maxQueue:     maxQueue,
+		queueTimeout: queueTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Do executes fn once a slot is available.
+func (b *Bulkhead) Do(ctx context.Context, fn WorkFuncCtx) error {
+	p := PriorityFromContext(ctx)
+	if err := b.acquire(ctx, p); err != nil {
+		return err
+	}
+	defer b.release(p)
+
+	start := time.Now()
+	defer func() { b.observe(time.Since(start)) }()
+
+	return fn(ctx)
+}
+
+// EstimatedWait returns how long a caller arriving now is expected to wait
+// for a slot, based on the queue length and the average service time.
+func (b *Bulkhead) EstimatedWait() time.Duration {
+	if len(b.sem) < cap(b.sem) {
+		return 0
+	}
+
+	return b.estimatedWait(int(b.queued.Load()) + 1)
+}
+
+// estimatedWait returns the expected wait of the caller at queue position n.
+func (b *Bulkhead) estimatedWait(n int) time.Duration {
+	return time.Duration(int64(n) * b.serviceTime.Load() / int64(max(cap(b.sem), 1)))
+}
+
+// observe folds an execution time into the average service time.
+func (b *Bulkhead) observe(d time.Duration) {
+	for {
+		old := b.serviceTime.Load()
+		next := int64(d)
+		if old != 0 {
+			next = old + (next-old)/5
+		}
+
+		if b.serviceTime.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// InFlight returns the number of executions currently holding a slot.
+func (b *Bulkhead) InFlight() int {
+	return len(b.sem)
+}
+
+// Queued returns the number of callers waiting for a slot.
+func (b *Bulkhead) Queued() int {
+	return int(b.queued.Load())
+}
+
+func (b *Bulkhead) acquire(ctx context.Context, p Priority) error {
+	if b.shared == nil || p == Critical {
+		return b.take(ctx, b.sem, p)
+	}
+
+	if err := b.take(ctx, b.shared, p); err != nil {
+		return err
+	}
+	if err := b.take(ctx, b.sem, p); err != nil {
+		<-b.shared
+		return err
+	}
+
+	return nil
+}
+
+// take acquires a token from sem, queueing for it if the priority allows.
+func (b *Bulkhead) take(ctx context.Context, sem chan struct{}, p Priority) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+		// saturated, try to queue.
+	}
+
+	if p == BestEffort {
+		return rejectWith(rejectedBulkheadFull)
+	}
+
+	position := b.queued.Add(1)
+	if position > int64(b.maxQueue) && p != Critical {
+		b.queued.Add(-1)
+		return rejectWith(rejectedBulkheadFull)
+	}
+	defer b.queued.Add(-1)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		expected := b.estimatedWait(int(position)) + time.Duration(b.serviceTime.Load())
+		if time.Until(deadline) < expected {
+			return rejectWith(rejectedBulkheadLate)
+		}
+	}
+
+	var timeout <-chan time.Time
+	if b.queueTimeout > 0 {
+		timer := time.NewTimer(b.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-timeout:
+		return rejectWith(rejectedBulkheadFull)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bulkhead) release(p Priority) {
+	<-b.sem
+	if b.shared != nil && p != Critical {
+		<-b.shared
+	}
+}
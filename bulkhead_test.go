@@ -0,0 +1,203 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hold occupies a bulkhead slot until the returned release func is called.
+func hold(t *testing.T, b *Bulkhead) (release func()) {
+	t.Helper()
+	started := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		_ = b.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-done
+			return nil
+		})
+	}()
+	<-started
+
+	return func() { close(done) }
+}
+
+func TestBulkhead_RejectsWhenFull(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 0, 0)
+
+	release := hold(t, b)
+	defer release()
+
+	if b.InFlight() != 1 {
+		t.Fatalf("Expected 1 in flight, got %d", b.InFlight())
+	}
+
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull, got %v", err)
+	}
+}
+
+func TestBulkhead_QueueWaitsForSlot(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 1, time.Second)
+
+	release := hold(t, b)
+	time.AfterFunc(20*time.Millisecond, release)
+
+	ran := false
+	err := b.Do(context.Background(), func(context.Context) error {
+		ran = true
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ran {
+		t.Fatal("Expected queued call to run once a slot freed up")
+	}
+}
+
+func TestBulkhead_QueueTimeout(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 1, 20*time.Millisecond)
+
+	release := hold(t, b)
+	defer release()
+
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull after queue timeout, got %v", err)
+	}
+	if b.Queued() != 0 {
+		t.Fatalf("Expected empty queue, got %d", b.Queued())
+	}
+}
+
+func TestBulkhead_QueueBound(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 1, 0)
+
+	release := hold(t, b)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- b.Do(ctx, func(context.Context) error { return nil })
+	}()
+	for b.Queued() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The single queue slot is taken, so this call is rejected outright.
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull with a full queue, got %v", err)
+	}
+
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled for queued call, got %v", err)
+	}
+}
+
+func TestBulkhead_BestEffortNeverQueues(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 5, time.Second)
+
+	release := hold(t, b)
+	defer release()
+
+	ctx := WithPriority(context.Background(), BestEffort)
+	start := time.Now()
+	err := b.Do(ctx, func(context.Context) error { return nil })
+
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull for best-effort call, got %v", err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Fatal("Expected best-effort call to be rejected without waiting")
+	}
+}
+
+func TestBulkhead_CriticalReserve(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(2, 0, 0, WithCriticalReserve(1))
+
+	// One normal call takes the only shared slot.
+	release := hold(t, b)
+	defer release()
+
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected normal call to be rejected, got %v", err)
+	}
+
+	ctx := WithPriority(context.Background(), Critical)
+	if err := b.Do(ctx, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected critical call to use the reserved slot, got %v", err)
+	}
+}
+
+func TestBulkhead_CriticalQueuesBeyondBound(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 0, 0)
+
+	release := hold(t, b)
+	time.AfterFunc(20*time.Millisecond, release)
+
+	ctx := WithPriority(context.Background(), Critical)
+	if err := b.Do(ctx, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected critical call to wait for a slot, got %v", err)
+	}
+}
+
+func TestBulkhead_DeadlineUnreachable(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 5, 0)
+	b.serviceTime.Store(int64(100 * time.Millisecond))
+
+	release := hold(t, b)
+	defer release()
+
+	if wait := b.EstimatedWait(); wait != 100*time.Millisecond {
+		t.Fatalf("Expected estimated wait of 100ms, got %v", wait)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := b.Do(ctx, func(context.Context) error { return nil })
+	if !errors.Is(err, ErrDeadlineUnreachable) {
+		t.Fatalf("Expected ErrDeadlineUnreachable, got %v", err)
+	}
+	if time.Since(start) > 20*time.Millisecond {
+		t.Fatal("Expected the call to be rejected without waiting")
+	}
+}
+
+func TestBulkhead_ServiceTimeAverage(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(2, 0, 0)
+
+	_ = b.Do(context.Background(), func(context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	if avg := time.Duration(b.serviceTime.Load()); avg < 10*time.Millisecond {
+		t.Fatalf("Expected average service time of at least 10ms, got %v", avg)
+	}
+	if wait := b.EstimatedWait(); wait != 0 {
+		t.Fatalf("Expected no wait with free slots, got %v", wait)
+	}
+}
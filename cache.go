@@ -0,0 +1,136 @@
+package failover
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a stored result and when it was produced.
+type cacheEntry[T any] struct {
+	value  T
+	stored time.Time
+}
+
+// StaleCache remembers the last successful result of an operation per key.
+// Results younger than the TTL are served without calling the operation;
+// when the operation fails (including being rejected by an open breaker)
+// the last known-good result is served instead, as long as it is within
+// the maximum staleness.
+//
+// Entries are only dropped once too old to be served, when their key is
+// next looked up. Without WithMaxEntries the cache therefore grows with
+// every distinct key, which should then come from a bounded set.
+type StaleCache[T any] struct {
+	mu sync.Mutex // Protects entries
+
+	ttl      time.Duration // How long a result is served without a call
+	maxStale time.Duration // How old a result may be when served on failure
+	maxSize  int           // Cap on entries, zero for unbounded
+
+	entries map[string]cacheEntry[T]
+}
+
+// CacheOption configures optional StaleCache behavior.
+type CacheOption func(*cacheConfig)
+
+// cacheConfig holds the options of a StaleCache, which cannot be taken by
+// a non-generic CacheOption directly.
+type cacheConfig struct {
+	maxSize int
+}
+
+// WithMaxEntries caps the cache at n entries. Storing a new key in a full
+// cache first drops the entries too old to be served, then the oldest.
+func WithMaxEntries(n int) CacheOption {
+	return func(c *cacheConfig) {
+		c.maxSize = n
+	}
+}
+
+// NewStaleCache creates a StaleCache. A ttl of zero calls the operation
+// every time and only uses the cache on failure.
+func NewStaleCache[T any](ttl, maxStale time.Duration, opts ...CacheOption) *StaleCache[T] {
+	var cfg cacheConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &StaleCache[T]{
+		ttl:      ttl,
+		maxStale: maxStale,
+		maxSize:  cfg.maxSize,
+		entries:  make(map[string]cacheEntry[T]),
+	}
+}
+
+// Do returns the cached result for key if it is fresh, and otherwise calls
+// fn. If fn fails and a result no older than the maximum staleness is
+// cached, that result is returned with a nil error.
+func (c *StaleCache[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	entry, ok := c.get(key)
+	if ok && time.Since(entry.stored) < c.ttl {
+		return entry.value, nil
+	}
+
+	v, err := fn(ctx)
+	if err == nil {
+		c.put(key, v)
+		return v, nil
+	}
+
+	if ok && time.Since(entry.stored) <= c.maxStale {
+		return entry.value, nil
+	}
+
+	return v, err
+}
+
+// Invalidate removes the cached result for key.
+func (c *StaleCache[T]) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+func (c *StaleCache[T]) get(key string) (cacheEntry[T], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && time.Since(entry.stored) > max(c.ttl, c.maxStale) {
+		delete(c.entries, key) // too old to ever be served again
+		return cacheEntry[T]{}, false
+	}
+
+	return entry, ok
+}
+
+func (c *StaleCache[T]) put(key string, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && c.maxSize > 0 && len(c.entries) >= c.maxSize {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry[T]{value: v, stored: now}
+}
+
+// evict makes room for one entry: it drops every entry too old to be
+// served, or the oldest if none is.
+func (c *StaleCache[T]) evict(now time.Time) {
+	var oldest string
+	for key, entry := range c.entries {
+		if now.Sub(entry.stored) > max(c.ttl, c.maxStale) {
+			delete(c.entries, key)
+		} else if e, ok := c.entries[oldest]; !ok || entry.stored.Before(e.stored) {
+			oldest = key
+		}
+	}
+
+	if len(c.entries) >= c.maxSize {
+		delete(c.entries, oldest)
+	}
+}
@@ -0,0 +1,92 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStaleCache_ServesFresh(t *testing.T) {
+	t.Parallel()
+	c := NewStaleCache[int](time.Minute, time.Hour)
+	ctx := context.Background()
+
+	calls := 0
+	fn := func(context.Context) (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	v1, _ := c.Do(ctx, "k", fn)
+	v2, _ := c.Do(ctx, "k", fn)
+
+	if v1 != 1 || v2 != 1 || calls != 1 {
+		t.Fatalf("Expected cached value 1 from a single call, got %d, %d after %d calls", v1, v2, calls)
+	}
+}
+
+func TestStaleCache_StaleOnError(t *testing.T) {
+	t.Parallel()
+	c := NewStaleCache[string](0, time.Hour)
+	ctx := context.Background()
+
+	_, _ = c.Do(ctx, "k", func(context.Context) (string, error) { return "good", nil })
+
+	v, err := c.Do(ctx, "k", func(context.Context) (string, error) { return "", ErrCircuitOpen })
+	if err != nil || v != "good" {
+		t.Fatalf("Expected stale (good, nil), got (%q, %v)", v, err)
+	}
+
+	// Keys are independent.
+	_, err = c.Do(ctx, "other", func(context.Context) (string, error) { return "", errTest })
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v for uncached key, got %v", errTest, err)
+	}
+}
+
+func TestStaleCache_MaxStaleness(t *testing.T) {
+	t.Parallel()
+	c := NewStaleCache[string](0, 10*time.Millisecond)
+	ctx := context.Background()
+
+	_, _ = c.Do(ctx, "k", func(context.Context) (string, error) { return "good", nil })
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := c.Do(ctx, "k", func(context.Context) (string, error) { return "", errTest })
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v past max staleness, got %v", errTest, err)
+	}
+}
+
+func TestStaleCache_Invalidate(t *testing.T) {
+	t.Parallel()
+	c := NewStaleCache[string](time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, _ = c.Do(ctx, "k", func(context.Context) (string, error) { return "old", nil })
+	c.Invalidate("k")
+
+	v, _ := c.Do(ctx, "k", func(context.Context) (string, error) { return "new", nil })
+	if v != "new" {
+		t.Fatalf("Expected new value after Invalidate, got %q", v)
+	}
+}
+
+func TestStaleCache_MaxEntries(t *testing.T) {
+	t.Parallel()
+	c := NewStaleCache[string](time.Minute, time.Hour, WithMaxEntries(2))
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = c.Do(ctx, key, func(context.Context) (string, error) { return key, nil })
+		time.Sleep(time.Millisecond) // order the stored times
+	}
+
+	if n := len(c.entries); n != 2 {
+		t.Fatalf("Expected 2 entries, got %d", n)
+	}
+	if _, ok := c.entries["a"]; ok {
+		t.Fatal("Expected the oldest entry to be evicted")
+	}
+}
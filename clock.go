@@ -0,0 +1,16 @@
+package failover
+
+import "time"
+
+// Clock tells the time and waits for it to pass. Policies that take one
+// can run on simulated time, as with the clock of package failovertest.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of package time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,101 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// coalescedCall is an execution shared by every caller of the same key.
+type coalescedCall[T any] struct {
+	done     chan struct{} // Closed once value and err are set
+	value    T
+	err      error
+	panicked *coalescedPanic // Set if fn panicked
+}
+
+// coalescedPanic is a panic in a shared execution, raised again in every
+// caller waiting for it along with the stack where it happened.
+type coalescedPanic struct {
+	value any
+	stack []byte
+}
+
+// Error implements error.
+func (p *coalescedPanic) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (p *coalescedPanic) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// Coalescer collapses concurrent calls with the same key into a single
+// execution whose result is returned to all of them, so retry storms from
+// many callers turn into one request to the struggling backend.
+type Coalescer[T any] struct {
+	mu    sync.Mutex // Protects calls
+	calls map[string]*coalescedCall[T]
+}
+
+// NewCoalescer creates an empty Coalescer.
+func NewCoalescer[T any]() *Coalescer[T] {
+	return &Coalescer[T]{calls: make(map[string]*coalescedCall[T])}
+}
+
+// Do executes fn unless a call with the same key is already in flight, in
+// which case it waits for and returns that call's result.
+//
+// The shared execution runs with the first caller's context values but not
+// its cancellation, so one caller giving up does not fail the others. A
+// caller whose own ctx is done stops waiting and gets ctx.Err().
+//
+// If fn panics, every waiting caller panics with an error that wraps the
+// panic value and carries the stack of the shared execution.
+func (c *Coalescer[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		call = &coalescedCall[T]{done: make(chan struct{})}
+		c.calls[key] = call
+		go c.run(context.WithoutCancel(ctx), key, call, fn)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.panicked != nil {
+			panic(call.panicked)
+		}
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// InFlight returns the number of keys currently executing.
+func (c *Coalescer[T]) InFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.calls)
+}
+
+func (c *Coalescer[T]) run(ctx context.Context, key string, call *coalescedCall[T], fn func(ctx context.Context) (T, error)) {
+	defer func() {
+		if v := recover(); v != nil {
+			call.panicked = &coalescedPanic{value: v, stack: debug.Stack()}
+		}
+
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn(ctx)
+}
@@ -0,0 +1,139 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescer_SharesExecution(t *testing.T) {
+	t.Parallel()
+	c := NewCoalescer[int]()
+
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (int, error) {
+		executions.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan int, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _ := c.Do(context.Background(), "key", fn)
+			results <- v
+		}()
+	}
+
+	for c.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // let every caller join
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := executions.Load(); n != 1 {
+		t.Fatalf("Expected 1 execution, got %d", n)
+	}
+	for v := range results {
+		if v != 42 {
+			t.Fatalf("Expected every caller to get 42, got %d", v)
+		}
+	}
+}
+
+func TestCoalescer_KeysAreIndependent(t *testing.T) {
+	t.Parallel()
+	c := NewCoalescer[string]()
+	ctx := context.Background()
+
+	a, _ := c.Do(ctx, "a", func(context.Context) (string, error) { return "a", nil })
+	b, err := c.Do(ctx, "b", func(context.Context) (string, error) { return "", errTest })
+
+	if a != "a" || !errors.Is(err, errTest) || b != "" {
+		t.Fatalf("Expected independent results, got %q, %q, %v", a, b, err)
+	}
+	if c.InFlight() != 0 {
+		t.Fatalf("Expected no calls in flight, got %d", c.InFlight())
+	}
+}
+
+func TestCoalescer_CallerCancel(t *testing.T) {
+	t.Parallel()
+	c := NewCoalescer[int]()
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := c.Do(ctx, "key", func(ctx context.Context) (int, error) {
+		<-release
+		return 1, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// A second caller still gets the shared result, unaffected by the cancel.
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Do(context.Background(), "key", nil)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected shared call to succeed, got %v", err)
+	}
+}
+
+func TestCoalescer_Panic(t *testing.T) {
+	t.Parallel()
+	c := NewCoalescer[int]()
+
+	release := make(chan struct{})
+	fn := func(context.Context) (int, error) {
+		<-release
+		panic(errTest)
+	}
+
+	var wg sync.WaitGroup
+	recovered := make(chan any, 3)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { recovered <- recover() }()
+			c.Do(context.Background(), "key", fn)
+		}()
+	}
+
+	for c.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // let every caller join
+	close(release)
+	wg.Wait()
+	close(recovered)
+
+	for v := range recovered {
+		if err, ok := v.(error); !ok || !errors.Is(err, errTest) {
+			t.Fatalf("Expected every caller to panic with %v, got %v", errTest, v)
+		}
+	}
+	if n := c.InFlight(); n != 0 {
+		t.Fatalf("Expected no calls in flight, got %d", n)
+	}
+}
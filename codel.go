@@ -0,0 +1,131 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLoadShed is returned when a request is shed instead of being queued
+// any longer.
+var ErrLoadShed = errors.New("request shed due to overload")
+
+// CoDelQueue limits concurrent executions like a Bulkhead, but manages its
+// wait queue with controlled delay (CoDel): while the queue drains quickly
+// a request may wait up to interval for a slot, and once the minimum time
+// spent waiting has stayed above target for a whole interval the queue is
+// considered standing and requests are shed after waiting only target.
+// This keeps latency bounded during overload instead of letting a long
+// queue build up behind a dependency that cannot keep up.
+//
+// The queue honors the Priority carried by the context: Critical requests
+// may always wait up to interval, while BestEffort requests wait at most
+// target and are shed without waiting while the queue is overloaded.
+type CoDelQueue struct {
+	sem      chan struct{} // Holds one token per running execution
+	target   time.Duration // Acceptable queueing delay
+	interval time.Duration // Window over which the minimum delay is measured
+
+	mu            sync.Mutex // Protects the fields below
+	intervalStart time.Time
+	minDelay      time.Duration // Smallest delay seen in the current interval
+	overloaded    bool          // The previous interval never dipped below target
+}
+
+// NewCoDelQueue creates a CoDelQueue allowing maxConcurrent executions at
+// once. Typical values are a target of 5ms and an interval of 100ms.
+func NewCoDelQueue(maxConcurrent int, target, interval time.Duration) *CoDelQueue {
+	return &CoDelQueue{
+		sem:           make(chan struct{}, maxConcurrent),
+		target:        target,
+		interval:      interval,
+		intervalStart: time.Now(),
+		minDelay:      -1,
+	}
+}
+
+// Do executes fn once a slot is available, or returns ErrLoadShed if the
+// request waited longer than the queue currently allows.
+func (q *CoDelQueue) Do(ctx context.Context, fn WorkFuncCtx) error {
+	if err := q.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() { <-q.sem }()
+
+	return fn(ctx)
+}
+
+// Overloaded reports whether the queue is currently shedding at target.
+func (q *CoDelQueue) Overloaded() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.overloaded
+}
+
+func (q *CoDelQueue) acquire(ctx context.Context) error {
+	select {
+	case q.sem <- struct{}{}:
+		q.observe(0)
+		return nil
+	default:
+		// saturated, wait in the queue.
+	}
+
+	wait := q.maxWait(PriorityFromContext(ctx))
+	if wait <= 0 {
+		return rejectWith(rejectedLoadShed)
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case q.sem <- struct{}{}:
+		q.observe(time.Since(start))
+		return nil
+	case <-timer.C:
+		q.observe(time.Since(start))
+		return rejectWith(rejectedLoadShed)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// maxWait returns how long a newly queued request of priority p may wait.
+func (q *CoDelQueue) maxWait(p Priority) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case p == Critical:
+		return q.interval
+	case p == BestEffort && q.overloaded:
+		return 0
+	case p == BestEffort || q.overloaded:
+		return q.target
+	}
+
+	return q.interval
+}
+
+// observe records the queueing delay of a request and, at the end of each
+// interval, decides whether the queue is standing.
+func (q *CoDelQueue) observe(delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(q.intervalStart) >= q.interval {
+		q.overloaded = q.minDelay > q.target
+		q.intervalStart = now
+		q.minDelay = delay
+		return
+	}
+
+	if q.minDelay < 0 || delay < q.minDelay {
+		q.minDelay = delay
+	}
+}
@@ -0,0 +1,111 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCoDelQueue_WaitsWhenNotOverloaded(t *testing.T) {
+	t.Parallel()
+	q := NewCoDelQueue(1, 5*time.Millisecond, 200*time.Millisecond)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = q.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	time.AfterFunc(30*time.Millisecond, func() { close(release) })
+
+	// Waiting 30ms exceeds target but not interval, and the queue is not
+	// yet known to be standing.
+	if err := q.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected queued call to run, got %v", err)
+	}
+}
+
+func TestCoDelQueue_ShedsWhenStanding(t *testing.T) {
+	t.Parallel()
+	q := NewCoDelQueue(1, 5*time.Millisecond, 20*time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go func() {
+		_ = q.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// The blocked slot means every queued call waits a full interval.
+	for range 2 {
+		if err := q.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrLoadShed) {
+			t.Fatalf("Expected ErrLoadShed, got %v", err)
+		}
+	}
+	if !q.Overloaded() {
+		t.Fatal("Expected queue to be considered overloaded")
+	}
+
+	// Once overloaded, requests are shed after only the target delay.
+	start := time.Now()
+	_ = q.Do(context.Background(), func(context.Context) error { return nil })
+	if elapsed := time.Since(start); elapsed > 15*time.Millisecond {
+		t.Fatalf("Expected shedding after ~5ms, waited %v", elapsed)
+	}
+}
+
+func TestCoDelQueue_RecoversWhenDrained(t *testing.T) {
+	t.Parallel()
+	q := NewCoDelQueue(1, 5*time.Millisecond, 10*time.Millisecond)
+	q.overloaded = true
+
+	// Uncontended calls have zero delay; after an interval the queue
+	// leaves the overloaded state.
+	_ = q.Do(context.Background(), func(context.Context) error { return nil })
+	time.Sleep(15 * time.Millisecond)
+	_ = q.Do(context.Background(), func(context.Context) error { return nil })
+
+	if q.Overloaded() {
+		t.Fatal("Expected queue to recover once delays dropped below target")
+	}
+}
+
+func TestCoDelQueue_Priority(t *testing.T) {
+	t.Parallel()
+	q := NewCoDelQueue(1, 5*time.Millisecond, 50*time.Millisecond)
+	q.overloaded = true
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = q.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	time.AfterFunc(20*time.Millisecond, func() { close(release) })
+
+	// Best-effort traffic is shed at once while overloaded...
+	bestEffort := WithPriority(context.Background(), BestEffort)
+	if err := q.Do(bestEffort, func(context.Context) error { return nil }); !errors.Is(err, ErrLoadShed) {
+		t.Fatalf("Expected ErrLoadShed for best-effort call, got %v", err)
+	}
+
+	// ...while critical traffic still waits out the 20ms until the slot frees.
+	critical := WithPriority(context.Background(), Critical)
+	if err := q.Do(critical, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected critical call to be admitted, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/dadanrm/failover/connectfailover
+
+go 1.25.0
+
+require (
+	connectrpc.com/connect v1.21.0
+	github.com/dadanrm/failover v0.0.0
+)
+
+require google.golang.org/protobuf v1.36.12
+
+replace github.com/dadanrm/failover => ../
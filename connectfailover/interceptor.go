@@ -0,0 +1,257 @@
+// Package connectfailover integrates the failover primitives with
+// connect-go clients.
+package connectfailover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/dadanrm/failover"
+)
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// errTimeout is the cause of the deadline set with WithTimeout. It is both
+// failover.ErrTimeout and context.DeadlineExceeded, so a call cut off by it
+// still fails with CodeDeadlineExceeded.
+var errTimeout = fmt.Errorf("%w: %w", failover.ErrTimeout, context.DeadlineExceeded)
+
+// Interceptor guards every procedure a client calls with its own circuit
+// breaker and retries unary calls failing with a retryable code. Install it
+// with connect.WithInterceptors:
+//
+//	client := greetv1connect.NewGreetServiceClient(http.DefaultClient, url,
+//		connect.WithInterceptors(connectfailover.NewInterceptor()))
+//
+// Only outcomes that say something about the server's health count against
+// a breaker: unavailable, resource_exhausted, deadline_exceeded, internal
+// and unknown. A call rejected by an open breaker fails with unavailable,
+// and its error also matches failover.ErrCircuitOpen. A server pushback in
+// the grpc-retry-pushback-ms trailer delays the next attempt accordingly.
+//
+// The deadline of a call, including one set with WithTimeout, covers all
+// its attempts and is sent along to the server by every protocol connect
+// speaks. Streams and handlers are passed through unchanged.
+type Interceptor struct {
+	attempts   int // Calls per request, including the first
+	backoff    failover.Backoff
+	retryable  []connect.Code                          // Codes worth another attempt
+	newBreaker func(procedure string) failover.Breaker // Nil disables breakers
+	timeout    time.Duration                           // Deadline for calls without one, zero for none
+
+	mu       sync.Mutex
+	breakers map[string]failover.Breaker // By procedure
+}
+
+// Option configures optional Interceptor behavior.
+type Option func(*Interceptor)
+
+// WithRetry makes up to attempts calls per request, waiting per backoff
+// between them. The default is 3 attempts with a jittered exponential
+// backoff from 100ms to 2s. Use 1 attempt to disable retries.
+func WithRetry(attempts int, backoff failover.Backoff) Option {
+	return func(i *Interceptor) {
+		i.attempts = max(attempts, 1)
+		i.backoff = backoff
+	}
+}
+
+// WithRetryableCodes sets the codes that are retried. The default is
+// unavailable and resource_exhausted.
+func WithRetryableCodes(codes ...connect.Code) Option {
+	return func(i *Interceptor) {
+		i.retryable = codes
+	}
+}
+
+// WithBreakers creates the breaker for each procedure with fn, called the
+// first time the procedure is invoked. The default opens after 5
+// consecutive failures for 30 seconds. A nil fn disables the breakers.
+func WithBreakers(fn func(procedure string) failover.Breaker) Option {
+	return func(i *Interceptor) {
+		i.newBreaker = fn
+	}
+}
+
+// WithTimeout sets the deadline, covering all attempts, of calls whose
+// context has none, canceling them with a cause that matches
+// failover.ErrTimeout. Calls that already carry a deadline keep it.
+func WithTimeout(d time.Duration) Option {
+	return func(i *Interceptor) {
+		i.timeout = d
+	}
+}
+
+// NewInterceptor creates an Interceptor.
+func NewInterceptor(opts ...Option) *Interceptor {
+	i := &Interceptor{
+		attempts: 3,
+		backoff: failover.ExponentialBackoff{
+			Initial: 100 * time.Millisecond,
+			Max:     2 * time.Second,
+			Jitter:  0.2,
+		},
+		retryable: []connect.Code{connect.CodeUnavailable, connect.CodeResourceExhausted},
+		newBreaker: func(string) failover.Breaker {
+			return failover.NewCircuitBreaker(5, 1, 30*time.Second)
+		},
+		breakers: make(map[string]failover.Breaker),
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if !req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		if _, ok := ctx.Deadline(); !ok && i.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeoutCause(ctx, i.timeout, errTimeout)
+			defer cancel()
+		}
+
+		procedure := req.Spec().Procedure
+		breaker := i.breaker(procedure)
+		retry := failover.NewRetryPolicy(i.attempts, 0, failover.WithBackoff(i.backoff), failover.WithRetryIf(i.shouldRetry))
+
+		var resp connect.AnyResponse
+		err := retry.Do(ctx, func(ctx context.Context) error {
+			var callErr error
+			err := breaker.Execute(func() error {
+				resp, callErr = next(ctx, req)
+				if ctx.Err() != nil || !serverFailure(callErr) {
+					return nil
+				}
+				return callErr
+			})
+
+			if errors.Is(err, failover.ErrCircuitOpen) {
+				return connect.NewError(connect.CodeUnavailable, fmt.Errorf("%s: %w", procedure, err))
+			}
+			if delay, ok := pushback(callErr); ok {
+				return &failover.RetryAfterError{Err: callErr, Delay: delay}
+			}
+			return callErr
+		})
+
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) && connect.CodeOf(err) == connect.CodeUnknown {
+			code := connect.CodeCanceled
+			if errors.Is(err, context.DeadlineExceeded) {
+				code = connect.CodeDeadlineExceeded
+			}
+			return nil, connect.NewError(code, err)
+		}
+
+		var budget *failover.BudgetExhaustedError
+		if errors.As(err, &budget) {
+			return nil, connect.NewError(connect.CodeDeadlineExceeded, err)
+		}
+		var exhausted *failover.MaxAttemptsError
+		if errors.As(err, &exhausted) {
+			err = exhausted.LastErr
+		}
+		var ra *failover.RetryAfterError
+		if errors.As(err, &ra) {
+			return nil, ra.Err
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor, leaving streams
+// unchanged.
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor, leaving handlers
+// unchanged.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// breaker returns the breaker for procedure, creating it on first use.
+func (i *Interceptor) breaker(procedure string) failover.Breaker {
+	if i.newBreaker == nil {
+		return failover.NoopBreaker{}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	b, ok := i.breakers[procedure]
+	if !ok {
+		b = i.newBreaker(procedure)
+		i.breakers[procedure] = b
+	}
+
+	return b
+}
+
+// shouldRetry reports whether an attempt's error is worth another attempt.
+func (i *Interceptor) shouldRetry(err error) bool {
+	if errors.Is(err, failover.ErrCircuitOpen) {
+		return false
+	}
+
+	// A negative pushback is the server asking not to retry at all.
+	var ra *failover.RetryAfterError
+	if errors.As(err, &ra) && ra.Delay < 0 {
+		return false
+	}
+
+	return slices.Contains(i.retryable, connect.CodeOf(err))
+}
+
+// serverFailure reports whether err counts against a breaker.
+func serverFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeResourceExhausted, connect.CodeDeadlineExceeded, connect.CodeInternal, connect.CodeUnknown:
+		return true
+	}
+
+	return false
+}
+
+// pushback returns the delay a server asked for in the
+// grpc-retry-pushback-ms trailer of err, negative if it asked for no retry.
+func pushback(err error) (time.Duration, bool) {
+	var ce *connect.Error
+	if !errors.As(err, &ce) {
+		return 0, false
+	}
+
+	value := ce.Meta().Get("Grpc-Retry-Pushback-Ms")
+	if value == "" {
+		return 0, false
+	}
+
+	ms, perr := strconv.ParseInt(value, 10, 64)
+	if perr != nil || ms < 0 {
+		return -1, true
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
@@ -0,0 +1,166 @@
+package connectfailover
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/dadanrm/failover"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const procedure = "/test.v1.EchoService/Echo"
+
+// flakyEcho is an echo handler failing with the next of its errors until
+// they run out, counting calls.
+type flakyEcho struct {
+	errs     []*connect.Error
+	calls    atomic.Int32
+	deadline atomic.Bool // Whether a call arrived with a deadline
+}
+
+func (h *flakyEcho) echo(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+	n := int(h.calls.Add(1))
+	if _, ok := ctx.Deadline(); ok {
+		h.deadline.Store(true)
+	}
+	if n <= len(h.errs) {
+		return nil, h.errs[n-1]
+	}
+	return connect.NewResponse(req.Msg), nil
+}
+
+// echoClient starts h and returns a client calling it through an
+// Interceptor created with opts.
+func echoClient(t *testing.T, h *flakyEcho, opts ...Option) *connect.Client[wrapperspb.StringValue, wrapperspb.StringValue] {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure, h.echo))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+		srv.Client(), srv.URL+procedure, connect.WithInterceptors(NewInterceptor(opts...)))
+}
+
+func call(client *connect.Client[wrapperspb.StringValue, wrapperspb.StringValue]) error {
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+	return err
+}
+
+func unavailable() *connect.Error {
+	return connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+}
+
+func TestInterceptor_RetriesUnavailable(t *testing.T) {
+	t.Parallel()
+	h := &flakyEcho{errs: []*connect.Error{unavailable(), unavailable()}}
+	client := echoClient(t, h, WithRetry(3, failover.ConstantBackoff(time.Millisecond)))
+
+	resp, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if resp.Msg.GetValue() != "hello" {
+		t.Fatalf("Expected the echo hello, got %q", resp.Msg.GetValue())
+	}
+	if n := h.calls.Load(); n != 3 {
+		t.Fatalf("Expected 3 calls, got %d", n)
+	}
+}
+
+func TestInterceptor_NoRetryForOtherCodes(t *testing.T) {
+	t.Parallel()
+	h := &flakyEcho{errs: []*connect.Error{connect.NewError(connect.CodeInvalidArgument, errors.New("bad"))}}
+	client := echoClient(t, h, WithRetry(3, failover.ConstantBackoff(time.Millisecond)))
+
+	err := call(client)
+
+	if code := connect.CodeOf(err); code != connect.CodeInvalidArgument {
+		t.Fatalf("Expected %v, got %v", connect.CodeInvalidArgument, code)
+	}
+	if n := h.calls.Load(); n != 1 {
+		t.Fatalf("Expected 1 call, got %d", n)
+	}
+}
+
+func TestInterceptor_Breaker(t *testing.T) {
+	t.Parallel()
+	h := &flakyEcho{errs: []*connect.Error{unavailable(), unavailable(), unavailable()}}
+	client := echoClient(t, h,
+		WithRetry(1, failover.ConstantBackoff(time.Millisecond)),
+		WithBreakers(func(string) failover.Breaker { return failover.NewCircuitBreaker(2, 1, time.Minute) }))
+
+	call(client)
+	call(client)
+	err := call(client)
+
+	if !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected %v, got %v", failover.ErrCircuitOpen, err)
+	}
+	if code := connect.CodeOf(err); code != connect.CodeUnavailable {
+		t.Fatalf("Expected %v, got %v", connect.CodeUnavailable, code)
+	}
+	if n := h.calls.Load(); n != 2 {
+		t.Fatalf("Expected the open breaker to stop calls after 2, got %d", n)
+	}
+}
+
+func TestInterceptor_Pushback(t *testing.T) {
+	t.Parallel()
+	pushed := unavailable()
+	pushed.Meta().Set("Grpc-Retry-Pushback-Ms", "50")
+	h := &flakyEcho{errs: []*connect.Error{pushed}}
+	client := echoClient(t, h, WithRetry(2, failover.ConstantBackoff(time.Millisecond)))
+
+	start := time.Now()
+	err := call(client)
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Expected to wait the 50ms pushback, waited %v", elapsed)
+	}
+}
+
+func TestInterceptor_Timeout(t *testing.T) {
+	t.Parallel()
+	h := &flakyEcho{}
+	client := echoClient(t, h, WithTimeout(time.Second))
+
+	if err := call(client); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !h.deadline.Load() {
+		t.Fatal("Expected the deadline to reach the server")
+	}
+}
+
+func TestInterceptor_TimeoutCause(t *testing.T) {
+	t.Parallel()
+	var cause error
+	record := connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			<-ctx.Done()
+			cause = context.Cause(ctx)
+			return nil, connect.NewError(connect.CodeDeadlineExceeded, ctx.Err())
+		}
+	})
+	client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+		http.DefaultClient, "http://example.invalid"+procedure,
+		connect.WithInterceptors(NewInterceptor(WithRetry(1, failover.ConstantBackoff(time.Millisecond)), WithTimeout(10*time.Millisecond)), record))
+
+	if err := call(client); connect.CodeOf(err) != connect.CodeDeadlineExceeded {
+		t.Fatalf("Expected CodeDeadlineExceeded, got %v", err)
+	}
+	if !errors.Is(cause, failover.ErrTimeout) || !errors.Is(cause, context.DeadlineExceeded) {
+		t.Fatalf("Expected a cause matching ErrTimeout, got %v", cause)
+	}
+}
@@ -0,0 +1,241 @@
+// Package consulfailover discovers endpoints from the Consul service
+// catalog.
+//
+// It uses Consul's HTTP API directly, so importing it adds no
+// dependencies. Only instances passing their health checks are returned,
+// and changes are picked up with blocking queries rather than polling.
+package consulfailover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ failover.EndpointProvider = (*Provider)(nil)
+
+// Provider is a failover.EndpointProvider for the healthy instances of a
+// Consul service.
+type Provider struct {
+	service    string
+	address    string // Consul agent base URL
+	token      string
+	tag        string
+	datacenter string
+	wait       time.Duration // How long a blocking query waits for a change
+	retry      time.Duration // Delay before querying again after a failure
+	client     *http.Client
+}
+
+// Option configures optional Provider behavior.
+type Option func(*Provider)
+
+// WithAddress sets the base URL of the Consul agent. The default is
+// http://127.0.0.1:8500.
+func WithAddress(address string) Option {
+	return func(p *Provider) {
+		p.address = strings.TrimSuffix(address, "/")
+	}
+}
+
+// WithToken sets the ACL token sent with every query.
+func WithToken(token string) Option {
+	return func(p *Provider) {
+		p.token = token
+	}
+}
+
+// WithTag only returns instances registered with tag.
+func WithTag(tag string) Option {
+	return func(p *Provider) {
+		p.tag = tag
+	}
+}
+
+// WithDatacenter queries dc instead of the agent's datacenter.
+func WithDatacenter(dc string) Option {
+	return func(p *Provider) {
+		p.datacenter = dc
+	}
+}
+
+// WithHTTPClient sets the client used to reach Consul.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithWaitTime sets how long a blocking query waits for a change before
+// returning. The default is five minutes.
+func WithWaitTime(d time.Duration) Option {
+	return func(p *Provider) {
+		p.wait = d
+	}
+}
+
+// WithRetryDelay sets how long to wait before querying again after a
+// failure. The default is one second.
+func WithRetryDelay(d time.Duration) Option {
+	return func(p *Provider) {
+		p.retry = d
+	}
+}
+
+// NewProvider creates a Provider for service.
+func NewProvider(service string, opts ...Option) *Provider {
+	p := &Provider{
+		service: service,
+		address: "http://127.0.0.1:8500",
+		wait:    5 * time.Minute,
+		retry:   time.Second,
+		client:  http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// serviceEntry holds the fields of a /v1/health/service entry the provider
+// uses.
+type serviceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		ID      string
+		Address string
+		Port    int
+		Weights struct {
+			Passing int
+		}
+	}
+}
+
+// List returns the passing instances of the service.
+func (p *Provider) List(ctx context.Context) ([]*failover.Endpoint, error) {
+	endpoints, _, err := p.query(ctx, 0)
+	return endpoints, err
+}
+
+// Watch sends the passing instances of the service every time they change,
+// using blocking queries. It fails if the first query does.
+func (p *Provider) Watch(ctx context.Context) (<-chan []*failover.Endpoint, error) {
+	endpoints, index, err := p.query(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []*failover.Endpoint, 1)
+	ch <- endpoints
+
+	go func() {
+		defer close(ch)
+
+		for {
+			next, nextIndex, err := p.query(ctx, index)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				select {
+				case <-time.After(p.retry):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			// Consul may go back in index after a restart; start over then.
+			if nextIndex < index {
+				nextIndex = 0
+			}
+			index = nextIndex
+
+			if slices.EqualFunc(endpoints, next, func(a, b *failover.Endpoint) bool {
+				return a.Name == b.Name && a.Address == b.Address && a.Weight == b.Weight
+			}) {
+				continue
+			}
+			endpoints = next
+
+			select {
+			case ch <- next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// query fetches the passing instances, blocking until the catalog moves
+// past index when it is not zero.
+func (p *Provider) query(ctx context.Context, index uint64) ([]*failover.Endpoint, uint64, error) {
+	query := url.Values{"passing": {"true"}}
+	if p.tag != "" {
+		query.Set("tag", p.tag)
+	}
+	if p.datacenter != "" {
+		query.Set("dc", p.datacenter)
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", strconv.FormatInt(p.wait.Milliseconds(), 10)+"ms")
+	}
+
+	u := p.address + "/v1/health/service/" + url.PathEscape(p.service) + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.token != "" {
+		req.Header.Set("X-Consul-Token", p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consulfailover: query %s: %s", p.service, resp.Status)
+	}
+
+	var entries []serviceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, err
+	}
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	endpoints := make([]*failover.Endpoint, len(entries))
+	for i, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+
+		endpoints[i] = &failover.Endpoint{
+			Name:    e.Service.ID,
+			Address: net.JoinHostPort(host, strconv.Itoa(e.Service.Port)),
+			Weight:  e.Service.Weights.Passing,
+		}
+	}
+	slices.SortFunc(endpoints, func(a, b *failover.Endpoint) int { return strings.Compare(a.Name, b.Name) })
+
+	return endpoints, next, nil
+}
@@ -0,0 +1,104 @@
+package consulfailover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProvider_List(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/v1/health/service/api" || q.Get("passing") != "true" || q.Get("tag") != "v2" ||
+			q.Get("dc") != "eu" || r.Header.Get("X-Consul-Token") != "secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("X-Consul-Index", "7")
+		fmt.Fprint(w, `[
+			{"Node":{"Address":"10.0.0.2"},"Service":{"ID":"api-2","Address":"","Port":8080,"Weights":{"Passing":1}}},
+			{"Node":{"Address":"10.0.0.9"},"Service":{"ID":"api-1","Address":"10.0.1.1","Port":8080,"Weights":{"Passing":3}}}
+		]`)
+	}))
+	defer srv.Close()
+
+	p := NewProvider("api", WithAddress(srv.URL), WithToken("secret"), WithTag("v2"), WithDatacenter("eu"))
+	endpoints, err := p.List(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if len(endpoints) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %d", len(endpoints))
+	}
+	if ep := endpoints[0]; ep.Name != "api-1" || ep.Address != "10.0.1.1:8080" || ep.Weight != 3 {
+		t.Fatalf("Expected api-1 at its service address, got %+v", ep)
+	}
+	if ep := endpoints[1]; ep.Address != "10.0.0.2:8080" {
+		t.Fatalf("Expected api-2 at its node address, got %+v", ep)
+	}
+}
+
+func TestProvider_Watch(t *testing.T) {
+	t.Parallel()
+	var queries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := queries.Add(1)
+		index := r.URL.Query().Get("index")
+
+		switch {
+		case n == 1 && index == "":
+			w.Header().Set("X-Consul-Index", "1")
+			fmt.Fprint(w, `[{"Service":{"ID":"a","Address":"10.0.0.1","Port":80}}]`)
+		case n == 2 && index == "1":
+			// Blocking query timed out without a change.
+			w.Header().Set("X-Consul-Index", "1")
+			fmt.Fprint(w, `[{"Service":{"ID":"a","Address":"10.0.0.1","Port":80}}]`)
+		case n == 3 && index == "1":
+			w.Header().Set("X-Consul-Index", "2")
+			fmt.Fprint(w, `[{"Service":{"ID":"a","Address":"10.0.0.1","Port":80}},{"Service":{"ID":"b","Address":"10.0.0.2","Port":80}}]`)
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	p := NewProvider("api", WithAddress(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if got := <-updates; len(got) != 1 {
+		t.Fatalf("Expected 1 endpoint, got %d", len(got))
+	}
+
+	select {
+	case got := <-updates:
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 endpoints after the change, got %d", len(got))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the change to be sent")
+	}
+}
+
+func TestProvider_Error(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no leader", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if _, err := NewProvider("api", WithAddress(srv.URL)).Watch(context.Background()); err == nil {
+		t.Fatal("Expected an error from a failing agent")
+	}
+}
@@ -0,0 +1,134 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Consumer wraps the handler of a message consumer with per-message
+// retries, whatever the broker. A message that still fails after its
+// attempts are used up is published to a dead letter destination rather
+// than redelivered forever.
+//
+// Attempts can span deliveries: with WithAttemptCounter the count is read
+// from and written to the message, typically a header, so that a message
+// the broker redelivers after a crash or a nack carries on from where it
+// was. With WithDeliveryAttempts, only that many attempts are made per
+// delivery before the error is returned for the broker to redeliver later.
+type Consumer[M any] struct {
+	handler     func(ctx context.Context, msg M) error
+	maxAttempts int // Attempts in total, across deliveries
+	perDelivery int // Attempts per delivery, zero for all remaining
+	backoff     Backoff
+
+	getAttempts func(msg M) int
+	setAttempts func(msg M, attempts int)
+	deadLetter  func(ctx context.Context, msg M, err error) error
+}
+
+// ConsumerOption configures optional Consumer behavior.
+type ConsumerOption[M any] func(*Consumer[M])
+
+// WithAttemptCounter tracks a message's attempts across deliveries: get
+// returns the attempts already made, zero for a new message, and set
+// records the count before the message goes back to the broker or to the
+// dead letter destination.
+func WithAttemptCounter[M any](get func(msg M) int, set func(msg M, attempts int)) ConsumerOption[M] {
+	return func(c *Consumer[M]) {
+		c.getAttempts = get
+		c.setAttempts = set
+	}
+}
+
+// WithDeadLetterFunc publishes messages that used up their attempts with
+// fn, along with the last error. The message is then considered handled,
+// unless fn fails. Without it, the last error is returned.
+func WithDeadLetterFunc[M any](fn func(ctx context.Context, msg M, err error) error) ConsumerOption[M] {
+	return func(c *Consumer[M]) {
+		c.deadLetter = fn
+	}
+}
+
+// WithDeliveryAttempts makes at most n attempts per delivery, returning
+// the error once they fail so that the broker redelivers the message
+// later. It needs WithAttemptCounter to keep count across deliveries.
+func WithDeliveryAttempts[M any](n int) ConsumerOption[M] {
+	return func(c *Consumer[M]) {
+		c.perDelivery = n
+	}
+}
+
+// WithConsumerBackoff sets the wait between attempts within a delivery.
+// The default doubles from 100ms, with jitter.
+func WithConsumerBackoff[M any](b Backoff) ConsumerOption[M] {
+	return func(c *Consumer[M]) {
+		c.backoff = b
+	}
+}
+
+// NewConsumer creates a Consumer giving each message up to maxAttempts
+// calls of handler.
+func NewConsumer[M any](handler func(ctx context.Context, msg M) error, maxAttempts int, opts ...ConsumerOption[M]) *Consumer[M] {
+	c := &Consumer[M]{
+		handler:     handler,
+		maxAttempts: maxAttempts,
+		backoff:     ExponentialBackoff{Initial: 100 * time.Millisecond, Jitter: 0.2},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Handle processes one delivery of msg. It returns nil once the message is
+// handled or dead-lettered, and an error when the broker should deliver it
+// again.
+func (c *Consumer[M]) Handle(ctx context.Context, msg M) error {
+	attempt := 0
+	if c.getAttempts != nil {
+		attempt = c.getAttempts(msg)
+	}
+
+	for tries := 1; ; tries++ {
+		attempt++
+		err := c.handler(ctx, msg)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= c.maxAttempts {
+			c.record(msg, attempt)
+			if c.deadLetter == nil {
+				return err
+			}
+			if dlErr := c.deadLetter(ctx, msg, err); dlErr != nil {
+				return fmt.Errorf("dead-lettering message: %w (after %w)", dlErr, err)
+			}
+			return nil
+		}
+
+		if c.perDelivery > 0 && tries >= c.perDelivery {
+			c.record(msg, attempt)
+			return err
+		}
+
+		timer := time.NewTimer(retryDelay(c.backoff, tries, err))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			c.record(msg, attempt)
+			return ctx.Err()
+		}
+	}
+}
+
+// record stores the attempt count on msg, if tracked.
+func (c *Consumer[M]) record(msg M, attempts int) {
+	if c.setAttempts != nil {
+		c.setAttempts(msg, attempts)
+	}
+}
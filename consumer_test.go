@@ -0,0 +1,117 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testMessage is a broker message with headers.
+type testMessage struct {
+	body    string
+	headers map[string]string
+}
+
+func attemptsHeader(msg *testMessage) int {
+	n, _ := strconv.Atoi(msg.headers["x-attempts"])
+	return n
+}
+
+func setAttemptsHeader(msg *testMessage, n int) {
+	msg.headers["x-attempts"] = strconv.Itoa(n)
+}
+
+// failingHandler fails the first failures calls.
+func failingHandler(calls *int, failures int) func(context.Context, *testMessage) error {
+	return func(context.Context, *testMessage) error {
+		*calls++
+		if *calls <= failures {
+			return errTest
+		}
+		return nil
+	}
+}
+
+func TestConsumer_RetriesWithinDelivery(t *testing.T) {
+	t.Parallel()
+	var calls int
+	c := NewConsumer(failingHandler(&calls, 2), 3, WithConsumerBackoff[*testMessage](ConstantBackoff(time.Millisecond)))
+
+	if err := c.Handle(context.Background(), &testMessage{headers: map[string]string{}}); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestConsumer_DeadLetter(t *testing.T) {
+	t.Parallel()
+	var calls int
+	var dead []*testMessage
+	c := NewConsumer(failingHandler(&calls, 10), 2,
+		WithConsumerBackoff[*testMessage](ConstantBackoff(time.Millisecond)),
+		WithAttemptCounter(attemptsHeader, setAttemptsHeader),
+		WithDeadLetterFunc(func(_ context.Context, msg *testMessage, err error) error {
+			if !errors.Is(err, errTest) {
+				t.Errorf("Expected the last error %v, got %v", errTest, err)
+			}
+			dead = append(dead, msg)
+			return nil
+		}),
+	)
+
+	msg := &testMessage{body: "order", headers: map[string]string{}}
+	if err := c.Handle(context.Background(), msg); err != nil {
+		t.Fatalf("Expected the dead-lettered message to count as handled, got %v", err)
+	}
+	if len(dead) != 1 || dead[0].headers["x-attempts"] != "2" {
+		t.Fatalf("Expected the message dead-lettered after 2 attempts, got %v", dead)
+	}
+}
+
+func TestConsumer_AttemptsAcrossDeliveries(t *testing.T) {
+	t.Parallel()
+	var calls int
+	var dead int
+	c := NewConsumer(failingHandler(&calls, 10), 3,
+		WithAttemptCounter(attemptsHeader, setAttemptsHeader),
+		WithDeliveryAttempts[*testMessage](1),
+		WithDeadLetterFunc(func(context.Context, *testMessage, error) error {
+			dead++
+			return nil
+		}),
+	)
+	msg := &testMessage{headers: map[string]string{}}
+	ctx := context.Background()
+
+	// Each delivery makes one attempt and hands the message back.
+	for i := 1; i <= 2; i++ {
+		if err := c.Handle(ctx, msg); !errors.Is(err, errTest) {
+			t.Fatalf("Expected delivery %d to be returned for redelivery, got %v", i, err)
+		}
+		if got := attemptsHeader(msg); got != i {
+			t.Fatalf("Expected %d attempts recorded, got %d", i, got)
+		}
+	}
+
+	if err := c.Handle(ctx, msg); err != nil || dead != 1 {
+		t.Fatalf("Expected the third delivery to be dead-lettered, got %v (%d)", err, dead)
+	}
+}
+
+func TestConsumer_DeadLetterFailure(t *testing.T) {
+	t.Parallel()
+	errPublish := errors.New("publish failed")
+	var calls int
+	c := NewConsumer(failingHandler(&calls, 10), 1,
+		WithDeadLetterFunc(func(context.Context, *testMessage, error) error { return errPublish }),
+	)
+
+	err := c.Handle(context.Background(), &testMessage{headers: map[string]string{}})
+	if !errors.Is(err, errPublish) || !errors.Is(err, errTest) {
+		t.Fatalf("Expected both the publish and handler errors, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCooldown is returned when a call is rejected because the previous
+// execution started less than the cooldown interval ago.
+var ErrCooldown = errors.New("operation is cooling down")
+
+// CooldownMode selects what a Cooldown does with calls made during the
+// interval after an execution.
+type CooldownMode int
+
+const (
+	// CooldownLeading executes the first call immediately and rejects
+	// calls made during the following interval with ErrCooldown.
+	CooldownLeading CooldownMode = iota
+	// CooldownTrailing defers calls made during the interval to a single
+	// execution at its end, whose result they all receive.
+	CooldownTrailing
+)
+
+// cooldownRun is an execution shared by the callers that joined it.
+type cooldownRun struct {
+	done chan struct{} // Closed once err is set
+	err  error
+}
+
+// Cooldown enforces a minimum interval between executions of an expensive
+// operation, such as a cache rebuild or a reconnection. Callers arriving
+// while an execution is in flight or scheduled share its result.
+type Cooldown struct {
+	mu sync.Mutex // Protects the fields below
+
+	interval time.Duration
+	mode     CooldownMode
+
+	lastStart time.Time
+	current   *cooldownRun // In flight or scheduled execution, if any
+}
+
+// NewCooldown creates a Cooldown allowing one execution per interval.
+func NewCooldown(interval time.Duration, mode CooldownMode) *Cooldown {
+	return &Cooldown{interval: interval, mode: mode}
+}
+
+// Do executes fn, joins the execution already in flight or scheduled, or
+// returns ErrCooldown, depending on the time since the last execution and
+// the mode. The execution runs with the context values of the caller that
+// started it, but not its cancellation.
+func (c *Cooldown) Do(ctx context.Context, fn WorkFuncCtx) error {
+	c.mu.Lock()
+	run := c.current
+	if run == nil {
+		delay := c.interval - time.Since(c.lastStart)
+		if delay > 0 && c.mode == CooldownLeading {
+			c.mu.Unlock()
+			return rejectWith(rejectedCooldown)
+		}
+
+		run = &cooldownRun{done: make(chan struct{})}
+		c.current = run
+		go c.execute(context.WithoutCancel(ctx), run, fn, delay)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-run.done:
+		return run.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Cooldown) execute(ctx context.Context, run *cooldownRun, fn WorkFuncCtx, delay time.Duration) {
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	c.mu.Lock()
+	c.lastStart = time.Now()
+	c.mu.Unlock()
+
+	run.err = fn(ctx)
+
+	c.mu.Lock()
+	c.current = nil
+	c.mu.Unlock()
+	close(run.done)
+}
@@ -0,0 +1,108 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCooldown_Leading(t *testing.T) {
+	t.Parallel()
+	c := NewCooldown(50*time.Millisecond, CooldownLeading)
+	ctx := context.Background()
+
+	calls := 0
+	fn := func(context.Context) error {
+		calls++
+		return nil
+	}
+
+	if err := c.Do(ctx, fn); err != nil {
+		t.Fatalf("Expected first call to execute, got %v", err)
+	}
+	if err := c.Do(ctx, fn); !errors.Is(err, ErrCooldown) {
+		t.Fatalf("Expected ErrCooldown within the interval, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := c.Do(ctx, fn); err != nil {
+		t.Fatalf("Expected call after the interval to execute, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 executions, got %d", calls)
+	}
+}
+
+func TestCooldown_JoinsInFlight(t *testing.T) {
+	t.Parallel()
+	c := NewCooldown(time.Minute, CooldownLeading)
+
+	var executions atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) error {
+		executions.Add(1)
+		<-release
+		return errTest
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.Do(context.Background(), fn)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	if n := executions.Load(); n != 1 {
+		t.Fatalf("Expected 1 execution, got %d", n)
+	}
+	for err := range errs {
+		if !errors.Is(err, errTest) {
+			t.Fatalf("Expected every caller to get the in-flight result, got %v", err)
+		}
+	}
+}
+
+func TestCooldown_Trailing(t *testing.T) {
+	t.Parallel()
+	c := NewCooldown(30*time.Millisecond, CooldownTrailing)
+	ctx := context.Background()
+
+	var executions atomic.Int32
+	fn := func(context.Context) error {
+		executions.Add(1)
+		return nil
+	}
+
+	_ = c.Do(ctx, fn)
+
+	// Both calls land within the interval and share one deferred execution.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Do(ctx, fn); err != nil {
+				t.Errorf("Expected trailing call to succeed, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Expected trailing execution to wait for the interval, took %v", elapsed)
+	}
+	if n := executions.Load(); n != 2 {
+		t.Fatalf("Expected 2 executions in total, got %d", n)
+	}
+}
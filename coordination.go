@@ -0,0 +1,220 @@
+package failover
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HealthSource reports the status of named targets. HealthChecker and
+// Quorum implement it.
+type HealthSource interface {
+	Status(name string) HealthStatus
+}
+
+var (
+	_ HealthSource     = (*HealthChecker)(nil)
+	_ HealthSource     = (*Quorum)(nil)
+	_ ObservationStore = (*MemoryObservationStore)(nil)
+)
+
+// ObservationStore shares health observations between the instances of a
+// service. Implementations back it with a store every instance can reach,
+// such as etcd or Redis, and must expire observations after their TTL so
+// that an instance that goes away stops voting.
+type ObservationStore interface {
+	// Report records that instance observed target as healthy or not.
+	Report(ctx context.Context, target, instance string, healthy bool, ttl time.Duration) error
+	// Observations returns the unexpired observations of target, by instance.
+	Observations(ctx context.Context, target string) (map[string]bool, error)
+}
+
+// Quorum coordinates failover decisions across instances: each instance
+// reports what its own HealthChecker sees, and a target is only considered
+// Down when enough instances agree. One instance with a bad network path
+// then cannot make the fleet fail over, or flap, on its own.
+//
+// Use it as the health source of a FailoverGroup with WithHealthSource.
+type Quorum struct {
+	store    ObservationStore
+	instance string        // This instance's identity in the store
+	size     int           // Unhealthy votes needed for Down, zero for a majority
+	ttl      time.Duration // How long an observation counts
+	interval time.Duration // How often observations are shared and read
+
+	mu       sync.Mutex // Protects statuses
+	statuses map[string]HealthStatus
+}
+
+// QuorumOption configures optional Quorum behavior.
+type QuorumOption func(*Quorum)
+
+// WithQuorumSize sets how many instances must report a target unhealthy
+// for it to be Down. By default a majority of the reporting instances is
+// needed.
+func WithQuorumSize(n int) QuorumOption {
+	return func(q *Quorum) {
+		q.size = n
+	}
+}
+
+// WithObservationTTL sets how long an observation counts before it must be
+// reported again. The default is three times the interval.
+func WithObservationTTL(d time.Duration) QuorumOption {
+	return func(q *Quorum) {
+		q.ttl = d
+	}
+}
+
+// NewQuorum creates a Quorum for the instance named instance, sharing and
+// reading observations through store every interval.
+func NewQuorum(store ObservationStore, instance string, interval time.Duration, opts ...QuorumOption) *Quorum {
+	q := &Quorum{
+		store:    store,
+		instance: instance,
+		interval: interval,
+		ttl:      3 * interval,
+		statuses: make(map[string]HealthStatus),
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// Status returns the agreed status of a target as of the last Sync:
+// Down when a quorum reports it unhealthy, Up when some instance reports
+// it and no quorum disagrees, Unknown when nobody does.
+func (q *Quorum) Status(name string) HealthStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.statuses[name]
+}
+
+// Run calls Sync with the local checker every interval until ctx is done,
+// then returns ctx.Err(). Store errors are retried on the next interval;
+// the last agreed statuses stay in place meanwhile.
+func (q *Quorum) Run(ctx context.Context, local *HealthChecker) error {
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		q.Sync(ctx, local)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Sync reports the local checker's view of every target it knows to the
+// store and recomputes the agreed statuses. Targets the local checker has
+// no verdict on yet are not reported, but their agreed status is still
+// read.
+func (q *Quorum) Sync(ctx context.Context, local *HealthChecker) error {
+	var firstErr error
+	statuses := make(map[string]HealthStatus)
+
+	for target, status := range local.Statuses() {
+		if status != Unknown {
+			if err := q.store.Report(ctx, target, q.instance, status == Up, q.ttl); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		observations, err := q.store.Observations(ctx, target)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			statuses[target] = q.Status(target) // keep the last verdict
+			continue
+		}
+		statuses[target] = q.agree(observations)
+	}
+
+	q.mu.Lock()
+	q.statuses = statuses
+	q.mu.Unlock()
+
+	return firstErr
+}
+
+// agree turns the observations of a target into a status.
+func (q *Quorum) agree(observations map[string]bool) HealthStatus {
+	if len(observations) == 0 {
+		return Unknown
+	}
+
+	unhealthy := 0
+	for _, healthy := range observations {
+		if !healthy {
+			unhealthy++
+		}
+	}
+
+	needed := q.size
+	if needed <= 0 {
+		needed = len(observations)/2 + 1
+	}
+	if unhealthy >= needed {
+		return Down
+	}
+
+	return Up
+}
+
+// MemoryObservationStore is an ObservationStore kept in memory. It only
+// coordinates Quorums within one process, which is mostly useful in tests.
+type MemoryObservationStore struct {
+	mu           sync.Mutex
+	observations map[string]map[string]observation
+}
+
+type observation struct {
+	healthy bool
+	expires time.Time
+}
+
+// NewMemoryObservationStore creates an empty MemoryObservationStore.
+func NewMemoryObservationStore() *MemoryObservationStore {
+	return &MemoryObservationStore{observations: make(map[string]map[string]observation)}
+}
+
+// Report records the observation.
+func (s *MemoryObservationStore) Report(_ context.Context, target, instance string, healthy bool, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byInstance, ok := s.observations[target]
+	if !ok {
+		byInstance = make(map[string]observation)
+		s.observations[target] = byInstance
+	}
+	byInstance[instance] = observation{healthy: healthy, expires: time.Now().Add(ttl)}
+
+	return nil
+}
+
+// Observations returns the unexpired observations of target.
+func (s *MemoryObservationStore) Observations(_ context.Context, target string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	observations := make(map[string]bool)
+	for instance, o := range s.observations[target] {
+		if now.Before(o.expires) {
+			observations[instance] = o.healthy
+		} else {
+			delete(s.observations[target], instance)
+		}
+	}
+
+	return observations, nil
+}
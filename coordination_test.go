@@ -0,0 +1,100 @@
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// instance is one member of a simulated fleet: its own checker and quorum.
+type instance struct {
+	checker *HealthChecker
+	quorum  *Quorum
+}
+
+// fleet creates n instances sharing store, whose checkers see "primary" as
+// healthy or not according to healthy[i]. Each has run one probe.
+func fleet(t *testing.T, store ObservationStore, healthy []bool, opts ...QuorumOption) []instance {
+	t.Helper()
+	members := make([]instance, len(healthy))
+
+	for i, ok := range healthy {
+		h := NewHealthChecker(time.Hour)
+		h.Add("primary", func(context.Context) error {
+			if ok {
+				return nil
+			}
+			return errTest
+		})
+		h.Check(context.Background(), "primary")
+
+		members[i] = instance{checker: h, quorum: NewQuorum(store, string(rune('a'+i)), time.Hour, opts...)}
+	}
+
+	for range 2 { // the second pass sees every report
+		for _, m := range members {
+			if err := m.quorum.Sync(context.Background(), m.checker); err != nil {
+				t.Fatalf("Expected nil error, got %v", err)
+			}
+		}
+	}
+
+	return members
+}
+
+func TestQuorum_MinorityCannotFailOver(t *testing.T) {
+	t.Parallel()
+	members := fleet(t, NewMemoryObservationStore(), []bool{false, true, true})
+
+	for _, m := range members {
+		if s := m.quorum.Status("primary"); s != Up {
+			t.Fatalf("Expected one bad observer to leave primary %v, got %v", Up, s)
+		}
+	}
+
+	// The instance that sees the primary down still routes to it.
+	g := NewFailoverGroup([]*Endpoint{{Name: "primary"}, {Name: "secondary"}}, WithHealthSource(members[0].quorum))
+	var got []string
+	g.Do(context.Background(), record(&got))
+	if got[0] != "primary" {
+		t.Fatalf("Expected call to stay on primary, got %v", got)
+	}
+}
+
+func TestQuorum_MajorityFailsOver(t *testing.T) {
+	t.Parallel()
+	members := fleet(t, NewMemoryObservationStore(), []bool{false, false, true})
+
+	if s := members[2].quorum.Status("primary"); s != Down {
+		t.Fatalf("Expected a majority to take primary %v, got %v", Down, s)
+	}
+
+	g := NewFailoverGroup([]*Endpoint{{Name: "primary"}, {Name: "secondary"}}, WithHealthSource(members[2].quorum))
+	var got []string
+	g.Do(context.Background(), record(&got))
+	if got[0] != "secondary" {
+		t.Fatalf("Expected call to fail over to secondary, got %v", got)
+	}
+}
+
+func TestQuorum_Size(t *testing.T) {
+	t.Parallel()
+	members := fleet(t, NewMemoryObservationStore(), []bool{false, false, true, true}, WithQuorumSize(2))
+
+	if s := members[0].quorum.Status("primary"); s != Down {
+		t.Fatalf("Expected 2 votes to reach a quorum of 2, got %v", s)
+	}
+}
+
+func TestQuorum_ObservationsExpire(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryObservationStore()
+	store.Report(context.Background(), "primary", "gone", false, 10*time.Millisecond)
+	store.Report(context.Background(), "primary", "gone-too", false, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	members := fleet(t, store, []bool{true})
+	if s := members[0].quorum.Status("primary"); s != Up {
+		t.Fatalf("Expected expired observations not to count, got %v", s)
+	}
+}
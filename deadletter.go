@@ -0,0 +1,84 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// AttemptRecord describes one failed execution of an operation.
+type AttemptRecord struct {
+	Attempt int       `json:"attempt"`
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error"`
+}
+
+// DeadLetterRecord describes an operation that was given up on.
+type DeadLetterRecord struct {
+	Name      string          `json:"name,omitempty"`      // Queue handler name, if any
+	Operation string          `json:"operation,omitempty"` // Operation named with WithOperation, if any
+	Payload   []byte          `json:"payload,omitempty"`
+	Error     string          `json:"error"` // The final error
+	Attempts  []AttemptRecord `json:"attempts"`
+	Time      time.Time       `json:"time"`
+}
+
+// DeadLetter receives operations that exhausted their retries, so they can
+// be inspected and replayed by a human instead of being lost.
+type DeadLetter interface {
+	Send(ctx context.Context, record DeadLetterRecord) error
+}
+
+// deadLetterPayloadKey is the context key for a dead letter payload.
+type deadLetterPayloadKey struct{}
+
+// WithDeadLetterPayload returns a copy of ctx carrying payload, which a
+// RetryPolicy with a dead letter includes in the record it sends when the
+// call is given up on.
+func WithDeadLetterPayload(ctx context.Context, payload []byte) context.Context {
+	return context.WithValue(ctx, deadLetterPayloadKey{}, payload)
+}
+
+func deadLetterPayload(ctx context.Context) []byte {
+	payload, _ := ctx.Value(deadLetterPayloadKey{}).([]byte)
+	return payload
+}
+
+// JSONLDeadLetter is a DeadLetter that appends each record as a line of
+// JSON to a file.
+type JSONLDeadLetter struct {
+	mu   sync.Mutex // Serializes writes
+	file *os.File
+}
+
+// NewJSONLDeadLetter opens path for appending, creating it if needed.
+func NewJSONLDeadLetter(path string) (*JSONLDeadLetter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONLDeadLetter{file: file}, nil
+}
+
+// Send implements DeadLetter.
+func (d *JSONLDeadLetter) Send(_ context.Context, record DeadLetterRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err = d.file.Write(line)
+	return err
+}
+
+// Close closes the underlying file.
+func (d *JSONLDeadLetter) Close() error {
+	return d.file.Close()
+}
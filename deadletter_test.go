@@ -0,0 +1,140 @@
+package failover
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryDeadLetter records what it is sent.
+type memoryDeadLetter struct {
+	mu      sync.Mutex
+	records []DeadLetterRecord
+}
+
+func (d *memoryDeadLetter) Send(_ context.Context, record DeadLetterRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.records = append(d.records, record)
+	return nil
+}
+
+func TestRetryPolicy_DeadLetter(t *testing.T) {
+	t.Parallel()
+	dl := &memoryDeadLetter{}
+	r := NewRetryPolicy(3, time.Millisecond, WithDeadLetter(dl))
+
+	ctx := WithDeadLetterPayload(context.Background(), []byte("order-42"))
+	_ = r.Do(ctx, func(context.Context) error { return errTest })
+
+	if len(dl.records) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(dl.records))
+	}
+	rec := dl.records[0]
+	if string(rec.Payload) != "order-42" || rec.Error != errTest.Error() || len(rec.Attempts) != 3 {
+		t.Fatalf("Expected payload, final error and 3 attempts, got %+v", rec)
+	}
+}
+
+func TestRetryPolicy_DeadLetterSkipsSuccessAndCancel(t *testing.T) {
+	t.Parallel()
+	dl := &memoryDeadLetter{}
+	r := NewRetryPolicy(3, time.Millisecond, WithDeadLetter(dl))
+
+	_ = r.Do(context.Background(), func(context.Context) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = r.Do(ctx, func(context.Context) error {
+		cancel()
+		return errTest
+	})
+
+	if len(dl.records) != 0 {
+		t.Fatalf("Expected no dead letters, got %+v", dl.records)
+	}
+}
+
+func TestRetryPolicy_DeadLetterSkipsNonRetryable(t *testing.T) {
+	t.Parallel()
+	dl := &memoryDeadLetter{}
+	r := NewRetryPolicy(3, time.Millisecond, WithDeadLetter(dl), WithRetryIf(func(error) bool { return false }))
+
+	if err := r.Do(context.Background(), func(context.Context) error { return errTest }); err != errTest {
+		t.Fatalf("Expected errTest, got %v", err)
+	}
+	if len(dl.records) != 0 {
+		t.Fatalf("Expected no dead letter for a non-retryable error, got %+v", dl.records)
+	}
+}
+
+func TestRetryQueue_DeadLetter(t *testing.T) {
+	t.Parallel()
+	dl := &memoryDeadLetter{}
+	store := NewMemoryQueueStore()
+	q := NewRetryQueue(store,
+		WithQueueBackoff(ConstantBackoff(0)),
+		WithQueueMaxAttempts(2),
+		WithQueueDeadLetter(dl),
+	)
+	ctx := context.Background()
+
+	q.Register("op", func(context.Context, []byte) error { return errTest })
+	_, _ = q.Enqueue(ctx, "op", []byte("payload"))
+	_ = q.RunOnce(ctx)
+	_ = q.RunOnce(ctx)
+
+	if len(dl.records) != 1 || dl.records[0].Name != "op" || len(dl.records[0].Attempts) != 2 {
+		t.Fatalf("Expected the dropped item dead-lettered with 2 attempts, got %+v", dl.records)
+	}
+}
+
+func TestJSONLDeadLetter(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+
+	dl, err := NewJSONLDeadLetter(path)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	_ = dl.Send(context.Background(), DeadLetterRecord{Name: "a", Error: "boom"})
+	_ = dl.Send(context.Background(), DeadLetterRecord{Name: "b", Error: "bang"})
+	_ = dl.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec DeadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Expected valid JSON line, got %v", err)
+		}
+		names = append(names, rec.Name)
+	}
+
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("Expected records a and b, got %v", names)
+	}
+}
+
+func TestRetryPolicy_DeadLetterOperation(t *testing.T) {
+	t.Parallel()
+	dl := &memoryDeadLetter{}
+	retry := NewRetryPolicy(1, time.Millisecond, WithDeadLetter(dl))
+
+	retry.Do(WithOperation(context.Background(), "GetUser"), func(context.Context) error { return errTest })
+
+	if len(dl.records) != 1 || dl.records[0].Operation != "GetUser" {
+		t.Fatalf("Expected a dead letter for GetUser, got %+v", dl.records)
+	}
+}
@@ -0,0 +1,278 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrRaceLost is the cause, as told by context.Cause, of the context of a
+// dial canceled because a dial to another address connected first.
+var ErrRaceLost = errors.New("another attempt won the race")
+
+// DialFunc opens a connection to address on the named network, like
+// net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Dialer connects to the first reachable address of an ordered list. It
+// remembers which addresses failed recently and tries them last, and skips
+// addresses whose breaker is open.
+//
+// By default addresses are tried one after the other. With WithStagger
+// they are raced Happy Eyeballs style: the next address is tried when the
+// previous one fails or has not connected within the stagger delay, the
+// first connection wins and the others are abandoned.
+type Dialer struct {
+	addrs      []string
+	dial       DialFunc
+	stagger    time.Duration // Delay before racing the next address, zero to dial in turn
+	penalty    time.Duration // How long an address that failed is tried last
+	newBreaker func(address string) Breaker
+
+	mu       sync.Mutex           // Protects failed and breakers
+	failed   map[string]time.Time // When each address last failed
+	breakers map[string]Breaker   // By address
+}
+
+// DialerOption configures optional Dialer behavior.
+type DialerOption func(*Dialer)
+
+// WithDialFunc opens connections with fn instead of a zero net.Dialer.
+func WithDialFunc(fn DialFunc) DialerOption {
+	return func(d *Dialer) {
+		d.dial = fn
+	}
+}
+
+// WithStagger races the addresses, starting the next one when the previous
+// has not connected after delay. RFC 8305 recommends 250ms.
+func WithStagger(delay time.Duration) DialerOption {
+	return func(d *Dialer) {
+		d.stagger = delay
+	}
+}
+
+// WithFailurePenalty sets how long an address that failed to connect is
+// tried after the others. The default is 30 seconds.
+func WithFailurePenalty(d time.Duration) DialerOption {
+	return func(dd *Dialer) {
+		dd.penalty = d
+	}
+}
+
+// WithDialBreakers guards each address with the breaker fn creates for it,
+// the first time it is dialed. Addresses whose breaker is open are skipped.
+func WithDialBreakers(fn func(address string) Breaker) DialerOption {
+	return func(d *Dialer) {
+		d.newBreaker = fn
+	}
+}
+
+// NewDialer creates a Dialer over addrs, in order of preference.
+func NewDialer(addrs []string, opts ...DialerOption) *Dialer {
+	var nd net.Dialer
+	d := &Dialer{
+		addrs:    addrs,
+		dial:     nd.DialContext,
+		penalty:  30 * time.Second,
+		failed:   make(map[string]time.Time),
+		breakers: make(map[string]Breaker),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Dial connects to one of the Dialer's addresses on the named network. It
+// returns ErrNoEndpoint, joined with the error of every address tried, if
+// none could be reached.
+func (d *Dialer) Dial(ctx context.Context, network string) (net.Conn, error) {
+	addrs := d.order()
+
+	var (
+		conn net.Conn
+		errs []error
+	)
+	if d.stagger > 0 {
+		conn, errs = d.race(ctx, network, addrs)
+	} else {
+		conn, errs = d.inTurn(ctx, network, addrs)
+	}
+	if conn != nil {
+		return conn, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrNoEndpoint
+	}
+	return nil, fmt.Errorf("%w: %w", ErrNoEndpoint, errors.Join(errs...))
+}
+
+// DialContext is Dial with the signature of net.Dialer.DialContext, for use
+// as http.Transport.DialContext and the like. The address asked for is
+// ignored in favor of the Dialer's.
+func (d *Dialer) DialContext(ctx context.Context, network, _ string) (net.Conn, error) {
+	return d.Dial(ctx, network)
+}
+
+// inTurn dials addrs one after the other until one connects.
+func (d *Dialer) inTurn(ctx context.Context, network string, addrs []string) (net.Conn, []error) {
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := d.dialAddr(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, errs
+}
+
+// race dials addrs Happy Eyeballs style and returns the first connection.
+func (d *Dialer) race(ctx context.Context, network string, addrs []string) (net.Conn, []error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(ErrRaceLost)
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(addrs))
+
+	timer := time.NewTimer(d.stagger)
+	defer timer.Stop()
+
+	next, pending := 0, 0
+	startNext := func() {
+		if next == len(addrs) {
+			return
+		}
+
+		addr := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := d.dialAddr(ctx, network, addr)
+			results <- result{conn, err}
+		}()
+		timer.Reset(d.stagger)
+	}
+
+	var errs []error
+	for startNext(); pending > 0; {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// Close the connections of the losers that still make it.
+				go func(n int) {
+					for range n {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+
+			errs = append(errs, r.err)
+			startNext()
+
+		case <-timer.C:
+			startNext()
+		}
+	}
+
+	return nil, errs
+}
+
+// dialAddr dials addr through its breaker and records the outcome.
+func (d *Dialer) dialAddr(ctx context.Context, network, addr string) (net.Conn, error) {
+	var conn net.Conn
+	err := runBreaker(ctx, d.breaker(addr), func(ctx context.Context) error {
+		var err error
+		conn, err = d.dial(ctx, network, addr)
+
+		// An abandoned dial says nothing about the address.
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+		return err
+	})
+
+	if conn == nil && err == nil {
+		err = ctx.Err()
+	}
+	if err != nil && !errors.Is(err, ErrCircuitOpen) && ctx.Err() == nil {
+		d.mu.Lock()
+		d.failed[addr] = time.Now()
+		d.mu.Unlock()
+	} else if err == nil {
+		d.mu.Lock()
+		delete(d.failed, addr)
+		d.mu.Unlock()
+	}
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	return conn, err
+}
+
+// order returns the addresses to try: those that did not fail recently, in
+// order of preference, then the others from the least recent failure. An
+// address behind an open breaker is left out.
+func (d *Dialer) order() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var healthy, failed []string
+	for _, addr := range d.addrs {
+		if b, ok := d.breakers[addr]; ok && b.State() == Open {
+			continue
+		}
+
+		if at, ok := d.failed[addr]; ok && now.Sub(at) < d.penalty {
+			failed = append(failed, addr)
+		} else {
+			healthy = append(healthy, addr)
+		}
+	}
+
+	slices.SortStableFunc(failed, func(a, b string) int {
+		return d.failed[a].Compare(d.failed[b])
+	})
+
+	return append(healthy, failed...)
+}
+
+// breaker returns the breaker of addr, creating it on first use.
+func (d *Dialer) breaker(addr string) Breaker {
+	if d.newBreaker == nil {
+		return NoopBreaker{}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b, ok := d.breakers[addr]
+	if !ok {
+		b = d.newBreaker(addr)
+		d.breakers[addr] = b
+	}
+
+	return b
+}
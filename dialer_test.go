@@ -0,0 +1,173 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNetwork is a DialFunc whose addresses each connect after a delay or
+// fail, recording the order they were dialed in.
+type fakeNetwork struct {
+	mu     sync.Mutex
+	delay  map[string]time.Duration
+	down   map[string]bool
+	dialed []string
+}
+
+func newFakeNetwork() *fakeNetwork {
+	return &fakeNetwork{delay: make(map[string]time.Duration), down: make(map[string]bool)}
+}
+
+func (n *fakeNetwork) dial(ctx context.Context, _, addr string) (net.Conn, error) {
+	n.mu.Lock()
+	n.dialed = append(n.dialed, addr)
+	delay, down := n.delay[addr], n.down[addr]
+	n.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if down {
+		return nil, errTest
+	}
+
+	client, server := net.Pipe()
+	server.Close()
+	return &namedConn{Conn: client, addr: addr}, nil
+}
+
+func (n *fakeNetwork) history() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.dialed...)
+}
+
+// namedConn is a connection that remembers its address.
+type namedConn struct {
+	net.Conn
+	addr string
+}
+
+func TestDialer_InOrder(t *testing.T) {
+	t.Parallel()
+	n := newFakeNetwork()
+	n.down["a"] = true
+	d := NewDialer([]string{"a", "b", "c"}, WithDialFunc(n.dial))
+
+	conn, err := d.Dial(context.Background(), "tcp")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	if conn.(*namedConn).addr != "b" {
+		t.Fatalf("Expected a connection to b, got %s", conn.(*namedConn).addr)
+	}
+
+	// a failed recently, so it is tried last.
+	conn, _ = d.Dial(context.Background(), "tcp")
+	conn.Close()
+	if got := n.history(); len(got) != 3 || got[2] != "b" {
+		t.Fatalf("Expected b to be dialed before the failed a, got %v", got)
+	}
+}
+
+func TestDialer_AllFail(t *testing.T) {
+	t.Parallel()
+	n := newFakeNetwork()
+	n.down["a"], n.down["b"] = true, true
+	d := NewDialer([]string{"a", "b"}, WithDialFunc(n.dial))
+
+	_, err := d.Dial(context.Background(), "tcp")
+	if !errors.Is(err, ErrNoEndpoint) || !errors.Is(err, errTest) {
+		t.Fatalf("Expected ErrNoEndpoint joined with the dial errors, got %v", err)
+	}
+}
+
+func TestDialer_Stagger(t *testing.T) {
+	t.Parallel()
+	n := newFakeNetwork()
+	n.delay["slow"] = time.Second
+	d := NewDialer([]string{"slow", "fast"}, WithDialFunc(n.dial), WithStagger(20*time.Millisecond))
+
+	start := time.Now()
+	conn, err := d.Dial(context.Background(), "tcp")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	if conn.(*namedConn).addr != "fast" {
+		t.Fatalf("Expected the fast address to win, got %s", conn.(*namedConn).addr)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Expected the race not to wait for the slow address, took %v", elapsed)
+	}
+}
+
+func TestDialer_RaceLostCause(t *testing.T) {
+	t.Parallel()
+	causes := make(chan error, 1)
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == "slow" {
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return nil, ctx.Err()
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	d := NewDialer([]string{"slow", "fast"}, WithDialFunc(dial), WithStagger(5*time.Millisecond))
+
+	conn, err := d.Dial(context.Background(), "tcp")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	if cause := <-causes; !errors.Is(cause, ErrRaceLost) {
+		t.Fatalf("Expected the loser canceled with ErrRaceLost, got %v", cause)
+	}
+}
+
+func TestDialer_StaggerStartsNextOnFailure(t *testing.T) {
+	t.Parallel()
+	n := newFakeNetwork()
+	n.down["a"] = true
+	d := NewDialer([]string{"a", "b"}, WithDialFunc(n.dial), WithStagger(time.Hour))
+
+	conn, err := d.Dial(context.Background(), "tcp")
+	if err != nil {
+		t.Fatalf("Expected b to be dialed as soon as a failed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestDialer_Breakers(t *testing.T) {
+	t.Parallel()
+	n := newFakeNetwork()
+	n.down["a"] = true
+	d := NewDialer([]string{"a", "b"},
+		WithDialFunc(n.dial),
+		WithDialBreakers(func(string) Breaker { return NewCircuitBreaker(1, 1, time.Hour) }),
+	)
+
+	for range 2 {
+		conn, err := d.Dial(context.Background(), "tcp")
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		conn.Close()
+	}
+
+	if got := n.history(); len(got) != 3 {
+		t.Fatalf("Expected a to be skipped once its breaker opened, got %v", got)
+	}
+}
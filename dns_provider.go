@@ -0,0 +1,262 @@
+package failover
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNSRecord is an address resolved from DNS.
+type DNSRecord struct {
+	Target   string        // IP address, or host name for SRV records
+	Port     int           // Port from the SRV record, zero for A/AAAA records
+	Priority int           // SRV priority, lower is preferred
+	Weight   int           // SRV weight among records of the same priority
+	TTL      time.Duration // Time to live, zero when the resolver does not report it
+}
+
+// DNSResolver resolves the records a DNSProvider turns into endpoints.
+// The standard library resolver does not expose TTLs; plug in one that
+// does for the provider to refresh on record expiry rather than on its
+// interval.
+type DNSResolver interface {
+	LookupAddr(ctx context.Context, host string) ([]DNSRecord, error)
+	LookupSRV(ctx context.Context, service, proto, name string) ([]DNSRecord, error)
+}
+
+// netResolver adapts a net.Resolver to a DNSResolver.
+type netResolver struct{ r *net.Resolver }
+
+// NetResolver returns a DNSResolver backed by r, which reports no TTLs.
+func NetResolver(r *net.Resolver) DNSResolver {
+	return netResolver{r: r}
+}
+
+func (n netResolver) LookupAddr(ctx context.Context, host string) ([]DNSRecord, error) {
+	addrs, err := n.r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]DNSRecord, len(addrs))
+	for i, addr := range addrs {
+		records[i] = DNSRecord{Target: addr.IP.String()}
+	}
+
+	return records, nil
+}
+
+func (n netResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]DNSRecord, error) {
+	_, srvs, err := n.r.LookupSRV(ctx, service, proto, name)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]DNSRecord, len(srvs))
+	for i, srv := range srvs {
+		records[i] = DNSRecord{
+			Target:   strings.TrimSuffix(srv.Target, "."),
+			Port:     int(srv.Port),
+			Priority: int(srv.Priority),
+			Weight:   int(srv.Weight),
+		}
+	}
+
+	return records, nil
+}
+
+// DNSProvider is an EndpointProvider that discovers endpoints by resolving
+// A/AAAA or SRV records on an interval. Endpoints are named by their
+// host:port address; SRV endpoints are ordered by priority and carry the
+// record weight, so a FailoverGroup prefers the lowest priority and a
+// Balancer honors the weights.
+//
+// A failed lookup keeps the last known endpoints rather than emptying the
+// set, since losing DNS should not take down every backend.
+type DNSProvider struct {
+	lookup func(ctx context.Context) ([]DNSRecord, error)
+	port   int // Port used for A/AAAA records
+
+	resolver   DNSResolver
+	interval   time.Duration // Refresh interval when records carry no TTL
+	minRefresh time.Duration // Floor on the refresh interval, however low the TTL
+	jitter     float64       // Fraction of the refresh interval to randomize
+}
+
+// DNSOption configures optional DNSProvider behavior.
+type DNSOption func(*DNSProvider)
+
+// WithDNSResolver sets the resolver. The default is NetResolver of
+// net.DefaultResolver.
+func WithDNSResolver(r DNSResolver) DNSOption {
+	return func(p *DNSProvider) {
+		p.resolver = r
+	}
+}
+
+// WithRefreshInterval sets how often records are resolved again when they
+// carry no TTL, and caps the refresh interval when they do. The default is
+// 30 seconds.
+func WithRefreshInterval(d time.Duration) DNSOption {
+	return func(p *DNSProvider) {
+		p.interval = d
+	}
+}
+
+// WithMinRefresh sets the shortest refresh interval, however low the TTLs.
+// The default is one second.
+func WithMinRefresh(d time.Duration) DNSOption {
+	return func(p *DNSProvider) {
+		p.minRefresh = d
+	}
+}
+
+// WithRefreshJitter randomizes each refresh interval by up to fraction of
+// itself in either direction, so a fleet of clients does not resolve in
+// lockstep. The default is 0.1.
+func WithRefreshJitter(fraction float64) DNSOption {
+	return func(p *DNSProvider) {
+		p.jitter = fraction
+	}
+}
+
+// NewDNSProvider creates a DNSProvider resolving the A and AAAA records of
+// host, with every address reached on port.
+func NewDNSProvider(host string, port int, opts ...DNSOption) *DNSProvider {
+	p := newDNSProvider(opts)
+	p.port = port
+	p.lookup = func(ctx context.Context) ([]DNSRecord, error) {
+		return p.resolver.LookupAddr(ctx, host)
+	}
+
+	return p
+}
+
+// NewSRVProvider creates a DNSProvider resolving the _service._proto.name
+// SRV records.
+func NewSRVProvider(service, proto, name string, opts ...DNSOption) *DNSProvider {
+	p := newDNSProvider(opts)
+	p.lookup = func(ctx context.Context) ([]DNSRecord, error) {
+		return p.resolver.LookupSRV(ctx, service, proto, name)
+	}
+
+	return p
+}
+
+func newDNSProvider(opts []DNSOption) *DNSProvider {
+	p := &DNSProvider{
+		resolver:   NetResolver(net.DefaultResolver),
+		interval:   30 * time.Second,
+		minRefresh: time.Second,
+		jitter:     0.1,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// List resolves the records and returns their endpoints.
+func (p *DNSProvider) List(ctx context.Context) ([]*Endpoint, error) {
+	endpoints, _, err := p.resolve(ctx)
+	return endpoints, err
+}
+
+// Watch resolves the records when they expire, or on the refresh interval,
+// and sends the endpoints whenever they change. It fails if the first
+// lookup does.
+func (p *DNSProvider) Watch(ctx context.Context) (<-chan []*Endpoint, error) {
+	endpoints, ttl, err := p.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []*Endpoint, 1)
+	ch <- endpoints
+
+	go func() {
+		defer close(ch)
+
+		timer := time.NewTimer(p.refresh(ttl))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+
+			next, nextTTL, err := p.resolve(ctx)
+			if err == nil {
+				ttl = nextTTL
+				if !sameEndpoints(endpoints, next) {
+					endpoints = next
+					select {
+					case ch <- next:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			timer.Reset(p.refresh(ttl))
+		}
+	}()
+
+	return ch, nil
+}
+
+// resolve looks the records up and returns their endpoints with the lowest
+// TTL among them.
+func (p *DNSProvider) resolve(ctx context.Context) ([]*Endpoint, time.Duration, error) {
+	records, err := p.lookup(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	slices.SortStableFunc(records, func(a, b DNSRecord) int { return a.Priority - b.Priority })
+
+	var ttl time.Duration
+	endpoints := make([]*Endpoint, len(records))
+	for i, r := range records {
+		port := r.Port
+		if port == 0 {
+			port = p.port
+		}
+
+		addr := net.JoinHostPort(r.Target, strconv.Itoa(port))
+		endpoints[i] = &Endpoint{Name: addr, Address: addr, Weight: r.Weight}
+
+		if r.TTL > 0 && (ttl == 0 || r.TTL < ttl) {
+			ttl = r.TTL
+		}
+	}
+
+	return endpoints, ttl, nil
+}
+
+// refresh returns how long to wait before resolving again.
+func (p *DNSProvider) refresh(ttl time.Duration) time.Duration {
+	d := p.interval
+	if ttl > 0 && ttl < d {
+		d = ttl
+	}
+
+	d = time.Duration(float64(d) * (1 - p.jitter + 2*p.jitter*rand.Float64()))
+	return max(d, p.minRefresh)
+}
+
+// sameEndpoints reports whether a and b hold the same endpoints in the
+// same order.
+func sameEndpoints(a, b []*Endpoint) bool {
+	return slices.EqualFunc(a, b, func(x, y *Endpoint) bool {
+		return x.Name == y.Name && x.Address == y.Address && x.Weight == y.Weight
+	})
+}
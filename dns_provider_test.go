@@ -0,0 +1,129 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver is a DNSResolver serving records set by the test.
+type fakeResolver struct {
+	mu      sync.Mutex
+	records []DNSRecord
+	err     error
+	lookups int
+}
+
+func (f *fakeResolver) set(err error, records ...DNSRecord) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records, f.err = records, err
+}
+
+func (f *fakeResolver) LookupAddr(context.Context, string) ([]DNSRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	return append([]DNSRecord(nil), f.records...), f.err
+}
+
+func (f *fakeResolver) LookupSRV(ctx context.Context, _, _, name string) ([]DNSRecord, error) {
+	return f.LookupAddr(ctx, name)
+}
+
+func TestDNSProvider_List(t *testing.T) {
+	t.Parallel()
+	r := &fakeResolver{}
+	r.set(nil, DNSRecord{Target: "10.0.0.1"}, DNSRecord{Target: "::1"})
+	p := NewDNSProvider("api.internal", 8080, WithDNSResolver(r))
+
+	endpoints, err := p.List(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if n := names(endpoints); len(n) != 2 || n[0] != "10.0.0.1:8080" || n[1] != "[::1]:8080" {
+		t.Fatalf("Expected [10.0.0.1:8080 [::1]:8080], got %v", n)
+	}
+}
+
+func TestSRVProvider_PriorityAndWeight(t *testing.T) {
+	t.Parallel()
+	r := &fakeResolver{}
+	r.set(nil,
+		DNSRecord{Target: "backup", Port: 9000, Priority: 20, Weight: 1},
+		DNSRecord{Target: "main", Port: 9000, Priority: 10, Weight: 5},
+	)
+	p := NewSRVProvider("grpc", "tcp", "svc.internal", WithDNSResolver(r))
+
+	endpoints, _ := p.List(context.Background())
+	if endpoints[0].Name != "main:9000" || endpoints[0].Weight != 5 || endpoints[1].Name != "backup:9000" {
+		t.Fatalf("Expected main before backup with its weight, got %+v %+v", endpoints[0], endpoints[1])
+	}
+}
+
+func TestDNSProvider_Watch(t *testing.T) {
+	t.Parallel()
+	r := &fakeResolver{}
+	r.set(nil, DNSRecord{Target: "10.0.0.1"})
+	p := NewDNSProvider("api", 80, WithDNSResolver(r), WithRefreshInterval(10*time.Millisecond), WithMinRefresh(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	<-updates
+
+	// A failed lookup keeps the last endpoints, a change is sent.
+	r.set(errTest)
+	time.Sleep(30 * time.Millisecond)
+	r.set(nil, DNSRecord{Target: "10.0.0.1"}, DNSRecord{Target: "10.0.0.2"})
+
+	select {
+	case got := <-updates:
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 endpoints, got %v", names(got))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the changed records to be sent")
+	}
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestDNSProvider_TTL(t *testing.T) {
+	t.Parallel()
+	r := &fakeResolver{}
+	r.set(nil, DNSRecord{Target: "10.0.0.1", TTL: 5 * time.Millisecond})
+	p := NewDNSProvider("api", 80, WithDNSResolver(r), WithRefreshInterval(time.Hour),
+		WithMinRefresh(time.Millisecond), WithRefreshJitter(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	updates, _ := p.Watch(ctx)
+	for range updates {
+	}
+
+	r.mu.Lock()
+	lookups := r.lookups
+	r.mu.Unlock()
+	if lookups < 4 {
+		t.Fatalf("Expected records refreshed on TTL expiry, got %d lookups", lookups)
+	}
+}
+
+func TestDNSProvider_WatchFails(t *testing.T) {
+	t.Parallel()
+	r := &fakeResolver{}
+	r.set(errTest)
+	p := NewDNSProvider("api", 80, WithDNSResolver(r))
+
+	if _, err := p.Watch(context.Background()); !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+}
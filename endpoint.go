@@ -0,0 +1,55 @@
+package failover
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoEndpoint is returned when no endpoint is available to take a call.
+var ErrNoEndpoint = errors.New("no healthy endpoint available")
+
+// Endpoint is one backend that a FailoverGroup or Balancer can route to.
+type Endpoint struct {
+	Name    string  // Identifies the endpoint, and its target in a HealthChecker
+	Address string  // Where the endpoint is reached, for use by the caller's fn
+	Weight  int     // Relative share of traffic in a Balancer, 1 when zero
+	Breaker Breaker // Optional per-endpoint breaker, calls go straight through when nil
+
+	// Bulkhead optionally bounds the endpoint's concurrent calls. Its
+	// in-flight count is also what PowerOfTwoChoices compares.
+	Bulkhead *Bulkhead
+}
+
+// EndpointFunc is a unit of work routed to a specific endpoint.
+type EndpointFunc func(ctx context.Context, ep *Endpoint) error
+
+// weight returns the endpoint's weight, defaulting to 1.
+func (ep *Endpoint) weight() int {
+	return max(ep.Weight, 1)
+}
+
+// inFlight returns the number of calls running on the endpoint, as tracked
+// by its bulkhead, or zero without one.
+func (ep *Endpoint) inFlight() int {
+	if ep.Bulkhead == nil {
+		return 0
+	}
+
+	return ep.Bulkhead.InFlight()
+}
+
+// call runs fn against ep through its breaker and bulkhead, if any, in the
+// same order as a Pipeline.
+func (ep *Endpoint) call(ctx context.Context, fn EndpointFunc) error {
+	run := func(ctx context.Context) error { return fn(ctx, ep) }
+	if ep.Bulkhead != nil {
+		inner := run
+		run = func(ctx context.Context) error { return ep.Bulkhead.Do(ctx, inner) }
+	}
+
+	if ep.Breaker == nil {
+		return run(ctx)
+	}
+
+	return runBreaker(ctx, ep.Breaker, run)
+}
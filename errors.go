@@ -0,0 +1,157 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The errors below tell the failure modes of the package apart by type, so
+// that callers can branch on them with errors.As rather than on messages.
+// Each unwraps to the sentinel or underlying error it describes, so
+// errors.Is keeps working as before.
+
+// MaxAttemptsError is returned by RetryPolicy when every attempt failed.
+type MaxAttemptsError struct {
+	Attempts int   // Attempts made
+	LastErr  error // Error of the last attempt
+}
+
+// Error implements error.
+func (e *MaxAttemptsError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", e.Attempts, e.LastErr)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *MaxAttemptsError) Unwrap() error {
+	return e.LastErr
+}
+
+// BudgetExhaustedError is returned by RetryPolicy when the context's
+// deadline would pass before the next attempt could start, so that the
+// call fails at once instead of waiting out the deadline. It is a
+// context.DeadlineExceeded as well as the error of the last attempt.
+type BudgetExhaustedError struct {
+	Attempts  int           // Attempts made
+	LastErr   error         // Error of the last attempt
+	Remaining time.Duration // Time left before the deadline
+	Delay     time.Duration // Wait the next attempt needed
+}
+
+// Error implements error.
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("deadline leaves %v, less than the %v before attempt %d: %v", e.Remaining, e.Delay, e.Attempts+1, e.LastErr)
+}
+
+// Unwrap returns the error of the last attempt and
+// context.DeadlineExceeded.
+func (e *BudgetExhaustedError) Unwrap() []error {
+	return []error{e.LastErr, context.DeadlineExceeded}
+}
+
+// RejectedError is returned by a policy that turned a call away without
+// running it, such as an open breaker or a full bulkhead.
+type RejectedError struct {
+	Policy string // Kind of policy, such as "breaker" or "bulkhead"
+	Reason string // Why, as named in logs and metrics, such as "circuit_open"
+	Err    error  // Error of the reason, such as ErrCircuitOpen or a CircuitOpenError
+}
+
+// Error implements error.
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the reason.
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// rejections names the errors with which policies turn calls away.
+var rejections = []struct {
+	err    error
+	reason string
+}{
+	{ErrCircuitOpen, "circuit_open"},
+	{ErrBulkheadFull, "bulkhead_full"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrLimitExceeded, "limit_exceeded"},
+	{ErrLoadShed, "load_shed"},
+	{ErrCooldown, "cooldown"},
+	{ErrDeadlineUnreachable, "deadline_unreachable"},
+}
+
+// RejectionReason returns why err turned a call away, as named in logs and
+// metrics, such as "circuit_open", or "" if it did not.
+func RejectionReason(err error) string {
+	for _, r := range rejections {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+
+	return ""
+}
+
+// CircuitOpenError is the reason a CircuitBreaker rejects calls after it
+// opened on a failure: it is ErrCircuitOpen as well as the failure that
+// opened it, so that callers and logs can tell the root cause.
+type CircuitOpenError struct {
+	LastErr  error     // Failure that opened the breaker, nil if forced open
+	OpenedAt time.Time // When the breaker opened
+	RetryAt  time.Time // When the breaker lets a trial call through, zero if forced open
+}
+
+// Error implements error.
+func (e *CircuitOpenError) Error() string {
+	if e.LastErr == nil {
+		return fmt.Sprintf("%v since %v", ErrCircuitOpen, e.OpenedAt.Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("%v since %v: %v", ErrCircuitOpen, e.OpenedAt.Format(time.RFC3339), e.LastErr)
+}
+
+// Unwrap returns ErrCircuitOpen and the failure that opened the breaker.
+func (e *CircuitOpenError) Unwrap() []error {
+	if e.LastErr == nil {
+		return []error{ErrCircuitOpen}
+	}
+
+	return []error{ErrCircuitOpen, e.LastErr}
+}
+
+// Rejections returned by the package's policies, each returned as a copy
+// made by rejectWith.
+var (
+	rejectedCircuitOpen     = RejectedError{Policy: "breaker", Reason: "circuit_open", Err: ErrCircuitOpen}
+	rejectedBulkheadFull    = RejectedError{Policy: "bulkhead", Reason: "bulkhead_full", Err: ErrBulkheadFull}
+	rejectedBulkheadLate    = RejectedError{Policy: "bulkhead", Reason: "deadline_unreachable", Err: ErrDeadlineUnreachable}
+	rejectedRateLimited     = RejectedError{Policy: "rate_limiter", Reason: "rate_limited", Err: ErrRateLimited}
+	rejectedRateLimiterLate = RejectedError{Policy: "rate_limiter", Reason: "deadline_unreachable", Err: ErrDeadlineUnreachable}
+	rejectedLimitExceeded   = RejectedError{Policy: "adaptive_limiter", Reason: "limit_exceeded", Err: ErrLimitExceeded}
+	rejectedLoadShed        = RejectedError{Policy: "codel", Reason: "load_shed", Err: ErrLoadShed}
+	rejectedCooldown        = RejectedError{Policy: "cooldown", Reason: "cooldown", Err: ErrCooldown}
+)
+
+// rejectWith returns a new error for one rejection of kind e, so that a
+// caller changing the error it got cannot change those of other calls.
+func rejectWith(e RejectedError) *RejectedError {
+	return &e
+}
+
+// TimeoutError is returned by Timeout when an operation overruns its
+// deadline.
+type TimeoutError struct {
+	Timeout time.Duration // Deadline the operation was given
+}
+
+// Error implements error.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%v after %v", ErrTimeout, e.Timeout)
+}
+
+// Unwrap returns ErrTimeout.
+func (e *TimeoutError) Unwrap() error {
+	return ErrTimeout
+}
@@ -0,0 +1,224 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxAttemptsError(t *testing.T) {
+	t.Parallel()
+	retry := NewRetryPolicy(3, time.Millisecond)
+
+	err := retry.Do(context.Background(), func(context.Context) error { return errTest })
+
+	var exhausted *MaxAttemptsError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("Expected MaxAttemptsError, got %v", err)
+	}
+	if exhausted.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", exhausted.Attempts)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("Expected the last error to be wrapped, got %v", err)
+	}
+}
+
+func TestMaxAttemptsError_NotRetryable(t *testing.T) {
+	t.Parallel()
+	retry := NewRetryPolicy(3, time.Millisecond, WithRetryIf(func(error) bool { return false }))
+
+	err := retry.Do(context.Background(), func(context.Context) error { return errTest })
+	if err != errTest {
+		t.Errorf("Expected the error as is, got %v", err)
+	}
+}
+
+func TestBudgetExhaustedError(t *testing.T) {
+	t.Parallel()
+	retry := NewRetryPolicy(3, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := retry.Do(ctx, func(context.Context) error {
+		calls++
+		return errTest
+	})
+
+	var budget *BudgetExhaustedError
+	if !errors.As(err, &budget) {
+		t.Fatalf("Expected BudgetExhaustedError, got %v", err)
+	}
+	if calls != 1 || budget.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d calls and %d attempts", calls, budget.Attempts)
+	}
+	if budget.Delay != time.Hour || budget.Remaining > time.Minute {
+		t.Errorf("Expected a 1h delay within 1m, got %v within %v", budget.Delay, budget.Remaining)
+	}
+	if !errors.Is(err, errTest) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the last error and DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to fail at once, took %v", elapsed)
+	}
+}
+
+func TestRejectedError(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	cb.Execute(func() error { return errTest })
+
+	err := cb.Execute(func() error { return nil })
+
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("Expected RejectedError, got %v", err)
+	}
+	if rejected.Policy != "breaker" || rejected.Reason != "circuit_open" {
+		t.Errorf("Expected breaker/circuit_open, got %s/%s", rejected.Policy, rejected.Reason)
+	}
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestRejectionReason(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: rejectWith(rejectedCircuitOpen), want: "circuit_open"},
+		{err: rejectWith(rejectedBulkheadLate), want: "deadline_unreachable"},
+		{err: ErrCooldown, want: "cooldown"},
+		{err: errTest, want: ""},
+		{err: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := RejectionReason(tt.err); got != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestCircuitOpenError(t *testing.T) {
+	t.Parallel()
+	clock := &stepClock{now: time.Unix(1000, 0)}
+	cb := NewCircuitBreaker(1, 1, time.Hour, WithBreakerClock(clock))
+	cb.Execute(func() error { return errTest })
+	clock.now = clock.now.Add(time.Minute)
+
+	err := cb.Execute(func() error { return nil })
+
+	var open *CircuitOpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("Expected CircuitOpenError, got %v", err)
+	}
+	if open.LastErr != errTest || !errors.Is(err, errTest) {
+		t.Errorf("Expected the failure that opened the breaker, got %v", open.LastErr)
+	}
+	if !open.OpenedAt.Equal(time.Unix(1000, 0)) {
+		t.Errorf("Expected opened at %v, got %v", time.Unix(1000, 0), open.OpenedAt)
+	}
+	if want := time.Unix(1000, 0).Add(time.Hour); !open.RetryAt.Equal(want) {
+		t.Errorf("Expected a trial call at %v, got %v", want, open.RetryAt)
+	}
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestRejectedError_NotShared(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	cb.Execute(func() error { return errTest })
+
+	var rejected *RejectedError
+	var open *CircuitOpenError
+	err := cb.Execute(func() error { return nil })
+	errors.As(err, &rejected)
+	errors.As(err, &open)
+	rejected.Reason = "changed"
+	open.LastErr = nil
+
+	err = cb.Execute(func() error { return nil })
+	if !errors.As(err, &rejected) || rejected.Reason != "circuit_open" || !errors.Is(err, errTest) {
+		t.Fatalf("Expected an unchanged rejection, got %+v", rejected)
+	}
+
+	rl := NewRateLimiter(1, 1)
+	rl.Execute(func() error { return nil })
+	if !errors.As(rl.Execute(func() error { return nil }), &rejected) {
+		t.Fatal("Expected a RejectedError")
+	}
+	rejected.Err = nil
+	if err := rl.Execute(func() error { return nil }); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestCircuitOpenError_ForceOpen(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	cb.Execute(func() error { return errTest })
+	cb.ForceOpen()
+
+	err := cb.Execute(func() error { return nil })
+
+	var open *CircuitOpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("Expected CircuitOpenError, got %v", err)
+	}
+	if open.LastErr != nil || errors.Is(err, errTest) || !open.RetryAt.IsZero() {
+		t.Errorf("Expected no failure behind a forced open, got %v", open.LastErr)
+	}
+}
+
+func TestRejectedError_Bulkhead(t *testing.T) {
+	t.Parallel()
+	b := NewBulkhead(1, 0, 0)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go b.Do(context.Background(), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != "bulkhead_full" {
+		t.Fatalf("Expected a bulkhead_full RejectedError, got %v", err)
+	}
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Expected ErrBulkheadFull, got %v", err)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	t.Parallel()
+	timeout := NewTimeout(10 * time.Millisecond)
+
+	err := timeout.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("Expected TimeoutError, got %v", err)
+	}
+	if te.Timeout != 10*time.Millisecond {
+		t.Errorf("Expected 10ms, got %v", te.Timeout)
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
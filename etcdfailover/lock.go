@@ -0,0 +1,115 @@
+package etcdfailover
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ failover.LeaseLock = (*Lock)(nil)
+
+// Lock is a failover.LeaseLock held as an etcd key attached to a lease.
+// The key is created only if absent, so a single instance holds it at a
+// time, and vanishes with the lease when the holder stops renewing.
+type Lock struct {
+	p     *Provider // Reuses the provider's connection settings
+	key   string
+	value string // Identifies the holder in the key's value
+
+	mu    sync.Mutex // Protects lease
+	lease string     // Lease ID while held, empty otherwise
+}
+
+// NewLock creates the Lock through which holder competes for key. It takes
+// the same options as NewProvider.
+func NewLock(key, holder string, opts ...Option) *Lock {
+	return &Lock{p: NewProvider(key, opts...), key: key, value: holder}
+}
+
+// TryAcquire creates the key under a new lease of ttl if it is absent, or
+// keeps the current lease alive if this instance holds it.
+func (l *Lock) TryAcquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lease != "" {
+		return l.keepAlive(ctx)
+	}
+
+	var granted struct {
+		ID string `json:"ID"`
+	}
+	seconds := int64(max(ttl.Round(time.Second), time.Second) / time.Second)
+	if err := l.call(ctx, "/v3/lease/grant", map[string]any{"TTL": strconv.FormatInt(seconds, 10)}, &granted); err != nil {
+		return false, err
+	}
+
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	err := l.call(ctx, "/v3/kv/txn", map[string]any{
+		"compare": []map[string]any{{"key": []byte(l.key), "target": "CREATE", "create_revision": "0"}},
+		"success": []map[string]any{{"request_put": map[string]any{
+			"key": []byte(l.key), "value": []byte(l.value), "lease": granted.ID,
+		}}},
+	}, &txn)
+	if err != nil || !txn.Succeeded {
+		l.call(ctx, "/v3/lease/revoke", map[string]any{"ID": granted.ID}, nil)
+		return false, err
+	}
+
+	l.lease = granted.ID
+	return true, nil
+}
+
+// keepAlive renews the held lease, forgetting it if it already expired.
+func (l *Lock) keepAlive(ctx context.Context) (bool, error) {
+	var resp struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := l.call(ctx, "/v3/lease/keepalive", map[string]any{"ID": l.lease}, &resp); err != nil {
+		return false, err
+	}
+
+	if ttl, _ := strconv.ParseInt(resp.Result.TTL, 10, 64); ttl <= 0 {
+		l.lease = ""
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Release revokes the lease, deleting the key with it.
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lease == "" {
+		return nil
+	}
+
+	err := l.call(ctx, "/v3/lease/revoke", map[string]any{"ID": l.lease}, nil)
+	l.lease = ""
+	return err
+}
+
+// call posts body to path and decodes the response into out, if not nil.
+func (l *Lock) call(ctx context.Context, path string, body, out any) error {
+	resp, err := l.p.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,90 @@
+package etcdfailover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// leaseServer fakes the lease and txn gateway endpoints for a single key.
+type leaseServer struct {
+	mu     sync.Mutex
+	next   int
+	leases map[string]bool // Live leases
+	owner  string          // Lease the key is attached to, empty when absent
+}
+
+func (s *leaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		s.next++
+		id := strconv.Itoa(s.next)
+		s.leases[id] = true
+		fmt.Fprintf(w, `{"ID":%q,"TTL":%q}`, id, body["TTL"])
+	case "/v3/kv/txn":
+		if s.owner != "" && s.leases[s.owner] {
+			fmt.Fprint(w, `{"succeeded":false}`)
+			return
+		}
+		put := body["success"].([]any)[0].(map[string]any)["request_put"].(map[string]any)
+		s.owner = put["lease"].(string)
+		fmt.Fprint(w, `{"succeeded":true}`)
+	case "/v3/lease/keepalive":
+		if !s.leases[body["ID"].(string)] {
+			fmt.Fprint(w, `{"result":{"ID":"0"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"result":{"TTL":"10"}}`)
+	case "/v3/lease/revoke":
+		delete(s.leases, body["ID"].(string))
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	fake := &leaseServer{leases: make(map[string]bool)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx := context.Background()
+
+	a := NewLock("/locks/job", "a", WithEndpoint(srv.URL))
+	b := NewLock("/locks/job", "b", WithEndpoint(srv.URL))
+
+	if held, err := a.TryAcquire(ctx, 10*time.Second); !held || err != nil {
+		t.Fatalf("Expected a to acquire the free lock, got %v (%v)", held, err)
+	}
+	if held, _ := b.TryAcquire(ctx, 10*time.Second); held {
+		t.Fatal("Expected b not to acquire a held lock")
+	}
+	if held, _ := a.TryAcquire(ctx, 10*time.Second); !held {
+		t.Fatal("Expected a to renew its lock")
+	}
+
+	if err := a.Release(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if held, _ := b.TryAcquire(ctx, 10*time.Second); !held {
+		t.Fatal("Expected b to acquire the released lock")
+	}
+
+	// An expired lease is noticed on renewal.
+	fake.mu.Lock()
+	clear(fake.leases)
+	fake.mu.Unlock()
+	if held, _ := b.TryAcquire(ctx, 10*time.Second); held {
+		t.Fatal("Expected renewal of an expired lease to fail")
+	}
+}
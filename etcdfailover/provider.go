@@ -0,0 +1,296 @@
+// Package etcdfailover discovers endpoints registered under an etcd key
+// prefix.
+//
+// It uses etcd's v3 JSON gateway directly, so importing it adds no
+// dependencies. Each key under the prefix is one endpoint, named by the
+// rest of the key; its value is the endpoint address, either as a plain
+// string or as the {"Addr": "host:port"} JSON used by etcd's gRPC naming.
+//
+// The package also provides an ObservationStore for coordinating failover
+// decisions between instances with a failover.Quorum.
+package etcdfailover
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ failover.EndpointProvider = (*Provider)(nil)
+
+// Provider is a failover.EndpointProvider for the keys under an etcd
+// prefix.
+type Provider struct {
+	prefix   string
+	endpoint string // etcd base URL
+	token    string
+	retry    time.Duration
+	client   *http.Client
+}
+
+// Option configures optional Provider behavior.
+type Option func(*Provider)
+
+// WithEndpoint sets the base URL of an etcd member. The default is
+// http://127.0.0.1:2379.
+func WithEndpoint(endpoint string) Option {
+	return func(p *Provider) {
+		p.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithToken sets the auth token sent with every request, as returned by
+// etcd's /v3/auth/authenticate.
+func WithToken(token string) Option {
+	return func(p *Provider) {
+		p.token = token
+	}
+}
+
+// WithHTTPClient sets the client used to reach etcd, e.g. one configured
+// for mutual TLS.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithRetryDelay sets how long to wait before reading again after a failed
+// watch. The default is one second.
+func WithRetryDelay(d time.Duration) Option {
+	return func(p *Provider) {
+		p.retry = d
+	}
+}
+
+// NewProvider creates a Provider for the keys under prefix.
+func NewProvider(prefix string, opts ...Option) *Provider {
+	p := &Provider{
+		prefix:   prefix,
+		endpoint: "http://127.0.0.1:2379",
+		retry:    time.Second,
+		client:   http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// keyValue is an etcd key-value pair, with bytes base64 encoded as in the
+// JSON gateway.
+type keyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type rangeResponse struct {
+	Header struct {
+		Revision string `json:"revision"`
+	} `json:"header"`
+	Kvs []keyValue `json:"kvs"`
+}
+
+type watchResponse struct {
+	Result struct {
+		Canceled bool `json:"canceled"`
+		Events   []struct {
+			Type string   `json:"type"` // Empty for PUT
+			Kv   keyValue `json:"kv"`
+		} `json:"events"`
+	} `json:"result"`
+	Error json.RawMessage `json:"error"`
+}
+
+// List returns the endpoints currently registered under the prefix.
+func (p *Provider) List(ctx context.Context) ([]*failover.Endpoint, error) {
+	keys, _, err := p.read(ctx, p.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.endpoints(keys), nil
+}
+
+// Watch sends the registered endpoints every time a key under the prefix
+// changes. A broken watch is resumed by reading the prefix again after the
+// retry delay. It fails if the first read does.
+func (p *Provider) Watch(ctx context.Context) (<-chan []*failover.Endpoint, error) {
+	keys, revision, err := p.read(ctx, p.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []*failover.Endpoint, 1)
+	ch <- p.endpoints(keys)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			err := p.watch(ctx, keys, revision, ch)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				select {
+				case <-time.After(p.retry):
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if keys, revision, err = p.read(ctx, p.prefix); err == nil {
+				select {
+				case ch <- p.endpoints(keys):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// watch streams changes after revision into keys, sending the endpoints
+// after every batch of events, until the stream ends.
+func (p *Provider) watch(ctx context.Context, keys map[string]string, revision int64, ch chan<- []*failover.Endpoint) error {
+	resp, err := p.post(ctx, "/v3/watch", map[string]any{
+		"create_request": map[string]any{
+			"key":            []byte(p.prefix),
+			"range_end":      rangeEnd(p.prefix),
+			"start_revision": strconv.FormatInt(revision+1, 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var msg watchResponse
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return err
+		}
+		if msg.Error != nil || msg.Result.Canceled {
+			return fmt.Errorf("etcdfailover: watch canceled: %s", scanner.Bytes())
+		}
+		if len(msg.Result.Events) == 0 {
+			continue // creation or progress notification
+		}
+
+		for _, e := range msg.Result.Events {
+			if e.Type == "DELETE" {
+				delete(keys, string(e.Kv.Key))
+			} else {
+				keys[string(e.Kv.Key)] = string(e.Kv.Value)
+			}
+		}
+
+		select {
+		case ch <- p.endpoints(keys):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return scanner.Err()
+}
+
+// read returns the keys under prefix with their values, and the revision
+// they were read at.
+func (p *Provider) read(ctx context.Context, prefix string) (map[string]string, int64, error) {
+	resp, err := p.post(ctx, "/v3/kv/range", map[string]any{
+		"key":       []byte(prefix),
+		"range_end": rangeEnd(prefix),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var r rangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, 0, err
+	}
+	revision, _ := strconv.ParseInt(r.Header.Revision, 10, 64)
+
+	keys := make(map[string]string, len(r.Kvs))
+	for _, kv := range r.Kvs {
+		keys[string(kv.Key)] = string(kv.Value)
+	}
+
+	return keys, revision, nil
+}
+
+// endpoints returns the endpoints for keys, sorted by name.
+func (p *Provider) endpoints(keys map[string]string) []*failover.Endpoint {
+	endpoints := make([]*failover.Endpoint, 0, len(keys))
+	for key, value := range keys {
+		addr := value
+		var named struct{ Addr string }
+		if json.Unmarshal([]byte(value), &named) == nil && named.Addr != "" {
+			addr = named.Addr
+		}
+
+		endpoints = append(endpoints, &failover.Endpoint{Name: strings.TrimPrefix(key, p.prefix), Address: addr})
+	}
+	slices.SortFunc(endpoints, func(a, b *failover.Endpoint) int { return strings.Compare(a.Name, b.Name) })
+
+	return endpoints
+}
+
+func (p *Provider) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("etcdfailover: %s: %s", path, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// rangeEnd returns the end of the key range covering every key with prefix.
+func rangeEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	return []byte{0} // every key
+}
@@ -0,0 +1,119 @@
+package etcdfailover
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// etcdServer fakes the range and watch gateway endpoints for the
+// /services/api/ prefix; lines sent on the returned channel are streamed to
+// watchers.
+func etcdServer(t *testing.T) (*httptest.Server, chan string) {
+	t.Helper()
+	events := make(chan string)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get("Authorization") != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			if body["key"] != b64("/services/api/") || body["range_end"] != b64("/services/api0") {
+				http.Error(w, "unexpected range", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"header":{"revision":"10"},"kvs":[{"key":%q,"value":%q},{"key":%q,"value":%q}]}`,
+				b64("/services/api/b"), b64(`{"Addr":"10.0.0.2:80"}`), b64("/services/api/a"), b64("10.0.0.1:80"))
+		case "/v3/watch":
+			create := body["create_request"].(map[string]any)
+			if create["start_revision"] != "11" {
+				http.Error(w, "unexpected revision", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, `{"result":{"created":true}}`)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case line := <-events:
+					fmt.Fprintln(w, line)
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, events
+}
+
+func TestProvider_List(t *testing.T) {
+	t.Parallel()
+	srv, _ := etcdServer(t)
+	p := NewProvider("/services/api/", WithEndpoint(srv.URL), WithToken("token"))
+
+	endpoints, err := p.List(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(endpoints) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %d", len(endpoints))
+	}
+	if ep := endpoints[0]; ep.Name != "a" || ep.Address != "10.0.0.1:80" {
+		t.Fatalf("Expected a at a plain address, got %+v", ep)
+	}
+	if ep := endpoints[1]; ep.Name != "b" || ep.Address != "10.0.0.2:80" {
+		t.Fatalf("Expected b at its JSON address, got %+v", ep)
+	}
+}
+
+func TestProvider_Watch(t *testing.T) {
+	t.Parallel()
+	srv, events := etcdServer(t)
+	p := NewProvider("/services/api/", WithEndpoint(srv.URL), WithToken("token"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	<-updates
+
+	events <- fmt.Sprintf(`{"result":{"events":[{"kv":{"key":%q,"value":%q}},{"type":"DELETE","kv":{"key":%q}}]}}`,
+		b64("/services/api/c"), b64("10.0.0.3:80"), b64("/services/api/a"))
+
+	select {
+	case got := <-updates:
+		if len(got) != 2 || got[0].Name != "b" || got[1].Name != "c" {
+			t.Fatalf("Expected [b c], got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the change to be sent")
+	}
+}
+
+func TestRangeEnd(t *testing.T) {
+	t.Parallel()
+	if got := string(rangeEnd("abc")); got != "abd" {
+		t.Fatalf("Expected abd, got %q", got)
+	}
+	if got := rangeEnd("a\xff"); string(got) != "b" {
+		t.Fatalf("Expected b, got %q", got)
+	}
+}
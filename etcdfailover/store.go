@@ -0,0 +1,72 @@
+package etcdfailover
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ failover.ObservationStore = (*ObservationStore)(nil)
+
+// ObservationStore is a failover.ObservationStore that keeps observations
+// in etcd under a key prefix, one key per target and instance. Each
+// observation is attached to a lease of its TTL, so etcd drops the votes
+// of instances that stop reporting.
+type ObservationStore struct {
+	p *Provider // Reuses the provider's connection settings
+}
+
+// NewObservationStore creates an ObservationStore keeping observations
+// under prefix. It takes the same options as NewProvider.
+func NewObservationStore(prefix string, opts ...Option) *ObservationStore {
+	return &ObservationStore{p: NewProvider(prefix, opts...)}
+}
+
+// Report stores the observation under a lease of ttl.
+func (s *ObservationStore) Report(ctx context.Context, target, instance string, healthy bool, ttl time.Duration) error {
+	resp, err := s.p.post(ctx, "/v3/lease/grant", map[string]any{
+		"TTL": strconv.FormatInt(int64(max(ttl.Round(time.Second), time.Second)/time.Second), 10),
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var lease struct {
+		ID string `json:"ID"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&lease); err != nil {
+		return err
+	}
+
+	put, err := s.p.post(ctx, "/v3/kv/put", map[string]any{
+		"key":   []byte(s.p.prefix + target + "/" + instance),
+		"value": []byte(strconv.FormatBool(healthy)),
+		"lease": lease.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return put.Body.Close()
+}
+
+// Observations returns the observations of target that have not expired.
+func (s *ObservationStore) Observations(ctx context.Context, target string) (map[string]bool, error) {
+	prefix := s.p.prefix + target + "/"
+	keys, _, err := s.p.read(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	observations := make(map[string]bool, len(keys))
+	for key, value := range keys {
+		observations[strings.TrimPrefix(key, prefix)] = value == "true"
+	}
+
+	return observations, nil
+}
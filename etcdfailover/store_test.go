@@ -0,0 +1,87 @@
+package etcdfailover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// kvServer fakes the lease, put and range gateway endpoints with an
+// in-memory map, ignoring lease expiry.
+func kvServer(t *testing.T) (*httptest.Server, map[string]string) {
+	t.Helper()
+	var mu sync.Mutex
+	kv := make(map[string]string)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TTL      string
+			Key      []byte `json:"key"`
+			Value    []byte `json:"value"`
+			RangeEnd []byte `json:"range_end"`
+			Lease    string `json:"lease"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v3/lease/grant":
+			if body.TTL != "30" {
+				http.Error(w, "unexpected TTL "+body.TTL, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"ID":"42","TTL":"30"}`)
+		case "/v3/kv/put":
+			if body.Lease != "42" {
+				http.Error(w, "missing lease", http.StatusBadRequest)
+				return
+			}
+			kv[string(body.Key)] = string(body.Value)
+			fmt.Fprint(w, `{}`)
+		case "/v3/kv/range":
+			var kvs []string
+			for k, v := range kv {
+				if k >= string(body.Key) && k < string(body.RangeEnd) {
+					kvs = append(kvs, fmt.Sprintf(`{"key":%q,"value":%q}`, b64(k), b64(v)))
+				}
+			}
+			fmt.Fprintf(w, `{"header":{"revision":"1"},"kvs":[%s]}`, strings.Join(kvs, ","))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, kv
+}
+
+func TestObservationStore(t *testing.T) {
+	t.Parallel()
+	srv, kv := kvServer(t)
+	s := NewObservationStore("/failover/", WithEndpoint(srv.URL))
+	ctx := context.Background()
+
+	if err := s.Report(ctx, "primary", "a", false, 30*time.Second); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	s.Report(ctx, "primary", "b", true, 30*time.Second)
+	s.Report(ctx, "primary-2", "a", true, 30*time.Second)
+
+	if kv["/failover/primary/a"] != "false" {
+		t.Fatalf("Expected observation stored under its key, got %v", kv)
+	}
+
+	observations, err := s.Observations(ctx, "primary")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(observations) != 2 || observations["a"] || !observations["b"] {
+		t.Fatalf("Expected a unhealthy and b healthy, got %v", observations)
+	}
+}
@@ -0,0 +1,46 @@
+package failover
+
+import "expvar"
+
+// breakerVar is the expvar form of a breaker.
+type breakerVar struct {
+	State  string  `json:"state"`
+	Counts *Counts `json:"counts,omitempty"` // Nil for breakers that do not count
+}
+
+// PublishExpvar publishes the breakers and retry policies of r as the
+// expvar variables prefix+".breakers" and prefix+".retries", served at
+// /debug/vars by the expvar handler. They are read from r whenever they are
+// served, so policies registered later show up too. Breakers report their
+// state, and their Counts if they are a CircuitBreaker or otherwise provide
+// them; retry policies report their RetryStats. A CircuitBreaker reports
+// its Snapshot, so WithSnapshotInterval bounds how often serving the
+// variables reads its counters.
+//
+// A nil r publishes DefaultRegistry. Like expvar.Publish, it panics if a
+// variable of either name exists.
+func PublishExpvar(prefix string, r *Registry) {
+	expvar.Publish(prefix+".breakers", expvar.Func(func() any {
+		vars := make(map[string]breakerVar)
+		for name, b := range r.Breakers() {
+			v := breakerVar{State: b.State().String()}
+			if cb, ok := b.(*CircuitBreaker); ok {
+				snap := cb.Snapshot()
+				v.State, v.Counts = snap.State.String(), &snap.Counts
+			} else if c, ok := b.(interface{ Counts() Counts }); ok {
+				counts := c.Counts()
+				v.Counts = &counts
+			}
+			vars[name] = v
+		}
+		return vars
+	}))
+
+	expvar.Publish(prefix+".retries", expvar.Func(func() any {
+		vars := make(map[string]RetryStats)
+		for name, p := range r.Retries() {
+			vars[name] = p.Stats()
+		}
+		return vars
+	}))
+}
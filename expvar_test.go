@@ -0,0 +1,45 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	PublishExpvar("test_publish", r)
+
+	// Policies registered after publishing show up too.
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	cb.Execute(func() error { return errTest })
+	cb.Execute(func() error { return nil })
+	r.AddBreaker("db", cb)
+	r.AddBreaker("cache", NoopBreaker{})
+
+	retry := NewRetryPolicy(2, time.Millisecond)
+	retry.Do(context.Background(), func(context.Context) error { return errTest })
+	r.AddRetry("db", retry)
+
+	var breakers map[string]breakerVar
+	if err := json.Unmarshal([]byte(expvar.Get("test_publish.breakers").String()), &breakers); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if db := breakers["db"]; db.State != "open" || db.Counts == nil || db.Counts.TotalFailures != 1 || db.Counts.Rejections != 1 {
+		t.Fatalf("Expected db open after 1 failure and 1 rejection, got %+v", db)
+	}
+	if cache := breakers["cache"]; cache.State != "closed" || cache.Counts != nil {
+		t.Fatalf("Expected cache closed without counts, got %+v", cache)
+	}
+
+	var retries map[string]RetryStats
+	if err := json.Unmarshal([]byte(expvar.Get("test_publish.retries").String()), &retries); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if db := retries["db"]; db != (RetryStats{Calls: 1, Attempts: 2, Failures: 1}) {
+		t.Fatalf("Expected 1 failed call of 2 attempts, got %+v", db)
+	}
+}
@@ -0,0 +1,39 @@
+package failover
+
+import "time"
+
+// FailbackPolicy decides when a FailoverGroup that failed over moves calls
+// back to a higher-priority endpoint that became available again.
+type FailbackPolicy interface {
+	// AllowFailback reports whether calls may move back to ep, which has been
+	// available again for the given duration.
+	AllowFailback(ep *Endpoint, available time.Duration) bool
+}
+
+// FailbackFunc adapts a function to a FailbackPolicy.
+type FailbackFunc func(ep *Endpoint, available time.Duration) bool
+
+// AllowFailback calls f(ep, available).
+func (f FailbackFunc) AllowFailback(ep *Endpoint, available time.Duration) bool {
+	return f(ep, available)
+}
+
+// ImmediateFailback moves calls back as soon as a higher-priority endpoint
+// is available. It is the FailoverGroup default.
+func ImmediateFailback() FailbackPolicy {
+	return FailbackFunc(func(*Endpoint, time.Duration) bool { return true })
+}
+
+// StableFailback moves calls back once a higher-priority endpoint has been
+// available for period, so an endpoint that is flapping does not pull
+// traffic back and forth.
+func StableFailback(period time.Duration) FailbackPolicy {
+	return FailbackFunc(func(_ *Endpoint, available time.Duration) bool { return available >= period })
+}
+
+// ManualFailback never moves calls back on its own; traffic stays on the
+// endpoint it failed over to until it fails in turn or
+// FailoverGroup.Failback is called.
+func ManualFailback() FailbackPolicy {
+	return FailbackFunc(func(*Endpoint, time.Duration) bool { return false })
+}
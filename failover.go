@@ -3,48 +3,24 @@ package failover
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // WorkFunc is a simple function signature for operations that can fail.
 type WorkFunc func() error
 
+// WorkFuncCtx is a WorkFunc that receives the context of the call.
+type WorkFuncCtx func(ctx context.Context) error
+
 // Retry executes a WorkFunc, retrying it on failure.
 // It uses exponential backoff for delays between retries.
 func Retry(ctx context.Context, attempts int, initialDelay time.Duration, fn WorkFunc) error {
-	var err error
-	delay := initialDelay
-
-	for i := range attempts {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-
-		default:
-			// context is not done, proceed.
-		}
-
-		err = fn()
-
-		if err == nil {
-			return nil // success
-		}
-
-		// last attempt
-		if i == attempts-1 {
-			break
-		}
-
-		select {
-		case <-time.After(delay):
-			delay *= 2
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	}
-
-	return err
+	return NewRetryPolicy(attempts, initialDelay).Do(ctx, func(context.Context) error {
+		return fn()
+	})
 }
 
 type State int
@@ -58,90 +34,836 @@ const (
 	HalfOpen
 )
 
-// ErrCircuitOpen is returned  when the circuit breaker is open.
+// String returns the lower-case name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+
+	return "State(" + strconv.Itoa(int(s)) + ")"
+}
+
+// BreakerOverride is a state forced on a CircuitBreaker by an operator,
+// taking precedence over the outcomes of calls.
+type BreakerOverride int32
+
+const (
+	// NoOverride lets the breaker follow the outcomes of calls.
+	NoOverride BreakerOverride = iota
+	// ForcedOpen rejects every call until the breaker is reset.
+	ForcedOpen
+	// Disabled lets every call through without ever tripping.
+	Disabled
+)
+
+// String returns the lower-case name of the override.
+func (o BreakerOverride) String() string {
+	switch o {
+	case NoOverride:
+		return "none"
+	case ForcedOpen:
+		return "forced-open"
+	case Disabled:
+		return "disabled"
+	}
+
+	return "BreakerOverride(" + strconv.Itoa(int(o)) + ")"
+}
+
+// Counts are the outcomes a CircuitBreaker has seen since it was created.
+type Counts struct {
+	Requests            uint64 `json:"requests"` // Calls let through
+	TotalSuccesses      uint64 `json:"total_successes"`
+	TotalFailures       uint64 `json:"total_failures"`
+	Rejections          uint64 `json:"rejections"`           // Calls turned away with ErrCircuitOpen
+	ConsecutiveFailures uint64 `json:"consecutive_failures"` // Failures in a row while Closed
+}
+
+// ErrCircuitOpen is returned  when the circuit breaker is open. The
+// rejection is a CircuitOpenError, which also wraps the failure that
+// opened the breaker.
 var ErrCircuitOpen = errors.New("circuit breaker is open")
 
+// breakerWord packs what a call reads on its way in into one atomic word,
+// so that a successful call costs one load on the way in and one add on the
+// way out: the state in the low byte, the override in the next one and the
+// consecutive failures above them.
+type breakerWord struct{ v atomic.Uint64 }
+
+const (
+	overrideShift = 8
+	failureShift  = 16
+
+	stateMask    = 1<<overrideShift - 1
+	overrideMask = 1<<failureShift - 1 - stateMask
+)
+
+// wordState returns the state packed in w.
+func wordState(w uint64) State {
+	return State(w & stateMask)
+}
+
+// wordOverride returns the override packed in w.
+func wordOverride(w uint64) BreakerOverride {
+	return BreakerOverride(w & overrideMask >> overrideShift)
+}
+
+// wordFailures returns the consecutive failures packed in w.
+func wordFailures(w uint64) int64 {
+	return int64(w >> failureShift)
+}
+
+// Load returns the state.
+func (b *breakerWord) Load() State {
+	return wordState(b.v.Load())
+}
+
+// Store sets the state, resetting the failures when it is Closed.
+func (b *breakerWord) Store(s State) {
+	b.update(func(w uint64) uint64 {
+		if s == Closed {
+			w &= overrideMask
+		}
+		return w&^stateMask | uint64(s)
+	})
+}
+
+// addFailure counts a failure and returns the failures counted.
+func (b *breakerWord) addFailure() int64 {
+	return wordFailures(b.v.Add(1 << failureShift))
+}
+
+// resetFailures sets the failures back to zero.
+func (b *breakerWord) resetFailures() {
+	b.update(func(w uint64) uint64 { return w & (stateMask | overrideMask) })
+}
+
+// update replaces the word with fn of it.
+func (b *breakerWord) update(fn func(uint64) uint64) {
+	for {
+		old := b.v.Load()
+		if b.v.CompareAndSwap(old, fn(old)) {
+			return
+		}
+	}
+}
+
 // CircuitBreaker holds the state of the breaker.
+//
+// The state and counters are atomics so that calls only read them on the
+// way in and update them on the way out; the mutex is taken only to make
+// a state transition. A successful call to a Closed breaker without a
+// failure rate neither allocates nor takes the mutex: it loads the
+// breaker's word on the way in and adds to its success total on the way
+// out.
 type CircuitBreaker struct {
-	mu sync.Mutex // Protects the state fields
+	mu sync.Mutex // Serializes state transitions
+
+	state    breakerWord                     // State, override and consecutive failures
+	settings atomic.Pointer[breakerSettings] // Replaced whole by Reconfigure and the setters
+
+	successCount    atomic.Int64
+	lastFailureTime atomic.Int64                     // When the breaker last opened, in Unix nanoseconds
+	rejection       atomic.Pointer[CircuitOpenError] // Copied into the error of calls while open, set when the breaker opens
+
+	totalSuccesses atomic.Uint64
+	totalFailures  atomic.Uint64
+	rejections     atomic.Uint64
+	shards         *shardedCounts // Replaces the three totals above, nil unless WithShardedCounters
+
+	snapshot         atomic.Pointer[BreakerSnapshot] // Last published by Snapshot
+	snapshotInterval time.Duration                   // How long Snapshot reuses a published snapshot
+
+	window      *rollingWindow // Recent outcomes, nil unless rate-based tripping is enabled
+	failureRate float64        // Failure ratio within window that trips to Open
+	minRequests int            // Calls required in window before the rate is considered
+	sloTarget   float64        // Target success ratio when an error budget is configured
+
+	onStateChange func(from, to State) // Called after every transition, if set
+	tripIf        func(Counts) bool    // Trips Closed on a failure when it returns true, if set
+	failureIf     func(error) bool     // Reports whether an error is a failure, nil for all
+	excludeIf     func(error) bool     // Reports whether an error goes uncounted, nil for none
+	recent        *failureLog          // Last failures, nil unless WithRecentFailures
 
-	state            State
+	clock Clock // Source of the time
+
+	flapTrips  int                 // Trips within flapPeriod that make the breaker flapping, zero to disable
+	flapPeriod time.Duration       // Trailing period trips are counted over
+	onFlap     func(flapping bool) // Called when the breaker starts and stops flapping, if set
+	trips      []time.Time         // Recent trips to Open, oldest first, protected by mu
+	damping    atomic.Int64        // Open timeout while flapping, in nanoseconds, zero when not flapping
+}
+
+// breakerSettings are the thresholds of a CircuitBreaker that can be
+// changed while it is in use.
+type breakerSettings struct {
 	failureThreshold int // How many failures to trip to Open
 	successThreshold int // How many success in HalfOpen to Closed
 	openTimeout      time.Duration
+}
 
-	failureCount    int
-	successCount    int
-	lastFailureTime time.Time
+// BreakerOption configures optional CircuitBreaker behavior.
+type BreakerOption func(*CircuitBreaker)
+
+// WithFailureRate trips the breaker when the ratio of failed calls within
+// the trailing window reaches rate (0 < rate <= 1). It applies in addition
+// to the consecutive failure threshold; pass a failureThreshold of zero to
+// NewCircuitBreaker to trip on rate alone.
+func WithFailureRate(rate float64, window time.Duration) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.failureRate = rate
+		cb.window = newRollingWindow(window)
+	}
+}
+
+// WithRecentFailures keeps the last n failures the breaker counted, with
+// when they happened, their operation, error and latency, for
+// RecentFailures and the admin handler to show what tripped it.
+func WithRecentFailures(n int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.recent = newFailureLog(n)
+	}
+}
+
+// WithMinimumRequests sets how many calls must be recorded within the
+// failure rate window before the rate can trip the breaker. Below this
+// volume the breaker stays Closed regardless of the observed rate.
+func WithMinimumRequests(n int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.minRequests = n
+	}
+}
+
+// WithErrorBudget trips the breaker when failures within the trailing window
+// burn the error budget implied by an SLO target success ratio (e.g. 0.995)
+// at burnRate times the sustainable pace. A burnRate of 1 opens the circuit
+// as soon as the window's failures would exhaust the whole budget; higher
+// values tolerate faster burns before opening. It is bound by the same
+// WithMinimumRequests guard as WithFailureRate, and replaces any rate set
+// with it.
+func WithErrorBudget(target float64, window time.Duration, burnRate float64) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.sloTarget = target
+		cb.failureRate = min((1-target)*burnRate, 1)
+		cb.window = newRollingWindow(window)
+	}
+}
+
+// WithShardedCounters spreads the breaker's counters over shards, rounded
+// up to a power of two, or over one shard per CPU when shards is zero.
+// Calls on different CPUs then count their outcomes without contending for
+// a cache line, which pays off when hundreds of goroutines share a busy
+// breaker. It covers the totals of Counts and the failure rate window;
+// both are added up when read, so a read may miss calls still being
+// counted. The consecutive failures stay exact.
+func WithShardedCounters(shards int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.shards = newShardedCounts(shardCount(shards))
+	}
+}
+
+// WithTripIf also trips a Closed breaker when fn, called with the Counts
+// after each failure, returns true, such as to trip on a condition the
+// thresholds cannot express.
+func WithTripIf(fn func(Counts) bool) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.tripIf = fn
+	}
+}
+
+// WithFailureIf only counts errors for which fn returns true as failures;
+// any other error counts as a success, such as a not-found that shows the
+// dependency is up. The error is still returned to the caller.
+func WithFailureIf(fn func(error) bool) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.failureIf = fn
+	}
+}
+
+// WithExcludeIf leaves calls that fail with an error for which fn returns
+// true out of the breaker's counts altogether, as if they were never made,
+// such as calls canceled by their caller.
+func WithExcludeIf(fn func(error) bool) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.excludeIf = fn
+	}
+}
+
+// WithStateChangeFunc calls fn after every state transition of the
+// breaker, outside its lock, so that fn may use the breaker. Transitions
+// from Open to HalfOpen are seen when the first call after the open timeout
+// arrives.
+func WithStateChangeFunc(fn func(from, to State)) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.onStateChange = fn
+	}
+}
+
+// WithFlapDetection marks the breaker as flapping when it trips to Open
+// trips times within period, the sign of a marginal dependency rather than
+// an outage. While flapping, each further trip doubles the open timeout, up
+// to period, so that the dependency is given longer to settle; the breaker
+// stops flapping once it trips less often again. fn, if not nil, is called
+// outside the lock when the breaker starts and stops flapping.
+func WithFlapDetection(trips int, period time.Duration, fn func(flapping bool)) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.flapTrips = trips
+		cb.flapPeriod = period
+		cb.onFlap = fn
+	}
+}
+
+// WithBreakerClock makes the breaker tell the time with c instead of the
+// system clock, such as to simulate it.
+func WithBreakerClock(c Clock) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.clock = c
+	}
 }
 
 // NewCircuitBreaker creates a new CircuitBreaker with default settings.
-func NewCircuitBreaker(failureThreshold, successThreshold int, openTimeout time.Duration) *CircuitBreaker {
-	return &CircuitBreaker{
-		state:            Closed,
-		failureThreshold: failureThreshold,
-		successThreshold: successThreshold,
-		openTimeout:      openTimeout,
+// It opens after failureThreshold consecutive failures, or on the first
+// one if failureThreshold is zero. With WithFailureRate, WithErrorBudget or
+// WithTripIf, a zero failureThreshold disables the count instead, so that
+// the breaker trips on those alone.
+func NewCircuitBreaker(failureThreshold, successThreshold int, openTimeout time.Duration, opts ...BreakerOption) *CircuitBreaker {
+	cb := &CircuitBreaker{clock: systemClock{}}
+	cb.state.Store(Closed)
+	cb.Reconfigure(failureThreshold, successThreshold, openTimeout)
+
+	for _, opt := range opts {
+		opt(cb)
+	}
+
+	if cb.shards != nil && cb.window != nil {
+		cb.window.split(len(cb.shards.shards))
 	}
+
+	return cb
 }
 
 // Execute wraps a function call with the circuit breaker logic.
 func (cb *CircuitBreaker) Execute(fn WorkFunc) error {
+	w, ok := cb.allow()
+	if !ok {
+		return cb.rejected()
+	}
+
+	if cb.recent != nil {
+		start := cb.clock.Now()
+		err := fn()
+		cb.done(w, err)
+		cb.logFailure("", start, err)
+		return err
+	}
+
+	err := fn()
+	cb.done(w, err)
+	return err
+}
+
+// Do is Execute for a function that takes a context, making the breaker a
+// Policy.
+func (cb *CircuitBreaker) Do(ctx context.Context, fn WorkFuncCtx) error {
+	w, ok := cb.allow()
+	if !ok {
+		return cb.rejected()
+	}
+
+	if cb.recent != nil {
+		start := cb.clock.Now()
+		err := fn(ctx)
+		cb.done(w, err)
+		cb.logFailure(OperationFromContext(ctx), start, err)
+		return err
+	}
+
+	err := fn(ctx)
+	cb.done(w, err)
+	return err
+}
+
+// RecordFailure counts err as the outcome of a call that went through the
+// breaker's admission elsewhere, such as a pooled connection that fails
+// after it was handed out. It is judged like the error of a call in the
+// breaker's current state, but is never rejected. A nil err is ignored.
+func (cb *CircuitBreaker) RecordFailure(err error) {
+	if err == nil {
+		return
+	}
+
+	w := cb.state.v.Load()
+	cb.done(w, err)
+	if cb.recent != nil {
+		cb.logFailure("", cb.clock.Now(), err)
+	}
+}
+
+// allow reports whether a call may proceed, and the breaker's word as the
+// call found it, for done.
+func (cb *CircuitBreaker) allow() (uint64, bool) {
+	w := cb.state.v.Load()
+	switch wordOverride(w) {
+	case Disabled:
+		return w, true
+	case ForcedOpen:
+		cb.reject()
+		return w, false
+	}
+
+	if wordState(w) != Open {
+		return w, true
+	}
+	if cb.allowHalfOpen() {
+		return w&^stateMask | uint64(HalfOpen), true
+	}
+
+	cb.reject()
+	return w, false
+}
+
+// done records the outcome of a call admitted with the word w. The call is
+// judged by the state it was admitted in, so that the fast path need not
+// read the word again.
+func (cb *CircuitBreaker) done(w uint64, err error) {
+	err, counted := cb.judge(err)
+	if !counted {
+		return
+	}
+
+	switch {
+	case cb.shards == nil && err == nil:
+		cb.totalSuccesses.Add(1)
+	case cb.shards == nil:
+		cb.totalFailures.Add(1)
+	case err == nil:
+		cb.shards.shard().successes.Add(1)
+	default:
+		cb.shards.shard().failures.Add(1)
+	}
+
+	if wordOverride(w) != NoOverride {
+		return
+	}
+
+	if err == nil {
+		cb.onSuccess(w)
+		return
+	}
+
+	cb.onFailure(w, err)
+}
+
+// judge returns err if it counts as a failure and nil otherwise, and
+// whether the outcome is counted at all.
+func (cb *CircuitBreaker) judge(err error) (error, bool) {
+	if err == nil {
+		return nil, true
+	}
+	if cb.excludeIf != nil && cb.excludeIf(err) {
+		return nil, false
+	}
+	if cb.failureIf != nil && !cb.failureIf(err) {
+		return nil, true
+	}
+
+	return err, true
+}
+
+// logFailure records a call for operation that started at start and ended
+// with err among the recent failures, if err counts as one.
+func (cb *CircuitBreaker) logFailure(operation string, start time.Time, err error) {
+	if err, _ = cb.judge(err); err == nil {
+		return
+	}
+
+	now := cb.clock.Now()
+	cb.recent.add(FailureRecord{Time: now, Operation: operation, Error: err.Error(), Latency: now.Sub(start)})
+}
+
+// RecentFailures returns the failures kept by WithRecentFailures, oldest
+// first.
+func (cb *CircuitBreaker) RecentFailures() []FailureRecord {
+	return cb.recent.list()
+}
+
+// rejected returns the error for a call the open breaker turned away.
+func (cb *CircuitBreaker) rejected() error {
+	if r := cb.rejection.Load(); r != nil {
+		open, e := *r, rejectedCircuitOpen
+		e.Err = &open
+		return &e
+	}
+
+	return rejectWith(rejectedCircuitOpen)
+}
+
+// reject counts a call turned away.
+func (cb *CircuitBreaker) reject() {
+	if cb.shards != nil {
+		cb.shards.shard().rejections.Add(1)
+		return
+	}
+
+	cb.rejections.Add(1)
+}
+
+// Counts returns the outcomes the breaker has seen. It reads atomics only,
+// so it never waits for the request path; see Snapshot to read it together
+// with the state.
+func (cb *CircuitBreaker) Counts() Counts {
+	return cb.counts(cb.state.v.Load())
+}
+
+// counts returns the outcomes the breaker has seen, with the consecutive
+// failures of the word w.
+func (cb *CircuitBreaker) counts(w uint64) Counts {
+	successes, failures, rejections := cb.totalSuccesses.Load(), cb.totalFailures.Load(), cb.rejections.Load()
+	if cb.shards != nil {
+		successes, failures, rejections = cb.shards.load()
+	}
+
+	return Counts{
+		Requests:            successes + failures,
+		TotalSuccesses:      successes,
+		TotalFailures:       failures,
+		Rejections:          rejections,
+		ConsecutiveFailures: uint64(wordFailures(w)),
+	}
+}
+
+// consecutiveFailures returns the failures counted since the last success.
+func (cb *CircuitBreaker) consecutiveFailures() int64 {
+	return wordFailures(cb.state.v.Load())
+}
+
+// State returns the current state of the breaker. An Open breaker whose
+// timeout has expired reports HalfOpen, the state the next call finds it in.
+// Like Counts, it never waits for the request path.
+func (cb *CircuitBreaker) State() State {
+	return cb.reported(cb.state.v.Load())
+}
+
+// reported returns the state State reports for the word w.
+func (cb *CircuitBreaker) reported(w uint64) State {
+	state := wordState(w)
+	if state == Open && wordOverride(w) != ForcedOpen && cb.openExpired() {
+		return HalfOpen
+	}
+
+	return state
+}
+
+// Reset closes the breaker, forgetting the failures and trips it has seen,
+// and lifts any override.
+func (cb *CircuitBreaker) Reset() {
+	cb.force(NoOverride, Closed)
+}
+
+// ForceOpen opens the breaker and keeps it open, rejecting every call,
+// until Reset is called.
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.force(ForcedOpen, Open)
+}
+
+// Disable closes the breaker and keeps it closed, letting every call
+// through, until Reset is called. Outcomes are still counted.
+func (cb *CircuitBreaker) Disable() {
+	cb.force(Disabled, Closed)
+}
+
+// Override returns the override in force, if any.
+func (cb *CircuitBreaker) Override() BreakerOverride {
+	return wordOverride(cb.state.v.Load())
+}
+
+// force sets the override and moves the breaker to state.
+func (cb *CircuitBreaker) force(o BreakerOverride, state State) {
 	cb.mu.Lock()
+	from := cb.state.Load()
+	flapping := cb.Flapping()
+	cb.trips = nil
+	cb.damping.Store(0)
+	cb.successCount.Store(0)
+	if cb.window != nil {
+		cb.window.reset()
+	}
+	if state == Open {
+		now := cb.clock.Now()
+		cb.lastFailureTime.Store(now.UnixNano())
+		cb.opened(now, nil)
+	}
+	cb.state.v.Store(uint64(state) | uint64(o)<<overrideShift)
+	cb.mu.Unlock()
 
-	if cb.state == Open {
-		if time.Since(cb.lastFailureTime) > cb.openTimeout {
-			cb.state = HalfOpen
-			cb.successCount = 0
+	if from != state && cb.onStateChange != nil {
+		cb.onStateChange(from, state)
+	}
+	if flapping && cb.onFlap != nil {
+		cb.onFlap(false)
+	}
+}
+
+// Reconfigure replaces the thresholds and open timeout the breaker was
+// created with, keeping its state and counts. Calls in flight may still see
+// the old values.
+func (cb *CircuitBreaker) Reconfigure(failureThreshold, successThreshold int, openTimeout time.Duration) {
+	cb.settings.Store(&breakerSettings{
+		failureThreshold: failureThreshold,
+		successThreshold: successThreshold,
+		openTimeout:      openTimeout,
+	})
+}
+
+// SetFailureThreshold changes how many consecutive failures trip the
+// breaker, with zero meaning what it does for NewCircuitBreaker.
+func (cb *CircuitBreaker) SetFailureThreshold(n int) {
+	cb.update(func(s *breakerSettings) { s.failureThreshold = n })
+}
+
+// SetSuccessThreshold changes how many successes in HalfOpen close the
+// breaker.
+func (cb *CircuitBreaker) SetSuccessThreshold(n int) {
+	cb.update(func(s *breakerSettings) { s.successThreshold = n })
+}
+
+// SetOpenTimeout changes how long the breaker stays Open before letting a
+// trial call through, including for the current open period.
+func (cb *CircuitBreaker) SetOpenTimeout(d time.Duration) {
+	cb.update(func(s *breakerSettings) { s.openTimeout = d })
+}
 
-		} else {
-			cb.mu.Unlock()
-			return ErrCircuitOpen
+// update replaces the settings with a copy changed by fn, so that calls
+// always see a consistent set.
+func (cb *CircuitBreaker) update(fn func(*breakerSettings)) {
+	for {
+		old := cb.settings.Load()
+		s := *old
+		fn(&s)
+		if cb.settings.CompareAndSwap(old, &s) {
+			return
 		}
 	}
+}
 
-	cb.mu.Unlock()
+// Flapping reports whether the breaker is flapping, as detected with
+// WithFlapDetection.
+func (cb *CircuitBreaker) Flapping() bool {
+	return cb.damping.Load() > 0
+}
 
-	err := fn()
+// openExpired reports whether the open timeout, or the damped one while
+// flapping, has elapsed since the breaker last opened.
+func (cb *CircuitBreaker) openExpired() bool {
+	return cb.clock.Now().Sub(time.Unix(0, cb.lastFailureTime.Load())) > cb.openTimeout()
+}
+
+// openTimeout returns how long the breaker stays open: the configured
+// timeout, or the damped one while flapping.
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if d := cb.damping.Load(); d > 0 {
+		return time.Duration(d)
+	}
+
+	return cb.settings.Load().openTimeout
+}
 
+// allowHalfOpen moves an Open breaker whose timeout has expired to HalfOpen
+// and reports whether the call may proceed.
+func (cb *CircuitBreaker) allowHalfOpen() bool {
+	if !cb.openExpired() {
+		return false
+	}
+
+	allowed, moved := cb.halfOpen()
+	if moved && cb.onStateChange != nil {
+		cb.onStateChange(Open, HalfOpen)
+	}
+
+	return allowed
+}
+
+// halfOpen moves an Open breaker whose timeout has expired to HalfOpen. It
+// reports whether the call may proceed and whether it made the move.
+func (cb *CircuitBreaker) halfOpen() (allowed, moved bool) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	if err == nil {
-		return cb.onSuccess()
+	if cb.state.Load() != Open {
+		return true, false // another call already moved on
 	}
 
-	cb.onFailure()
-	return err
+	// Re-check under the lock: the breaker may have re-opened meanwhile.
+	if !cb.openExpired() {
+		return false, false
+	}
+
+	cb.successCount.Store(0)
+	cb.state.Store(HalfOpen)
+	return true, true
 }
 
-// onSuccess handles a successful call.
-func (cb *CircuitBreaker) onSuccess() error {
-	switch cb.state {
+// onSuccess handles a successful call admitted with the word w.
+func (cb *CircuitBreaker) onSuccess(w uint64) {
+	switch wordState(w) {
 	case HalfOpen:
-		cb.successCount++
-		if cb.successCount >= cb.successThreshold {
-			cb.state = Closed
-			cb.failureCount = 0
+		if cb.successCount.Add(1) >= int64(cb.settings.Load().successThreshold) {
+			cb.transition(HalfOpen, Closed, nil)
 		}
 	case Closed:
-		cb.failureCount = 0
+		// Avoid the write, and the cache line bounce, when already zero.
+		if wordFailures(w) != 0 {
+			cb.state.resetFailures()
+		}
+		if cb.window != nil {
+			cb.window.success(cb.clock.Now())
+		}
 	}
-
-	return nil
 }
 
-func (cb *CircuitBreaker) onFailure() error {
-	switch cb.state {
+// onFailure handles a call admitted with the word w that failed with err.
+func (cb *CircuitBreaker) onFailure(w uint64, err error) {
+	switch wordState(w) {
 	case HalfOpen:
-		cb.state = Open
-		cb.lastFailureTime = time.Now()
+		cb.transition(HalfOpen, Open, err)
 	case Closed:
-		cb.failureCount++
-		if cb.failureCount >= cb.failureThreshold {
-			cb.state = Open
-			cb.lastFailureTime = time.Now()
+		failures := cb.state.addFailure()
+		exceeded := false
+		if cb.window != nil {
+			now := cb.clock.Now()
+			cb.window.failure(now)
+			exceeded = cb.rateExceeded(now)
+		}
+
+		if cb.countTrips() && failures >= int64(cb.settings.Load().failureThreshold) || exceeded ||
+			cb.tripIf != nil && cb.tripIf(cb.Counts()) {
+			cb.transition(Closed, Open, err)
 		}
 	}
-	return nil
+}
+
+// countTrips reports whether consecutive failures trip the breaker: always
+// with a failure threshold, and with a zero one unless another condition
+// trips it.
+func (cb *CircuitBreaker) countTrips() bool {
+	return cb.settings.Load().failureThreshold > 0 || cb.window == nil && cb.tripIf == nil
+}
+
+// transition moves the breaker from one state to another, unless a
+// concurrent call has already moved it away from the expected state. cause
+// is the failure that opened it, if it moves to Open.
+func (cb *CircuitBreaker) transition(from, to State, cause error) {
+	moved, flapping, flapChanged := cb.move(from, to, cause)
+	if moved && cb.onStateChange != nil {
+		cb.onStateChange(from, to)
+	}
+	if flapChanged && cb.onFlap != nil {
+		cb.onFlap(flapping)
+	}
+}
+
+// move makes the transition under the lock and reports whether it did, and
+// whether that made the breaker start or stop flapping.
+func (cb *CircuitBreaker) move(from, to State, cause error) (moved, flapping, flapChanged bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state.Load() != from {
+		return false, false, false
+	}
+
+	was := cb.Flapping()
+	switch to {
+	case Open:
+		now := cb.clock.Now()
+		cb.lastFailureTime.Store(now.UnixNano())
+		cb.damp(now)
+		cb.opened(now, cause)
+	case Closed:
+		if cb.window != nil {
+			cb.window.reset()
+		}
+	}
+
+	cb.state.Store(to)
+	return true, cb.Flapping(), cb.Flapping() != was
+}
+
+// opened sets the error returned to the calls rejected after the breaker
+// opened at now because of cause, nil when forced open. It is called with
+// the lock held.
+func (cb *CircuitBreaker) opened(now time.Time, cause error) {
+	open := &CircuitOpenError{LastErr: cause, OpenedAt: now}
+	if cause != nil {
+		open.RetryAt = now.Add(cb.openTimeout())
+	}
+	cb.rejection.Store(open)
+}
+
+// damp records a trip at now and sets the open timeout to use while
+// flapping, or none if the breaker is not. It is called with the lock held.
+func (cb *CircuitBreaker) damp(now time.Time) {
+	if cb.flapTrips <= 0 {
+		return
+	}
+
+	cb.trips = append(cb.trips, now)
+	for len(cb.trips) > 0 && now.Sub(cb.trips[0]) > cb.flapPeriod {
+		cb.trips = cb.trips[1:]
+	}
+
+	extra := len(cb.trips) - cb.flapTrips
+	if extra < 0 {
+		cb.damping.Store(0)
+		return
+	}
+
+	base := cb.settings.Load().openTimeout
+	timeout := base
+	for range extra + 1 {
+		timeout = min(timeout*2, max(cb.flapPeriod, base))
+	}
+	cb.damping.Store(max(int64(timeout), 1)) // non-zero, so that Flapping holds
+
+}
+
+// rateExceeded reports whether the failure rate within the window has
+// reached the trip rate with enough volume to be meaningful.
+func (cb *CircuitBreaker) rateExceeded(now time.Time) bool {
+	if cb.window == nil {
+		return false
+	}
+
+	successes, failures := cb.window.counts(now)
+	total := successes + failures
+	if total == 0 || total < cb.minRequests {
+		return false
+	}
+
+	return float64(failures)/float64(total) >= cb.failureRate
+}
+
+// ErrorBudgetRemaining reports the fraction of the error budget left within
+// the current window, from 1 (no failures) down to 0 (exhausted). It returns
+// 1 when no error budget is configured or no calls have been recorded.
+func (cb *CircuitBreaker) ErrorBudgetRemaining() float64 {
+	if cb.window == nil || cb.sloTarget == 0 {
+		return 1
+	}
+
+	successes, failures := cb.window.counts(cb.clock.Now())
+	allowed := (1 - cb.sloTarget) * float64(successes+failures)
+	if allowed == 0 {
+		if failures > 0 {
+			return 0
+		}
+		return 1
+	}
+
+	return max(1-float64(failures)/allowed, 0)
 }
@@ -0,0 +1,217 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FailoverGroup routes calls to the highest-priority available endpoint of
+// an ordered list (primary, secondary, tertiary...). An endpoint is skipped
+// while its breaker is open or its health check reports it Down. When a
+// higher-priority endpoint than the active one becomes available again, the
+// FailbackPolicy decides when calls move back to it.
+type FailoverGroup struct {
+	mu        sync.Mutex // Protects endpoints, active and available
+	endpoints []*Endpoint
+	active    *Endpoint               // Endpoint that took the last call
+	previous  *Endpoint               // Active endpoint before Failback, for onSwitch
+	available map[*Endpoint]time.Time // When each available endpoint was first seen available
+
+	health     HealthSource // Optional source of endpoint status
+	failback   FailbackPolicy
+	failoverOn func(error) bool         // Errors that move a call on to the next endpoint, nil for none
+	onSwitch   func(from, to *Endpoint) // Called when calls move to another endpoint, if set
+}
+
+// FailoverGroupOption configures optional FailoverGroup behavior.
+type FailoverGroupOption func(*FailoverGroup)
+
+// WithHealthChecker skips endpoints that h reports as Down. Endpoints are
+// looked up in h by name; Unknown ones are considered available.
+func WithHealthChecker(h *HealthChecker) FailoverGroupOption {
+	return WithHealthSource(h)
+}
+
+// WithHealthSource skips endpoints that s reports as Down, like
+// WithHealthChecker. Pass a Quorum to fail over only on a cluster-wide
+// verdict.
+func WithHealthSource(s HealthSource) FailoverGroupOption {
+	return func(g *FailoverGroup) {
+		g.health = s
+	}
+}
+
+// WithFailback sets how the group moves back to a recovered higher-priority
+// endpoint. The default is ImmediateFailback.
+func WithFailback(p FailbackPolicy) FailoverGroupOption {
+	return func(g *FailoverGroup) {
+		g.failback = p
+	}
+}
+
+// WithFailoverOn moves a call on to the next endpoint when it fails with an
+// error for which fn returns true, as when the endpoint's breaker is open.
+// It suits errors that say the endpoint could not be reached at all, so
+// that the call can not have had an effect.
+func WithFailoverOn(fn func(error) bool) FailoverGroupOption {
+	return func(g *FailoverGroup) {
+		g.failoverOn = fn
+	}
+}
+
+// WithSwitchFunc calls fn, outside the group's lock, every time calls move
+// from one endpoint to another, whether failing over or back.
+func WithSwitchFunc(fn func(from, to *Endpoint)) FailoverGroupOption {
+	return func(g *FailoverGroup) {
+		g.onSwitch = fn
+	}
+}
+
+// NewFailoverGroup creates a FailoverGroup over endpoints, in priority
+// order.
+func NewFailoverGroup(endpoints []*Endpoint, opts ...FailoverGroupOption) *FailoverGroup {
+	g := &FailoverGroup{
+		endpoints: endpoints,
+		available: make(map[*Endpoint]time.Time),
+		failback:  ImmediateFailback(),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Do runs fn against the highest-priority available endpoint the failback
+// policy allows. When an endpoint's breaker rejects the call, the next
+// endpoint is tried within the same call, and endpoints held back by the
+// failback policy are tried last. It returns ErrNoEndpoint if none could
+// take the call, wrapping the last error that moved the call on with
+// WithFailoverOn, if any.
+func (g *FailoverGroup) Do(ctx context.Context, fn EndpointFunc) error {
+	var last error
+	for _, ep := range g.candidates() {
+		err := ep.call(ctx, fn)
+		if errors.Is(err, ErrCircuitOpen) {
+			continue
+		}
+		if err != nil && g.failoverOn != nil && g.failoverOn(err) && ctx.Err() == nil {
+			last = err
+			continue
+		}
+
+		g.mu.Lock()
+		from := g.active
+		g.active = ep
+		if from == nil {
+			from = g.previous // moving on from an endpoint forgotten by Failback
+		}
+		g.previous = nil
+		g.mu.Unlock()
+
+		if from != nil && from != ep && g.onSwitch != nil {
+			g.onSwitch(from, ep)
+		}
+		return err
+	}
+
+	if last != nil {
+		return fmt.Errorf("%w: %w", ErrNoEndpoint, last)
+	}
+	return ErrNoEndpoint
+}
+
+// Active returns the endpoint that took the last call, or nil before the
+// first one.
+func (g *FailoverGroup) Active() *Endpoint {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.active
+}
+
+// Endpoints returns the group's endpoints in priority order.
+func (g *FailoverGroup) Endpoints() []*Endpoint {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]*Endpoint(nil), g.endpoints...)
+}
+
+// SetEndpoints replaces the group's endpoints, in priority order. Endpoints
+// with the same name and address as a current one keep the current one's
+// state.
+func (g *FailoverGroup) SetEndpoints(endpoints []*Endpoint) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.endpoints = mergeEndpoints(g.endpoints, endpoints)
+	for ep := range g.available {
+		if !slices.Contains(g.endpoints, ep) {
+			delete(g.available, ep)
+		}
+	}
+}
+
+// Failback moves calls back to the highest-priority available endpoint,
+// regardless of the failback policy. It is how traffic returns to the
+// primary with ManualFailback.
+func (g *FailoverGroup) Failback() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.active != nil {
+		g.previous = g.active
+	}
+	g.active = nil
+}
+
+// candidates returns the endpoints to try, in order: available endpoints the
+// failback policy allows by priority, then the ones it holds back.
+func (g *FailoverGroup) candidates() []*Endpoint {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	// Only hold endpoints back while the active one can still take calls.
+	active := slices.Index(g.endpoints, g.active)
+	if active >= 0 && !g.isAvailable(g.active) {
+		active = -1
+	}
+
+	var allowed, held []*Endpoint
+	for i, ep := range g.endpoints {
+		if !g.isAvailable(ep) {
+			delete(g.available, ep)
+			continue
+		}
+
+		since, ok := g.available[ep]
+		if !ok {
+			since = now
+			g.available[ep] = now
+		}
+
+		if active >= 0 && i < active && !g.failback.AllowFailback(ep, now.Sub(since)) {
+			held = append(held, ep)
+			continue
+		}
+		allowed = append(allowed, ep)
+	}
+
+	return append(allowed, held...)
+}
+
+// isAvailable reports whether ep is neither Down nor behind an open breaker.
+func (g *FailoverGroup) isAvailable(ep *Endpoint) bool {
+	if g.health != nil && g.health.Status(ep.Name) == Down {
+		return false
+	}
+
+	return ep.Breaker == nil || ep.Breaker.State() != Open
+}
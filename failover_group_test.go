@@ -0,0 +1,220 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// record returns an EndpointFunc that records the endpoint it ran on and
+// fails on the endpoints named in failing.
+func record(got *[]string, failing ...string) EndpointFunc {
+	return func(_ context.Context, ep *Endpoint) error {
+		*got = append(*got, ep.Name)
+		for _, name := range failing {
+			if ep.Name == name {
+				return errTest
+			}
+		}
+		return nil
+	}
+}
+
+func TestFailoverGroup_PrefersPrimary(t *testing.T) {
+	t.Parallel()
+	g := NewFailoverGroup([]*Endpoint{{Name: "primary"}, {Name: "secondary"}})
+
+	var got []string
+	if err := g.Do(context.Background(), record(&got)); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(got) != 1 || got[0] != "primary" {
+		t.Fatalf("Expected call routed to primary, got %v", got)
+	}
+	if g.Active().Name != "primary" {
+		t.Fatalf("Expected active endpoint primary, got %s", g.Active().Name)
+	}
+}
+
+func TestFailoverGroup_SwitchesOnOpenBreaker(t *testing.T) {
+	t.Parallel()
+	primary := &Endpoint{Name: "primary", Breaker: NewCircuitBreaker(2, 1, 50*time.Millisecond)}
+	secondary := &Endpoint{Name: "secondary", Breaker: NewCircuitBreaker(2, 1, 50*time.Millisecond)}
+	g := NewFailoverGroup([]*Endpoint{primary, secondary})
+	ctx := context.Background()
+
+	var got []string
+	for range 2 {
+		if err := g.Do(ctx, record(&got, "primary")); !errors.Is(err, errTest) {
+			t.Fatalf("Expected error %v, got %v", errTest, err)
+		}
+	}
+
+	got = nil
+	if err := g.Do(ctx, record(&got, "primary")); err != nil {
+		t.Fatalf("Expected secondary to take the call, got %v", err)
+	}
+	if len(got) != 1 || got[0] != "secondary" || g.Active() != secondary {
+		t.Fatalf("Expected call routed to secondary, got %v", got)
+	}
+
+	// Once the primary's breaker lets a trial call through, traffic moves back.
+	time.Sleep(60 * time.Millisecond)
+	got = nil
+	g.Do(ctx, record(&got))
+	if len(got) != 1 || got[0] != "primary" || g.Active() != primary {
+		t.Fatalf("Expected call routed back to primary, got %v", got)
+	}
+}
+
+func TestFailoverGroup_SkipsUnhealthy(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(time.Hour)
+	h.Add("primary", func(context.Context) error { return errTest })
+	h.Check(context.Background(), "primary")
+
+	g := NewFailoverGroup([]*Endpoint{{Name: "primary"}, {Name: "secondary"}}, WithHealthChecker(h))
+
+	var got []string
+	g.Do(context.Background(), record(&got))
+	if len(got) != 1 || got[0] != "secondary" {
+		t.Fatalf("Expected call routed to secondary, got %v", got)
+	}
+}
+
+func TestFailoverGroup_NoEndpoint(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	cb.Execute(func() error { return errTest })
+	g := NewFailoverGroup([]*Endpoint{{Name: "only", Breaker: cb}})
+
+	var got []string
+	if err := g.Do(context.Background(), record(&got)); !errors.Is(err, ErrNoEndpoint) {
+		t.Fatalf("Expected ErrNoEndpoint, got %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Expected no call to run, got %v", got)
+	}
+}
+
+// failoverFromPrimary returns a group of primary and secondary whose
+// primary breaker was just tripped and has taken the group to secondary.
+func failoverFromPrimary(t *testing.T, openTimeout time.Duration, opts ...FailoverGroupOption) *FailoverGroup {
+	t.Helper()
+	primary := &Endpoint{Name: "primary", Breaker: NewCircuitBreaker(1, 1, openTimeout)}
+	secondary := &Endpoint{Name: "secondary", Breaker: NewCircuitBreaker(1, 1, time.Hour)}
+	g := NewFailoverGroup([]*Endpoint{primary, secondary}, opts...)
+
+	var got []string
+	g.Do(context.Background(), record(&got, "primary"))
+	g.Do(context.Background(), record(&got))
+	if g.Active().Name != "secondary" {
+		t.Fatalf("Expected group to fail over to secondary, got %s", g.Active().Name)
+	}
+
+	return g
+}
+
+func TestFailoverGroup_ManualFailback(t *testing.T) {
+	t.Parallel()
+	g := failoverFromPrimary(t, 10*time.Millisecond, WithFailback(ManualFailback()))
+	ctx := context.Background()
+
+	time.Sleep(20 * time.Millisecond)
+	var got []string
+	g.Do(ctx, record(&got))
+	if got[0] != "secondary" {
+		t.Fatalf("Expected calls to stay on secondary, got %v", got)
+	}
+
+	g.Failback()
+	got = nil
+	g.Do(ctx, record(&got))
+	if got[0] != "primary" {
+		t.Fatalf("Expected call on primary after Failback, got %v", got)
+	}
+}
+
+func TestFailoverGroup_ManualFailbackWhenActiveFails(t *testing.T) {
+	t.Parallel()
+	g := failoverFromPrimary(t, 10*time.Millisecond, WithFailback(ManualFailback()))
+	ctx := context.Background()
+	time.Sleep(20 * time.Millisecond)
+
+	var got []string
+	g.Do(ctx, record(&got, "secondary")) // trips the secondary's breaker
+	if got[0] != "secondary" {
+		t.Fatalf("Expected secondary to take the call, got %v", got)
+	}
+
+	got = nil
+	g.Do(ctx, record(&got))
+	if len(got) != 1 || got[0] != "primary" {
+		t.Fatalf("Expected call on primary once secondary is unavailable, got %v", got)
+	}
+}
+
+func TestFailoverGroup_StableFailback(t *testing.T) {
+	t.Parallel()
+	g := failoverFromPrimary(t, 10*time.Millisecond, WithFailback(StableFailback(40*time.Millisecond)))
+	ctx := context.Background()
+
+	time.Sleep(20 * time.Millisecond)
+	var got []string
+	g.Do(ctx, record(&got)) // first sight of the recovered primary
+	if got[0] != "secondary" {
+		t.Fatalf("Expected calls to stay on secondary during the stability period, got %v", got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	got = nil
+	g.Do(ctx, record(&got))
+	if got[0] != "primary" {
+		t.Fatalf("Expected call on primary after the stability period, got %v", got)
+	}
+}
+
+func TestFailoverGroup_FailoverOn(t *testing.T) {
+	t.Parallel()
+	g := NewFailoverGroup([]*Endpoint{{Name: "primary"}, {Name: "secondary"}},
+		WithFailoverOn(func(err error) bool { return errors.Is(err, errTest) }))
+
+	var got []string
+	if err := g.Do(context.Background(), record(&got, "primary")); err != nil {
+		t.Fatalf("Expected secondary to take the call, got %v", err)
+	}
+	if len(got) != 2 || got[1] != "secondary" || g.Active().Name != "secondary" {
+		t.Fatalf("Expected call moved on to secondary, got %v", got)
+	}
+
+	got = nil
+	err := g.Do(context.Background(), record(&got, "primary", "secondary"))
+	if !errors.Is(err, ErrNoEndpoint) || !errors.Is(err, errTest) {
+		t.Fatalf("Expected ErrNoEndpoint wrapping %v, got %v", errTest, err)
+	}
+}
+
+func TestFailoverGroup_SwitchFunc(t *testing.T) {
+	t.Parallel()
+	primary := &Endpoint{Name: "primary", Breaker: NewCircuitBreaker(1, 1, time.Minute)}
+	secondary := &Endpoint{Name: "secondary"}
+
+	var switches []string
+	g := NewFailoverGroup([]*Endpoint{primary, secondary}, WithSwitchFunc(func(from, to *Endpoint) {
+		switches = append(switches, from.Name+">"+to.Name)
+	}))
+	ctx := context.Background()
+
+	var got []string
+	g.Do(ctx, record(&got, "primary")) // trips the primary, first call is not a switch
+	g.Do(ctx, record(&got))
+	g.Do(ctx, record(&got))
+
+	primary.Breaker.(*CircuitBreaker).Reset()
+	g.Do(ctx, record(&got))
+
+	if len(switches) != 2 || switches[0] != "primary>secondary" || switches[1] != "secondary>primary" {
+		t.Fatalf("Expected a switch over and back, got %v", switches)
+	}
+}
@@ -3,6 +3,8 @@ package failover
 import (
 	"context"
 	"errors"
+	"math"
+	"sync"
 	"testing"
 	"time"
 )
@@ -105,16 +107,16 @@ func TestCircuitBreaker_Flow(t *testing.T) {
 	if err := cb.Execute(succeed); err != nil {
 		t.Fatalf("State Closed: Expected nil error, got %v", err)
 	}
-	if cb.state != Closed {
-		t.Fatalf("State Closed: Expected state Closed, got %v", cb.state)
+	if cb.state.Load() != Closed {
+		t.Fatalf("State Closed: Expected state Closed, got %v", cb.state.Load())
 	}
 
 	// --- 2. Trip to Open
 	if err := cb.Execute(fail); !errors.Is(err, errTest) {
 		t.Fatalf("State Closed->Open: Expected test error, got %v", err)
 	}
-	if cb.state != Closed {
-		t.Fatalf("State Closed->Open: Expected state Closed after 1 failure, got %v", cb.state)
+	if cb.state.Load() != Closed {
+		t.Fatalf("State Closed->Open: Expected state Closed after 1 failure, got %v", cb.state.Load())
 	}
 
 	// Fail 2 (this should trip the breaker)
@@ -122,8 +124,8 @@ func TestCircuitBreaker_Flow(t *testing.T) {
 	if err := cb.Execute(fail); !errors.Is(err, errTest) {
 		t.Fatalf("State Closed->Open: Expected test error 2nd failure, got %v", err)
 	}
-	if cb.state != Open {
-		t.Fatalf("State Closed->Open: Expected test error on 2nd failure, got %v", cb.state)
+	if cb.state.Load() != Open {
+		t.Fatalf("State Closed->Open: Expected test error on 2nd failure, got %v", cb.state.Load())
 	}
 
 	// --- 4. Move to Half-Open ---
@@ -134,8 +136,8 @@ func TestCircuitBreaker_Flow(t *testing.T) {
 	if err := cb.Execute(fail); !errors.Is(err, errTest) {
 		t.Fatalf("State HalfOpen->Open: Expected test error, got %v", err)
 	}
-	if cb.state != Open {
-		t.Fatalf("State HalfOpen->Open: Expected state Open after failure, got %v", cb.state)
+	if cb.state.Load() != Open {
+		t.Fatalf("State HalfOpen->Open: Expected state Open after failure, got %v", cb.state.Load())
 	}
 
 	// --- 6. Move to Half-Open (again) ---
@@ -147,22 +149,22 @@ func TestCircuitBreaker_Flow(t *testing.T) {
 	if err := cb.Execute(succeed); err != nil {
 		t.Fatalf("State HalfOpen->Closed: Expected nil error on 1st success, got %v", err)
 	}
-	if cb.state != HalfOpen { // Not yet closed
-		t.Fatalf("State HalfOpen->Closed: Expected state HalfOpen, got %v", cb.state)
+	if cb.state.Load() != HalfOpen { // Not yet closed
+		t.Fatalf("State HalfOpen->Closed: Expected state HalfOpen, got %v", cb.state.Load())
 	}
-	if cb.successCount != 1 {
-		t.Fatalf("State HalfOpen->Closed: Expected successCount 1, got %d", cb.successCount)
+	if cb.successCount.Load() != 1 {
+		t.Fatalf("State HalfOpen->Closed: Expected successCount 1, got %d", cb.successCount.Load())
 	}
 
 	// Success 2 (This should close the circuit)
 	if err := cb.Execute(succeed); err != nil {
 		t.Fatalf("State HalfOpen->Closed: Expected nil error on 2nd success, got %v", err)
 	}
-	if cb.state != Closed {
-		t.Fatalf("State HalfOpen->Closed: Expected state Closed, got %v", cb.state)
+	if cb.state.Load() != Closed {
+		t.Fatalf("State HalfOpen->Closed: Expected state Closed, got %v", cb.state.Load())
 	}
-	if cb.failureCount != 0 {
-		t.Fatalf("State HalfOpen->Closed: Expected failureCount to be 0, got %d", cb.failureCount)
+	if cb.consecutiveFailures() != 0 {
+		t.Fatalf("State HalfOpen->Closed: Expected failureCount to be 0, got %d", cb.consecutiveFailures())
 	}
 
 	// --- 8. Back to Closed ---
@@ -170,8 +172,8 @@ func TestCircuitBreaker_Flow(t *testing.T) {
 	if err := cb.Execute(succeed); err != nil {
 		t.Fatalf("State Closed (final): Expected nil error, got %v", err)
 	}
-	if cb.state != Closed {
-		t.Fatalf("State Closed (final): Expected state Closed, got %v", cb.state)
+	if cb.state.Load() != Closed {
+		t.Fatalf("State Closed (final): Expected state Closed, got %v", cb.state.Load())
 	}
 }
 
@@ -184,31 +186,548 @@ func TestCircuitBreaker_ResetOnSuccessInClosed(t *testing.T) {
 
 	// Fail 1
 	_ = cb.Execute(fail)
-	if cb.failureCount != 1 {
-		t.Fatalf("Expected failureCount 1, got %d", cb.failureCount)
+	if cb.consecutiveFailures() != 1 {
+		t.Fatalf("Expected failureCount 1, got %d", cb.consecutiveFailures())
 	}
 
 	// Fail 2
 	_ = cb.Execute(fail)
-	if cb.failureCount != 2 {
-		t.Fatalf("Expected failureCount 2, got %d", cb.failureCount)
+	if cb.consecutiveFailures() != 2 {
+		t.Fatalf("Expected failureCount 2, got %d", cb.consecutiveFailures())
 	}
 
 	// Success (should reset counter)
 	_ = cb.Execute(succeed)
-	if cb.failureCount != 0 {
-		t.Fatalf("Expected failureCount to reset to 0 after success, got %d", cb.failureCount)
+	if cb.consecutiveFailures() != 0 {
+		t.Fatalf("Expected failureCount to reset to 0 after success, got %d", cb.consecutiveFailures())
 	}
-	if cb.state != Closed {
-		t.Fatalf("Expected state to remain Closed, got %v", cb.state)
+	if cb.state.Load() != Closed {
+		t.Fatalf("Expected state to remain Closed, got %v", cb.state.Load())
 	}
 
 	// Fail 3 (should not trip, since counter was reset)
 	_ = cb.Execute(fail)
-	if cb.failureCount != 1 {
-		t.Fatalf("Expected failureCount 1, got %d", cb.failureCount)
+	if cb.consecutiveFailures() != 1 {
+		t.Fatalf("Expected failureCount 1, got %d", cb.consecutiveFailures())
 	}
-	if cb.state != Closed {
-		t.Fatalf("Expected state to remain Closed, got %v", cb.state)
+	if cb.state.Load() != Closed {
+		t.Fatalf("Expected state to remain Closed, got %v", cb.state.Load())
+	}
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	t.Parallel()
+	// Trip on rate alone: 50% failures over at least 4 calls.
+	cb := NewCircuitBreaker(0, 1, 1*time.Minute,
+		WithFailureRate(0.5, 1*time.Minute),
+		WithMinimumRequests(4),
+	)
+
+	fail := func() error { return errTest }
+	succeed := func() error { return nil }
+
+	// 1 failure out of 2 calls is 50%, but below the minimum volume.
+	_ = cb.Execute(succeed)
+	_ = cb.Execute(fail)
+	if cb.state.Load() != Closed {
+		t.Fatalf("Expected state Closed below minimum requests, got %v", cb.state.Load())
+	}
+
+	_ = cb.Execute(succeed)
+	if cb.state.Load() != Closed {
+		t.Fatalf("Expected state Closed at 33%% failure rate, got %v", cb.state.Load())
+	}
+
+	// 2 failures out of 4 calls reaches both the rate and the volume.
+	_ = cb.Execute(fail)
+	if cb.state.Load() != Open {
+		t.Fatalf("Expected state Open at 50%% failure rate, got %v", cb.state.Load())
+	}
+}
+
+func TestCircuitBreaker_FailureRateWithConsecutiveThreshold(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(2, 1, 1*time.Minute,
+		WithFailureRate(0.9, 1*time.Minute),
+		WithMinimumRequests(100),
+	)
+
+	fail := func() error { return errTest }
+
+	// The consecutive threshold still trips even though the rate cannot.
+	_ = cb.Execute(fail)
+	_ = cb.Execute(fail)
+	if cb.state.Load() != Open {
+		t.Fatalf("Expected state Open after 2 consecutive failures, got %v", cb.state.Load())
+	}
+}
+
+func TestCircuitBreaker_ErrorBudget(t *testing.T) {
+	t.Parallel()
+	// 90% target leaves a 10% budget; a burn rate of 2 trips at 20% failures.
+	cb := NewCircuitBreaker(0, 1, 1*time.Minute,
+		WithErrorBudget(0.9, 1*time.Minute, 2),
+		WithMinimumRequests(10),
+	)
+
+	fail := func() error { return errTest }
+	succeed := func() error { return nil }
+
+	for range 8 {
+		_ = cb.Execute(succeed)
+	}
+	_ = cb.Execute(fail)
+
+	if remaining := cb.ErrorBudgetRemaining(); remaining != 0 {
+		t.Fatalf("Expected exhausted budget after 1 of 9 calls failed, got %v", remaining)
+	}
+	if cb.state.Load() != Closed {
+		t.Fatalf("Expected state Closed below minimum requests, got %v", cb.state.Load())
+	}
+
+	// 2 failures out of 10 calls is a 20% failure rate.
+	_ = cb.Execute(fail)
+	if cb.state.Load() != Open {
+		t.Fatalf("Expected state Open at twice the budget burn, got %v", cb.state.Load())
+	}
+}
+
+func TestCircuitBreaker_ErrorBudgetRemaining(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(0, 1, 1*time.Minute, WithErrorBudget(0.5, 1*time.Minute, 1))
+
+	if remaining := cb.ErrorBudgetRemaining(); remaining != 1 {
+		t.Fatalf("Expected full budget with no traffic, got %v", remaining)
+	}
+
+	for range 3 {
+		_ = cb.Execute(func() error { return nil })
+	}
+	_ = cb.Execute(func() error { return errTest })
+
+	// 4 calls allow 2 failures; 1 has been used.
+	if remaining := cb.ErrorBudgetRemaining(); remaining != 0.5 {
+		t.Fatalf("Expected half the budget remaining, got %v", remaining)
+	}
+}
+
+func TestCircuitBreaker_ConcurrentTrip(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(10, 1, 1*time.Minute)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = cb.Execute(func() error { return errTest })
+		}()
+	}
+	wg.Wait()
+
+	if cb.state.Load() != Open {
+		t.Fatalf("Expected state Open after concurrent failures, got %v", cb.state.Load())
+	}
+	if err := cb.Execute(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_ZeroThreshold(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(0, 1, time.Minute)
+	cb.Execute(func() error { return errTest })
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected a zero threshold to trip on the first failure, got %v", s)
+	}
+
+	cb = NewCircuitBreaker(0, 1, time.Minute, WithFailureRate(0.5, time.Minute), WithMinimumRequests(3))
+	cb.Execute(func() error { return errTest })
+	if s := cb.State(); s != Closed {
+		t.Fatalf("Expected a rate alone to decide, got %v", s)
+	}
+}
+
+func TestCircuitBreaker_FailuresKeepOverride(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(0, 1, time.Minute)
+
+	cb.ForceOpen()
+	for range 3 {
+		cb.state.addFailure()
+	}
+	if cb.State() != Open || cb.Override() != ForcedOpen {
+		t.Fatalf("Expected failures to leave Open and ForcedOpen, got %v and %v", cb.State(), cb.Override())
+	}
+
+	cb.state.Store(Closed)
+	if cb.consecutiveFailures() != 0 {
+		t.Fatalf("Expected Closed to reset the failures, got %d", cb.consecutiveFailures())
+	}
+	if cb.Override() != ForcedOpen {
+		t.Fatalf("Expected the override to survive, got %v", cb.Override())
+	}
+}
+
+func TestCircuitBreaker_ShardedCounters(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(0, 1, time.Minute, WithShardedCounters(4), WithFailureRate(0.5, time.Minute), WithMinimumRequests(4))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = cb.Execute(func() error { return nil })
+		}()
+	}
+	wg.Wait()
+
+	for range 4 {
+		_ = cb.Execute(func() error { return errTest })
+	}
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected the sharded window to trip the breaker, got %v", s)
+	}
+	_ = cb.Execute(func() error { return nil })
+
+	want := Counts{Requests: 8, TotalSuccesses: 4, TotalFailures: 4, Rejections: 1, ConsecutiveFailures: 4}
+	if c := cb.Counts(); c != want {
+		t.Fatalf("Expected counts %+v, got %+v", want, c)
+	}
+}
+
+func TestCircuitBreaker_TripIf(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(0, 1, time.Minute, WithTripIf(func(c Counts) bool {
+		return c.Requests >= 3 && c.TotalFailures*2 > c.Requests
+	}))
+
+	cb.Execute(func() error { return errTest })
+	cb.Execute(func() error { return nil })
+	if s := cb.State(); s != Closed {
+		t.Fatalf("Expected state %v below the volume, got %v", Closed, s)
+	}
+
+	cb.Execute(func() error { return errTest })
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected state %v, got %v", Open, s)
+	}
+}
+
+func TestCircuitBreaker_FailureIf(t *testing.T) {
+	t.Parallel()
+	errNotFound := errors.New("not found")
+	cb := NewCircuitBreaker(1, 1, time.Minute, WithFailureIf(func(err error) bool { return err != errNotFound }))
+
+	if err := cb.Execute(func() error { return errNotFound }); err != errNotFound {
+		t.Fatalf("Expected error %v, got %v", errNotFound, err)
+	}
+	if c := cb.Counts(); c.TotalSuccesses != 1 || cb.State() != Closed {
+		t.Fatalf("Expected the error to count as a success, got %+v and %v", c, cb.State())
+	}
+}
+
+func TestCircuitBreaker_ExcludeIf(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute, WithExcludeIf(func(err error) bool { return errors.Is(err, context.Canceled) }))
+
+	if err := cb.Execute(func() error { return context.Canceled }); err != context.Canceled {
+		t.Fatalf("Expected error %v, got %v", context.Canceled, err)
+	}
+	if c := cb.Counts(); c != (Counts{}) || cb.State() != Closed {
+		t.Fatalf("Expected the call to go uncounted, got %+v and %v", c, cb.State())
+	}
+}
+
+func TestCircuitBreaker_ClosedAllocs(t *testing.T) {
+	// Not parallel: AllocsPerRun counts the allocations of every goroutine.
+	cb := NewCircuitBreaker(5, 1, time.Minute)
+	ctx := context.Background()
+	fn := func() error { return nil }
+	fnCtx := func(context.Context) error { return nil }
+
+	if n := testing.AllocsPerRun(100, func() { _ = cb.Execute(fn) }); n != 0 {
+		t.Fatalf("Expected Execute to make no allocations, got %v", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { _ = cb.Do(ctx, fnCtx) }); n != 0 {
+		t.Fatalf("Expected Do to make no allocations, got %v", n)
+	}
+}
+
+// --- Benchmarks ---
+
+func BenchmarkCircuitBreaker_Execute(b *testing.B) {
+	cb := NewCircuitBreaker(5, 1, time.Minute)
+	fn := func() error { return nil }
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = cb.Execute(fn)
+		}
+	})
+}
+
+func BenchmarkCircuitBreaker_ExecuteSerial(b *testing.B) {
+	cb := NewCircuitBreaker(5, 1, time.Minute)
+	fn := func() error { return nil }
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = cb.Execute(fn)
+	}
+}
+
+func BenchmarkCircuitBreaker_ExecuteFailing(b *testing.B) {
+	cb := NewCircuitBreaker(math.MaxInt, 1, time.Minute) // never trips
+	fn := func() error { return errTest }
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = cb.Execute(fn)
+		}
+	})
+}
+
+func BenchmarkCircuitBreaker_ExecuteSharded(b *testing.B) {
+	cb := NewCircuitBreaker(5, 1, time.Minute, WithShardedCounters(0))
+	fn := func() error { return nil }
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = cb.Execute(fn)
+		}
+	})
+}
+
+func BenchmarkCircuitBreaker_ExecuteWithFailureRateSharded(b *testing.B) {
+	cb := NewCircuitBreaker(0, 1, time.Minute, WithFailureRate(0.5, time.Minute), WithShardedCounters(0))
+	fn := func() error { return nil }
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = cb.Execute(fn)
+		}
+	})
+}
+
+func BenchmarkCircuitBreaker_ExecuteWithFailureRate(b *testing.B) {
+	cb := NewCircuitBreaker(0, 1, time.Minute, WithFailureRate(0.5, time.Minute))
+	fn := func() error { return nil }
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = cb.Execute(fn)
+		}
+	})
+}
+
+func TestCircuitBreaker_Do(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+
+	if err := cb.Do(context.Background(), func(context.Context) error { return errTest }); !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+	if err := cb.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_RecordFailure(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(2, 1, time.Minute)
+
+	cb.RecordFailure(nil)
+	cb.RecordFailure(errTest)
+	cb.RecordFailure(errTest)
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected Open, got %v", s)
+	}
+
+	// A failure recorded while open is counted, not rejected.
+	cb.RecordFailure(errTest)
+	if c := cb.Counts(); c.TotalFailures != 3 || c.Rejections != 0 {
+		t.Fatalf("Expected 3 failures and no rejections, got %+v", c)
+	}
+}
+
+func TestCircuitBreaker_StateAfterOpenTimeout(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, 20*time.Millisecond)
+	cb.Execute(func() error { return errTest })
+
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected state %v, got %v", Open, s)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if s := cb.State(); s != HalfOpen {
+		t.Fatalf("Expected expired Open breaker to report %v, got %v", HalfOpen, s)
+	}
+}
+
+func TestCircuitBreaker_StateChangeFunc(t *testing.T) {
+	t.Parallel()
+	var changes []string
+	var cb *CircuitBreaker
+	cb = NewCircuitBreaker(1, 1, 20*time.Millisecond, WithStateChangeFunc(func(from, to State) {
+		// Reading the breaker from the callback must not deadlock.
+		if s := cb.State(); s != to {
+			t.Errorf("Expected the breaker to be %v in the callback, got %v", to, s)
+		}
+		changes = append(changes, from.String()+"->"+to.String())
+	}))
+
+	cb.Execute(func() error { return errTest })
+	time.Sleep(30 * time.Millisecond)
+	cb.Execute(func() error { return nil })
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] || changes[2] != want[2] {
+		t.Fatalf("Expected transitions %v, got %v", want, changes)
+	}
+}
+
+func TestCircuitBreaker_Counts(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(2, 1, time.Minute)
+
+	cb.Execute(func() error { return nil })
+	cb.Execute(func() error { return errTest })
+	cb.Execute(func() error { return errTest })
+	cb.Execute(func() error { return nil }) // rejected
+
+	want := Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2, Rejections: 1, ConsecutiveFailures: 2}
+	if c := cb.Counts(); c != want {
+		t.Fatalf("Expected counts %+v, got %+v", want, c)
+	}
+}
+
+func TestCircuitBreaker_ForceOpen(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Millisecond)
+
+	cb.ForceOpen()
+	time.Sleep(5 * time.Millisecond)
+
+	// The open timeout does not lift a forced open.
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected state %v, got %v", Open, s)
+	}
+	if err := cb.Execute(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	cb.Reset()
+	if err := cb.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("Expected nil error after reset, got %v", err)
+	}
+	if o := cb.Override(); o != NoOverride {
+		t.Fatalf("Expected override %v, got %v", NoOverride, o)
+	}
+}
+
+func TestCircuitBreaker_Disable(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	cb.Execute(func() error { return errTest })
+
+	cb.Disable()
+	for range 3 {
+		if err := cb.Execute(func() error { return errTest }); !errors.Is(err, errTest) {
+			t.Fatalf("Expected error %v, got %v", errTest, err)
+		}
+	}
+
+	if s := cb.State(); s != Closed {
+		t.Fatalf("Expected a disabled breaker to stay %v, got %v", Closed, s)
+	}
+	if c := cb.Counts(); c.TotalFailures != 4 {
+		t.Fatalf("Expected 4 failures counted, got %d", c.TotalFailures)
+	}
+}
+
+func TestCircuitBreaker_Reconfigure(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(3, 1, time.Minute)
+	cb.Execute(func() error { return errTest })
+
+	// The failure already counted carries over to the new threshold.
+	cb.Reconfigure(2, 1, time.Millisecond)
+	cb.Execute(func() error { return errTest })
+	if s := cb.State(); s != Open {
+		t.Fatalf("Expected state %v, got %v", Open, s)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if err := cb.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("Expected the new open timeout to let a call through, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_Setters(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+
+	cb.SetFailureThreshold(2)
+	cb.SetSuccessThreshold(2)
+	cb.SetOpenTimeout(time.Millisecond)
+
+	cb.Execute(func() error { return errTest })
+	if s := cb.State(); s != Closed {
+		t.Fatalf("Expected state %v after one failure, got %v", Closed, s)
+	}
+	cb.Execute(func() error { return errTest })
+
+	time.Sleep(5 * time.Millisecond)
+	cb.Execute(func() error { return nil })
+	if s := cb.State(); s != HalfOpen {
+		t.Fatalf("Expected state %v after one success, got %v", HalfOpen, s)
+	}
+}
+
+func TestCircuitBreaker_FlapDetection(t *testing.T) {
+	t.Parallel()
+	var flaps []bool
+	cb := NewCircuitBreaker(1, 1, 5*time.Millisecond, WithFlapDetection(2, time.Minute, func(flapping bool) {
+		flaps = append(flaps, flapping)
+	}))
+
+	trip := func() {
+		t.Helper()
+		time.Sleep(10 * time.Millisecond)
+		cb.Execute(func() error { return errTest })
+		if s := cb.State(); s != Open {
+			t.Fatalf("Expected state %v, got %v", Open, s)
+		}
+	}
+
+	trip()
+	if cb.Flapping() {
+		t.Fatal("Expected one trip not to be flapping")
+	}
+	trip() // the trial call of HalfOpen fails
+
+	if !cb.Flapping() {
+		t.Fatal("Expected two trips to be flapping")
+	}
+	// The open timeout is damped to 10ms.
+	time.Sleep(7 * time.Millisecond)
+	if err := cb.Execute(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen while damped, got %v", err)
+	}
+
+	cb.Reset()
+	if cb.Flapping() {
+		t.Fatal("Expected Reset to stop the flapping")
+	}
+	if len(flaps) != 2 || !flaps[0] || flaps[1] {
+		t.Fatalf("Expected flapping to start and stop, got %v", flaps)
 	}
 }
@@ -0,0 +1,258 @@
+// Package failoverconfig builds failover policies from a YAML or JSON
+// document, so that retries, breakers, bulkheads, timeouts and the
+// pipelines combining them can be tuned without a rebuild:
+//
+//	retries:
+//	  db: {attempts: 3, initial_delay: 100ms, max_delay: 2s, jitter: 0.2}
+//	breakers:
+//	  db: {failure_threshold: 5, success_threshold: 1, open_timeout: 30s}
+//	timeouts:
+//	  db-call: {timeout: 500ms}
+//	pipelines:
+//	  db: {retry: db, breaker: db, timeout: db-call}
+//
+// A Reloader applies later versions of the document to the live policies,
+// pushed with Apply or fed with Watch from a Source such as a FileSource or
+// an HTTPSource.
+package failoverconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalid is returned for a document that does not describe valid
+// policies.
+var ErrInvalid = errors.New("failoverconfig: invalid config")
+
+// Duration is a time.Duration written as a string such as "1.5s".
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config describes named policies. Retries and breakers are registered as
+// such; bulkheads, timeouts and pipelines as policies, so their names must
+// not collide.
+type Config struct {
+	Retries   map[string]RetryConfig    `json:"retries,omitempty" yaml:"retries,omitempty"`
+	Breakers  map[string]BreakerConfig  `json:"breakers,omitempty" yaml:"breakers,omitempty"`
+	Bulkheads map[string]BulkheadConfig `json:"bulkheads,omitempty" yaml:"bulkheads,omitempty"`
+	Timeouts  map[string]TimeoutConfig  `json:"timeouts,omitempty" yaml:"timeouts,omitempty"`
+	Pipelines map[string]PipelineConfig `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+}
+
+// RetryConfig describes a failover.RetryPolicy with exponential backoff.
+type RetryConfig struct {
+	Attempts     int      `json:"attempts" yaml:"attempts"`                         // Calls to make, at least 1
+	InitialDelay Duration `json:"initial_delay" yaml:"initial_delay"`               // Delay before the first retry
+	MaxDelay     Duration `json:"max_delay,omitempty" yaml:"max_delay,omitempty"`   // Upper bound on the delay, zero for none
+	Multiplier   float64  `json:"multiplier,omitempty" yaml:"multiplier,omitempty"` // Growth factor per retry; 2 if unset
+	Jitter       float64  `json:"jitter,omitempty" yaml:"jitter,omitempty"`         // Fraction of the delay to randomize, in [0, 1]
+}
+
+// BreakerConfig describes a failover.CircuitBreaker. Setting FailureRate
+// trips it on the share of failures over Window instead of on consecutive
+// failures.
+type BreakerConfig struct {
+	FailureThreshold int      `json:"failure_threshold" yaml:"failure_threshold"`
+	SuccessThreshold int      `json:"success_threshold" yaml:"success_threshold"`
+	OpenTimeout      Duration `json:"open_timeout" yaml:"open_timeout"`
+	FailureRate      float64  `json:"failure_rate,omitempty" yaml:"failure_rate,omitempty"` // In (0, 1], zero for none
+	Window           Duration `json:"window,omitempty" yaml:"window,omitempty"`             // Required with FailureRate
+	MinRequests      int      `json:"min_requests,omitempty" yaml:"min_requests,omitempty"` // Calls in Window before the rate counts
+}
+
+// BulkheadConfig describes a failover.Bulkhead.
+type BulkheadConfig struct {
+	MaxConcurrent int      `json:"max_concurrent" yaml:"max_concurrent"`
+	MaxQueue      int      `json:"max_queue,omitempty" yaml:"max_queue,omitempty"`
+	QueueTimeout  Duration `json:"queue_timeout,omitempty" yaml:"queue_timeout,omitempty"`
+}
+
+// TimeoutConfig describes a failover.Timeout.
+type TimeoutConfig struct {
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+}
+
+// PipelineConfig describes a failover.Pipeline by the names of the
+// policies it is made of, each optional. Pipelines share the named
+// breakers and bulkheads they refer to.
+type PipelineConfig struct {
+	Retry    string `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Breaker  string `json:"breaker,omitempty" yaml:"breaker,omitempty"`
+	Timeout  string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Bulkhead string `json:"bulkhead,omitempty" yaml:"bulkhead,omitempty"`
+}
+
+// Parse decodes a YAML or JSON document. Unknown fields are an error, so
+// that a misspelled setting is not silently ignored.
+func Parse(data []byte) (*Config, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	return &cfg, nil
+}
+
+// Load reads, parses and builds the document at path.
+func Load(path string) (*failover.Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.Build()
+}
+
+// Validate reports the first problem with c, wrapping ErrInvalid.
+func (c *Config) Validate() error {
+	for _, name := range sortedKeys(c.Retries) {
+		r := c.Retries[name]
+		switch {
+		case r.Attempts < 1:
+			return invalid("retry", name, "attempts must be at least 1")
+		case r.InitialDelay < 0 || r.MaxDelay < 0:
+			return invalid("retry", name, "delays must not be negative")
+		case r.Multiplier < 0:
+			return invalid("retry", name, "multiplier must not be negative")
+		case r.Jitter < 0 || r.Jitter > 1:
+			return invalid("retry", name, "jitter must be in [0, 1]")
+		}
+	}
+
+	for _, name := range sortedKeys(c.Breakers) {
+		b := c.Breakers[name]
+		switch {
+		case b.FailureThreshold < 0:
+			return invalid("breaker", name, "failure_threshold must not be negative")
+		case b.FailureThreshold == 0 && b.FailureRate == 0:
+			return invalid("breaker", name, "failure_threshold or failure_rate must be set")
+		case b.SuccessThreshold < 1:
+			return invalid("breaker", name, "success_threshold must be at least 1")
+		case b.OpenTimeout <= 0:
+			return invalid("breaker", name, "open_timeout must be positive")
+		case b.FailureRate < 0 || b.FailureRate > 1:
+			return invalid("breaker", name, "failure_rate must be in [0, 1]")
+		case b.FailureRate > 0 && b.Window <= 0:
+			return invalid("breaker", name, "window must be positive with failure_rate")
+		}
+	}
+
+	policies := make(map[string]string) // Kind by name, across the kinds registered as policies
+	for _, name := range sortedKeys(c.Bulkheads) {
+		b := c.Bulkheads[name]
+		switch {
+		case b.MaxConcurrent < 1:
+			return invalid("bulkhead", name, "max_concurrent must be at least 1")
+		case b.MaxQueue < 0 || b.QueueTimeout < 0:
+			return invalid("bulkhead", name, "max_queue and queue_timeout must not be negative")
+		}
+		policies[name] = "bulkhead"
+	}
+
+	for _, name := range sortedKeys(c.Timeouts) {
+		if c.Timeouts[name].Timeout <= 0 {
+			return invalid("timeout", name, "timeout must be positive")
+		}
+		if kind, ok := policies[name]; ok {
+			return invalid("timeout", name, "name is taken by a "+kind)
+		}
+		policies[name] = "timeout"
+	}
+
+	for _, name := range sortedKeys(c.Pipelines) {
+		p := c.Pipelines[name]
+		if kind, ok := policies[name]; ok {
+			return invalid("pipeline", name, "name is taken by a "+kind)
+		}
+		if err := refers(name, "retry", p.Retry, c.Retries); err != nil {
+			return err
+		}
+		if err := refers(name, "breaker", p.Breaker, c.Breakers); err != nil {
+			return err
+		}
+		if err := refers(name, "timeout", p.Timeout, c.Timeouts); err != nil {
+			return err
+		}
+		if err := refers(name, "bulkhead", p.Bulkhead, c.Bulkheads); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Build validates c and constructs its policies into a new registry:
+// retries with AddRetry, breakers with AddBreaker, and bulkheads, timeouts
+// and pipelines with AddPolicy. Use a Reloader instead to apply later
+// changes to the policies.
+func (c *Config) Build() (*failover.Registry, error) {
+	r, err := NewReloader(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Registry(), nil
+}
+
+// refers checks that pipeline refers to a defined policy of kind, if any.
+func refers[T any](pipeline, kind, name string, defined map[string]T) error {
+	if _, ok := defined[name]; name != "" && !ok {
+		return invalid("pipeline", pipeline, fmt.Sprintf("unknown %s %q", kind, name))
+	}
+
+	return nil
+}
+
+func invalid(kind, name, problem string) error {
+	return fmt.Errorf("%w: %s %q: %s", ErrInvalid, kind, name, problem)
+}
+
+// names returns the names of every policy in c.
+func (c *Config) names() []string {
+	var names []string
+	names = slices.AppendSeq(names, maps.Keys(c.Retries))
+	names = slices.AppendSeq(names, maps.Keys(c.Breakers))
+	names = slices.AppendSeq(names, maps.Keys(c.Bulkheads))
+	names = slices.AppendSeq(names, maps.Keys(c.Timeouts))
+	names = slices.AppendSeq(names, maps.Keys(c.Pipelines))
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	return slices.Sorted(maps.Keys(m))
+}
@@ -0,0 +1,166 @@
+package failoverconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var errTest = errors.New("test error")
+
+const testYAML = `
+retries:
+  db: {attempts: 3, initial_delay: 1ms, max_delay: 5ms}
+breakers:
+  db: {failure_threshold: 1, success_threshold: 1, open_timeout: 1m}
+bulkheads:
+  db-pool: {max_concurrent: 2}
+timeouts:
+  db-call: {timeout: 50ms}
+pipelines:
+  db: {retry: db, breaker: db, timeout: db-call, bulkhead: db-pool}
+`
+
+func TestParse_YAML(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(testYAML))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if r := cfg.Retries["db"]; r.Attempts != 3 || time.Duration(r.MaxDelay) != 5*time.Millisecond {
+		t.Fatalf("Expected 3 attempts up to 5ms apart, got %+v", r)
+	}
+	if p := cfg.Pipelines["db"]; p.Timeout != "db-call" || p.Bulkhead != "db-pool" {
+		t.Fatalf("Expected the pipeline's references, got %+v", p)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(`{"breakers": {"api": {"failure_threshold": 0, "success_threshold": 2,
+		"open_timeout": "10s", "failure_rate": 0.5, "window": "1m", "min_requests": 20}}}`))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if b := cfg.Breakers["api"]; b.FailureRate != 0.5 || time.Duration(b.Window) != time.Minute || b.MinRequests != 20 {
+		t.Fatalf("Expected a rate breaker, got %+v", b)
+	}
+	if _, err := cfg.Build(); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, doc := range []string{
+		"retries: {db: {attempts: 3, initial_delay: soon}}",
+		"retries: {db: {attempts: 3, retries: 2}}",
+	} {
+		if _, err := Parse([]byte(doc)); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("Expected ErrInvalid for %q, got %v", doc, err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	cfg, _ := Parse([]byte(testYAML))
+	reg, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if _, ok := reg.Retries()["db"]; !ok {
+		t.Fatal("Expected the retry to be registered")
+	}
+	cb, ok := reg.Breakers()["db"]
+	if !ok {
+		t.Fatal("Expected the breaker to be registered")
+	}
+	for _, name := range []string{"db-pool", "db-call", "db"} {
+		if _, ok := reg.Policy(name); !ok {
+			t.Fatalf("Expected policy %q to be registered", name)
+		}
+	}
+
+	// The pipeline shares the named breaker: one failed call trips it, and
+	// the retries are rejected by it.
+	pipeline, _ := reg.Policy("db")
+	calls := 0
+	err = pipeline.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	})
+	if !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+	if cb.State() != failover.Open {
+		t.Fatalf("Expected the registered breaker to be open, got %v", cb.State())
+	}
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"attempts":        "retries: {db: {attempts: 0}}",
+		"jitter":          "retries: {db: {attempts: 1, jitter: 2}}",
+		"threshold":       "breakers: {db: {success_threshold: 1, open_timeout: 1s}}",
+		"rate window":     "breakers: {db: {success_threshold: 1, open_timeout: 1s, failure_rate: 0.5}}",
+		"bulkhead":        "bulkheads: {db: {max_concurrent: 0}}",
+		"timeout":         "timeouts: {db: {timeout: 0s}}",
+		"unknown retry":   "pipelines: {db: {retry: missing}}",
+		"unknown breaker": "pipelines: {db: {breaker: missing}}",
+		"name collision":  "timeouts: {db: {timeout: 1s}}\npipelines: {db: {timeout: db}}",
+	}
+
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := Parse([]byte(doc))
+			if err != nil {
+				t.Fatalf("Expected nil error, got %v", err)
+			}
+			if _, err := cfg.Build(); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "failover.yaml")
+	if err := os.WriteFile(path, []byte(testYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if n := len(reg.Policies()); n != 3 {
+		t.Fatalf("Expected 3 policies, got %d", n)
+	}
+
+	if _, err := Load(path + ".missing"); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Fatalf("Expected a read error, got %v", err)
+	}
+}
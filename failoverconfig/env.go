@@ -0,0 +1,130 @@
+package failoverconfig
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Overlay sets the fields of c from the variables of environ, as returned
+// by os.Environ, that start with prefix, so that a deployment can tune its
+// policies without a config file of its own. Variables are named
+//
+//	<prefix>_<KIND>_<NAME>_<FIELD>
+//
+// with KIND one of RETRY, BREAKER, BULKHEAD, TIMEOUT or PIPELINE, and
+// FIELD the field's name in the document in upper case, such as
+// FAILOVER_BREAKER_PAYMENTS_FAILURE_THRESHOLD=10. NAME matches the policy
+// whose name is the same once upper-cased with dashes and dots turned into
+// underscores; a policy that does not exist is added, named NAME in lower
+// case. Unknown kinds and fields are an error, as misspelled variables
+// would otherwise be ignored.
+func (c *Config) Overlay(prefix string, environ []string) error {
+	kinds := map[string]reflect.Value{
+		"RETRY":    reflect.ValueOf(&c.Retries).Elem(),
+		"BREAKER":  reflect.ValueOf(&c.Breakers).Elem(),
+		"BULKHEAD": reflect.ValueOf(&c.Bulkheads).Elem(),
+		"TIMEOUT":  reflect.ValueOf(&c.Timeouts).Elem(),
+		"PIPELINE": reflect.ValueOf(&c.Pipelines).Elem(),
+	}
+
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(key, prefix+"_")
+		if !ok {
+			continue
+		}
+
+		kind, rest, _ := strings.Cut(rest, "_")
+		m, ok := kinds[kind]
+		if !ok {
+			return fmt.Errorf("%w: %s: unknown kind %q", ErrInvalid, key, kind)
+		}
+
+		if err := overlay(m, rest, value); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalid, key, err)
+		}
+	}
+
+	return nil
+}
+
+// overlay sets the field named by the end of key, in the entry of m named
+// by the rest of it, to value.
+func overlay(m reflect.Value, key, value string) error {
+	typ := m.Type().Elem()
+	for i := range typ.NumField() {
+		tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		name, ok := strings.CutSuffix(key, "_"+strings.ToUpper(tag))
+		if !ok || name == "" {
+			continue
+		}
+
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+		name = envName(m, name)
+		entry := reflect.New(typ).Elem()
+		if v := m.MapIndex(reflect.ValueOf(name)); v.IsValid() {
+			entry.Set(v)
+		}
+
+		if err := setField(entry.Field(i), value); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(name), entry)
+		return nil
+	}
+
+	return fmt.Errorf("unknown field in %q", key)
+}
+
+// envName returns the name of the entry of m that name refers to, or name
+// in lower case if there is none.
+func envName(m reflect.Value, name string) string {
+	for _, k := range m.MapKeys() {
+		if envKey(k.String()) == name {
+			return k.String()
+		}
+	}
+
+	return strings.ToLower(name)
+}
+
+// envKey returns name as it appears in a variable name.
+func envKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, strings.ToUpper(name))
+}
+
+// setField parses value into field.
+func setField(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		field.SetString(value)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package failoverconfig
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConfig_Overlay(t *testing.T) {
+	t.Parallel()
+
+	cfg, _ := Parse([]byte(testYAML))
+	err := cfg.Overlay("FAILOVER", []string{
+		"HOME=/root",
+		"FAILOVER_BREAKER_DB_FAILURE_THRESHOLD=10",
+		"FAILOVER_TIMEOUT_DB_CALL_TIMEOUT=2s",
+		"FAILOVER_RETRY_PAYMENTS_ATTEMPTS=4",
+		"FAILOVER_RETRY_PAYMENTS_JITTER=0.5",
+		"FAILOVER_PIPELINE_DB_RETRY=payments",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if b := cfg.Breakers["db"]; b.FailureThreshold != 10 || b.SuccessThreshold != 1 {
+		t.Fatalf("Expected only the failure threshold to change, got %+v", b)
+	}
+	if d := time.Duration(cfg.Timeouts["db-call"].Timeout); d != 2*time.Second {
+		t.Fatalf("Expected timeout 2s, got %v", d)
+	}
+	if r := cfg.Retries["payments"]; r.Attempts != 4 || r.Jitter != 0.5 {
+		t.Fatalf("Expected a new retry, got %+v", r)
+	}
+	if p := cfg.Pipelines["db"]; p.Retry != "payments" || p.Breaker != "db" {
+		t.Fatalf("Expected the pipeline to use the new retry, got %+v", p)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
+
+func TestConfig_Overlay_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, kv := range []string{
+		"FAILOVER_CACHE_DB_SIZE=1",
+		"FAILOVER_RETRY_DB_RETRIES=1",
+		"FAILOVER_RETRY_ATTEMPTS=1",
+		"FAILOVER_RETRY_DB_ATTEMPTS=many",
+		"FAILOVER_TIMEOUT_DB_TIMEOUT=soon",
+	} {
+		var cfg Config
+		if err := cfg.Overlay("FAILOVER", []string{kv}); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("Expected ErrInvalid for %s, got %v", kv, err)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/dadanrm/failover/failoverconfig
+
+go 1.24.7
+
+require (
+	github.com/dadanrm/failover v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/dadanrm/failover => ../
@@ -0,0 +1,136 @@
+package failoverconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var _ Source = (*HTTPSource)(nil)
+
+// HTTPSource is a Source that polls an HTTP endpoint, so that settings
+// can be tuned for a whole fleet from one place. It sends the ETag of the
+// last document in If-None-Match, so an unchanged document costs a 304.
+type HTTPSource struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	header   http.Header
+}
+
+// HTTPSourceOption configures optional HTTPSource behavior.
+type HTTPSourceOption func(*HTTPSource)
+
+// WithHTTPClient sets the client used to fetch the document. The default
+// is http.DefaultClient.
+func WithHTTPClient(client *http.Client) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.client = client
+	}
+}
+
+// WithHeader adds a header to every request, such as for authentication.
+func WithHeader(key, value string) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.header.Add(key, value)
+	}
+}
+
+// NewHTTPSource creates an HTTPSource that fetches url every interval.
+func NewHTTPSource(url string, interval time.Duration, opts ...HTTPSourceOption) *HTTPSource {
+	s := &HTTPSource{
+		url:      url,
+		interval: interval,
+		client:   http.DefaultClient,
+		header:   make(http.Header),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Watch sends the document every time it changes. It fails if the first
+// fetch does; later failures are retried at the next interval.
+func (s *HTTPSource) Watch(ctx context.Context) (<-chan []byte, error) {
+	data, etag, err := s.fetch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []byte, 1)
+	ch <- data
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			next, nextETag, err := s.fetch(ctx, etag)
+			if err != nil || next == nil {
+				continue // failed, or not modified
+			}
+			etag = nextETag
+			if bytes.Equal(next, data) {
+				continue
+			}
+			data = next
+
+			select {
+			case ch <- next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// fetch gets the document and its ETag, or nil if it still matches etag.
+func (s *HTTPSource) fetch(ctx context.Context, etag string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for key, values := range s.header {
+		req.Header[key] = values
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, nil
+	default:
+		return nil, "", fmt.Errorf("failoverconfig: fetching %s: %s", s.url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, resp.Header.Get("ETag"), nil
+}
@@ -0,0 +1,76 @@
+package failoverconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPSource(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	doc, etag := "a", `"1"`
+	var notModified atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(doc))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	docs, err := NewHTTPSource(srv.URL, time.Millisecond, WithHeader("Authorization", "Bearer token")).Watch(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if d := string(<-docs); d != "a" {
+		t.Fatalf("Expected the current document, got %q", d)
+	}
+
+	for notModified.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	doc, etag = "b", `"2"`
+	mu.Unlock()
+
+	select {
+	case d := <-docs:
+		if string(d) != "b" {
+			t.Fatalf("Expected the new document, got %q", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the change to be sent")
+	}
+}
+
+func TestHTTPSource_Error(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := NewHTTPSource(srv.URL, time.Millisecond).Watch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Expected a 404 error, got %v", err)
+	}
+}
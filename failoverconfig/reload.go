@@ -0,0 +1,267 @@
+package failoverconfig
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// Reloader keeps the policies of a Config in a registry and applies new
+// configs to them while they are in use:
+//
+//   - a breaker whose thresholds or open timeout change is reconfigured in
+//     place, keeping its state; changing its failure rate, window or
+//     minimum requests replaces it with a Closed one,
+//   - a retry whose attempts or backoff change is updated in place,
+//     keeping its stats; calls in flight finish with the settings they
+//     started with,
+//   - bulkheads, timeouts and pipelines are registered once and swap what
+//     they run on, so callers holding them see the change. Calls in flight
+//     finish on the policies they started with.
+//
+// Pipelines are rebuilt on every change, so they always refer to the
+// current policies; retries and breakers taken from the registry directly
+// are the ones of the config applied at the time.
+type Reloader struct {
+	mu  sync.Mutex // Serializes Apply
+	cfg *Config    // Applied config
+	reg *failover.Registry
+
+	retries   map[string]*failover.RetryPolicy
+	breakers  map[string]*failover.CircuitBreaker
+	bulkheads map[string]*failover.Bulkhead
+	timeouts  map[string]*failover.Timeout
+	live      map[string]*livePolicy // Registered bulkheads, timeouts and pipelines
+
+	onApply func(err error) // Called after every Apply, if set
+}
+
+// ReloaderOption configures optional Reloader behavior.
+type ReloaderOption func(*Reloader)
+
+// WithApplyFunc calls fn after every config the reloader is given, with
+// the reason it was rejected or nil once it is applied, such as to log
+// reloads.
+func WithApplyFunc(fn func(err error)) ReloaderOption {
+	return func(r *Reloader) {
+		r.onApply = fn
+	}
+}
+
+// WithRegistry registers the policies in reg, such as
+// failover.DefaultRegistry, instead of a new registry, so that they sit
+// alongside policies registered in code. Names the config does not define
+// are left alone.
+func WithRegistry(reg *failover.Registry) ReloaderOption {
+	return func(r *Reloader) {
+		r.reg = reg
+	}
+}
+
+// NewReloader builds the policies of cfg into a new registry.
+func NewReloader(cfg *Config, opts ...ReloaderOption) (*Reloader, error) {
+	r := &Reloader{
+		cfg:       &Config{},
+		reg:       failover.NewRegistry(),
+		retries:   make(map[string]*failover.RetryPolicy),
+		breakers:  make(map[string]*failover.CircuitBreaker),
+		bulkheads: make(map[string]*failover.Bulkhead),
+		timeouts:  make(map[string]*failover.Timeout),
+		live:      make(map[string]*livePolicy),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if err := r.Apply(cfg); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Registry returns the registry holding the policies.
+func (r *Reloader) Registry() *failover.Registry {
+	return r.reg
+}
+
+// Config returns the config last applied.
+func (r *Reloader) Config() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cfg
+}
+
+// Load parses data and applies it.
+func (r *Reloader) Load(data []byte) error {
+	cfg, err := Parse(data)
+	if err != nil {
+		r.applied(err)
+		return err
+	}
+
+	return r.Apply(cfg)
+}
+
+// Apply changes the policies to those of cfg. If cfg is not valid, nothing
+// changes.
+func (r *Reloader) Apply(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		r.applied(err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.cfg
+	for name, c := range cfg.Retries {
+		o, ok := old.Retries[name]
+		switch {
+		case !ok:
+			r.retries[name] = newRetry(c)
+		case o != c:
+			r.retries[name].SetMaxAttempts(c.Attempts)
+			r.retries[name].SetBackoff(retryBackoff(c))
+		}
+	}
+
+	for name, c := range cfg.Breakers {
+		o, ok := old.Breakers[name]
+		switch {
+		case !ok || o.FailureRate != c.FailureRate || o.Window != c.Window || o.MinRequests != c.MinRequests:
+			r.breakers[name] = newBreaker(c)
+		case o != c:
+			r.breakers[name].Reconfigure(c.FailureThreshold, c.SuccessThreshold, time.Duration(c.OpenTimeout))
+		}
+	}
+
+	policies := make(map[string]failover.Policy)
+	for name, c := range cfg.Bulkheads {
+		if o, ok := old.Bulkheads[name]; !ok || o != c {
+			r.bulkheads[name] = failover.NewBulkhead(c.MaxConcurrent, c.MaxQueue, time.Duration(c.QueueTimeout))
+		}
+		policies[name] = r.bulkheads[name]
+	}
+
+	for name, c := range cfg.Timeouts {
+		if o, ok := old.Timeouts[name]; !ok || o != c {
+			r.timeouts[name] = failover.NewTimeout(time.Duration(c.Timeout))
+		}
+		policies[name] = r.timeouts[name]
+	}
+
+	for name, c := range cfg.Pipelines {
+		policies[name] = r.newPipeline(cfg, c)
+	}
+
+	deleteMissing(r.retries, cfg.Retries)
+	deleteMissing(r.breakers, cfg.Breakers)
+	deleteMissing(r.bulkheads, cfg.Bulkheads)
+	deleteMissing(r.timeouts, cfg.Timeouts)
+	deleteMissing(r.live, policies)
+	for name, p := range policies {
+		if r.live[name] == nil {
+			r.live[name] = &livePolicy{}
+		}
+		r.live[name].p.Store(&p)
+	}
+
+	r.register(old, cfg)
+	r.cfg = cfg
+	r.applied(nil)
+	return nil
+}
+
+// register updates the registry from the policies of old to those of cfg
+// in one step, so that lookups never find a name of either missing.
+func (r *Reloader) register(old, cfg *Config) {
+	next := failover.NewRegistry()
+	for name, p := range r.retries {
+		next.AddRetry(name, p)
+	}
+	for name, cb := range r.breakers {
+		next.AddBreaker(name, cb)
+	}
+	for name, p := range r.live {
+		next.AddPolicy(name, p)
+	}
+
+	r.reg.Replace(append(old.names(), cfg.names()...), next)
+}
+
+// newPipeline builds the pipeline c of cfg from the current policies.
+func (r *Reloader) newPipeline(cfg *Config, c PipelineConfig) *failover.Pipeline {
+	var opts []failover.PipelineOption
+	if c.Retry != "" {
+		opts = append(opts, failover.WithRetry(r.retries[c.Retry]))
+	}
+	if c.Breaker != "" {
+		opts = append(opts, failover.WithBreaker(r.breakers[c.Breaker]))
+	}
+	if c.Timeout != "" {
+		opts = append(opts, failover.WithTimeout(time.Duration(cfg.Timeouts[c.Timeout].Timeout)))
+	}
+	if c.Bulkhead != "" {
+		opts = append(opts, failover.WithBulkhead(r.bulkheads[c.Bulkhead]))
+	}
+
+	return failover.NewPipeline(opts...)
+}
+
+// applied reports the outcome of a config to onApply.
+func (r *Reloader) applied(err error) {
+	if r.onApply != nil {
+		r.onApply(err)
+	}
+}
+
+// livePolicy is a registered policy whose implementation is swapped on
+// reload.
+type livePolicy struct {
+	p atomic.Pointer[failover.Policy]
+}
+
+// Do runs fn through the current policy.
+func (l *livePolicy) Do(ctx context.Context, fn failover.WorkFuncCtx) error {
+	return (*l.p.Load()).Do(ctx, fn)
+}
+
+func newRetry(c RetryConfig) *failover.RetryPolicy {
+	return failover.NewRetryPolicy(c.Attempts, time.Duration(c.InitialDelay), failover.WithBackoff(retryBackoff(c)))
+}
+
+func retryBackoff(c RetryConfig) failover.Backoff {
+	return failover.ExponentialBackoff{
+		Initial:    time.Duration(c.InitialDelay),
+		Max:        time.Duration(c.MaxDelay),
+		Multiplier: c.Multiplier,
+		Jitter:     c.Jitter,
+	}
+}
+
+func newBreaker(c BreakerConfig) *failover.CircuitBreaker {
+	var opts []failover.BreakerOption
+	if c.FailureRate > 0 {
+		opts = append(opts, failover.WithFailureRate(c.FailureRate, time.Duration(c.Window)))
+	}
+	if c.MinRequests > 0 {
+		opts = append(opts, failover.WithMinimumRequests(c.MinRequests))
+	}
+
+	return failover.NewCircuitBreaker(c.FailureThreshold, c.SuccessThreshold, time.Duration(c.OpenTimeout), opts...)
+}
+
+// deleteMissing deletes the entries of m whose name is not in keep.
+func deleteMissing[T, U any](m map[string]T, keep map[string]U) {
+	maps.DeleteFunc(m, func(name string, _ T) bool {
+		_, ok := keep[name]
+		return !ok
+	})
+}
@@ -0,0 +1,157 @@
+package failoverconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+func TestReloader_Breaker(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewReloader(&Config{Breakers: map[string]BreakerConfig{
+		"db": {FailureThreshold: 1, SuccessThreshold: 1, OpenTimeout: Duration(time.Minute)},
+	}})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	cb := r.Registry().Breakers()["db"]
+	cb.Execute(func() error { return errTest })
+
+	// A new open timeout is applied in place, keeping the breaker open.
+	r.Apply(&Config{Breakers: map[string]BreakerConfig{
+		"db": {FailureThreshold: 1, SuccessThreshold: 1, OpenTimeout: Duration(time.Millisecond)},
+	}})
+	if got := r.Registry().Breakers()["db"]; got != cb || got.State() != failover.Open {
+		t.Fatalf("Expected the same open breaker, got %v", got.State())
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := cb.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("Expected the new open timeout to apply, got %v", err)
+	}
+
+	// A failure rate cannot be added in place.
+	r.Apply(&Config{Breakers: map[string]BreakerConfig{
+		"db": {SuccessThreshold: 1, OpenTimeout: Duration(time.Minute), FailureRate: 0.5, Window: Duration(time.Minute)},
+	}})
+	if got := r.Registry().Breakers()["db"]; got == cb {
+		t.Fatal("Expected the breaker to be replaced")
+	}
+}
+
+func TestReloader_Retry(t *testing.T) {
+	t.Parallel()
+
+	r, _ := NewReloader(&Config{Retries: map[string]RetryConfig{"db": {Attempts: 2}}})
+	retry, _ := r.Registry().Retry("db")
+	retry.Do(context.Background(), func(context.Context) error { return errTest })
+
+	// New attempts are applied in place, keeping the stats.
+	r.Apply(&Config{Retries: map[string]RetryConfig{"db": {Attempts: 3}}})
+	if got, _ := r.Registry().Retry("db"); got != retry {
+		t.Fatal("Expected the same retry policy")
+	}
+	if stats := retry.Stats(); stats.Attempts != 2 {
+		t.Fatalf("Expected the stats to stay, got %+v", stats)
+	}
+
+	calls := 0
+	retry.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	})
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestReloader_Pipeline(t *testing.T) {
+	t.Parallel()
+
+	cfg := func(timeout time.Duration) *Config {
+		return &Config{
+			Timeouts:  map[string]TimeoutConfig{"call": {Timeout: Duration(timeout)}},
+			Pipelines: map[string]PipelineConfig{"db": {Timeout: "call"}},
+		}
+	}
+	r, _ := NewReloader(cfg(time.Millisecond))
+	pipeline, _ := r.Registry().Policy("db")
+
+	slow := func(ctx context.Context) error {
+		select {
+		case <-time.After(20 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := pipeline.Do(context.Background(), slow); !errors.Is(err, failover.ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+
+	// The pipeline taken before the reload runs with the new timeout.
+	if err := r.Apply(cfg(time.Second)); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if err := pipeline.Do(context.Background(), slow); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
+
+func TestReloader_Remove(t *testing.T) {
+	t.Parallel()
+
+	r, _ := NewReloader(&Config{
+		Retries:   map[string]RetryConfig{"db": {Attempts: 2}},
+		Bulkheads: map[string]BulkheadConfig{"db": {MaxConcurrent: 1}},
+	})
+
+	r.Apply(&Config{Retries: map[string]RetryConfig{"db": {Attempts: 2}}})
+	if _, ok := r.Registry().Policy("db"); ok {
+		t.Fatal("Expected the bulkhead to be removed")
+	}
+	if _, ok := r.Registry().Retries()["db"]; !ok {
+		t.Fatal("Expected the retry of the same name to stay")
+	}
+}
+
+func TestReloader_Invalid(t *testing.T) {
+	t.Parallel()
+
+	r, _ := NewReloader(&Config{Timeouts: map[string]TimeoutConfig{"call": {Timeout: Duration(time.Second)}}})
+	before := r.Config()
+
+	if err := r.Load([]byte("timeouts: {call: {timeout: 0s}}")); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Expected ErrInvalid, got %v", err)
+	}
+	if r.Config() != before {
+		t.Fatal("Expected the applied config to stay")
+	}
+}
+
+func TestReloader_WithRegistry(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	reg.AddPolicy("code", failover.NoopRetrier{})
+
+	r, err := NewReloader(&Config{Retries: map[string]RetryConfig{
+		"db": {Attempts: 3, InitialDelay: Duration(time.Millisecond)},
+	}}, WithRegistry(reg))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if r.Registry() != reg {
+		t.Fatal("Expected the given registry")
+	}
+	if _, ok := reg.Retry("db"); !ok {
+		t.Fatal("Expected the retry policy in the given registry")
+	}
+
+	r.Apply(&Config{})
+	if _, ok := reg.Policy("code"); !ok {
+		t.Fatal("Expected names outside the config to be left alone")
+	}
+}
@@ -0,0 +1,91 @@
+package failoverconfig
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+)
+
+// Source is where config documents come from, such as a file or a
+// central service.
+type Source interface {
+	// Watch returns a channel that receives the document every time it
+	// changes, starting with the current one. The channel is closed once
+	// ctx is done.
+	Watch(ctx context.Context) (<-chan []byte, error)
+}
+
+// Watch applies every document from src to r until ctx is done or the
+// source stops, and returns the reason. Documents that fail to parse or
+// validate are skipped, leaving the last good config in place; see
+// WithApplyFunc to hear about them.
+func Watch(ctx context.Context, src Source, r *Reloader) error {
+	docs, err := src.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	for data := range docs {
+		_ = r.Load(data)
+	}
+
+	return ctx.Err()
+}
+
+var _ Source = (*FileSource)(nil)
+
+// FileSource is a Source that polls a file for changes, which works the
+// same for files edited in place and for mounted ConfigMaps that are
+// swapped through symlinks.
+type FileSource struct {
+	path     string
+	interval time.Duration
+}
+
+// NewFileSource creates a FileSource that reads path every interval.
+func NewFileSource(path string, interval time.Duration) *FileSource {
+	return &FileSource{path: path, interval: interval}
+}
+
+// Watch sends the file's content every time it changes. It fails if the
+// file cannot be read at first; later read errors are retried at the next
+// interval.
+func (s *FileSource) Watch(ctx context.Context) (<-chan []byte, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []byte, 1)
+	ch <- data
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			next, err := os.ReadFile(s.path)
+			if err != nil || bytes.Equal(next, data) {
+				continue
+			}
+			data = next
+
+			select {
+			case ch <- next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
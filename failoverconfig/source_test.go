@@ -0,0 +1,80 @@
+package failoverconfig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSource(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "failover.yaml")
+	os.WriteFile(path, []byte("a"), 0o600)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	docs, err := NewFileSource(path, time.Millisecond).Watch(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if d := string(<-docs); d != "a" {
+		t.Fatalf("Expected the current content, got %q", d)
+	}
+
+	os.WriteFile(path, []byte("b"), 0o600)
+	select {
+	case d := <-docs:
+		if string(d) != "b" {
+			t.Fatalf("Expected the new content, got %q", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the change to be sent")
+	}
+
+	cancel()
+	for range docs {
+	}
+}
+
+func TestFileSource_Missing(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewFileSource(filepath.Join(t.TempDir(), "missing"), time.Millisecond).Watch(context.Background())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "failover.yaml")
+	os.WriteFile(path, []byte("timeouts: {call: {timeout: 1s}}"), 0o600)
+
+	applied := make(chan error, 10)
+	r, _ := NewReloader(&Config{}, WithApplyFunc(func(err error) { applied <- err }))
+	<-applied
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Watch(ctx, NewFileSource(path, time.Millisecond), r) }()
+
+	if err := <-applied; err != nil {
+		t.Fatalf("Expected the file to be applied, got %v", err)
+	}
+	os.WriteFile(path, []byte("timeouts: {call: {timeout: -1s}}"), 0o600)
+	if err := <-applied; !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Expected ErrInvalid, got %v", err)
+	}
+	if _, ok := r.Registry().Policy("call"); !ok {
+		t.Fatal("Expected the last good config to stay in place")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package failovertest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dadanrm/failover"
+)
+
+// AssertState fails t unless b is in state want.
+func AssertState(t testing.TB, b failover.Breaker, want failover.State) {
+	t.Helper()
+
+	if got := b.State(); got != want {
+		t.Errorf("Expected breaker state %v, got %v", want, got)
+	}
+}
+
+// AssertRejected fails t unless err says a breaker rejected the call.
+func AssertRejected(t testing.TB, err error) {
+	t.Helper()
+
+	if !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Errorf("Expected the call to be rejected with %v, got %v", failover.ErrCircuitOpen, err)
+	}
+}
+
+// AssertAttempts fails t unless r made want attempts in all.
+func AssertAttempts(t testing.TB, r *StubRetryer, want int) {
+	t.Helper()
+
+	if got := len(r.Attempts()); got != want {
+		t.Errorf("Expected %d attempts, got %d", want, got)
+	}
+}
@@ -0,0 +1,45 @@
+package failovertest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dadanrm/failover"
+)
+
+// recorder is a testing.TB that records failures instead of failing.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{TB: t}
+
+	b := NewManualBreaker(failover.Open)
+	AssertState(rec, b, failover.Open)
+	AssertRejected(rec, b.Execute(func() error { return nil }))
+
+	r := NewStubRetryer(2)
+	r.Do(context.Background(), func(context.Context) error { return errTest })
+	AssertAttempts(rec, r, 2)
+
+	if len(rec.failures) != 0 {
+		t.Fatalf("Expected the assertions to hold, got %v", rec.failures)
+	}
+
+	AssertState(rec, b, failover.Closed)
+	AssertRejected(rec, nil)
+	AssertAttempts(rec, r, 1)
+	if len(rec.failures) != 3 {
+		t.Fatalf("Expected 3 failed assertions, got %v", rec.failures)
+	}
+}
@@ -0,0 +1,68 @@
+// Package failovertest helps test and tune code that uses the failover
+// policies.
+//
+// ManualBreaker and StubRetryer stand in for real policies in unit tests,
+// without real time or real thresholds, and the Assert helpers check what
+// they saw. A simulated Clock and a Runner that plays a scripted scenario
+// against a policy configuration report how it behaves, so that thresholds
+// can be tuned offline and deterministically.
+package failovertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ failover.Clock = (*Clock)(nil)
+
+// Clock is a simulated failover.Clock. Time only moves when told to, and
+// waiting moves it at once: After advances the clock by the wait and
+// returns a channel that is ready. It suits simulations run from a single
+// goroutine, where nothing else could happen during the wait.
+type Clock struct {
+	mu  sync.Mutex // Protects now
+	now time.Time
+}
+
+// NewClock creates a Clock set to start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the simulated time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After advances the clock by d and returns a channel holding the new time.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Advance(d)
+	return ch
+}
+
+// Advance moves the clock forward by d, ignoring negative durations, and
+// returns the new time.
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(max(d, 0))
+	return c.now
+}
+
+// Set moves the clock forward to t. A t before the current time is
+// ignored, as time does not go back.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t.After(c.now) {
+		c.now = t
+	}
+}
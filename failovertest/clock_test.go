@@ -0,0 +1,27 @@
+package failovertest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+
+	if got := <-c.After(time.Second); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("Expected After to advance by 1s, got %v", got)
+	}
+
+	c.Set(start) // in the past
+	c.Advance(-time.Hour)
+	if got := c.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("Expected time not to go back, got %v", got)
+	}
+
+	c.Set(start.Add(time.Minute))
+	if got := c.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("Expected the set time, got %v", got)
+	}
+}
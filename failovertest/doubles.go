@@ -0,0 +1,147 @@
+package failovertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dadanrm/failover"
+)
+
+var (
+	_ failover.Breaker = (*ManualBreaker)(nil)
+	_ failover.Policy  = (*ManualBreaker)(nil)
+	_ failover.Retrier = (*StubRetryer)(nil)
+)
+
+// ManualBreaker is a failover.Breaker whose state tests set directly, for
+// exercising the code paths of an open or half-open breaker without
+// tripping a real one. It rejects calls while Open and lets them through
+// otherwise, counting both; it never changes state on its own.
+type ManualBreaker struct {
+	mu     sync.Mutex // Protects state and counts
+	state  failover.State
+	counts failover.Counts
+}
+
+// NewManualBreaker creates a ManualBreaker in state.
+func NewManualBreaker(state failover.State) *ManualBreaker {
+	return &ManualBreaker{state: state}
+}
+
+// SetState sets the breaker's state.
+func (b *ManualBreaker) SetState(s failover.State) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = s
+}
+
+// State returns the state last set.
+func (b *ManualBreaker) State() failover.State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Execute returns failover.ErrCircuitOpen while Open and calls fn otherwise.
+func (b *ManualBreaker) Execute(fn failover.WorkFunc) error {
+	b.mu.Lock()
+	open := b.state == failover.Open
+	if open {
+		b.counts.Rejections++
+	} else {
+		b.counts.Requests++
+	}
+	b.mu.Unlock()
+
+	if open {
+		return failover.ErrCircuitOpen
+	}
+
+	err := fn()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.counts.TotalFailures++
+		b.counts.ConsecutiveFailures++
+	} else {
+		b.counts.TotalSuccesses++
+		b.counts.ConsecutiveFailures = 0
+	}
+
+	return err
+}
+
+// Do is Execute for a function taking ctx.
+func (b *ManualBreaker) Do(ctx context.Context, fn failover.WorkFuncCtx) error {
+	return b.Execute(func() error { return fn(ctx) })
+}
+
+// Counts returns the calls the breaker has let through and rejected.
+func (b *ManualBreaker) Counts() failover.Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.counts
+}
+
+// StubRetryer is a failover.Retrier that retries at once, without
+// sleeping, and records every attempt, so that tests of code taking a
+// Retrier run fast and can check what was retried.
+type StubRetryer struct {
+	attempts int
+
+	mu    sync.Mutex // Protects calls and errs
+	calls int
+	errs  []error // Outcome of every attempt, nil for success
+}
+
+// NewStubRetryer creates a StubRetryer making up to attempts calls.
+func NewStubRetryer(attempts int) *StubRetryer {
+	return &StubRetryer{attempts: attempts}
+}
+
+// Do calls fn until it succeeds, the attempts are used up or ctx is done.
+func (r *StubRetryer) Do(ctx context.Context, fn failover.WorkFuncCtx) error {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+
+	var err error
+	for range r.attempts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err = fn(ctx)
+
+		r.mu.Lock()
+		r.errs = append(r.errs, err)
+		r.mu.Unlock()
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// Calls returns how many times Do was called.
+func (r *StubRetryer) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.calls
+}
+
+// Attempts returns the outcome of every attempt made, in order, nil for
+// the successes.
+func (r *StubRetryer) Attempts() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]error(nil), r.errs...)
+}
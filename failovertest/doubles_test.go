@@ -0,0 +1,66 @@
+package failovertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dadanrm/failover"
+)
+
+func TestManualBreaker(t *testing.T) {
+	t.Parallel()
+	b := NewManualBreaker(failover.Open)
+
+	if err := b.Execute(func() error { return nil }); !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	b.SetState(failover.HalfOpen)
+	for range 3 {
+		b.Do(context.Background(), func(context.Context) error { return errTest })
+	}
+	if s := b.State(); s != failover.HalfOpen {
+		t.Fatalf("Expected the state to stay %v, got %v", failover.HalfOpen, s)
+	}
+
+	want := failover.Counts{Requests: 3, TotalFailures: 3, Rejections: 1, ConsecutiveFailures: 3}
+	if c := b.Counts(); c != want {
+		t.Fatalf("Expected counts %+v, got %+v", want, c)
+	}
+}
+
+func TestStubRetryer(t *testing.T) {
+	t.Parallel()
+	r := NewStubRetryer(3)
+
+	n := 0
+	err := r.Do(context.Background(), func(context.Context) error {
+		n++
+		if n < 2 {
+			return errTest
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	r.Do(context.Background(), func(context.Context) error { return errTest })
+
+	attempts := r.Attempts()
+	if r.Calls() != 2 || len(attempts) != 5 || attempts[0] != errTest || attempts[1] != nil {
+		t.Fatalf("Expected 2 calls with 5 attempts, got %d with %v", r.Calls(), attempts)
+	}
+}
+
+func TestStubRetryer_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewStubRetryer(3).Do(ctx, func(context.Context) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package failovertest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// Phase is a stretch of a Scenario during which the dependency behaves the
+// same way for every attempt.
+type Phase struct {
+	Duration time.Duration // How long the phase lasts
+	Latency  time.Duration // How long each attempt takes
+	Err      error         // What each attempt returns, nil for success
+}
+
+// Scenario scripts how a dependency behaves over time and how often it is
+// called.
+type Scenario struct {
+	Phases   []Phase
+	Interval time.Duration // Time between the starts of two calls
+}
+
+// Report is how a policy fared in a Scenario.
+type Report struct {
+	Calls         int     // Calls made to the policy
+	Attempts      int     // Calls the policy made to the dependency
+	Failures      int     // Calls that ended with an error, rejections included
+	Rejections    int     // Calls that ended with failover.ErrCircuitOpen
+	Trips         int     // Transitions to Open, seen with StateChangeFunc
+	Amplification float64 // Attempts per call, the load retries add
+
+	TimeToTrip   time.Duration // From the start of the first failing phase to the first trip after it
+	RecoveryTime time.Duration // From the end of the last failing phase to the first success after it
+	Recovered    bool          // Whether a call succeeded after the last failing phase
+}
+
+// Runner plays a Scenario against a policy on simulated time. Calls are
+// made one after the other from a single goroutine: each starts Interval
+// after the previous one did, or when it returns if it took longer.
+//
+// Policies only see the simulated time if they are given the Runner's
+// Clock, with failover.WithBreakerClock and failover.WithRetryClock;
+// timeouts and other policies run on real time.
+type Runner struct {
+	scenario Scenario
+	clock    *Clock
+
+	mu    sync.Mutex  // Protects trips
+	trips []time.Time // When breakers tripped
+}
+
+// NewRunner creates a Runner for s.
+func NewRunner(s Scenario) *Runner {
+	return &Runner{
+		scenario: s,
+		clock:    NewClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+// Clock returns the simulated clock the scenario runs on.
+func (r *Runner) Clock() *Clock {
+	return r.clock
+}
+
+// StateChangeFunc returns a function to pass to
+// failover.WithStateChangeFunc that counts the breaker's trips.
+func (r *Runner) StateChangeFunc() func(from, to failover.State) {
+	return func(_, to failover.State) {
+		if to != failover.Open {
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.trips = append(r.trips, r.clock.Now())
+	}
+}
+
+// Run plays the scenario against p from its start and reports the outcome.
+func (r *Runner) Run(p failover.Policy) Report {
+	var report Report
+
+	start := r.clock.Now()
+	var total, failStart, failEnd time.Duration
+	failStart = -1
+	for _, ph := range r.scenario.Phases {
+		if ph.Err != nil {
+			if failStart < 0 {
+				failStart = total
+			}
+			failEnd = total + ph.Duration
+		}
+		total += ph.Duration
+	}
+	interval := max(r.scenario.Interval, time.Nanosecond)
+
+	attempt := func(context.Context) error {
+		report.Attempts++
+		ph := r.phaseAt(r.clock.Now().Sub(start))
+		r.clock.Advance(ph.Latency)
+		return ph.Err
+	}
+
+	ctx := context.Background()
+	for at := time.Duration(0); at < total; at += interval {
+		r.clock.Set(start.Add(at))
+		if r.clock.Now().Sub(start) >= total {
+			break
+		}
+
+		report.Calls++
+		err := p.Do(ctx, attempt)
+		switch {
+		case errors.Is(err, failover.ErrCircuitOpen):
+			report.Rejections++
+			report.Failures++
+		case err != nil:
+			report.Failures++
+		case failStart >= 0 && !report.Recovered && r.clock.Now().Sub(start) >= failEnd:
+			report.Recovered = true
+			report.RecoveryTime = r.clock.Now().Sub(start) - failEnd
+		}
+	}
+
+	if report.Calls > 0 {
+		report.Amplification = float64(report.Attempts) / float64(report.Calls)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report.Trips = len(r.trips)
+	for _, t := range r.trips {
+		if failStart >= 0 && t.Sub(start) >= failStart {
+			report.TimeToTrip = t.Sub(start) - failStart
+			break
+		}
+	}
+
+	return report
+}
+
+// phaseAt returns the phase at offset from the start of the scenario; the
+// last phase lasts past its end.
+func (r *Runner) phaseAt(offset time.Duration) Phase {
+	for _, ph := range r.scenario.Phases {
+		if offset < ph.Duration {
+			return ph
+		}
+		offset -= ph.Duration
+	}
+
+	if n := len(r.scenario.Phases); n > 0 {
+		return r.scenario.Phases[n-1]
+	}
+	return Phase{}
+}
@@ -0,0 +1,64 @@
+package failovertest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var errTest = errors.New("test error")
+
+// outage is a minute of health, a minute of outage and a minute of health
+// again, called once a second.
+var outage = Scenario{
+	Phases: []Phase{
+		{Duration: time.Minute, Latency: 10 * time.Millisecond},
+		{Duration: time.Minute, Latency: 10 * time.Millisecond, Err: errTest},
+		{Duration: time.Minute, Latency: 10 * time.Millisecond},
+	},
+	Interval: time.Second,
+}
+
+func TestRunner_Breaker(t *testing.T) {
+	t.Parallel()
+	r := NewRunner(outage)
+	cb := failover.NewCircuitBreaker(5, 1, 10*time.Second,
+		failover.WithBreakerClock(r.Clock()), failover.WithStateChangeFunc(r.StateChangeFunc()))
+
+	report := r.Run(cb)
+
+	if report.Calls != 180 || report.Attempts >= report.Calls {
+		t.Fatalf("Expected the breaker to spare the dependency calls, got %+v", report)
+	}
+	// The breaker trips on the 5th failure and re-trips on each trial call
+	// every 10s during the rest of the outage.
+	if want := 4*time.Second + 10*time.Millisecond; report.TimeToTrip != want {
+		t.Fatalf("Expected a trip after %v, got %v", want, report.TimeToTrip)
+	}
+	if report.Trips != 6 {
+		t.Fatalf("Expected 6 trips, got %d", report.Trips)
+	}
+	if !report.Recovered || report.RecoveryTime >= 11*time.Second {
+		t.Fatalf("Expected recovery within the open timeout, got %+v", report)
+	}
+}
+
+func TestRunner_Retry(t *testing.T) {
+	t.Parallel()
+	r := NewRunner(outage)
+	retry := failover.NewRetryPolicy(3, 100*time.Millisecond, failover.WithRetryClock(r.Clock()))
+
+	report := r.Run(retry)
+
+	if report.Failures != 60 {
+		t.Fatalf("Expected every call of the outage to fail, got %d", report.Failures)
+	}
+	if want := float64(120+60*3) / 180; report.Amplification != want {
+		t.Fatalf("Expected amplification %v, got %v", want, report.Amplification)
+	}
+	if !report.Recovered || report.RecoveryTime > time.Second {
+		t.Fatalf("Expected recovery by the next call, got %+v", report)
+	}
+}
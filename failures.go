@@ -0,0 +1,63 @@
+package failover
+
+import (
+	"sync"
+	"time"
+)
+
+// FailureRecord is a failure kept by WithRecentFailures or
+// WithRetryRecentFailures, so that on-call engineers can see what tripped a
+// breaker or used up a call's retries.
+type FailureRecord struct {
+	Time      time.Time     `json:"time"`                // When the call ended
+	Operation string        `json:"operation,omitempty"` // Operation named with WithOperation, if any
+	Error     string        `json:"error"`
+	Attempt   int           `json:"attempt,omitempty"` // Number of the attempt, from 1, if known
+	Latency   time.Duration `json:"latency"`           // How long the call took
+}
+
+// failureLog is a ring buffer of the last failures of a policy. It is safe
+// for concurrent use.
+type failureLog struct {
+	mu      sync.Mutex      // Protects records and next
+	records []FailureRecord // Up to its capacity, the oldest at next once full
+	next    int             // Where the next record goes once full
+}
+
+// newFailureLog returns a log of the last n failures, or nil if n is not
+// positive.
+func newFailureLog(n int) *failureLog {
+	if n <= 0 {
+		return nil
+	}
+
+	return &failureLog{records: make([]FailureRecord, 0, n)}
+}
+
+// add records r, dropping the oldest record if the log is full.
+func (l *failureLog) add(r FailureRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.records) < cap(l.records) {
+		l.records = append(l.records, r)
+		return
+	}
+
+	l.records[l.next] = r
+	l.next = (l.next + 1) % len(l.records)
+}
+
+// list returns the records, oldest first. A nil log has none.
+func (l *failureLog) list() []FailureRecord {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]FailureRecord, 0, len(l.records))
+	records = append(records, l.records[l.next:]...)
+	return append(records, l.records[:l.next]...)
+}
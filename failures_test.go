@@ -0,0 +1,83 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailureLog_Ring(t *testing.T) {
+	t.Parallel()
+	l := newFailureLog(2)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		l.add(FailureRecord{Error: msg})
+	}
+
+	records := l.list()
+	if len(records) != 2 || records[0].Error != "b" || records[1].Error != "c" {
+		t.Fatalf("Expected b and c, oldest first, got %+v", records)
+	}
+	if newFailureLog(0) != nil || newFailureLog(0).list() != nil {
+		t.Fatal("Expected no log for a non-positive size")
+	}
+}
+
+func TestCircuitBreaker_RecentFailures(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(10, 1, time.Minute, WithRecentFailures(2),
+		WithExcludeIf(func(err error) bool { return errors.Is(err, context.Canceled) }))
+
+	cb.Execute(func() error { return nil })
+	cb.Execute(func() error { return context.Canceled })
+	cb.Do(WithOperation(context.Background(), "lookup"), func(context.Context) error { return errTest })
+
+	records := cb.RecentFailures()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 counted failure, got %+v", records)
+	}
+	if r := records[0]; r.Operation != "lookup" || r.Error != errTest.Error() || r.Time.IsZero() {
+		t.Fatalf("Expected the lookup failure, got %+v", r)
+	}
+
+	if records := NewCircuitBreaker(1, 1, time.Minute).RecentFailures(); records != nil {
+		t.Fatalf("Expected no records by default, got %+v", records)
+	}
+}
+
+func TestPipeline_RecentFailuresOperation(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(10, 1, time.Minute, WithRecentFailures(2))
+	p := NewPipeline(WithBreaker(cb))
+
+	p.Execute(WithOperation(context.Background(), "charge"), func(context.Context) error { return errTest })
+
+	if records := cb.RecentFailures(); len(records) != 1 || records[0].Operation != "charge" {
+		t.Fatalf("Expected the charge failure, got %+v", records)
+	}
+}
+
+func TestRetryPolicy_RecentFailures(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(3, time.Millisecond, WithRetryRecentFailures(5))
+
+	calls := 0
+	r.Do(WithOperation(context.Background(), "save"), func(context.Context) error {
+		calls++
+		if calls == 3 {
+			return nil
+		}
+		return errTest
+	})
+
+	records := r.RecentFailures()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 failed attempts, got %+v", records)
+	}
+	for i, rec := range records {
+		if rec.Attempt != i+1 || rec.Operation != "save" || rec.Error != errTest.Error() {
+			t.Errorf("Expected attempt %d of save, got %+v", i+1, rec)
+		}
+	}
+}
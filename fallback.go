@@ -0,0 +1,81 @@
+package failover
+
+import (
+	"context"
+	"errors"
+)
+
+// FallbackFunc produces the outcome of a call that failed with err. It may
+// return nil to mask the failure or another error to replace it.
+type FallbackFunc func(ctx context.Context, err error) error
+
+// Fallback replaces selected failures with an alternative outcome, so
+// degraded behavior is declared once instead of in every caller.
+type Fallback struct {
+	handler FallbackFunc
+	errs    []error // Errors that trigger the fallback, empty for any error
+}
+
+// NewFallback creates a Fallback that calls handler when an operation fails
+// with one of errs (matched with errors.Is), or with any error if errs is
+// empty.
+func NewFallback(handler FallbackFunc, errs ...error) *Fallback {
+	return &Fallback{handler: handler, errs: errs}
+}
+
+// Do executes fn and hands a matching failure to the fallback handler.
+func (f *Fallback) Do(ctx context.Context, fn WorkFuncCtx) error {
+	err := fn(ctx)
+	if err == nil || !matchesAny(err, f.errs) {
+		return err
+	}
+
+	return f.handler(ctx, err)
+}
+
+// ValueFallback replaces selected failures of an operation producing a T
+// with an alternative value.
+type ValueFallback[T any] struct {
+	handler func(ctx context.Context, err error) (T, error)
+	errs    []error // Errors that trigger the fallback, empty for any error
+}
+
+// NewValueFallback creates a ValueFallback that returns value in place of a
+// failure with one of errs, or with any error if errs is empty.
+func NewValueFallback[T any](value T, errs ...error) *ValueFallback[T] {
+	return NewValueFallbackFunc(func(context.Context, error) (T, error) {
+		return value, nil
+	}, errs...)
+}
+
+// NewValueFallbackFunc creates a ValueFallback that calls handler in place
+// of a failure with one of errs, or with any error if errs is empty.
+func NewValueFallbackFunc[T any](handler func(ctx context.Context, err error) (T, error), errs ...error) *ValueFallback[T] {
+	return &ValueFallback[T]{handler: handler, errs: errs}
+}
+
+// Do executes fn and hands a matching failure to the fallback handler.
+func (f *ValueFallback[T]) Do(ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	v, err := fn(ctx)
+	if err == nil || !matchesAny(err, f.errs) {
+		return v, err
+	}
+
+	return f.handler(ctx, err)
+}
+
+// matchesAny reports whether err matches one of targets, or whether targets
+// is empty.
+func matchesAny(err error, targets []error) bool {
+	if len(targets) == 0 {
+		return true
+	}
+
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFallback_AnyError(t *testing.T) {
+	t.Parallel()
+	var got error
+	fb := NewFallback(func(_ context.Context, err error) error {
+		got = err
+		return nil
+	})
+
+	if err := fb.Do(context.Background(), func(context.Context) error { return errTest }); err != nil {
+		t.Fatalf("Expected fallback to mask the error, got %v", err)
+	}
+	if !errors.Is(got, errTest) {
+		t.Fatalf("Expected handler to receive %v, got %v", errTest, got)
+	}
+}
+
+func TestFallback_SelectedErrors(t *testing.T) {
+	t.Parallel()
+	fb := NewFallback(func(context.Context, error) error { return nil }, ErrCircuitOpen)
+
+	if err := fb.Do(context.Background(), func(context.Context) error { return errTest }); !errors.Is(err, errTest) {
+		t.Fatalf("Expected unmatched error to pass through, got %v", err)
+	}
+
+	wrapped := func(context.Context) error { return fmt.Errorf("call: %w", ErrCircuitOpen) }
+	if err := fb.Do(context.Background(), wrapped); err != nil {
+		t.Fatalf("Expected wrapped ErrCircuitOpen to trigger fallback, got %v", err)
+	}
+}
+
+func TestFallback_WithCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	_ = cb.Execute(func() error { return errTest })
+
+	degraded := false
+	fb := NewFallback(func(context.Context, error) error {
+		degraded = true
+		return nil
+	}, ErrCircuitOpen)
+
+	err := fb.Do(context.Background(), func(context.Context) error {
+		return cb.Execute(func() error { return nil })
+	})
+
+	if err != nil || !degraded {
+		t.Fatalf("Expected open circuit to be served by the fallback, got %v", err)
+	}
+}
+
+func TestValueFallback(t *testing.T) {
+	t.Parallel()
+	fb := NewValueFallback("default", errTest)
+
+	v, err := fb.Do(context.Background(), func(context.Context) (string, error) {
+		return "", errTest
+	})
+	if err != nil || v != "default" {
+		t.Fatalf("Expected (default, nil), got (%q, %v)", v, err)
+	}
+
+	v, err = fb.Do(context.Background(), func(context.Context) (string, error) {
+		return "live", nil
+	})
+	if err != nil || v != "live" {
+		t.Fatalf("Expected (live, nil), got (%q, %v)", v, err)
+	}
+}
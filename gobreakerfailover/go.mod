@@ -0,0 +1,10 @@
+module github.com/dadanrm/failover/gobreakerfailover
+
+go 1.24.7
+
+require (
+	github.com/dadanrm/failover v0.0.0
+	github.com/sony/gobreaker/v2 v2.4.0
+)
+
+replace github.com/dadanrm/failover => ../
@@ -0,0 +1,155 @@
+// Package gobreakerfailover creates failover circuit breakers from the
+// Settings of github.com/sony/gobreaker/v2, so that breakers tuned for
+// gobreaker can move over without re-deriving their thresholds.
+package gobreakerfailover
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"github.com/sony/gobreaker/v2"
+)
+
+// Defaults of gobreaker for the settings left zero.
+const (
+	defaultTimeout   = 60 * time.Second
+	defaultFailures  = 6 // Consecutive failures that trip, as the default ReadyToTrip
+	defaultSuccesses = 1
+)
+
+// defaultReadyToTrip is the ReadyToTrip of gobreaker.
+func defaultReadyToTrip(c gobreaker.Counts) bool {
+	return c.ConsecutiveFailures >= defaultFailures
+}
+
+// NewCircuitBreaker creates a failover.CircuitBreaker that behaves like the
+// gobreaker breaker of st, applying gobreaker's defaults to the settings
+// left zero:
+//
+//   - Timeout is the open timeout.
+//   - MaxRequests is the number of successes that close a HalfOpen breaker.
+//     Unlike gobreaker, HalfOpen does not limit how many calls are let
+//     through meanwhile.
+//   - ReadyToTrip sees the counts since the last state change or, while
+//     Closed, since the last Interval ended. BucketPeriod is not used: the
+//     counts are cleared at the end of each Interval, as gobreaker does
+//     without it.
+//   - OnStateChange is called with st.Name and gobreaker's states.
+//   - IsSuccessful and IsExcluded classify errors as in gobreaker.
+//
+// The breaker never returns gobreaker's errors: a rejected call fails with
+// failover.ErrCircuitOpen. opts are applied first; use st.OnStateChange
+// rather than failover.WithStateChangeFunc, which the breaker relies on.
+func NewCircuitBreaker(st gobreaker.Settings, opts ...failover.BreakerOption) *failover.CircuitBreaker {
+	timeout := st.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	successes := int(st.MaxRequests)
+	if successes == 0 {
+		successes = defaultSuccesses
+	}
+
+	var cb *failover.CircuitBreaker
+	g := &generation{interval: st.Interval}
+	g.clear(failover.Counts{}, time.Now())
+
+	opts = append(opts, failover.WithStateChangeFunc(func(from, to failover.State) {
+		g.clear(cb.Counts(), time.Now())
+		if st.OnStateChange != nil {
+			st.OnStateChange(st.Name, state(from), state(to))
+		}
+	}))
+
+	// The default ReadyToTrip is the failure threshold, unless the counts
+	// it sees are cleared every Interval.
+	failures := 0
+	readyToTrip := st.ReadyToTrip
+	if readyToTrip == nil && st.Interval > 0 {
+		readyToTrip = defaultReadyToTrip
+	} else if readyToTrip == nil {
+		failures = defaultFailures
+	}
+	if readyToTrip != nil {
+		opts = append(opts, failover.WithTripIf(func(c failover.Counts) bool {
+			return readyToTrip(g.counts(c, time.Now()))
+		}))
+	}
+	if st.IsSuccessful != nil {
+		opts = append(opts, failover.WithFailureIf(func(err error) bool { return !st.IsSuccessful(err) }))
+	}
+	if st.IsExcluded != nil {
+		opts = append(opts, failover.WithExcludeIf(st.IsExcluded))
+	}
+
+	cb = failover.NewCircuitBreaker(failures, successes, timeout, opts...)
+	return cb
+}
+
+// generation turns the lifetime counts of a breaker into gobreaker's,
+// which start over at every state change and at the end of every
+// interval while Closed.
+type generation struct {
+	interval time.Duration // Zero to never clear the counts while Closed
+
+	mu     sync.Mutex
+	base   failover.Counts // Counts when the generation started
+	expiry time.Time       // When the generation ends, zero for never
+}
+
+// clear starts a new generation at now, from the counts c.
+func (g *generation) clear(c failover.Counts, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.base = c
+	g.expiry = time.Time{}
+	if g.interval > 0 {
+		g.expiry = now.Add(g.interval)
+	}
+}
+
+// counts returns the counts of the current generation given the lifetime
+// counts c, which include the failure being judged. A generation that has
+// ended gives way to one holding only that failure, as gobreaker starts
+// the new one before counting the call.
+func (g *generation) counts(c failover.Counts, now time.Time) gobreaker.Counts {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.expiry.IsZero() && !now.Before(g.expiry) {
+		g.base = c
+		g.base.Requests--
+		g.base.TotalFailures--
+		g.expiry = now.Add(g.interval)
+	}
+
+	failures := c.TotalFailures - g.base.TotalFailures
+	return gobreaker.Counts{
+		Requests:       clamp(c.Requests - g.base.Requests),
+		TotalSuccesses: clamp(c.TotalSuccesses - g.base.TotalSuccesses),
+		TotalFailures:  clamp(failures),
+		// The failures since the generation started, if the run of
+		// consecutive failures began before it.
+		ConsecutiveFailures: clamp(min(c.ConsecutiveFailures, failures)),
+	}
+}
+
+// clamp converts n to gobreaker's uint32 counts.
+func clamp(n uint64) uint32 {
+	return uint32(min(n, math.MaxUint32))
+}
+
+// state returns the gobreaker State of s.
+func state(s failover.State) gobreaker.State {
+	switch s {
+	case failover.Open:
+		return gobreaker.StateOpen
+	case failover.HalfOpen:
+		return gobreaker.StateHalfOpen
+	default:
+		return gobreaker.StateClosed
+	}
+}
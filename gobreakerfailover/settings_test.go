@@ -0,0 +1,122 @@
+package gobreakerfailover
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"github.com/sony/gobreaker/v2"
+)
+
+var errTest = errors.New("test error")
+
+func fail() error    { return errTest }
+func succeed() error { return nil }
+
+func TestNewCircuitBreaker_Defaults(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(gobreaker.Settings{Name: "db"})
+
+	for range defaultFailures - 1 {
+		cb.Execute(fail)
+	}
+	if s := cb.State(); s != failover.Closed {
+		t.Fatalf("Expected state %v below the default threshold, got %v", failover.Closed, s)
+	}
+
+	cb.Execute(fail)
+	if s := cb.State(); s != failover.Open {
+		t.Fatalf("Expected state %v, got %v", failover.Open, s)
+	}
+}
+
+func TestNewCircuitBreaker_ReadyToTrip(t *testing.T) {
+	t.Parallel()
+	var seen []gobreaker.Counts
+	cb := NewCircuitBreaker(gobreaker.Settings{
+		ReadyToTrip: func(c gobreaker.Counts) bool {
+			seen = append(seen, c)
+			return c.Requests >= 3 && c.TotalFailures >= 2
+		},
+	})
+
+	cb.Execute(fail)
+	cb.Execute(succeed)
+	cb.Execute(fail)
+	if s := cb.State(); s != failover.Open {
+		t.Fatalf("Expected state %v, got %v", failover.Open, s)
+	}
+
+	want := gobreaker.Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2, ConsecutiveFailures: 1}
+	if last := seen[len(seen)-1]; last != want {
+		t.Fatalf("Expected counts %+v, got %+v", want, last)
+	}
+}
+
+func TestNewCircuitBreaker_Interval(t *testing.T) {
+	t.Parallel()
+	var seen gobreaker.Counts
+	cb := NewCircuitBreaker(gobreaker.Settings{
+		Interval: 20 * time.Millisecond,
+		ReadyToTrip: func(c gobreaker.Counts) bool {
+			seen = c
+			return false
+		},
+	})
+
+	cb.Execute(fail)
+	cb.Execute(fail)
+	time.Sleep(30 * time.Millisecond)
+	cb.Execute(fail)
+
+	want := gobreaker.Counts{Requests: 1, TotalFailures: 1, ConsecutiveFailures: 1}
+	if seen != want {
+		t.Fatalf("Expected the interval to clear the counts to %+v, got %+v", want, seen)
+	}
+}
+
+func TestNewCircuitBreaker_OnStateChange(t *testing.T) {
+	t.Parallel()
+	var changes []string
+	cb := NewCircuitBreaker(gobreaker.Settings{
+		Name:        "db",
+		MaxRequests: 2,
+		Timeout:     10 * time.Millisecond,
+		ReadyToTrip: func(c gobreaker.Counts) bool { return c.ConsecutiveFailures >= 1 },
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			changes = append(changes, name+" "+from.String()+"->"+to.String())
+		},
+	})
+
+	cb.Execute(fail)
+	time.Sleep(20 * time.Millisecond)
+	cb.Execute(succeed)
+	if s := cb.State(); s != failover.HalfOpen {
+		t.Fatalf("Expected MaxRequests successes to close the breaker, got %v after one", s)
+	}
+	cb.Execute(succeed)
+
+	want := []string{"db closed->open", "db open->half-open", "db half-open->closed"}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] || changes[2] != want[2] {
+		t.Fatalf("Expected transitions %v, got %v", want, changes)
+	}
+}
+
+func TestNewCircuitBreaker_Classifiers(t *testing.T) {
+	t.Parallel()
+	errIgnored, errFine := errors.New("ignored"), errors.New("fine")
+	cb := NewCircuitBreaker(gobreaker.Settings{
+		IsSuccessful: func(err error) bool { return err == nil || err == errFine },
+		IsExcluded:   func(err error) bool { return err == errIgnored },
+	})
+
+	cb.Execute(func() error { return errFine })
+	cb.Execute(func() error { return errIgnored })
+	cb.Execute(fail)
+
+	want := failover.Counts{Requests: 2, TotalSuccesses: 1, TotalFailures: 1, ConsecutiveFailures: 1}
+	if c := cb.Counts(); c != want {
+		t.Fatalf("Expected counts %+v, got %+v", want, c)
+	}
+}
@@ -0,0 +1,83 @@
+package failover
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs a fan-out of calls under a shared policy, in the manner of
+// errgroup: each call is retried, or otherwise handled, by the policy, and
+// the first call to fail for good cancels the others. A Group must not be
+// reused after Wait returns.
+type Group struct {
+	policy Policy
+	cancel context.CancelCauseFunc
+	ctx    context.Context
+
+	wg  sync.WaitGroup
+	sem chan struct{} // Holds one token per running call, nil for no limit
+
+	once sync.Once // Guards err
+	err  error     // First error returned by the policy
+}
+
+// GroupOption configures optional Group behavior.
+type GroupOption func(*Group)
+
+// WithGroupLimit runs at most n calls at once; Go blocks until one of them
+// returns. A limit of zero or less means no limit.
+func WithGroupLimit(n int) GroupOption {
+	return func(g *Group) {
+		if n > 0 {
+			g.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// NewGroup creates a Group running its calls under policy, such as a
+// RetryPolicy or Pipeline, along with the context they are called with. The
+// context is canceled when a call fails, with the call's error as the
+// cause, or when Wait returns.
+func NewGroup(ctx context.Context, policy Policy, opts ...GroupOption) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &Group{policy: policy, cancel: cancel, ctx: ctx}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g, ctx
+}
+
+// Go runs fn under the group's policy in a new goroutine, once the
+// concurrency limit allows. Its error counts once the policy gives up, such
+// as when the error is not worth retrying or the attempts are used up.
+func (g *Group) Go(fn WorkFuncCtx) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		if err := g.policy.Do(g.ctx, fn); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait waits for every call started with Go and returns the first error
+// the policy returned, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	return g.err
+}
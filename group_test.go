@@ -0,0 +1,77 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_RetriesEachCall(t *testing.T) {
+	t.Parallel()
+	g, _ := NewGroup(context.Background(), NewRetryPolicy(3, time.Millisecond))
+
+	var attempts atomic.Int32
+	for range 4 {
+		var tries int
+		g.Go(func(context.Context) error {
+			attempts.Add(1)
+			if tries++; tries == 1 {
+				return errTest // every call's first attempt fails
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Expected the retries to recover, got %v", err)
+	}
+	if n := attempts.Load(); n != 8 {
+		t.Fatalf("Expected 8 attempts, got %d", n)
+	}
+}
+
+func TestGroup_FirstErrorCancels(t *testing.T) {
+	t.Parallel()
+	permanent := errors.New("permanent")
+	policy := NewRetryPolicy(5, time.Millisecond, WithRetryIf(func(err error) bool { return err != permanent }))
+	g, ctx := NewGroup(context.Background(), policy)
+
+	g.Go(func(context.Context) error { return permanent })
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	if err := g.Wait(); err != permanent {
+		t.Fatalf("Expected error %v, got %v", permanent, err)
+	}
+	if cause := context.Cause(ctx); cause != permanent {
+		t.Fatalf("Expected the context canceled with %v, got %v", permanent, cause)
+	}
+}
+
+func TestGroup_Limit(t *testing.T) {
+	t.Parallel()
+	g, _ := NewGroup(context.Background(), NoopRetrier{}, WithGroupLimit(2))
+
+	var running, peak atomic.Int32
+	for range 6 {
+		g.Go(func(context.Context) error {
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("Expected at most 2 calls at once, got %d", p)
+	}
+}
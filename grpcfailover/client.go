@@ -0,0 +1,267 @@
+package grpcfailover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// errTimeout is the cause of the deadline set with WithTimeout. It is both
+// failover.ErrTimeout and context.DeadlineExceeded, so a call cut off by it
+// still fails with DEADLINE_EXCEEDED.
+var errTimeout = fmt.Errorf("%w: %w", failover.ErrTimeout, context.DeadlineExceeded)
+
+// client is the configuration shared by the client interceptors.
+type client struct {
+	attempts   int // Calls per RPC, including the first
+	backoff    failover.Backoff
+	retryable  []codes.Code                         // Codes worth another attempt
+	newBreaker func(method string) failover.Breaker // Nil disables breakers
+	timeout    time.Duration                        // Deadline for calls without one, zero for none
+	resume     ResumeFunc                           // Resumes broken streams, nil to leave them broken
+	propagate  bool                                 // Sends TimeoutMetadataKey with each attempt
+
+	mu       sync.Mutex
+	breakers map[string]failover.Breaker // By full method name
+}
+
+// ClientOption configures optional client interceptor behavior.
+type ClientOption func(*client)
+
+// WithRetry makes up to attempts calls per RPC, waiting per backoff between
+// them. The default is 3 attempts with a jittered exponential backoff from
+// 100ms to 2s. Use 1 attempt to disable retries.
+func WithRetry(attempts int, backoff failover.Backoff) ClientOption {
+	return func(c *client) {
+		c.attempts = max(attempts, 1)
+		c.backoff = backoff
+	}
+}
+
+// WithRetryableCodes sets the status codes that are retried. The default is
+// UNAVAILABLE and RESOURCE_EXHAUSTED.
+func WithRetryableCodes(codes ...codes.Code) ClientOption {
+	return func(c *client) {
+		c.retryable = codes
+	}
+}
+
+// WithBreakers creates the breaker for each method with fn, called the
+// first time the method is invoked. The default opens after 5 consecutive
+// failures for 30 seconds. A nil fn disables the breakers.
+func WithBreakers(fn func(method string) failover.Breaker) ClientOption {
+	return func(c *client) {
+		c.newBreaker = fn
+	}
+}
+
+// WithTimeout sets the deadline, covering all attempts, of calls whose
+// context has none, canceling them with a cause that matches
+// failover.ErrTimeout. Calls that already carry a deadline keep it.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *client) {
+		c.timeout = d
+	}
+}
+
+func newClient(opts []ClientOption) *client {
+	c := &client{
+		attempts: 3,
+		backoff: failover.ExponentialBackoff{
+			Initial: 100 * time.Millisecond,
+			Max:     2 * time.Second,
+			Jitter:  0.2,
+		},
+		retryable: []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+		newBreaker: func(string) failover.Breaker {
+			return failover.NewCircuitBreaker(5, 1, 30*time.Second)
+		},
+		breakers: make(map[string]failover.Breaker),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// DialOption returns a grpc.DialOption installing the unary client
+// interceptor, so a client adopts it in one line:
+//
+//	conn, err := grpc.NewClient(target, grpcfailover.DialOption())
+//
+// Streams are left alone, as resuming them needs WithResume; install
+// StreamClientInterceptor with grpc.WithStreamInterceptor for those.
+func DialOption(opts ...ClientOption) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(opts...))
+}
+
+// UnaryClientInterceptor returns an interceptor that guards every method
+// with its own circuit breaker and retries calls failing with a retryable
+// status code.
+//
+// Only outcomes that say something about the server's health count against
+// a breaker: UNAVAILABLE, RESOURCE_EXHAUSTED, DEADLINE_EXCEEDED, INTERNAL
+// and UNKNOWN. A call rejected by an open breaker fails with UNAVAILABLE,
+// and its error also matches failover.ErrCircuitOpen. A server pushback in
+// the grpc-retry-pushback-ms trailer delays the next attempt accordingly.
+func UnaryClientInterceptor(opts ...ClientOption) grpc.UnaryClientInterceptor {
+	c := newClient(opts)
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeoutCause(ctx, c.timeout, errTimeout)
+			defer cancel()
+		}
+
+		breaker := c.breaker(method)
+		retry := failover.NewRetryPolicy(c.attempts, 0, failover.WithBackoff(c.backoff), failover.WithRetryIf(c.shouldRetry))
+
+		err := retry.Do(ctx, func(ctx context.Context) error {
+			if c.propagate {
+				ctx = AppendTimeout(ctx)
+			}
+
+			var trailer metadata.MD
+			opts := append(slices.Clip(callOpts), grpc.Trailer(&trailer))
+
+			var callErr error
+			err := breaker.Execute(func() error {
+				callErr = invoker(ctx, method, req, reply, cc, opts...)
+				if ctx.Err() != nil || !serverFailure(callErr) {
+					return nil
+				}
+				return callErr
+			})
+
+			if errors.Is(err, failover.ErrCircuitOpen) {
+				return &openError{method: method}
+			}
+			if callErr != nil {
+				if delay, ok := pushback(trailer); ok {
+					return &failover.RetryAfterError{Err: callErr, Delay: delay}
+				}
+			}
+			return callErr
+		})
+
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return status.FromContextError(err).Err()
+		}
+
+		return statusError(err)
+	}
+}
+
+// statusError returns the gRPC status error behind an error of the retry
+// policy: the last failure of a call that used up its attempts, and
+// DEADLINE_EXCEEDED for one whose deadline would pass before the next.
+func statusError(err error) error {
+	var budget *failover.BudgetExhaustedError
+	if errors.As(err, &budget) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	var exhausted *failover.MaxAttemptsError
+	if errors.As(err, &exhausted) {
+		err = exhausted.LastErr
+	}
+	var ra *failover.RetryAfterError
+	if errors.As(err, &ra) {
+		return ra.Err
+	}
+
+	return err
+}
+
+// breaker returns the breaker for method, creating it on first use.
+func (c *client) breaker(method string) failover.Breaker {
+	if c.newBreaker == nil {
+		return failover.NoopBreaker{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[method]
+	if !ok {
+		b = c.newBreaker(method)
+		c.breakers[method] = b
+	}
+
+	return b
+}
+
+// shouldRetry reports whether an attempt's error is worth another attempt.
+func (c *client) shouldRetry(err error) bool {
+	if errors.Is(err, failover.ErrCircuitOpen) {
+		return false
+	}
+
+	// A negative pushback is the server asking not to retry at all.
+	var ra *failover.RetryAfterError
+	if errors.As(err, &ra) && ra.Delay < 0 {
+		return false
+	}
+
+	return slices.Contains(c.retryable, status.Code(err))
+}
+
+// serverFailure reports whether err counts against a breaker.
+func serverFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		return true
+	}
+
+	return false
+}
+
+// pushback returns the delay a server asked for in the
+// grpc-retry-pushback-ms trailer, negative if it asked for no retry.
+func pushback(trailer metadata.MD) (time.Duration, bool) {
+	values := trailer.Get("grpc-retry-pushback-ms")
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	ms, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || ms < 0 {
+		return -1, true
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// openError is returned for calls rejected by an open breaker. It carries
+// the UNAVAILABLE status for gRPC and matches failover.ErrCircuitOpen.
+type openError struct {
+	method string
+}
+
+// Error implements error.
+func (e *openError) Error() string {
+	return "grpcfailover: " + e.method + ": " + failover.ErrCircuitOpen.Error()
+}
+
+// Unwrap returns failover.ErrCircuitOpen.
+func (e *openError) Unwrap() error {
+	return failover.ErrCircuitOpen
+}
+
+// GRPCStatus returns the UNAVAILABLE status of the error.
+func (e *openError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
@@ -0,0 +1,162 @@
+package grpcfailover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// flakyHealth fails the first failures Check calls with code, setting
+// trailer on them if given.
+type flakyHealth struct {
+	healthpb.UnimplementedHealthServer
+	failures int32
+	code     codes.Code
+	trailer  metadata.MD
+	calls    atomic.Int32
+}
+
+func (h *flakyHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if h.calls.Add(1) <= h.failures {
+		if h.trailer != nil {
+			_ = grpc.SetTrailer(ctx, h.trailer)
+		}
+		return nil, status.Error(h.code, "flaky")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// flakyClient returns a health client for srv through the failover
+// client interceptor configured with opts.
+func flakyClient(t *testing.T, srv *flakyHealth, opts ...ClientOption) healthpb.HealthClient {
+	t.Helper()
+	opts = append([]ClientOption{WithRetry(3, failover.ConstantBackoff(time.Millisecond))}, opts...)
+	conn := dial(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, srv) }, DialOption(opts...))
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryClientInterceptor_RetriesUnavailable(t *testing.T) {
+	t.Parallel()
+	srv := &flakyHealth{failures: 2, code: codes.Unavailable}
+	client := flakyClient(t, srv)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if got := srv.calls.Load(); got != 3 {
+		t.Fatalf("Expected 3 calls, got %d", got)
+	}
+}
+
+func TestUnaryClientInterceptor_NoRetryForOtherCodes(t *testing.T) {
+	t.Parallel()
+	srv := &flakyHealth{failures: 1, code: codes.InvalidArgument}
+	client := flakyClient(t, srv)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected INVALID_ARGUMENT, got %v", err)
+	}
+	if got := srv.calls.Load(); got != 1 {
+		t.Fatalf("Expected 1 call, got %d", got)
+	}
+}
+
+func TestUnaryClientInterceptor_Breaker(t *testing.T) {
+	t.Parallel()
+	srv := &flakyHealth{failures: 100, code: codes.Unavailable}
+	client := flakyClient(t, srv,
+		WithRetry(1, failover.ConstantBackoff(time.Millisecond)),
+		WithBreakers(func(string) failover.Breaker {
+			return failover.NewCircuitBreaker(2, 1, time.Minute)
+		}),
+	)
+	ctx := context.Background()
+
+	for range 2 {
+		_, _ = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if !errors.Is(err, failover.ErrCircuitOpen) || status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected an UNAVAILABLE ErrCircuitOpen, got %v", err)
+	}
+	if got := srv.calls.Load(); got != 2 {
+		t.Fatalf("Expected the open breaker to stop calls, got %d", got)
+	}
+}
+
+func TestUnaryClientInterceptor_Pushback(t *testing.T) {
+	t.Parallel()
+	srv := &flakyHealth{
+		failures: 1,
+		code:     codes.ResourceExhausted,
+		trailer:  metadata.Pairs("grpc-retry-pushback-ms", "50"),
+	}
+	client := flakyClient(t, srv)
+
+	start := time.Now()
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Expected the retry to wait for the pushback, took only %v", elapsed)
+	}
+}
+
+func TestUnaryClientInterceptor_PushbackStopsRetries(t *testing.T) {
+	t.Parallel()
+	srv := &flakyHealth{
+		failures: 1,
+		code:     codes.Unavailable,
+		trailer:  metadata.Pairs("grpc-retry-pushback-ms", "-1"),
+	}
+	client := flakyClient(t, srv)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected UNAVAILABLE, got %v", err)
+	}
+	if got := srv.calls.Load(); got != 1 {
+		t.Fatalf("Expected 1 call, got %d", got)
+	}
+}
+
+func TestUnaryClientInterceptor_Timeout(t *testing.T) {
+	t.Parallel()
+	srv := &flakyHealth{failures: 100, code: codes.Unavailable}
+	client := flakyClient(t, srv,
+		WithRetry(100, failover.ConstantBackoff(20*time.Millisecond)),
+		WithTimeout(50*time.Millisecond),
+	)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("Expected DEADLINE_EXCEEDED, got %v", err)
+	}
+}
+
+func TestUnaryClientInterceptor_TimeoutCause(t *testing.T) {
+	t.Parallel()
+	interceptor := UnaryClientInterceptor(WithRetry(1, failover.ConstantBackoff(time.Millisecond)), WithTimeout(10*time.Millisecond))
+
+	var cause error
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	interceptor(context.Background(), "/test/Method", nil, nil, nil, invoker)
+
+	if !errors.Is(cause, failover.ErrTimeout) || !errors.Is(cause, context.DeadlineExceeded) {
+		t.Fatalf("Expected a cause matching ErrTimeout, got %v", cause)
+	}
+}
@@ -0,0 +1,78 @@
+package grpcfailover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TimeoutMetadataKey is the metadata key carrying the time a call has left
+// before its deadline, in milliseconds. gRPC sends the deadline itself in
+// grpc-timeout; the key carries it across hops that drop that header, such
+// as HTTP gateways, and matches the httpfailover.TimeoutHeader of HTTP
+// services.
+const TimeoutMetadataKey = "x-request-timeout-ms"
+
+// ErrDeadlineBudget is the cause of the contexts the server interceptor
+// cancels when the budget in TimeoutMetadataKey runs out, read with
+// context.Cause. It is also context.DeadlineExceeded, so existing checks
+// still match.
+var ErrDeadlineBudget = fmt.Errorf("grpcfailover: caller's time budget ran out: %w", context.DeadlineExceeded)
+
+// maxTimeoutMs is the largest budget a Duration can hold, in milliseconds.
+const maxTimeoutMs = math.MaxInt64 / int64(time.Millisecond)
+
+// WithDeadlinePropagation sends TimeoutMetadataKey with every attempt of a
+// call whose context has a deadline, set to the time left when the attempt
+// starts.
+func WithDeadlinePropagation() ClientOption {
+	return func(c *client) {
+		c.propagate = true
+	}
+}
+
+// WithDeadlineMetadata makes the server interceptor honor the budget a
+// request carries in TimeoutMetadataKey: the handler runs with a context
+// that expires when the budget runs out, unless its deadline is already
+// sooner, with ErrDeadlineBudget as its cause, and a request arriving with
+// no time left fails with DEADLINE_EXCEEDED without running.
+func WithDeadlineMetadata() ServerOption {
+	return func(s *server) {
+		s.deadline = true
+	}
+}
+
+// AppendTimeout returns a copy of ctx whose outgoing metadata carries the
+// time left before its deadline in TimeoutMetadataKey, or ctx if it has no
+// deadline.
+func AppendTimeout(ctx context.Context) context.Context {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx
+	}
+
+	// Round up, so that a call with time left is not sent as expired.
+	ms := (time.Until(deadline) + time.Millisecond - 1) / time.Millisecond
+	return metadata.AppendToOutgoingContext(ctx, TimeoutMetadataKey, strconv.FormatInt(max(int64(ms), 0), 10))
+}
+
+// TimeoutFromMetadata returns the time budget in the TimeoutMetadataKey of
+// the incoming metadata of ctx, if it holds one. Budgets too large for a
+// Duration are ignored.
+func TimeoutFromMetadata(ctx context.Context) (time.Duration, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, TimeoutMetadataKey)
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	ms, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || ms < 0 || ms > maxTimeoutMs {
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
@@ -0,0 +1,96 @@
+package grpcfailover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// budgetHealth records the budget each Check call arrives with.
+type budgetHealth struct {
+	healthpb.UnimplementedHealthServer
+	budgets chan time.Duration
+}
+
+func (h *budgetHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	budget, ok := TimeoutFromMetadata(ctx)
+	if !ok {
+		budget = -1
+	}
+	h.budgets <- budget
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func TestUnaryClientInterceptor_DeadlinePropagation(t *testing.T) {
+	t.Parallel()
+	srv := &budgetHealth{budgets: make(chan time.Duration, 2)}
+	conn := dial(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, srv) }, DialOption(WithDeadlinePropagation()))
+	client := healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if budget := <-srv.budgets; budget <= 0 || budget > time.Second {
+		t.Errorf("Expected a budget within 1s, got %v", budget)
+	}
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if budget := <-srv.budgets; budget != -1 {
+		t.Errorf("Expected no budget without a deadline, got %v", budget)
+	}
+}
+
+func TestUnaryServerInterceptor_DeadlineMetadata(t *testing.T) {
+	t.Parallel()
+	interceptor := UnaryServerInterceptor(failover.NewBulkhead(1, 0, 0), WithDeadlineMetadata())
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+
+	var deadline time.Time
+	var ok bool
+	handler := func(ctx context.Context, _ any) (any, error) {
+		deadline, ok = ctx.Deadline()
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TimeoutMetadataKey, "2000"))
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ok || time.Until(deadline) > 2*time.Second || time.Until(deadline) < time.Second {
+		t.Fatalf("Expected a deadline about 2s away, got %v", time.Until(deadline))
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TimeoutMetadataKey, "10"))
+	var cause error
+	expire := func(ctx context.Context, _ any) (any, error) {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return nil, nil
+	}
+	interceptor(ctx, nil, info, expire)
+	if !errors.Is(cause, ErrDeadlineBudget) {
+		t.Fatalf("Expected ErrDeadlineBudget, got %v", cause)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TimeoutMetadataKey, "9223372036854775807"))
+	if _, err := interceptor(ctx, nil, info, handler); err != nil || ok {
+		t.Fatalf("Expected the huge budget to be ignored, got %v (deadline %v)", err, ok)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TimeoutMetadataKey, "0"))
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("Expected DEADLINE_EXCEEDED, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Package grpcfailover integrates the failover primitives with gRPC.
+package grpcfailover
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthProbe returns a failover.Probe that calls the standard
+// grpc.health.v1 Check method on conn for service (empty for the server as
+// a whole). Any status other than SERVING wraps failover.ErrUnhealthy; RPC
+// errors are returned as is.
+func HealthProbe(conn grpc.ClientConnInterface, service string) failover.Probe {
+	client := healthpb.NewHealthClient(conn)
+
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+
+		if status := resp.GetStatus(); status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("%w: %s", failover.ErrUnhealthy, status)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package grpcfailover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts a gRPC server with srv registered on an in-memory listener
+// and returns a client connection to it.
+func dial(t *testing.T, register func(*grpc.Server), opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	return dialServer(t, nil, register, opts...)
+}
+
+// dialServer is dial for a server created with serverOpts.
+func dialServer(t *testing.T, serverOpts []grpc.ServerOption, register func(*grpc.Server), opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(serverOpts...)
+	register(server)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestHealthProbe(t *testing.T) {
+	t.Parallel()
+	hs := health.NewServer()
+	conn := dial(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, hs) })
+	ctx := context.Background()
+
+	hs.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+	if err := HealthProbe(conn, "orders")(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	hs.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err := HealthProbe(conn, "orders")(ctx); !errors.Is(err, failover.ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy, got %v", err)
+	}
+
+	if err := HealthProbe(conn, "unknown")(ctx); err == nil {
+		t.Fatal("Expected an unknown service to fail the probe")
+	}
+}
+
+func TestHealthProbe_HealthChecker(t *testing.T) {
+	t.Parallel()
+	hs := health.NewServer()
+	conn := dial(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, hs) })
+
+	h := failover.NewHealthChecker(time.Hour)
+	h.Add("backend", HealthProbe(conn, ""))
+	h.Check(context.Background(), "backend")
+
+	if !h.Healthy("backend") {
+		t.Fatalf("Expected backend to be %v, got %v", failover.Up, h.Status("backend"))
+	}
+}
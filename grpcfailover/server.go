@@ -0,0 +1,123 @@
+package grpcfailover
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PriorityMetadataKey is the metadata key the server interceptor reads a
+// request's priority from by default: "critical", "normal" or
+// "best-effort".
+const PriorityMetadataKey = "failover-priority"
+
+// server is the configuration of the server interceptor.
+type server struct {
+	priority func(ctx context.Context, method string) failover.Priority
+	deadline bool // Honors TimeoutMetadataKey
+}
+
+// ServerOption configures optional server interceptor behavior.
+type ServerOption func(*server)
+
+// WithPriorityFunc decides the priority of each request with fn instead of
+// reading it from the PriorityMetadataKey metadata, for instance to make
+// health checks Critical and batch methods BestEffort.
+func WithPriorityFunc(fn func(ctx context.Context, method string) failover.Priority) ServerOption {
+	return func(s *server) {
+		s.priority = fn
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor running each request under
+// policy, typically a *failover.AdaptiveLimiter with WithPriorityHeadroom,
+// a *failover.Bulkhead or a *failover.CoDelQueue, which shed requests by
+// the priority the interceptor puts in their context.
+//
+// A request the policy rejects without running the handler fails with
+// RESOURCE_EXHAUSTED. If the policy can estimate when capacity frees up,
+// the estimate is sent in the grpc-retry-pushback-ms trailer, which
+// UnaryClientInterceptor honors. Errors from the handler are returned to
+// the policy as is; use IsOverload with failover.WithDropClassifier so
+// that only overload errors shrink an adaptive limit.
+func UnaryServerInterceptor(policy failover.Policy, opts ...ServerOption) grpc.UnaryServerInterceptor {
+	s := &server{priority: metadataPriority}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if budget, ok := TimeoutFromMetadata(ctx); ok && s.deadline {
+			if budget == 0 {
+				return nil, status.Errorf(codes.DeadlineExceeded, "%s: no time left", info.FullMethod)
+			}
+
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeoutCause(ctx, budget, ErrDeadlineBudget)
+			defer cancel()
+		}
+		ctx = failover.WithPriority(ctx, s.priority(ctx, info.FullMethod))
+
+		var (
+			resp   any
+			served atomic.Bool
+		)
+		err := policy.Do(ctx, func(ctx context.Context) error {
+			served.Store(true)
+
+			var err error
+			resp, err = handler(ctx, req)
+			return err
+		})
+
+		if err != nil && !served.Load() {
+			if e, ok := policy.(interface{ EstimatedWait() time.Duration }); ok {
+				if wait := e.EstimatedWait(); wait > 0 {
+					ms := strconv.FormatInt(max(wait.Milliseconds(), 1), 10)
+					_ = grpc.SetTrailer(ctx, metadata.Pairs("grpc-retry-pushback-ms", ms))
+				}
+			}
+			return nil, status.Errorf(codes.ResourceExhausted, "%s: %v", info.FullMethod, err)
+		}
+
+		return resp, err
+	}
+}
+
+// IsOverload reports whether err is a status saying the server could not
+// keep up: RESOURCE_EXHAUSTED, UNAVAILABLE or DEADLINE_EXCEEDED.
+func IsOverload(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
+
+// metadataPriority reads the priority from the PriorityMetadataKey
+// metadata, keeping any priority already in ctx when there is none.
+func metadataPriority(ctx context.Context, _ string) failover.Priority {
+	values := metadata.ValueFromIncomingContext(ctx, PriorityMetadataKey)
+	if len(values) == 0 {
+		return failover.PriorityFromContext(ctx)
+	}
+
+	switch strings.ToLower(values[0]) {
+	case "critical":
+		return failover.Critical
+	case "best-effort", "besteffort":
+		return failover.BestEffort
+	}
+
+	return failover.Normal
+}
@@ -0,0 +1,137 @@
+package grpcfailover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// blockingHealth holds every Check call until release is closed.
+type blockingHealth struct {
+	healthpb.UnimplementedHealthServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHealth) Check(context.Context, *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// protectedClient returns a health client for a blockingHealth server
+// guarded by policy, and starts one call that holds a slot until the test
+// ends.
+func protectedClient(t *testing.T, policy failover.Policy, opts ...ServerOption) healthpb.HealthClient {
+	t.Helper()
+	srv := &blockingHealth{started: make(chan struct{}, 10), release: make(chan struct{})}
+	conn := dialServer(t,
+		[]grpc.ServerOption{grpc.UnaryInterceptor(UnaryServerInterceptor(policy, opts...))},
+		func(s *grpc.Server) { healthpb.RegisterHealthServer(s, srv) },
+	)
+	t.Cleanup(func() { close(srv.release) })
+
+	client := healthpb.NewHealthClient(conn)
+	go func() { _, _ = client.Check(context.Background(), &healthpb.HealthCheckRequest{}) }()
+	<-srv.started
+	return client
+}
+
+func TestUnaryServerInterceptor_Sheds(t *testing.T) {
+	t.Parallel()
+	client := protectedClient(t, failover.NewAdaptiveLimiter(failover.AIMD{}, 1, 1, 1))
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+}
+
+func TestUnaryServerInterceptor_Priority(t *testing.T) {
+	t.Parallel()
+	limiter := failover.NewAdaptiveLimiter(failover.AIMD{}, 2, 2, 2, failover.WithPriorityHeadroom(0.5))
+	client := protectedClient(t, limiter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	bestEffort := metadata.AppendToOutgoingContext(ctx, PriorityMetadataKey, "best-effort")
+	if _, err := client.Check(bestEffort, &healthpb.HealthCheckRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected best-effort call to be shed, got %v", err)
+	}
+
+	// A Normal call is admitted and holds until the test ends.
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("Expected normal call to be admitted, got %v", err)
+	}
+}
+
+func TestUnaryServerInterceptor_PriorityFunc(t *testing.T) {
+	t.Parallel()
+	var got failover.Priority
+	policy := failover.PolicyFunc(func(ctx context.Context, fn failover.WorkFuncCtx) error {
+		got = failover.PriorityFromContext(ctx)
+		return failover.ErrLimitExceeded
+	})
+	srv := &blockingHealth{}
+	conn := dialServer(t,
+		[]grpc.ServerOption{grpc.UnaryInterceptor(UnaryServerInterceptor(policy,
+			WithPriorityFunc(func(_ context.Context, method string) failover.Priority {
+				if method == healthpb.Health_Check_FullMethodName {
+					return failover.Critical
+				}
+				return failover.Normal
+			}),
+		))},
+		func(s *grpc.Server) { healthpb.RegisterHealthServer(s, srv) },
+	)
+
+	_, _ = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if got != failover.Critical {
+		t.Fatalf("Expected priority Critical, got %v", got)
+	}
+}
+
+// estimatingPolicy rejects every call and estimates a wait of wait.
+type estimatingPolicy struct{ wait time.Duration }
+
+func (estimatingPolicy) Do(context.Context, failover.WorkFuncCtx) error {
+	return failover.ErrBulkheadFull
+}
+
+func (p estimatingPolicy) EstimatedWait() time.Duration { return p.wait }
+
+func TestUnaryServerInterceptor_Pushback(t *testing.T) {
+	t.Parallel()
+	srv := &blockingHealth{}
+	conn := dialServer(t,
+		[]grpc.ServerOption{grpc.UnaryInterceptor(UnaryServerInterceptor(estimatingPolicy{wait: 250 * time.Millisecond}))},
+		func(s *grpc.Server) { healthpb.RegisterHealthServer(s, srv) },
+	)
+
+	var trailer metadata.MD
+	_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+	if got := trailer.Get("grpc-retry-pushback-ms"); len(got) != 1 || got[0] != "250" {
+		t.Fatalf("Expected a pushback of 250ms, got %v", got)
+	}
+}
+
+func TestIsOverload(t *testing.T) {
+	t.Parallel()
+	if !IsOverload(status.Error(codes.ResourceExhausted, "busy")) {
+		t.Fatal("Expected RESOURCE_EXHAUSTED to be an overload")
+	}
+	if IsOverload(status.Error(codes.NotFound, "missing")) || IsOverload(nil) {
+		t.Fatal("Expected NOT_FOUND and nil not to be overloads")
+	}
+}
@@ -0,0 +1,162 @@
+package grpcfailover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ResumeFunc prepares a stream that replaces a broken one, typically by
+// sending a request that picks up where the old stream left off, such as
+// from the last offset the caller acknowledged. It must send everything
+// the method expects before the server responds, including CloseSend for
+// a server-streaming method, since the original request is not replayed.
+type ResumeFunc func(ctx context.Context, method string, stream grpc.ClientStream) error
+
+// WithResume makes the stream interceptor re-establish streams that break
+// with a retryable status code, calling fn on each new stream before
+// receiving from it. Reconnects are paced by the WithRetry backoff, and a
+// stream gives up after as many consecutive failed reconnects as it allows
+// attempts. Without it streams are not intercepted.
+func WithResume(fn ResumeFunc) ClientOption {
+	return func(c *client) {
+		c.resume = fn
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that transparently
+// replaces broken streams, as set up with WithResume, so long-lived watch
+// and subscribe streams survive server restarts and failovers. Messages
+// the caller receives carry on from the new stream once it is resumed.
+func StreamClientInterceptor(opts ...ClientOption) grpc.StreamClientInterceptor {
+	c := newClient(opts)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil || c.resume == nil {
+			return stream, err
+		}
+
+		return &resumingStream{
+			client: c,
+			ctx:    ctx,
+			open: func(ctx context.Context) (grpc.ClientStream, error) {
+				return streamer(ctx, desc, cc, method, slices.Clip(callOpts)...)
+			},
+			method: method,
+			stream: stream,
+		}, nil
+	}
+}
+
+// resumingStream is a grpc.ClientStream that replaces its underlying stream
+// when receiving from it fails with a retryable code.
+type resumingStream struct {
+	client *client
+	ctx    context.Context
+	open   func(ctx context.Context) (grpc.ClientStream, error)
+	method string
+
+	mu     sync.Mutex // Protects stream and cancel
+	stream grpc.ClientStream
+	cancel context.CancelFunc // Ends the current stream if it was reopened, nil otherwise
+}
+
+func (s *resumingStream) current() grpc.ClientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stream
+}
+
+// Header implements grpc.ClientStream.
+func (s *resumingStream) Header() (metadata.MD, error) {
+	return s.current().Header()
+}
+
+// Trailer implements grpc.ClientStream.
+func (s *resumingStream) Trailer() metadata.MD {
+	return s.current().Trailer()
+}
+
+// CloseSend implements grpc.ClientStream.
+func (s *resumingStream) CloseSend() error {
+	return s.current().CloseSend()
+}
+
+// Context implements grpc.ClientStream.
+func (s *resumingStream) Context() context.Context {
+	return s.current().Context()
+}
+
+// SendMsg implements grpc.ClientStream.
+func (s *resumingStream) SendMsg(m any) error {
+	return s.current().SendMsg(m)
+}
+
+// RecvMsg implements grpc.ClientStream, resuming the stream first if it
+// broke.
+func (s *resumingStream) RecvMsg(m any) error {
+	for {
+		err := s.current().RecvMsg(m)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, io.EOF) || s.ctx.Err() != nil || !s.client.shouldRetry(err) {
+			s.replace(nil, nil)
+			return err
+		}
+
+		if err := s.reconnect(); err != nil {
+			s.replace(nil, nil)
+			if s.ctx.Err() != nil {
+				return status.FromContextError(s.ctx.Err()).Err()
+			}
+			return statusError(err)
+		}
+	}
+}
+
+// reconnect opens and resumes a new stream in place of the broken one.
+func (s *resumingStream) reconnect() error {
+	c := s.client
+	retry := failover.NewRetryPolicy(c.attempts, 0, failover.WithBackoff(c.backoff), failover.WithRetryIf(c.shouldRetry))
+
+	return retry.Do(s.ctx, func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+
+		stream, err := s.open(ctx)
+		if err == nil {
+			err = c.resume(ctx, s.method, stream)
+		}
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		s.replace(stream, cancel)
+		return nil
+	})
+}
+
+// replace makes stream the current stream, ending the previous one if it
+// was reopened. A nil stream keeps the current one but releases it.
+func (s *resumingStream) replace(stream grpc.ClientStream, cancel context.CancelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if stream != nil {
+		s.stream = stream
+	}
+	s.cancel = cancel
+}
@@ -0,0 +1,136 @@
+package grpcfailover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// breakingWatch sends one update per Watch stream, then fails the stream
+// with code for the first breaks streams and ends later ones cleanly. The
+// update is NOT_SERVING for streams opened by resumeWatch, so tests can
+// tell which request opened a stream.
+type breakingWatch struct {
+	healthpb.UnimplementedHealthServer
+	breaks  int32
+	streams atomic.Int32
+	code    codes.Code
+}
+
+func (h *breakingWatch) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	resp := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}
+	if req.GetService() == "resumed" {
+		resp.Status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	if err := stream.Send(resp); err != nil {
+		return err
+	}
+
+	if h.streams.Add(1) <= h.breaks {
+		return status.Error(h.code, "going away")
+	}
+	return nil
+}
+
+// resumeWatch resumes a Watch stream by asking for the "resumed" service.
+func resumeWatch(calls *atomic.Int32) ResumeFunc {
+	return func(_ context.Context, method string, stream grpc.ClientStream) error {
+		calls.Add(1)
+		if method != healthpb.Health_Watch_FullMethodName {
+			return errors.New("unexpected method " + method)
+		}
+		if err := stream.SendMsg(&healthpb.HealthCheckRequest{Service: "resumed"}); err != nil {
+			return err
+		}
+		return stream.CloseSend()
+	}
+}
+
+func watchClient(t *testing.T, srv *breakingWatch, opts ...ClientOption) healthpb.HealthClient {
+	t.Helper()
+	opts = append([]ClientOption{WithRetry(3, failover.ConstantBackoff(time.Millisecond))}, opts...)
+	conn := dial(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, srv) },
+		grpc.WithStreamInterceptor(StreamClientInterceptor(opts...)))
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestStreamClientInterceptor_Resumes(t *testing.T) {
+	t.Parallel()
+	srv := &breakingWatch{breaks: 2, code: codes.Unavailable}
+	var resumes atomic.Int32
+	client := watchClient(t, srv, WithResume(resumeWatch(&resumes)))
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	var got []healthpb.HealthCheckResponse_ServingStatus
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected the stream to be resumed, got %v", err)
+		}
+		got = append(got, resp.GetStatus())
+	}
+
+	want := []healthpb.HealthCheckResponse_ServingStatus{
+		healthpb.HealthCheckResponse_SERVING,
+		healthpb.HealthCheckResponse_NOT_SERVING,
+		healthpb.HealthCheckResponse_NOT_SERVING,
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("Expected updates %v, got %v", want, got)
+	}
+	if resumes.Load() != 2 {
+		t.Fatalf("Expected 2 resumes, got %d", resumes.Load())
+	}
+}
+
+func TestStreamClientInterceptor_NotRetryable(t *testing.T) {
+	t.Parallel()
+	srv := &breakingWatch{breaks: 1, code: codes.PermissionDenied}
+	var resumes atomic.Int32
+	client := watchClient(t, srv, WithResume(resumeWatch(&resumes)))
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	_, _ = stream.Recv()
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PERMISSION_DENIED, got %v", err)
+	}
+	if resumes.Load() != 0 {
+		t.Fatalf("Expected no resume, got %d", resumes.Load())
+	}
+}
+
+func TestStreamClientInterceptor_WithoutResume(t *testing.T) {
+	t.Parallel()
+	srv := &breakingWatch{breaks: 1, code: codes.Unavailable}
+	client := watchClient(t, srv)
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	_, _ = stream.Recv()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected the broken stream to fail, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package failover
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Probe checks the health of a target, returning nil when it is healthy.
+type Probe func(ctx context.Context) error
+
+// HealthStatus is the health of a target as tracked by a HealthChecker.
+type HealthStatus int
+
+const (
+	// Unknown is the status of a target before enough probes have run to
+	// reach either threshold.
+	Unknown HealthStatus = iota
+	// Up means the target passed rise consecutive probes.
+	Up
+	// Down means the target failed fall consecutive probes.
+	Down
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthChange describes a target moving from one status to another.
+type HealthChange struct {
+	Target string
+	From   HealthStatus
+	To     HealthStatus
+	Err    error // The probe error that caused a move to Down
+}
+
+// target is the state kept for one probed target.
+type target struct {
+	probe     Probe
+	status    HealthStatus
+	successes int // Consecutive passing probes
+	failures  int // Consecutive failing probes
+	lastErr   error
+	cancel    context.CancelFunc // Stops the target's probe loop, nil when not running
+}
+
+// HealthChecker actively probes a set of targets on an interval and tracks
+// whether each one is Up or Down. A target only changes status after rise
+// consecutive passing or fall consecutive failing probes, so a single blip
+// does not flip it.
+type HealthChecker struct {
+	interval time.Duration
+	timeout  time.Duration // Per-probe timeout, zero for none
+	rise     int           // Passing probes needed to mark a target Up
+	fall     int           // Failing probes needed to mark a target Down
+
+	mu        sync.Mutex // Protects targets, listeners and ctx
+	targets   map[string]*target
+	listeners []func(HealthChange)
+	ctx       context.Context // The Run context, nil when not running
+}
+
+// HealthOption configures optional HealthChecker behavior.
+type HealthOption func(*HealthChecker)
+
+// WithRise sets how many consecutive passing probes mark a target Up.
+// The default is 1.
+func WithRise(n int) HealthOption {
+	return func(h *HealthChecker) {
+		h.rise = n
+	}
+}
+
+// WithFall sets how many consecutive failing probes mark a target Down.
+// The default is 1.
+func WithFall(n int) HealthOption {
+	return func(h *HealthChecker) {
+		h.fall = n
+	}
+}
+
+// WithProbeTimeout bounds each probe call to d.
+func WithProbeTimeout(d time.Duration) HealthOption {
+	return func(h *HealthChecker) {
+		h.timeout = d
+	}
+}
+
+// NewHealthChecker creates a HealthChecker probing each target every
+// interval.
+func NewHealthChecker(interval time.Duration, opts ...HealthOption) *HealthChecker {
+	h := &HealthChecker{
+		interval: interval,
+		rise:     1,
+		fall:     1,
+		targets:  make(map[string]*target),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Add registers a target, replacing any previous probe under the same
+// name. If the checker is running the target starts being probed at once.
+func (h *HealthChecker) Add(name string, probe Probe) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if old, ok := h.targets[name]; ok && old.cancel != nil {
+		old.cancel()
+	}
+
+	t := &target{probe: probe}
+	h.targets[name] = t
+	if h.ctx != nil {
+		h.start(name, t)
+	}
+}
+
+// Remove stops probing a target and forgets its status.
+func (h *HealthChecker) Remove(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.targets[name]; ok {
+		if t.cancel != nil {
+			t.cancel()
+		}
+		delete(h.targets, name)
+	}
+}
+
+// Subscribe registers fn to be called on every status change. Calls are
+// made synchronously from the probing goroutine, in order per target.
+func (h *HealthChecker) Subscribe(fn func(HealthChange)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.listeners = append(h.listeners, fn)
+}
+
+// Status returns the status of a target, Unknown if it is not registered.
+func (h *HealthChecker) Status(name string) HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.targets[name]; ok {
+		return t.status
+	}
+
+	return Unknown
+}
+
+// Healthy reports whether a target is Up.
+func (h *HealthChecker) Healthy(name string) bool {
+	return h.Status(name) == Up
+}
+
+// Statuses returns the status of every registered target.
+func (h *HealthChecker) Statuses() map[string]HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	statuses := make(map[string]HealthStatus, len(h.targets))
+	for name, t := range h.targets {
+		statuses[name] = t.status
+	}
+
+	return statuses
+}
+
+// Targets returns the names of the registered targets, sorted.
+func (h *HealthChecker) Targets() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.targets))
+	for name := range h.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Check probes a target once and records the result, returning the probe
+// error. It is what Run does on every interval, and is useful to get an
+// initial status before serving traffic.
+func (h *HealthChecker) Check(ctx context.Context, name string) error {
+	h.mu.Lock()
+	t, ok := h.targets[name]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	return h.check(ctx, name, t)
+}
+
+// Run probes every target on the interval until ctx is done, then returns
+// ctx.Err().
+func (h *HealthChecker) Run(ctx context.Context) error {
+	h.mu.Lock()
+	h.ctx = ctx
+	for name, t := range h.targets {
+		h.start(name, t)
+	}
+	h.mu.Unlock()
+
+	<-ctx.Done()
+
+	h.mu.Lock()
+	h.ctx = nil
+	for _, t := range h.targets {
+		if t.cancel != nil {
+			t.cancel()
+			t.cancel = nil
+		}
+	}
+	h.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// start launches the probe loop of a target. It must be called with the
+// lock held while running.
+func (h *HealthChecker) start(name string, t *target) {
+	ctx, cancel := context.WithCancel(h.ctx)
+	t.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			h.check(ctx, name, t)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// check runs the probe of t and applies the rise and fall thresholds.
+func (h *HealthChecker) check(ctx context.Context, name string, t *target) error {
+	probeCtx := ctx
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		probeCtx, cancel = context.WithTimeoutCause(ctx, h.timeout, errDeadline)
+		defer cancel()
+	}
+
+	err := t.probe(probeCtx)
+	if ctx.Err() != nil {
+		return err // stopped mid-probe, the result says nothing about the target
+	}
+
+	h.mu.Lock()
+	from := t.status
+	t.lastErr = err
+	if err == nil {
+		t.successes++
+		t.failures = 0
+		if t.successes >= h.rise {
+			t.status = Up
+		}
+	} else {
+		t.failures++
+		t.successes = 0
+		if t.failures >= h.fall {
+			t.status = Down
+		}
+	}
+	to := t.status
+	listeners := h.listeners
+	h.mu.Unlock()
+
+	if from != to {
+		change := HealthChange{Target: name, From: from, To: to}
+		if to == Down {
+			change.Err = err
+		}
+		for _, fn := range listeners {
+			fn(change)
+		}
+	}
+
+	return err
+}
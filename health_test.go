@@ -0,0 +1,157 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker_RiseFall(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(time.Hour, WithRise(2), WithFall(3))
+	ctx := context.Background()
+
+	var healthy atomic.Bool
+	h.Add("db", func(context.Context) error {
+		if healthy.Load() {
+			return nil
+		}
+		return errTest
+	})
+
+	healthy.Store(true)
+	h.Check(ctx, "db")
+	if s := h.Status("db"); s != Unknown {
+		t.Fatalf("Expected status %v after one pass, got %v", Unknown, s)
+	}
+	h.Check(ctx, "db")
+	if s := h.Status("db"); s != Up {
+		t.Fatalf("Expected status %v after rise passes, got %v", Up, s)
+	}
+
+	healthy.Store(false)
+	for range 2 {
+		h.Check(ctx, "db")
+	}
+	if !h.Healthy("db") {
+		t.Fatal("Expected target to stay Up below the fall threshold")
+	}
+	if err := h.Check(ctx, "db"); !errors.Is(err, errTest) {
+		t.Fatalf("Expected probe error %v, got %v", errTest, err)
+	}
+	if s := h.Status("db"); s != Down {
+		t.Fatalf("Expected status %v after fall failures, got %v", Down, s)
+	}
+}
+
+func TestHealthChecker_Subscribe(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(time.Hour)
+	ctx := context.Background()
+
+	var changes []HealthChange
+	h.Subscribe(func(c HealthChange) { changes = append(changes, c) })
+
+	fail := false
+	h.Add("api", func(context.Context) error {
+		if fail {
+			return errTest
+		}
+		return nil
+	})
+
+	h.Check(ctx, "api")
+	h.Check(ctx, "api") // no change, no notification
+	fail = true
+	h.Check(ctx, "api")
+
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(changes))
+	}
+	if c := changes[0]; c.Target != "api" || c.From != Unknown || c.To != Up {
+		t.Fatalf("Expected api Unknown->Up, got %+v", c)
+	}
+	if c := changes[1]; c.From != Up || c.To != Down || !errors.Is(c.Err, errTest) {
+		t.Fatalf("Expected api Up->Down with the probe error, got %+v", c)
+	}
+}
+
+func TestHealthChecker_Run(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(5 * time.Millisecond)
+
+	var calls atomic.Int32
+	h.Add("a", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.Run(ctx) }()
+
+	// A target added while running starts being probed at once.
+	added := make(chan struct{})
+	h.Add("b", func(context.Context) error {
+		select {
+		case <-added:
+		default:
+			close(added)
+		}
+		return errTest
+	})
+
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a target added while running to be probed")
+	}
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if n := calls.Load(); n < 3 {
+		t.Fatalf("Expected target to be probed on the interval, got %d calls", n)
+	}
+
+	statuses := h.Statuses()
+	if statuses["a"] != Up || statuses["b"] != Down {
+		t.Fatalf("Expected a up and b down, got %v", statuses)
+	}
+}
+
+func TestHealthChecker_ProbeTimeout(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(time.Hour, WithProbeTimeout(10*time.Millisecond))
+
+	h.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := h.Check(context.Background(), "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if s := h.Status("slow"); s != Down {
+		t.Fatalf("Expected status %v, got %v", Down, s)
+	}
+}
+
+func TestHealthChecker_Remove(t *testing.T) {
+	t.Parallel()
+	h := NewHealthChecker(time.Hour)
+	h.Add("a", func(context.Context) error { return nil })
+	h.Add("b", func(context.Context) error { return nil })
+	h.Remove("a")
+
+	if got := h.Targets(); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("Expected targets [b], got %v", got)
+	}
+	if s := h.Status("a"); s != Unknown {
+		t.Fatalf("Expected removed target to be %v, got %v", Unknown, s)
+	}
+}
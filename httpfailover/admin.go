@@ -0,0 +1,351 @@
+package httpfailover
+
+import (
+	"cmp"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// controllable is implemented by breakers an operator can override, such
+// as *failover.CircuitBreaker.
+type controllable interface {
+	Reset()
+	ForceOpen()
+	Disable()
+}
+
+// tunable is implemented by breakers whose settings can be changed at
+// runtime, such as *failover.CircuitBreaker.
+type tunable interface {
+	SetFailureThreshold(n int)
+	SetSuccessThreshold(n int)
+	SetOpenTimeout(d time.Duration)
+}
+
+// switchable is implemented by policies that can be turned on and off at
+// runtime, such as *failover.Inject.
+type switchable interface {
+	Enable()
+	Disable()
+	Enabled() bool
+}
+
+// adminBreaker is a breaker as listed by the admin handler.
+type adminBreaker struct {
+	Name     string                   `json:"name"`
+	State    string                   `json:"state"`
+	Override string                   `json:"override,omitempty"`
+	Counts   *failover.Counts         `json:"counts,omitempty"`
+	Failures []failover.FailureRecord `json:"failures,omitempty"` // With failover.WithRecentFailures
+}
+
+// adminRetry is a retry policy as listed by the admin handler.
+type adminRetry struct {
+	Name     string                   `json:"name"`
+	Stats    failover.RetryStats      `json:"stats"`
+	Failures []failover.FailureRecord `json:"failures,omitempty"` // With failover.WithRetryRecentFailures
+}
+
+// adminInjector is a switchable policy as listed by the admin handler.
+type adminInjector struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// adminState is everything the admin handler lists.
+type adminState struct {
+	Breakers  []adminBreaker  `json:"breakers"`
+	Retries   []adminRetry    `json:"retries"`
+	Injectors []adminInjector `json:"injectors"`
+}
+
+// AdminHandler returns a handler for inspecting and controlling the
+// policies of r, or of failover.DefaultRegistry if r is nil, at runtime.
+// Mount it with its prefix stripped:
+//
+//	mux.Handle("/debug/failover/", http.StripPrefix("/debug/failover", httpfailover.AdminHandler(reg)))
+//
+// GET / lists the breakers, with their state, override and counts, and the
+// retry policies, with their stats, as JSON, or as an HTML page for clients
+// that accept text/html. POST /breakers/{name}/reset, /open and /disable
+// call Reset, ForceOpen and Disable on the named breaker, which must be a
+// *failover.CircuitBreaker or otherwise support them.
+//
+// POST /breakers/{name}/settings changes the breaker's failure_threshold,
+// success_threshold and open_timeout, and POST /retries/{name}/settings the
+// retry policy's attempts, to the form values given; settings left out
+// stay as they are.
+//
+// Registered policies that can be switched on and off, such as
+// *failover.Inject for fault injection, are listed too, and POST
+// /policies/{name}/enable and /disable switch them.
+//
+// The handler changes the behavior of live traffic; keep it behind the
+// same protection as other debug endpoints.
+func AdminHandler(r *failover.Registry) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, req *http.Request) {
+		state := snapshot(r)
+		if strings.Contains(req.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = adminPage.Execute(w, state)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(state)
+	})
+
+	mux.HandleFunc("POST /breakers/{name}/{action}", func(w http.ResponseWriter, req *http.Request) {
+		b, ok := r.Breakers()[req.PathValue("name")]
+		if !ok {
+			http.Error(w, "no such breaker", http.StatusNotFound)
+			return
+		}
+		c, ok := b.(controllable)
+		if !ok {
+			http.Error(w, "breaker can not be controlled", http.StatusConflict)
+			return
+		}
+
+		switch req.PathValue("action") {
+		case "reset":
+			c.Reset()
+		case "open":
+			c.ForceOpen()
+		case "disable":
+			c.Disable()
+		default:
+			http.Error(w, "unknown action", http.StatusNotFound)
+			return
+		}
+
+		changed(w, req)
+	})
+
+	mux.HandleFunc("POST /breakers/{name}/settings", func(w http.ResponseWriter, req *http.Request) {
+		b, ok := r.Breakers()[req.PathValue("name")]
+		if !ok {
+			http.Error(w, "no such breaker", http.StatusNotFound)
+			return
+		}
+		t, ok := b.(tunable)
+		if !ok {
+			http.Error(w, "breaker can not be tuned", http.StatusConflict)
+			return
+		}
+
+		failures, err1 := formInt(req, "failure_threshold")
+		successes, err2 := formInt(req, "success_threshold")
+		timeout, err3 := formDuration(req, "open_timeout")
+		if err1 != nil || err2 != nil || err3 != nil {
+			http.Error(w, "invalid settings", http.StatusBadRequest)
+			return
+		}
+
+		if failures != nil {
+			t.SetFailureThreshold(*failures)
+		}
+		if successes != nil {
+			t.SetSuccessThreshold(*successes)
+		}
+		if timeout != nil {
+			t.SetOpenTimeout(*timeout)
+		}
+		changed(w, req)
+	})
+
+	mux.HandleFunc("POST /retries/{name}/settings", func(w http.ResponseWriter, req *http.Request) {
+		p, ok := r.Retries()[req.PathValue("name")]
+		if !ok {
+			http.Error(w, "no such retry policy", http.StatusNotFound)
+			return
+		}
+
+		attempts, err := formInt(req, "attempts")
+		if err != nil || attempts != nil && *attempts < 1 {
+			http.Error(w, "invalid settings", http.StatusBadRequest)
+			return
+		}
+
+		if attempts != nil {
+			p.SetMaxAttempts(*attempts)
+		}
+		changed(w, req)
+	})
+
+	mux.HandleFunc("POST /policies/{name}/{action}", func(w http.ResponseWriter, req *http.Request) {
+		p, ok := r.Policy(req.PathValue("name"))
+		if !ok {
+			http.Error(w, "no such policy", http.StatusNotFound)
+			return
+		}
+		sw, ok := p.(switchable)
+		if !ok {
+			http.Error(w, "policy can not be switched", http.StatusConflict)
+			return
+		}
+
+		switch req.PathValue("action") {
+		case "enable":
+			sw.Enable()
+		case "disable":
+			sw.Disable()
+		default:
+			http.Error(w, "unknown action", http.StatusNotFound)
+			return
+		}
+
+		changed(w, req)
+	})
+
+	return mux
+}
+
+// changed answers a request that changed a policy.
+func changed(w http.ResponseWriter, req *http.Request) {
+	// Send browsers back to the listing, relative to where the handler is
+	// mounted.
+	if strings.Contains(req.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Location", "../../")
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// formInt returns the non-negative integer form value key, nil if unset.
+func formInt(req *http.Request, key string) (*int, error) {
+	v := req.FormValue(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err == nil && n < 0 {
+		err = strconv.ErrRange
+	}
+	return &n, err
+}
+
+// formDuration returns the positive duration form value key, nil if unset.
+func formDuration(req *http.Request, key string) (*time.Duration, error) {
+	v := req.FormValue(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err == nil && d <= 0 {
+		err = strconv.ErrRange
+	}
+	return &d, err
+}
+
+// snapshot returns the policies of r, sorted by name.
+func snapshot(r *failover.Registry) adminState {
+	state := adminState{Breakers: []adminBreaker{}, Retries: []adminRetry{}, Injectors: []adminInjector{}}
+
+	for name, b := range r.Breakers() {
+		state.Breakers = append(state.Breakers, breakerState(name, b))
+	}
+	for name, p := range r.Retries() {
+		state.Retries = append(state.Retries, adminRetry{Name: name, Stats: p.Stats(), Failures: p.RecentFailures()})
+	}
+
+	for name, p := range r.Policies() {
+		if sw, ok := p.(switchable); ok {
+			state.Injectors = append(state.Injectors, adminInjector{Name: name, Enabled: sw.Enabled()})
+		}
+	}
+
+	slices.SortFunc(state.Breakers, func(a, b adminBreaker) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(state.Retries, func(a, b adminRetry) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(state.Injectors, func(a, b adminInjector) int { return cmp.Compare(a.Name, b.Name) })
+
+	return state
+}
+
+// breakerState returns the admin view of the breaker b.
+func breakerState(name string, b failover.Breaker) adminBreaker {
+	ab := breakerCounts(name, b)
+	if f, ok := b.(interface {
+		RecentFailures() []failover.FailureRecord
+	}); ok {
+		ab.Failures = f.RecentFailures()
+	}
+	return ab
+}
+
+// breakerCounts returns the state, override and counts of the breaker b,
+// read from its snapshot if it takes them.
+func breakerCounts(name string, b failover.Breaker) adminBreaker {
+	if s, ok := b.(interface {
+		Snapshot() failover.BreakerSnapshot
+	}); ok {
+		snap := s.Snapshot()
+		ab := adminBreaker{Name: name, State: snap.State.String(), Counts: &snap.Counts}
+		if snap.Override != failover.NoOverride {
+			ab.Override = snap.Override.String()
+		}
+		return ab
+	}
+
+	ab := adminBreaker{Name: name, State: b.State().String()}
+	if o, ok := b.(interface {
+		Override() failover.BreakerOverride
+	}); ok && o.Override() != failover.NoOverride {
+		ab.Override = o.Override().String()
+	}
+	if c, ok := b.(interface{ Counts() failover.Counts }); ok {
+		counts := c.Counts()
+		ab.Counts = &counts
+	}
+	return ab
+}
+
+// adminPage renders an adminState for browsers.
+var adminPage = template.Must(template.New("admin").Parse(`<!DOCTYPE html>
+<html>
+<head><title>failover</title></head>
+<body>
+<h1>Breakers</h1>
+<table>
+<tr><th>Name</th><th>State</th><th>Override</th><th>Requests</th><th>Failures</th><th>Rejections</th><th></th></tr>
+{{range .Breakers}}<tr>
+<td>{{.Name}}</td><td>{{.State}}</td><td>{{.Override}}</td>
+<td>{{with .Counts}}{{.Requests}}{{end}}</td><td>{{with .Counts}}{{.TotalFailures}}{{end}}</td><td>{{with .Counts}}{{.Rejections}}{{end}}</td>
+<td>
+<form method="post" action="breakers/{{.Name}}/reset" style="display:inline"><button>Reset</button></form>
+<form method="post" action="breakers/{{.Name}}/open" style="display:inline"><button>Force open</button></form>
+<form method="post" action="breakers/{{.Name}}/disable" style="display:inline"><button>Disable</button></form>
+</td>
+</tr>{{end}}
+</table>
+<h1>Retries</h1>
+<table>
+<tr><th>Name</th><th>Calls</th><th>Attempts</th><th>Failures</th></tr>
+{{range .Retries}}<tr><td>{{.Name}}</td><td>{{.Stats.Calls}}</td><td>{{.Stats.Attempts}}</td><td>{{.Stats.Failures}}</td></tr>
+{{end}}</table>
+<h1>Fault injection</h1>
+<table>
+<tr><th>Name</th><th>Enabled</th><th></th></tr>
+{{range .Injectors}}<tr><td>{{.Name}}</td><td>{{.Enabled}}</td>
+<td>
+<form method="post" action="policies/{{.Name}}/enable" style="display:inline"><button>Enable</button></form>
+<form method="post" action="policies/{{.Name}}/disable" style="display:inline"><button>Disable</button></form>
+</td>
+</tr>{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,230 @@
+package httpfailover
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// adminServer serves AdminHandler for reg under /debug/failover.
+func adminServer(t *testing.T, reg *failover.Registry) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/debug/failover/", http.StripPrefix("/debug/failover", AdminHandler(reg)))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAdminHandler_List(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute)
+	cb.Execute(func() error { return errTest })
+	reg.AddBreaker("db", cb)
+	reg.AddRetry("db", failover.NewRetryPolicy(3, time.Millisecond))
+	srv := adminServer(t, reg)
+
+	resp, err := srv.Client().Get(srv.URL + "/debug/failover/")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	var state adminState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(state.Breakers) != 1 || state.Breakers[0].State != "open" || state.Breakers[0].Counts.TotalFailures != 1 {
+		t.Fatalf("Expected db open after 1 failure, got %+v", state.Breakers)
+	}
+	if len(state.Retries) != 1 || state.Retries[0].Name != "db" {
+		t.Fatalf("Expected the db retry policy, got %+v", state.Retries)
+	}
+}
+
+func TestAdminHandler_RecentFailures(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute, failover.WithRecentFailures(5))
+	cb.Do(failover.WithOperation(context.Background(), "query"), func(context.Context) error { return errTest })
+	reg.AddBreaker("db", cb)
+	retry := failover.NewRetryPolicy(2, time.Millisecond, failover.WithRetryRecentFailures(5))
+	retry.Do(context.Background(), func(context.Context) error { return errTest })
+	reg.AddRetry("db", retry)
+	srv := adminServer(t, reg)
+
+	resp, err := srv.Client().Get(srv.URL + "/debug/failover/")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	var state adminState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if f := state.Breakers[0].Failures; len(f) != 1 || f[0].Operation != "query" || f[0].Error != errTest.Error() {
+		t.Fatalf("Expected the query failure, got %+v", f)
+	}
+	if f := state.Retries[0].Failures; len(f) != 2 || f[1].Attempt != 2 {
+		t.Fatalf("Expected 2 failed attempts, got %+v", f)
+	}
+}
+
+func TestAdminHandler_HTML(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	reg.AddBreaker("db", failover.NewCircuitBreaker(1, 1, time.Minute))
+	srv := adminServer(t, reg)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/debug/failover/", nil)
+	req.Header.Set("Accept", "text/html")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("Expected an HTML page, got %s", ct)
+	}
+	if !strings.Contains(string(body), "<td>db</td>") {
+		t.Fatalf("Expected the db breaker on the page, got %s", body)
+	}
+}
+
+func TestAdminHandler_Actions(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute)
+	reg.AddBreaker("db", cb)
+	reg.AddBreaker("noop", failover.NoopBreaker{})
+	srv := adminServer(t, reg)
+
+	post := func(path string) int {
+		t.Helper()
+		resp, err := srv.Client().Post(srv.URL+"/debug/failover"+path, "", nil)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/breakers/db/open"); code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	if cb.Override() != failover.ForcedOpen {
+		t.Fatalf("Expected db to be forced open, got %v", cb.Override())
+	}
+	if post("/breakers/db/disable"); cb.Override() != failover.Disabled {
+		t.Fatalf("Expected db to be disabled, got %v", cb.Override())
+	}
+	if post("/breakers/db/reset"); cb.Override() != failover.NoOverride {
+		t.Fatalf("Expected db to be reset, got %v", cb.Override())
+	}
+
+	if code := post("/breakers/missing/reset"); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d for an unknown breaker, got %d", http.StatusNotFound, code)
+	}
+	if code := post("/breakers/noop/open"); code != http.StatusConflict {
+		t.Fatalf("Expected status %d for a breaker without controls, got %d", http.StatusConflict, code)
+	}
+	if code := post("/breakers/db/explode"); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d for an unknown action, got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestAdminHandler_Settings(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute)
+	retry := failover.NewRetryPolicy(5, 0)
+	reg.AddBreaker("db", cb)
+	reg.AddRetry("db", retry)
+	srv := adminServer(t, reg)
+
+	post := func(path string, form url.Values) int {
+		t.Helper()
+		resp, err := srv.Client().PostForm(srv.URL+"/debug/failover"+path, form)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("/breakers/db/settings", url.Values{"failure_threshold": {"2"}}); code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	cb.Execute(func() error { return errTest })
+	if s := cb.State(); s != failover.Closed {
+		t.Fatalf("Expected the new threshold to keep db %v, got %v", failover.Closed, s)
+	}
+
+	if code := post("/retries/db/settings", url.Values{"attempts": {"2"}}); code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	retry.Do(context.Background(), func(context.Context) error { return errTest })
+	if a := retry.Stats().Attempts; a != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", a)
+	}
+
+	if code := post("/breakers/db/settings", url.Values{"open_timeout": {"soon"}}); code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for an invalid setting, got %d", http.StatusBadRequest, code)
+	}
+	if code := post("/retries/db/settings", url.Values{"attempts": {"0"}}); code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for zero attempts, got %d", http.StatusBadRequest, code)
+	}
+	if code := post("/retries/missing/settings", nil); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d for an unknown retry, got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestAdminHandler_Inject(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	inject := failover.NewInject(failover.WithInjectedOpenCircuit(0.5))
+	reg.AddPolicy("chaos", inject)
+	reg.AddPolicy("timeout", failover.NewTimeout(time.Second))
+	srv := adminServer(t, reg)
+
+	resp, err := srv.Client().Post(srv.URL+"/debug/failover/policies/chaos/enable", "", nil)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || !inject.Enabled() {
+		t.Fatalf("Expected chaos to be enabled, got status %d", resp.StatusCode)
+	}
+
+	resp, err = srv.Client().Post(srv.URL+"/debug/failover/policies/timeout/enable", "", nil)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status %d for a policy without a switch, got %d", http.StatusConflict, resp.StatusCode)
+	}
+
+	resp, err = srv.Client().Get(srv.URL + "/debug/failover/")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer resp.Body.Close()
+	var state adminState
+	json.NewDecoder(resp.Body).Decode(&state)
+	if len(state.Injectors) != 1 || state.Injectors[0].Name != "chaos" || !state.Injectors[0].Enabled {
+		t.Fatalf("Expected chaos listed as enabled, got %+v", state.Injectors)
+	}
+}
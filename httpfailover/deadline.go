@@ -0,0 +1,97 @@
+package httpfailover
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TimeoutHeader carries the time a request has left before its deadline,
+// in milliseconds, so that the next service can stop working on it, and
+// retrying it, once the caller has given up.
+const TimeoutHeader = "X-Request-Timeout-Ms"
+
+// ErrDeadlineBudget is the cause of the contexts DeadlineMiddleware cancels
+// when the budget of a request runs out, read with context.Cause. It is
+// also context.DeadlineExceeded, so existing checks still match.
+var ErrDeadlineBudget = fmt.Errorf("httpfailover: caller's time budget ran out: %w", context.DeadlineExceeded)
+
+// maxTimeoutMs is the largest budget a Duration can hold, in milliseconds.
+const maxTimeoutMs = math.MaxInt64 / int64(time.Millisecond)
+
+// WithDeadlinePropagation sets TimeoutHeader on every attempt of a request
+// whose context has a deadline, to the time left when the attempt starts.
+func WithDeadlinePropagation() Option {
+	return func(t *Transport) {
+		t.propagate = true
+	}
+}
+
+// SetTimeoutHeader sets TimeoutHeader in h to the time left before the
+// deadline of ctx. It leaves h unchanged if ctx has no deadline.
+func SetTimeoutHeader(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	// Round up, so that a request with time left is not sent as expired.
+	ms := (time.Until(deadline) + time.Millisecond - 1) / time.Millisecond
+	h.Set(TimeoutHeader, strconv.FormatInt(max(int64(ms), 0), 10))
+}
+
+// TimeoutFromHeader returns the time budget in TimeoutHeader of h, if it
+// holds one. Budgets too large for a Duration are ignored.
+func TimeoutFromHeader(h http.Header) (time.Duration, bool) {
+	ms, err := strconv.ParseInt(h.Get(TimeoutHeader), 10, 64)
+	if err != nil || ms < 0 || ms > maxTimeoutMs {
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// DeadlineMiddleware returns middleware that runs each request whose
+// TimeoutHeader holds a budget with a context that expires when the budget
+// runs out, with ErrDeadlineBudget as its cause, so that the handler, and
+// the calls it makes, give up with the caller. A request arriving with no
+// time left is rejected with 504 Gateway Timeout without running the
+// handler.
+func DeadlineMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			budget, ok := TimeoutFromHeader(r.Header)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if budget == 0 {
+				http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+				return
+			}
+
+			ctx, cancel := context.WithTimeoutCause(r.Context(), budget, ErrDeadlineBudget)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withTimeoutHeader returns req, or a copy of it carrying TimeoutHeader for
+// the deadline of ctx if the transport propagates deadlines.
+func (t *Transport) withTimeoutHeader(ctx context.Context, req *http.Request) *http.Request {
+	if !t.propagate {
+		return req
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return req
+	}
+
+	r := req.Clone(ctx)
+	SetTimeoutHeader(ctx, r.Header)
+	return r
+}
@@ -0,0 +1,158 @@
+package httpfailover
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+func TestTransport_DeadlinePropagation(t *testing.T) {
+	t.Parallel()
+	var budgets []time.Duration
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		budget, ok := TimeoutFromHeader(req.Header)
+		if !ok {
+			t.Error("Expected a timeout header")
+		}
+		budgets = append(budgets, budget)
+		if len(budgets) == 1 {
+			return nil, errTest
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	tr := NewTransport(base, WithRetry(2, failover.ConstantBackoff(20*time.Millisecond)), WithDeadlinePropagation())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(budgets) != 2 || budgets[0] > time.Second || budgets[1] >= budgets[0] {
+		t.Fatalf("Expected a shrinking budget within 1s, got %v", budgets)
+	}
+	if req.Header.Get(TimeoutHeader) != "" {
+		t.Error("Expected the caller's request to be left unchanged")
+	}
+}
+
+func TestTransport_DeadlinePropagationNoDeadline(t *testing.T) {
+	t.Parallel()
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get(TimeoutHeader) != "" {
+			t.Error("Expected no timeout header")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	tr := NewTransport(base, WithDeadlinePropagation())
+
+	resp, err := tr.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTimeoutFromHeader(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "1500", want: 1500 * time.Millisecond, ok: true},
+		{value: "0", want: 0, ok: true},
+		{value: "", ok: false},
+		{value: "-1", ok: false},
+		{value: "soon", ok: false},
+		{value: "9223372036855", ok: false},
+		{value: "9223372036854775807", ok: false},
+	}
+
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(TimeoutHeader, tt.value)
+		got, ok := TimeoutFromHeader(h)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q: expected %v (%v), got %v (%v)", tt.value, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestDeadlineMiddleware(t *testing.T) {
+	t.Parallel()
+	var deadline time.Time
+	var ok bool
+	handler := DeadlineMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "2000")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !ok || time.Until(deadline) > 2*time.Second || time.Until(deadline) < time.Second {
+		t.Fatalf("Expected a deadline about 2s away, got %v", time.Until(deadline))
+	}
+}
+
+func TestDeadlineMiddleware_Cause(t *testing.T) {
+	t.Parallel()
+	var cause error
+	handler := DeadlineMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		cause = context.Cause(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "10")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !errors.Is(cause, ErrDeadlineBudget) || !errors.Is(cause, context.DeadlineExceeded) {
+		t.Fatalf("Expected ErrDeadlineBudget, got %v", cause)
+	}
+}
+
+func TestDeadlineMiddleware_HugeBudget(t *testing.T) {
+	t.Parallel()
+	var ok bool
+	var err error
+	handler := DeadlineMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok = r.Context().Deadline()
+		err = r.Context().Err()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "9223372036854775807")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ok || err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected the huge budget to be ignored, got deadline %v, error %v, status %d", ok, err, rec.Code)
+	}
+}
+
+func TestDeadlineMiddleware_Expired(t *testing.T) {
+	t.Parallel()
+	called := false
+	handler := DeadlineMiddleware()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "0")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if called || rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected 504 without calling the handler, got %d (called %v)", rec.Code, called)
+	}
+}
@@ -0,0 +1,124 @@
+package httpfailover
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dadanrm/failover"
+)
+
+// healthHandler aggregates the health of dependencies for HealthHandler.
+type healthHandler struct {
+	sources  []healthTargets
+	breakers []criticalBreakers
+}
+
+// healthTargets are the targets of a health source that must be Up.
+type healthTargets struct {
+	source failover.HealthSource
+	names  []string // Empty for all the targets of a HealthChecker
+}
+
+// criticalBreakers are the breakers of a registry that must not be Open.
+type criticalBreakers struct {
+	registry *failover.Registry
+	names    []string // Empty for all the breakers of the registry
+}
+
+// healthReport is the body of a HealthHandler response.
+type healthReport struct {
+	Status string            `json:"status"` // "ok" or "unavailable"
+	Checks map[string]string `json:"checks"` // Status of each dependency, by kind and name
+}
+
+// HealthHandlerOption configures what a HealthHandler checks.
+type HealthHandlerOption func(*healthHandler)
+
+// WithHealthTargets requires the named targets of s to be Up. With no
+// names and a *failover.HealthChecker, every target it checks is required.
+// Targets whose status is still Unknown count as not healthy, so a pod is
+// not ready before its probes have passed.
+func WithHealthTargets(s failover.HealthSource, names ...string) HealthHandlerOption {
+	return func(h *healthHandler) {
+		h.sources = append(h.sources, healthTargets{source: s, names: names})
+	}
+}
+
+// WithCriticalBreakers requires the named breakers of r not to be Open, or
+// every breaker of r with no names. Breakers not registered (yet) are
+// skipped.
+func WithCriticalBreakers(r *failover.Registry, names ...string) HealthHandlerOption {
+	return func(h *healthHandler) {
+		h.breakers = append(h.breakers, criticalBreakers{registry: r, names: names})
+	}
+}
+
+// HealthHandler returns a handler for Kubernetes liveness or readiness
+// probes. It answers 200 when every dependency configured with opts is
+// healthy and 503 otherwise, with a JSON body giving the status of each.
+//
+// Readiness should cover the dependencies without which the pod can not
+// serve, so that traffic goes elsewhere while they are down. Liveness
+// normally covers none, as restarting the pod does not bring a dependency
+// back:
+//
+//	mux.Handle("/healthz", httpfailover.HealthHandler())
+//	mux.Handle("/readyz", httpfailover.HealthHandler(
+//		httpfailover.WithHealthTargets(checker, "postgres"),
+//		httpfailover.WithCriticalBreakers(registry, "payments")))
+func HealthHandler(opts ...HealthHandlerOption) http.Handler {
+	h := &healthHandler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	report := healthReport{Status: "ok", Checks: make(map[string]string)}
+	fail := func() { report.Status = "unavailable" }
+
+	for _, t := range h.sources {
+		names := t.names
+		if lister, ok := t.source.(interface{ Targets() []string }); ok && len(names) == 0 {
+			names = lister.Targets()
+		}
+		for _, name := range names {
+			status := t.source.Status(name)
+			report.Checks["health:"+name] = status.String()
+			if status != failover.Up {
+				fail()
+			}
+		}
+	}
+
+	for _, c := range h.breakers {
+		breakers := c.registry.Breakers()
+		names := c.names
+		if len(names) == 0 {
+			for name := range breakers {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			b, ok := breakers[name]
+			if !ok {
+				continue
+			}
+			state := b.State()
+			report.Checks["breaker:"+name] = state.String()
+			if state == failover.Open {
+				fail()
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
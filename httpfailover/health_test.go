@@ -0,0 +1,82 @@
+package httpfailover
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// probeHealth calls h and returns the status code and report.
+func probeHealth(t *testing.T, h http.Handler) (int, healthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report healthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestHealthHandler_NoDependencies(t *testing.T) {
+	t.Parallel()
+
+	if code, report := probeHealth(t, HealthHandler()); code != http.StatusOK || report.Status != "ok" {
+		t.Fatalf("Expected 200 ok, got %d %s", code, report.Status)
+	}
+}
+
+func TestHealthHandler_HealthTargets(t *testing.T) {
+	t.Parallel()
+	checker := failover.NewHealthChecker(time.Hour, failover.WithRise(1), failover.WithFall(1))
+	checker.Add("postgres", func(context.Context) error { return nil })
+	checker.Add("redis", func(context.Context) error { return errTest })
+	h := HealthHandler(WithHealthTargets(checker))
+
+	// Before any probe, targets are unknown and the pod is not ready.
+	if code, report := probeHealth(t, h); code != http.StatusServiceUnavailable || report.Checks["health:postgres"] != "unknown" {
+		t.Fatalf("Expected 503 with postgres unknown, got %d %v", code, report.Checks)
+	}
+
+	checker.Check(context.Background(), "postgres")
+	checker.Check(context.Background(), "redis")
+
+	code, report := probeHealth(t, h)
+	if code != http.StatusServiceUnavailable || report.Checks["health:redis"] != "down" {
+		t.Fatalf("Expected 503 with redis down, got %d %v", code, report.Checks)
+	}
+
+	// Only postgres is critical.
+	if code, _ := probeHealth(t, HealthHandler(WithHealthTargets(checker, "postgres"))); code != http.StatusOK {
+		t.Fatalf("Expected 200 with postgres up, got %d", code)
+	}
+}
+
+func TestHealthHandler_CriticalBreakers(t *testing.T) {
+	t.Parallel()
+	reg := failover.NewRegistry()
+	payments := failover.NewCircuitBreaker(1, 1, time.Minute)
+	reg.AddBreaker("payments", payments)
+	reg.AddBreaker("recommendations", failover.NoopBreaker{})
+	h := HealthHandler(WithCriticalBreakers(reg, "payments", "not-yet-created"))
+
+	if code, _ := probeHealth(t, h); code != http.StatusOK {
+		t.Fatalf("Expected 200 with payments closed, got %d", code)
+	}
+
+	payments.Execute(func() error { return errTest })
+
+	code, report := probeHealth(t, h)
+	if code != http.StatusServiceUnavailable || report.Checks["breaker:payments"] != "open" {
+		t.Fatalf("Expected 503 with payments open, got %d %v", code, report.Checks)
+	}
+	if _, ok := report.Checks["breaker:recommendations"]; ok {
+		t.Fatalf("Expected a non-critical breaker to be left out, got %v", report.Checks)
+	}
+}
@@ -0,0 +1,43 @@
+package httpfailover
+
+import (
+	"context"
+	"net/http"
+)
+
+type idempotentKey struct{}
+
+// WithIdempotent returns a copy of ctx that overrides whether requests sent
+// with it may be retried: true allows retrying a POST known to be safe to
+// repeat, false stops a GET with side effects from being sent twice.
+func WithIdempotent(ctx context.Context, idempotent bool) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, idempotent)
+}
+
+// WithIdempotencyHeader treats requests carrying a non-empty header name as
+// idempotent whatever their method, as servers deduplicating on it make
+// repeats safe. The default is Idempotency-Key; an empty name disables the
+// check.
+func WithIdempotencyHeader(name string) Option {
+	return func(t *Transport) {
+		t.keyHeader = name
+	}
+}
+
+// idempotent reports whether req may be sent more than once: the context
+// override if set, then the idempotency header, then the method.
+func (t *Transport) idempotent(req *http.Request) bool {
+	if v, ok := req.Context().Value(idempotentKey{}).(bool); ok {
+		return v
+	}
+	if t.keyHeader != "" && req.Header.Get(t.keyHeader) != "" {
+		return true
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
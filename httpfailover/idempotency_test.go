@@ -0,0 +1,99 @@
+package httpfailover
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+func TestTransport_Idempotent(t *testing.T) {
+	t.Parallel()
+	tr := NewTransport(nil)
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		ctx    func(context.Context) context.Context
+		want   bool
+	}{
+		{name: "GET", method: http.MethodGet, want: true},
+		{name: "PUT", method: http.MethodPut, want: true},
+		{name: "POST", method: http.MethodPost, want: false},
+		{name: "PATCH", method: http.MethodPatch, want: false},
+		{name: "POST with key", method: http.MethodPost, header: "abc", want: true},
+		{
+			name:   "POST forced",
+			method: http.MethodPost,
+			ctx:    func(ctx context.Context) context.Context { return WithIdempotent(ctx, true) },
+			want:   true,
+		},
+		{
+			name:   "GET forbidden",
+			method: http.MethodGet,
+			header: "abc",
+			ctx:    func(ctx context.Context) context.Context { return WithIdempotent(ctx, false) },
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.ctx != nil {
+			ctx = tt.ctx(ctx)
+		}
+		req, _ := http.NewRequestWithContext(ctx, tt.method, "http://example.com/", nil)
+		if tt.header != "" {
+			req.Header.Set("Idempotency-Key", tt.header)
+		}
+
+		if got := tr.idempotent(req); got != tt.want {
+			t.Errorf("%s: expected idempotent %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestTransport_RetriesPostWithIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	tr := NewTransport(recordingBase(&bodies), WithRetry(2, failover.ConstantBackoff(time.Millisecond)))
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "order-42")
+
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Fatalf("Expected the POST to be retried with its body, got %q", bodies)
+	}
+}
+
+func TestTransport_IdempotencyHeaderDisabled(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	tr := NewTransport(recordingBase(&bodies),
+		WithRetry(2, failover.ConstantBackoff(time.Millisecond)),
+		WithIdempotencyHeader(""),
+	)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "order-42")
+
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 call, got %d", len(bodies))
+	}
+}
@@ -0,0 +1,124 @@
+package httpfailover
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// waitEstimator is implemented by policies that can tell how long a
+// rejected caller should wait, such as *failover.Bulkhead and
+// *failover.RateLimiter.
+type waitEstimator interface {
+	EstimatedWait() time.Duration
+}
+
+// middleware is the configuration of a Middleware handler.
+type middleware struct {
+	retryAfter time.Duration // Retry-After sent when the policy gives no estimate
+	estimator  waitEstimator // Estimates Retry-After from the policy, if it can
+	reject     func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// MiddlewareOption configures optional Middleware behavior.
+type MiddlewareOption func(*middleware)
+
+// WithDefaultRetryAfter sets the Retry-After sent with a rejection when the
+// policy can not estimate the wait itself. The default is one second.
+func WithDefaultRetryAfter(d time.Duration) MiddlewareOption {
+	return func(m *middleware) {
+		m.retryAfter = d
+	}
+}
+
+// WithRejectHandler replaces the default response to a rejected request.
+// fn is called with the error from the policy; the Retry-After header is
+// already set when it is.
+func WithRejectHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) MiddlewareOption {
+	return func(m *middleware) {
+		m.reject = fn
+	}
+}
+
+// Middleware returns net/http middleware running each request under
+// policy, typically a *failover.Bulkhead, *failover.AdaptiveLimiter,
+// *failover.CoDelQueue or a failover.Limiter, or a Pipeline of them.
+//
+// A request the policy rejects without running the handler is answered
+// with 429 Too Many Requests if it was rate limited and 503 Service
+// Unavailable otherwise, along with a Retry-After header estimated from
+// the policy when it can tell. A failover.Limiter, such as a
+// *failover.RateLimiter, rejects requests beyond its rate at once rather
+// than making them wait for a token, as holding requests on a server only
+// adds to its load.
+//
+// The policy must not return while the handler is still running. Use
+// http.TimeoutHandler rather than a failover.Timeout to bound handlers.
+func Middleware(policy failover.Policy, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{
+		retryAfter: time.Second,
+		reject:     defaultReject,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if e, ok := policy.(waitEstimator); ok {
+		m.estimator = e
+	}
+	if l, ok := policy.(failover.Limiter); ok {
+		policy = failover.PolicyFunc(func(ctx context.Context, fn failover.WorkFuncCtx) error {
+			if !l.Allow() {
+				return failover.ErrRateLimited
+			}
+			return fn(ctx)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var served atomic.Bool
+			err := policy.Do(r.Context(), func(context.Context) error {
+				served.Store(true)
+				next.ServeHTTP(w, r)
+				return nil
+			})
+
+			if err != nil && !served.Load() {
+				w.Header().Set("Retry-After", m.retryAfterHeader())
+				m.reject(w, r, err)
+			}
+		})
+	}
+}
+
+// retryAfterHeader returns the Retry-After value for a rejection, in whole
+// seconds rounded up.
+func (m *middleware) retryAfterHeader() string {
+	d := m.retryAfter
+	if m.estimator != nil {
+		if wait := m.estimator.EstimatedWait(); wait > 0 {
+			d = wait
+		}
+	}
+
+	return strconv.FormatInt(max(int64(math.Ceil(d.Seconds())), 1), 10)
+}
+
+// defaultReject answers a rejected request with 429 if it was rate limited
+// and 503 otherwise.
+func defaultReject(w http.ResponseWriter, _ *http.Request, err error) {
+	status := http.StatusServiceUnavailable
+	if errors.Is(err, failover.ErrRateLimited) {
+		status = http.StatusTooManyRequests
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,123 @@
+package httpfailover
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// okHandler answers every request with 200.
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMiddleware_RateLimited(t *testing.T) {
+	t.Parallel()
+	h := Middleware(failover.NewRateLimiter(0.5, 1))(okHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the first request served, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("Expected Retry-After 2, got %q", got)
+	}
+}
+
+// closedLimiter is a failover.Limiter that never has a token.
+type closedLimiter struct{}
+
+func (closedLimiter) Allow() bool                { return false }
+func (closedLimiter) Wait(context.Context) error { return nil }
+
+func (closedLimiter) Do(ctx context.Context, fn failover.WorkFuncCtx) error { return fn(ctx) }
+
+func TestMiddleware_Limiter(t *testing.T) {
+	t.Parallel()
+	h := Middleware(closedLimiter{})(okHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected any Limiter to reject at once with 429, got %d", rec.Code)
+	}
+}
+
+func TestMiddleware_BulkheadFull(t *testing.T) {
+	t.Parallel()
+	bh := failover.NewBulkhead(1, 0, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := Middleware(bh)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	<-started
+	defer close(release)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("Expected the default Retry-After of 1, got %q", got)
+	}
+}
+
+func TestMiddleware_HandlerErrorsPassThrough(t *testing.T) {
+	t.Parallel()
+	policy := failover.PolicyFunc(func(ctx context.Context, fn failover.WorkFuncCtx) error {
+		_ = fn(ctx)
+		return errTest // failures after the handler ran leave its response alone
+	})
+	h := Middleware(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Retry-After") != "" {
+		t.Fatalf("Expected the handler's response, got %d", rec.Code)
+	}
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	t.Parallel()
+	policy := failover.PolicyFunc(func(context.Context, failover.WorkFuncCtx) error {
+		return failover.ErrLimitExceeded
+	})
+
+	var rejected error
+	h := Middleware(policy,
+		WithDefaultRetryAfter(90*time.Second),
+		WithRejectHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			rejected = err
+			w.WriteHeader(http.StatusBadGateway)
+		}),
+	)(okHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusBadGateway || rejected != failover.ErrLimitExceeded {
+		t.Fatalf("Expected the custom reject handler, got %d (%v)", rec.Code, rejected)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Fatalf("Expected Retry-After 90, got %q", got)
+	}
+}
@@ -0,0 +1,232 @@
+package httpfailover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// NotificationEvent is what a Notification is about.
+type NotificationEvent string
+
+// Events a Notifier sends.
+const (
+	BreakerOpened    NotificationEvent = "breaker_opened"    // A breaker tripped to Open
+	BreakerRecovered NotificationEvent = "breaker_recovered" // An open breaker closed again
+	BreakerFlapping  NotificationEvent = "breaker_flapping"  // A breaker started flapping
+	EndpointSwitched NotificationEvent = "endpoint_switched" // A failover group moved to another endpoint
+)
+
+// Notification is the JSON body a Notifier posts. Text makes it a valid
+// Slack incoming webhook message; the other fields are for receivers that
+// act on the details.
+type Notification struct {
+	Text  string            `json:"text"`
+	Event NotificationEvent `json:"event"`
+	Name  string            `json:"name"`           // Breaker or failover group
+	From  string            `json:"from,omitempty"` // Endpoint switched from
+	To    string            `json:"to,omitempty"`   // Endpoint switched to
+	Time  time.Time         `json:"time"`
+}
+
+// Notifier posts a Notification to webhooks when breakers open, recover and
+// start flapping, and when failover groups switch endpoints. It holds back
+// alert storms: per breaker or group, a notification repeating the last
+// one sent is dropped, and at most one is sent per minimum interval, the
+// latest of those held back following once the interval is over. Webhooks
+// are posted to in the background, so hooks never slow calls down.
+type Notifier struct {
+	urls        []string
+	client      *http.Client
+	minInterval time.Duration
+
+	mu      sync.Mutex // Protects sources
+	sources map[string]*notifySource
+	wg      sync.WaitGroup // Tracks posts in flight and held back, for Flush
+}
+
+// notifySource is the notification state of one breaker or group.
+type notifySource struct {
+	last    Notification // Last sent
+	sent    time.Time    // When last was sent
+	pending *Notification
+	timer   *time.Timer // Sends pending once the interval is over
+}
+
+// NotifierOption configures optional Notifier behavior.
+type NotifierOption func(*Notifier)
+
+// WithNotifyClient sets the client that posts to the webhooks. The default
+// is a client with a 10 second timeout.
+func WithNotifyClient(client *http.Client) NotifierOption {
+	return func(n *Notifier) {
+		n.client = client
+	}
+}
+
+// WithMinInterval sets the least time between two notifications about the
+// same breaker or group. The default is one minute.
+func WithMinInterval(d time.Duration) NotifierOption {
+	return func(n *Notifier) {
+		n.minInterval = d
+	}
+}
+
+// NewNotifier creates a Notifier posting to the webhook urls.
+func NewNotifier(urls []string, opts ...NotifierOption) *Notifier {
+	n := &Notifier{
+		urls:        urls,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		minInterval: time.Minute,
+		sources:     make(map[string]*notifySource),
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// StateChangeFunc returns a function to pass to failover.WithStateChangeFunc
+// that notifies when the breaker name opens and recovers.
+func (n *Notifier) StateChangeFunc(name string) func(from, to failover.State) {
+	return func(from, to failover.State) {
+		switch {
+		case to == failover.Open:
+			n.notify("breaker:"+name, Notification{
+				Text:  fmt.Sprintf("Circuit breaker %s opened", name),
+				Event: BreakerOpened,
+				Name:  name,
+			})
+		case to == failover.Closed:
+			n.notify("breaker:"+name, Notification{
+				Text:  fmt.Sprintf("Circuit breaker %s recovered", name),
+				Event: BreakerRecovered,
+				Name:  name,
+			})
+		}
+	}
+}
+
+// FlapFunc returns a function to pass to failover.WithFlapDetection that
+// notifies when the breaker name starts flapping. It is held back apart from
+// the open and recovery notifications of the breaker, so that it is not
+// lost among them.
+func (n *Notifier) FlapFunc(name string) func(flapping bool) {
+	return func(flapping bool) {
+		if flapping {
+			n.notify("flap:"+name, Notification{
+				Text:  fmt.Sprintf("Circuit breaker %s is flapping", name),
+				Event: BreakerFlapping,
+				Name:  name,
+			})
+		}
+	}
+}
+
+// SwitchFunc returns a function to pass to failover.WithSwitchFunc that
+// notifies when the failover group name switches endpoints.
+func (n *Notifier) SwitchFunc(name string) func(from, to *failover.Endpoint) {
+	return func(from, to *failover.Endpoint) {
+		n.notify("group:"+name, Notification{
+			Text:  fmt.Sprintf("Failover group %s switched from %s to %s", name, from.Name, to.Name),
+			Event: EndpointSwitched,
+			Name:  name,
+			From:  from.Name,
+			To:    to.Name,
+		})
+	}
+}
+
+// Flush waits for the notifications being posted, including those held
+// back until the minimum interval is over.
+func (n *Notifier) Flush() {
+	n.wg.Wait()
+}
+
+// notify sends note about source, unless it repeats the last one or comes
+// too soon after it.
+func (n *Notifier) notify(source string, note Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s, ok := n.sources[source]
+	if !ok {
+		s = &notifySource{}
+		n.sources[source] = s
+	}
+
+	switch {
+	case ok && same(s.last, note):
+		s.pending = nil // back where the last one left it
+	case ok && time.Since(s.sent) < n.minInterval:
+		s.pending = &note
+		if s.timer == nil {
+			n.wg.Add(1)
+			s.timer = time.AfterFunc(n.minInterval-time.Since(s.sent), func() { n.release(s) })
+		}
+	default:
+		n.send(s, note)
+	}
+}
+
+// release sends the notification s held back, if it still differs from the
+// last one sent.
+func (n *Notifier) release(s *notifySource) {
+	defer n.wg.Done()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s.timer = nil
+	if s.pending != nil && !same(s.last, *s.pending) {
+		n.send(s, *s.pending)
+	}
+	s.pending = nil
+}
+
+// send posts note and records it as the last one of s.
+func (n *Notifier) send(s *notifySource, note Notification) {
+	note.Time = time.Now()
+	s.last, s.sent = note, note.Time
+
+	body, err := json.Marshal(note)
+	if err != nil {
+		return
+	}
+
+	for _, url := range n.urls {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.post(url, body)
+		}()
+	}
+}
+
+// post sends body to url. Failures are dropped: a notification is not worth
+// holding back the next one.
+func (n *Notifier) post(url string, body []byte) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// same reports whether a and b say the same thing.
+func same(a, b Notification) bool {
+	return a.Event == b.Event && a.From == b.From && a.To == b.To
+}
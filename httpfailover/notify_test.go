@@ -0,0 +1,124 @@
+package httpfailover
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// webhook records the notifications posted to it.
+type webhook struct {
+	mu    sync.Mutex
+	notes []Notification
+}
+
+func (h *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var note Notification
+	json.NewDecoder(r.Body).Decode(&note)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.notes = append(h.notes, note)
+}
+
+func (h *webhook) events() []NotificationEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var events []NotificationEvent
+	for _, note := range h.notes {
+		events = append(events, note.Event)
+	}
+	return events
+}
+
+func TestNotifier_Breaker(t *testing.T) {
+	t.Parallel()
+	hook := &webhook{}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	n := NewNotifier([]string{srv.URL}, WithMinInterval(0))
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute, failover.WithStateChangeFunc(n.StateChangeFunc("db")))
+
+	cb.Execute(func() error { return errTest })
+	n.Flush()
+	cb.Reset()
+	n.Flush()
+
+	if e := hook.events(); len(e) != 2 || e[0] != BreakerOpened || e[1] != BreakerRecovered {
+		t.Fatalf("Expected open and recovery, got %v", e)
+	}
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if note := hook.notes[0]; note.Name != "db" || note.Text != "Circuit breaker db opened" || note.Time.IsZero() {
+		t.Fatalf("Expected a notification about db, got %+v", note)
+	}
+}
+
+func TestNotifier_Dedup(t *testing.T) {
+	t.Parallel()
+	hook := &webhook{}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	n := NewNotifier([]string{srv.URL}, WithMinInterval(20*time.Millisecond))
+	notify := n.StateChangeFunc("db")
+
+	// A flapping breaker: the open is sent, the repeats are dropped and only
+	// the final state follows once the interval is over.
+	start := time.Now()
+	notify(failover.Closed, failover.Open)
+	notify(failover.HalfOpen, failover.Open)
+	notify(failover.HalfOpen, failover.Closed)
+	notify(failover.Closed, failover.Open)
+	notify(failover.HalfOpen, failover.Closed)
+	n.Flush()
+
+	if e := hook.events(); len(e) != 2 || e[0] != BreakerOpened || e[1] != BreakerRecovered {
+		t.Fatalf("Expected the open and the final recovery, got %v", e)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Expected the recovery to wait for the interval, got %v", elapsed)
+	}
+}
+
+func TestNotifier_Switch(t *testing.T) {
+	t.Parallel()
+	hook := &webhook{}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	n := NewNotifier([]string{srv.URL})
+	n.SwitchFunc("db")(&failover.Endpoint{Name: "primary"}, &failover.Endpoint{Name: "secondary"})
+	n.Flush()
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if len(hook.notes) != 1 || hook.notes[0].From != "primary" || hook.notes[0].To != "secondary" {
+		t.Fatalf("Expected a switch from primary to secondary, got %+v", hook.notes)
+	}
+}
+
+func TestNotifier_Flapping(t *testing.T) {
+	t.Parallel()
+	hook := &webhook{}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	n := NewNotifier([]string{srv.URL})
+	n.StateChangeFunc("db")(failover.Closed, failover.Open)
+	n.FlapFunc("db")(true)
+	n.FlapFunc("db")(false)
+	n.Flush()
+
+	if e := hook.events(); len(e) != 2 || !slices.Contains(e, BreakerFlapping) {
+		t.Fatalf("Expected the open and the flapping, got %v", e)
+	}
+}
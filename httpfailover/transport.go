@@ -0,0 +1,407 @@
+// Package httpfailover applies the failover policies to HTTP clients and
+// servers.
+//
+// It only depends on the standard library. Transport wraps any
+// http.RoundTripper, so it composes with instrumented or custom transports
+// instead of replacing them. Middleware sheds inbound requests on the
+// server side, and AdminHandler lets operators inspect and override
+// policies at runtime. Notifier posts breaker and failover events to
+// webhooks such as Slack's.
+package httpfailover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// errFailedStatus marks an attempt whose response was classified as a
+// failure, so the breaker and retry policy see it as an error.
+var errFailedStatus = errors.New("httpfailover: failed response status")
+
+// errWaitTooLong marks a failed response whose Retry-After asks for a
+// longer wait than the transport or the request deadline allows; it is
+// returned rather than retried.
+var errWaitTooLong = fmt.Errorf("%w with a Retry-After too long to wait", errFailedStatus)
+
+// errRewind marks a failure to replay a request body, which ends the
+// request instead of being retried.
+var errRewind = errors.New("httpfailover: rewinding request body")
+
+// Classifier reports whether the outcome of a round trip is a failure.
+// Exactly one of resp and err is non-nil.
+type Classifier func(resp *http.Response, err error) bool
+
+// DefaultClassifier treats transport errors, 5xx responses and 429 Too
+// Many Requests as failures.
+func DefaultClassifier(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// Transport is an http.RoundTripper that guards every host with its own
+// circuit breaker and retries failed idempotent requests with backoff.
+//
+// Requests are retried only if they are idempotent, as decided by their
+// method unless overridden (see WithIdempotent), and their body, if any,
+// can be replayed: through GetBody when set, otherwise by buffering
+// it in memory if it is small enough. A body that can be neither is sent
+// once, never retried, so a retry can not send a truncated or empty body
+// in its place.
+//
+// A 429 or 503 response with a Retry-After header delays the next attempt
+// by at least the time the server asked for. If that is longer than the
+// limit set with WithMaxRetryAfter, or than the request has left before
+// its deadline, the response is returned without waiting.
+//
+// When every attempt fails with a failed status, the last response is
+// returned as is rather than turned into an error, so callers still see
+// what the server said.
+//
+// Twirp clients, which take any HTTP client, can use a Transport too:
+// Twirp answers unavailable with 503 and resource_exhausted with 429. As
+// Twirp sends every call as a POST, mark the calls that are safe to retry
+// with WithIdempotent.
+type Transport struct {
+	base       http.RoundTripper
+	attempts   int // Calls per retryable request, including the first
+	backoff    failover.Backoff
+	newBreaker func(host string) failover.Breaker // Nil disables breakers
+	classify   Classifier
+	bufferMax  int64         // Largest body buffered for replay, zero for none
+	maxWait    time.Duration // Longest Retry-After waited for
+	keyHeader  string        // Header marking a request idempotent, empty for none
+	propagate  bool          // Sets TimeoutHeader on each attempt
+
+	mu       sync.Mutex
+	breakers map[string]failover.Breaker // By request host
+}
+
+// Option configures optional Transport behavior.
+type Option func(*Transport)
+
+// WithRetry makes up to attempts calls for a retryable request, waiting
+// per backoff between them. The default is 3 attempts with a jittered
+// exponential backoff from 100ms to 2s. Use 1 attempt to disable retries.
+func WithRetry(attempts int, backoff failover.Backoff) Option {
+	return func(t *Transport) {
+		t.attempts = max(attempts, 1)
+		t.backoff = backoff
+	}
+}
+
+// WithBreakers creates the breaker for each host with fn, called the first
+// time a request for that host is seen. The default opens after 5
+// consecutive failures for 30 seconds. A nil fn disables the breakers.
+//
+// A request whose deadline passes counts as a failure, while one its caller
+// cancels comes back from the breaker's call with context.Canceled, which
+// the default leaves out of its counts with failover.WithExcludeIf; give
+// breakers of your own the same exclusion.
+func WithBreakers(fn func(host string) failover.Breaker) Option {
+	return func(t *Transport) {
+		t.newBreaker = fn
+	}
+}
+
+// WithBodyBuffer buffers request bodies of up to limit bytes that have no
+// GetBody, so they can be replayed on retry. Larger bodies are streamed and
+// their requests sent only once. The default is 64 KiB; zero disables
+// buffering.
+func WithBodyBuffer(limit int64) Option {
+	return func(t *Transport) {
+		t.bufferMax = limit
+	}
+}
+
+// WithMaxRetryAfter sets the longest Retry-After the transport waits for
+// before retrying. The default is 30 seconds.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(t *Transport) {
+		t.maxWait = d
+	}
+}
+
+// WithClassifier replaces DefaultClassifier in deciding which outcomes
+// count as failures for the breakers and retries.
+func WithClassifier(c Classifier) Option {
+	return func(t *Transport) {
+		t.classify = c
+	}
+}
+
+// NewTransport creates a Transport sending requests through base, or
+// http.DefaultTransport if base is nil.
+func NewTransport(base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &Transport{
+		base:     base,
+		attempts: 3,
+		backoff: failover.ExponentialBackoff{
+			Initial: 100 * time.Millisecond,
+			Max:     2 * time.Second,
+			Jitter:  0.2,
+		},
+		newBreaker: func(string) failover.Breaker {
+			return failover.NewCircuitBreaker(5, 1, 30*time.Second, failover.WithExcludeIf(canceled))
+		},
+		classify:  DefaultClassifier,
+		bufferMax: 64 << 10,
+		maxWait:   30 * time.Second,
+		keyHeader: "Idempotency-Key",
+		breakers:  make(map[string]failover.Breaker),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if t.idempotent(req) {
+		var (
+			replayable bool
+			err        error
+		)
+		if req, replayable, err = t.replayable(req); err != nil {
+			return nil, err
+		}
+		if replayable {
+			attempts = t.attempts
+		}
+	}
+
+	breaker := t.breaker(req.URL.Host)
+	ctx := req.Context()
+
+	var (
+		resp   *http.Response
+		failed *http.Response // Last response classified as a failure
+		first  = true
+		sent   bool // Whether the base transport was given the request
+	)
+
+	retry := failover.NewRetryPolicy(attempts, 0, failover.WithBackoff(t.backoff), failover.WithRetryIf(shouldRetry))
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		if failed != nil {
+			discard(failed)
+			failed = nil
+		}
+
+		r := req
+		if !first {
+			var err error
+			if r, err = rewind(req); err != nil {
+				return err
+			}
+		}
+		first = false
+		r = t.withTimeoutHeader(ctx, r)
+
+		var rtErr error
+		err := breaker.Execute(func() error {
+			sent = true
+			resp, rtErr = t.base.RoundTrip(r)
+
+			// A call that ran out of time is a failure of the host, one the
+			// caller canceled says nothing about it; neither is a success.
+			if rtErr != nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !t.classify(resp, rtErr) {
+				return nil
+			}
+			if rtErr != nil {
+				return rtErr
+			}
+			return errFailedStatus
+		})
+
+		switch {
+		case rtErr != nil:
+			return rtErr
+		case errors.Is(err, failover.ErrCircuitOpen):
+			return err // may wrap errFailedStatus, the failure that opened it
+		case errors.Is(err, errFailedStatus):
+			failed = resp
+			return t.statusError(ctx, resp)
+		}
+		return err
+	})
+
+	if failed != nil && errors.Is(err, errFailedStatus) {
+		return failed, nil
+	}
+	if failed != nil {
+		discard(failed)
+	}
+	if err != nil {
+		// A request rejected before it was sent still has its body open,
+		// and RoundTrip must close it.
+		if !sent && req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// statusError returns the error for a failed response, carrying the delay
+// from its Retry-After header if it has one worth waiting for.
+func (t *Transport) statusError(ctx context.Context, resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return errFailedStatus
+	}
+
+	delay, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return errFailedStatus
+	}
+	if delay > t.maxWait {
+		return errWaitTooLong
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return errWaitTooLong
+	}
+
+	return &failover.RetryAfterError{Err: errFailedStatus, Delay: delay}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, in either its
+// delay-seconds or HTTP-date form, into the wait it asks for from now. A
+// date in the past is a wait of zero. It reports false if value is empty
+// or malformed.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(min(seconds, int64(math.MaxInt64/time.Second))) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// canceled reports whether err is a cancellation by the caller, which the
+// default breakers leave out of their counts.
+func canceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// breaker returns the breaker for host, creating it on first use.
+func (t *Transport) breaker(host string) failover.Breaker {
+	if t.newBreaker == nil {
+		return failover.NoopBreaker{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.breakers[host]
+	if !ok {
+		b = t.newBreaker(host)
+		t.breakers[host] = b
+	}
+
+	return b
+}
+
+// replayable reports whether the body of req can be sent again, buffering
+// it if needed. The request returned replaces req for sending: it carries
+// the buffered body, or for a body too large to buffer, the part read so
+// far followed by the rest.
+func (t *Transport) replayable(req *http.Request) (*http.Request, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, true, nil
+	}
+	if t.bufferMax <= 0 {
+		return req, false, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, t.bufferMax+1))
+	if err != nil {
+		_ = req.Body.Close()
+		return nil, false, fmt.Errorf("httpfailover: reading request body: %w", err)
+	}
+
+	r := req.Clone(req.Context())
+	if int64(len(buf)) > t.bufferMax {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return r, false, nil
+	}
+
+	_ = req.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return r, true, nil
+}
+
+// rewind returns a copy of req with a fresh body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRewind, err)
+	}
+
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+// shouldRetry reports whether an attempt's error is worth another attempt.
+func shouldRetry(err error) bool {
+	return !errors.Is(err, failover.ErrCircuitOpen) &&
+		!errors.Is(err, errRewind) &&
+		!errors.Is(err, errWaitTooLong) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// discard drains and closes the body of a response that is not returned,
+// so its connection can be reused.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,427 @@
+package httpfailover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var errTest = errors.New("test error")
+
+// roundTripFunc adapts a function to an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// failingServer answers 503 to the first failures requests and 200 after.
+func failingServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, "unavailable")
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestTransport_RetriesIdempotent(t *testing.T) {
+	t.Parallel()
+	srv, calls := failingServer(t, 2)
+	client := &http.Client{Transport: NewTransport(nil, WithRetry(3, failover.ConstantBackoff(time.Millisecond)))}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("Expected 3 calls, got %d", got)
+	}
+}
+
+func TestTransport_NoRetryForPost(t *testing.T) {
+	t.Parallel()
+	srv, calls := failingServer(t, 1)
+	client := &http.Client{Transport: NewTransport(nil, WithRetry(3, failover.ConstantBackoff(time.Millisecond)))}
+
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("Expected POST to be sent once, got %d calls", got)
+	}
+}
+
+func TestTransport_ReturnsLastFailedResponse(t *testing.T) {
+	t.Parallel()
+	srv, calls := failingServer(t, 10)
+	client := &http.Client{Transport: NewTransport(nil, WithRetry(2, failover.ConstantBackoff(time.Millisecond)))}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusServiceUnavailable || string(body) != "unavailable" {
+		t.Fatalf("Expected the last 503 response, got %d %q", resp.StatusCode, body)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("Expected 2 calls, got %d", got)
+	}
+}
+
+func TestTransport_RetriesConnectionErrors(t *testing.T) {
+	t.Parallel()
+	var calls int
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, errTest
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	tr := NewTransport(base, WithRetry(3, failover.ConstantBackoff(time.Millisecond)))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestTransport_PerHostBreakers(t *testing.T) {
+	t.Parallel()
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "down.example.com" {
+			return nil, errTest
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	tr := NewTransport(base,
+		WithRetry(1, nil),
+		WithBreakers(func(string) failover.Breaker {
+			return failover.NewCircuitBreaker(2, 1, time.Minute)
+		}),
+	)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "http://down.example.com/", nil)
+		if _, err := tr.RoundTrip(req); !errors.Is(err, errTest) {
+			t.Fatalf("Expected error %v, got %v", errTest, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://down.example.com/", nil)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://up.example.com/", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected other host to be unaffected, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTransport_HungHostTripsBreaker(t *testing.T) {
+	t.Parallel()
+	hung := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-hung:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(hung) })
+
+	cb := failover.NewCircuitBreaker(2, 1, time.Minute)
+	tr := NewTransport(srv.Client().Transport, WithRetry(1, nil), WithBreakers(func(string) failover.Breaker { return cb }))
+
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if _, err := tr.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected DeadlineExceeded, got %v", err)
+		}
+		cancel()
+	}
+
+	if counts := cb.Counts(); cb.State() != failover.Open || counts.TotalSuccesses != 0 || counts.TotalFailures != 2 {
+		t.Fatalf("Expected the breaker open after 2 timeouts, got %v with %+v", cb.State(), counts)
+	}
+}
+
+func TestTransport_CallerCancelNotCounted(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute, failover.WithExcludeIf(canceled))
+	tr := NewTransport(srv.Client().Transport, WithRetry(1, nil), WithBreakers(func(string) failover.Breaker { return cb }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected Canceled, got %v", err)
+	}
+
+	if counts := cb.Counts(); cb.State() != failover.Closed || counts.TotalSuccesses != 0 || counts.TotalFailures != 0 {
+		t.Fatalf("Expected the cancel left out of the counts, got %v with %+v", cb.State(), counts)
+	}
+}
+
+// closeTracker is a request body recording whether it was closed.
+type closeTracker struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *closeTracker) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestTransport_ClosesBodyOnRejection(t *testing.T) {
+	t.Parallel()
+	cb := failover.NewCircuitBreaker(1, 1, time.Minute)
+	cb.Execute(func() error { return errTest })
+	tr := NewTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Error("Expected the request not to be sent")
+		return nil, errTest
+	}), WithBreakers(func(string) failover.Breaker { return cb }))
+
+	body := &closeTracker{Reader: strings.NewReader("payload")}
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/", body)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if !body.closed.Load() {
+		t.Fatal("Expected the body of the rejected request to be closed")
+	}
+}
+
+func TestTransport_ReplaysBody(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		status := http.StatusOK
+		if len(bodies) == 1 {
+			status = http.StatusBadGateway
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+	})
+	tr := NewTransport(base, WithRetry(2, failover.ConstantBackoff(time.Millisecond)))
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com/", strings.NewReader("payload"))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Fatalf("Expected the body to be replayed, got %q", bodies)
+	}
+}
+
+// opaqueBody hides the reader type, so http.NewRequest can not set GetBody.
+type opaqueBody struct{ io.Reader }
+
+// recordingBase answers 502 to the first request and 200 after, recording
+// the bodies it received.
+func recordingBase(bodies *[]string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		req.Body.Close()
+		*bodies = append(*bodies, string(b))
+		status := http.StatusOK
+		if len(*bodies) == 1 {
+			status = http.StatusBadGateway
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+	}
+}
+
+func TestTransport_BuffersSmallBody(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	tr := NewTransport(recordingBase(&bodies), WithRetry(2, failover.ConstantBackoff(time.Millisecond)))
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com/", opaqueBody{strings.NewReader("payload")})
+	if req.GetBody != nil {
+		t.Fatal("Expected the test body to have no GetBody")
+	}
+
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Fatalf("Expected the buffered body to be sent twice, got %q", bodies)
+	}
+	if req.GetBody != nil {
+		t.Fatal("Expected the caller's request to be left unchanged")
+	}
+}
+
+func TestTransport_LargeBodyNotRetried(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	tr := NewTransport(recordingBase(&bodies),
+		WithRetry(2, failover.ConstantBackoff(time.Millisecond)),
+		WithBodyBuffer(4),
+	)
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com/", opaqueBody{strings.NewReader("payload")})
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Expected the failed response, got %d", resp.StatusCode)
+	}
+	if len(bodies) != 1 || bodies[0] != "payload" {
+		t.Fatalf("Expected the whole body to be sent once, got %q", bodies)
+	}
+}
+
+func TestTransport_RewindFailureNotRetried(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	tr := NewTransport(recordingBase(&bodies), WithRetry(3, failover.ConstantBackoff(time.Millisecond)))
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com/", strings.NewReader("payload"))
+	req.GetBody = func() (io.ReadCloser, error) { return nil, errTest }
+
+	if _, err := tr.RoundTrip(req); !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 call, got %d", len(bodies))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRetryAfter(%q): expected %v %v, got %v %v", tt.value, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+// throttlingBase answers the first request with status and a Retry-After
+// header, and 200 after.
+func throttlingBase(status int, retryAfter string, calls *[]time.Time) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls = append(*calls, time.Now())
+		if len(*calls) > 1 {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}
+		header := http.Header{"Retry-After": {retryAfter}}
+		return &http.Response{StatusCode: status, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+}
+
+func TestTransport_HonorsRetryAfter(t *testing.T) {
+	t.Parallel()
+	var calls []time.Time
+	tr := NewTransport(throttlingBase(http.StatusTooManyRequests, "1", &calls),
+		WithRetry(2, failover.ConstantBackoff(time.Millisecond)),
+	)
+
+	resp, err := tr.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 calls, got %d", len(calls))
+	}
+	if gap := calls[1].Sub(calls[0]); gap < time.Second {
+		t.Fatalf("Expected the retry to wait for Retry-After, waited %v", gap)
+	}
+}
+
+func TestTransport_RetryAfterTooLong(t *testing.T) {
+	t.Parallel()
+	var calls []time.Time
+	tr := NewTransport(throttlingBase(http.StatusServiceUnavailable, "3600", &calls),
+		WithRetry(2, failover.ConstantBackoff(time.Millisecond)),
+	)
+
+	start := time.Now()
+	resp, err := tr.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || len(calls) != 1 {
+		t.Fatalf("Expected the 503 returned after 1 call, got %d after %d", resp.StatusCode, len(calls))
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Expected the transport not to wait")
+	}
+}
@@ -0,0 +1,97 @@
+// Package hystrixfailover builds failover pipelines from the per-command
+// configuration of github.com/afex/hystrix-go, for services moving off
+// hystrix one command at a time. A command becomes a pipeline of a breaker
+// tripping on the error percentage, a timeout and a bulkhead, the checks
+// hystrix makes around every run, in the same order.
+package hystrixfailover
+
+import (
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// Defaults of hystrix for the settings left zero.
+const (
+	defaultTimeout                = 1000 // Milliseconds
+	defaultMaxConcurrentRequests  = 10
+	defaultRequestVolumeThreshold = 20
+	defaultSleepWindow            = 5000 // Milliseconds
+	defaultErrorPercentThreshold  = 50
+)
+
+// metricsWindow is the rolling window over which hystrix computes the error
+// percentage.
+const metricsWindow = 10 * time.Second
+
+// CommandConfig is the configuration of a hystrix command, with the fields
+// and JSON names of hystrix.CommandConfig so that existing configuration
+// decodes into it unchanged. Durations are in milliseconds.
+type CommandConfig struct {
+	Timeout                int `json:"timeout"`                  // How long a run may take
+	MaxConcurrentRequests  int `json:"max_concurrent_requests"`  // Runs at once; further ones are rejected
+	RequestVolumeThreshold int `json:"request_volume_threshold"` // Calls in the window before the breaker may trip
+	SleepWindow            int `json:"sleep_window"`             // How long the breaker stays open
+	ErrorPercentThreshold  int `json:"error_percent_threshold"`  // Percentage of failed calls that trips the breaker
+}
+
+// withDefaults returns c with hystrix's defaults for the settings left
+// zero.
+func (c CommandConfig) withDefaults() CommandConfig {
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.MaxConcurrentRequests == 0 {
+		c.MaxConcurrentRequests = defaultMaxConcurrentRequests
+	}
+	if c.RequestVolumeThreshold == 0 {
+		c.RequestVolumeThreshold = defaultRequestVolumeThreshold
+	}
+	if c.SleepWindow == 0 {
+		c.SleepWindow = defaultSleepWindow
+	}
+	if c.ErrorPercentThreshold == 0 {
+		c.ErrorPercentThreshold = defaultErrorPercentThreshold
+	}
+
+	return c
+}
+
+// NewPipeline creates the pipeline of a command configured with cfg, and
+// returns its breaker for monitoring. The breaker opens for SleepWindow
+// once at least RequestVolumeThreshold calls in the last 10 seconds failed
+// at ErrorPercentThreshold percent; like hystrix, it counts timeouts and
+// bulkhead rejections as failures, and a single successful trial closes it
+// again. opts add to the pipeline, such as a fallback.
+func NewPipeline(cfg CommandConfig, opts ...failover.PipelineOption) (*failover.Pipeline, *failover.CircuitBreaker) {
+	cfg = cfg.withDefaults()
+
+	breaker := failover.NewCircuitBreaker(0, 1, millis(cfg.SleepWindow),
+		failover.WithFailureRate(float64(cfg.ErrorPercentThreshold)/100, metricsWindow),
+		failover.WithMinimumRequests(cfg.RequestVolumeThreshold))
+
+	opts = append([]failover.PipelineOption{
+		failover.WithBreaker(breaker),
+		failover.WithTimeout(millis(cfg.Timeout)),
+		failover.WithBulkhead(failover.NewBulkhead(cfg.MaxConcurrentRequests, 0, 0)),
+	}, opts...)
+
+	return failover.NewPipeline(opts...), breaker
+}
+
+// Configure registers in r the pipeline of each command in cmds as a
+// policy, and its breaker as a breaker, under the command's name, as
+// hystrix.Configure does for hystrix's own registry. Calls then go through
+// r.Policy(name) where they went through hystrix.Do(name, ...).
+func Configure(r *failover.Registry, cmds map[string]CommandConfig) {
+	for name, cfg := range cmds {
+		pipeline, breaker := NewPipeline(cfg)
+		r.AddPolicy(name, pipeline)
+		r.AddBreaker(name, breaker)
+	}
+}
+
+// millis returns n milliseconds.
+func millis(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
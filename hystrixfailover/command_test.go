@@ -0,0 +1,100 @@
+package hystrixfailover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var errTest = errors.New("test error")
+
+func TestCommandConfig_JSON(t *testing.T) {
+	t.Parallel()
+	var cfg CommandConfig
+	data := `{"timeout": 500, "max_concurrent_requests": 4, "request_volume_threshold": 2, "sleep_window": 100, "error_percent_threshold": 25}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	want := CommandConfig{Timeout: 500, MaxConcurrentRequests: 4, RequestVolumeThreshold: 2, SleepWindow: 100, ErrorPercentThreshold: 25}
+	if cfg != want {
+		t.Fatalf("Expected %+v, got %+v", want, cfg)
+	}
+}
+
+func TestNewPipeline_Defaults(t *testing.T) {
+	t.Parallel()
+	want := CommandConfig{Timeout: 1000, MaxConcurrentRequests: 10, RequestVolumeThreshold: 20, SleepWindow: 5000, ErrorPercentThreshold: 50}
+	if cfg := (CommandConfig{}).withDefaults(); cfg != want {
+		t.Fatalf("Expected %+v, got %+v", want, cfg)
+	}
+}
+
+func TestNewPipeline_ErrorPercent(t *testing.T) {
+	t.Parallel()
+	pipeline, breaker := NewPipeline(CommandConfig{RequestVolumeThreshold: 4, ErrorPercentThreshold: 50})
+	ctx := context.Background()
+
+	for _, err := range []error{nil, errTest, nil} {
+		pipeline.Do(ctx, func(context.Context) error { return err })
+	}
+	if s := breaker.State(); s != failover.Closed {
+		t.Fatalf("Expected state %v below the request volume, got %v", failover.Closed, s)
+	}
+
+	pipeline.Do(ctx, func(context.Context) error { return errTest })
+	if s := breaker.State(); s != failover.Open {
+		t.Fatalf("Expected state %v at 50%% errors, got %v", failover.Open, s)
+	}
+	if err := pipeline.Do(ctx, func(context.Context) error { return nil }); !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestNewPipeline_Timeout(t *testing.T) {
+	t.Parallel()
+	pipeline, _ := NewPipeline(CommandConfig{Timeout: 10})
+
+	err := pipeline.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, failover.ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestNewPipeline_MaxConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	pipeline, _ := NewPipeline(CommandConfig{MaxConcurrentRequests: 1})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go pipeline.Do(context.Background(), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	if err := pipeline.Do(context.Background(), func(context.Context) error { return nil }); !errors.Is(err, failover.ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull, got %v", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Parallel()
+	r := failover.NewRegistry()
+	Configure(r, map[string]CommandConfig{"payments": {SleepWindow: int(time.Second / time.Millisecond)}})
+
+	if _, ok := r.Policy("payments"); !ok {
+		t.Fatal("Expected the command's pipeline to be registered")
+	}
+	if _, ok := r.Breakers()["payments"]; !ok {
+		t.Fatal("Expected the command's breaker to be registered")
+	}
+}
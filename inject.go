@@ -0,0 +1,116 @@
+package failover
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Fault is a failure an Inject policy adds to calls.
+type Fault struct {
+	Probability float64       // Share of calls affected, in [0, 1]
+	Latency     time.Duration // Delay added before the call
+	Err         error         // Returned instead of making the call, if set
+}
+
+// Inject is a fault injection policy, to exercise the degradation paths of
+// a service in staging with the same policies it runs in production. It
+// delays calls and fails them with configured errors, each fault drawn
+// independently for every call: first the latencies add up, then the
+// first error drawn, if any, is returned without calling fn.
+//
+// An Inject starts disabled, so that it can be left in place and turned on
+// with Enable, or from the admin handler, where wanted.
+type Inject struct {
+	faults  []Fault
+	enabled atomic.Bool
+}
+
+// InjectOption adds a fault to an Inject policy.
+type InjectOption func(*Inject)
+
+// WithInjectedLatency delays the share p of calls by d.
+func WithInjectedLatency(p float64, d time.Duration) InjectOption {
+	return WithFault(Fault{Probability: p, Latency: d})
+}
+
+// WithInjectedError fails the share p of calls with err.
+func WithInjectedError(p float64, err error) InjectOption {
+	return WithFault(Fault{Probability: p, Err: err})
+}
+
+// WithInjectedOpenCircuit fails the share p of calls with ErrCircuitOpen,
+// as if a breaker had rejected them.
+func WithInjectedOpenCircuit(p float64) InjectOption {
+	return WithInjectedError(p, rejectWith(rejectedCircuitOpen))
+}
+
+// WithFault adds f.
+func WithFault(f Fault) InjectOption {
+	return func(i *Inject) {
+		i.faults = append(i.faults, f)
+	}
+}
+
+// NewInject creates a disabled Inject policy with the given faults.
+func NewInject(opts ...InjectOption) *Inject {
+	i := &Inject{}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Enable starts injecting faults.
+func (i *Inject) Enable() {
+	i.enabled.Store(true)
+}
+
+// Disable stops injecting faults. Calls already delayed still wait.
+func (i *Inject) Disable() {
+	i.enabled.Store(false)
+}
+
+// Enabled reports whether faults are being injected.
+func (i *Inject) Enabled() bool {
+	return i.enabled.Load()
+}
+
+// Do executes fn, with faults added while enabled. It returns the
+// context's error if ctx is done during an injected delay.
+func (i *Inject) Do(ctx context.Context, fn WorkFuncCtx) error {
+	if !i.enabled.Load() {
+		return fn(ctx)
+	}
+
+	var delay time.Duration
+	var err error
+	for _, f := range i.faults {
+		if rand.Float64() >= f.Probability {
+			continue
+		}
+		delay += f.Latency
+		if err == nil {
+			err = f.Err
+		}
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return fn(ctx)
+}
@@ -0,0 +1,60 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInject_Disabled(t *testing.T) {
+	t.Parallel()
+	i := NewInject(WithInjectedError(1, errTest))
+
+	if err := i.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected a disabled policy to inject nothing, got %v", err)
+	}
+}
+
+func TestInject_Error(t *testing.T) {
+	t.Parallel()
+	i := NewInject(WithInjectedOpenCircuit(1), WithInjectedError(1, errTest))
+	i.Enable()
+
+	called := false
+	err := i.Do(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the first fault drawn, got %v", err)
+	}
+	if called {
+		t.Fatal("Expected the call not to be made")
+	}
+
+	i.Disable()
+	if i.Enabled() {
+		t.Fatal("Expected the policy to be disabled")
+	}
+}
+
+func TestInject_Latency(t *testing.T) {
+	t.Parallel()
+	i := NewInject(WithInjectedLatency(1, 20*time.Millisecond), WithInjectedLatency(0, time.Hour))
+	i.Enable()
+
+	start := time.Now()
+	if err := i.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("Expected a 20ms delay, got %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := i.Do(ctx, func(context.Context) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
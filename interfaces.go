@@ -0,0 +1,91 @@
+package failover
+
+import "context"
+
+// Policy is the behavior shared by every resilience primitive in the
+// package: run fn under the policy and report the outcome. It makes
+// policies interchangeable in pipelines, registries and middleware.
+//
+// The method is Do rather than Execute because CircuitBreaker and
+// RateLimiter already use Execute for calls without a context.
+type Policy interface {
+	Do(ctx context.Context, fn WorkFuncCtx) error
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(ctx context.Context, fn WorkFuncCtx) error
+
+// Do calls f(ctx, fn).
+func (f PolicyFunc) Do(ctx context.Context, fn WorkFuncCtx) error {
+	return f(ctx, fn)
+}
+
+// Breaker is the behavior of a circuit breaker. Application code can depend
+// on it instead of *CircuitBreaker so that tests can inject fakes and
+// decorators can wrap the real implementation.
+type Breaker interface {
+	Execute(fn WorkFunc) error
+	State() State
+}
+
+// Retrier is the behavior of a retry policy.
+type Retrier interface {
+	Do(ctx context.Context, fn WorkFuncCtx) error
+}
+
+// Limiter is the behavior of a rate limiter, such as RateLimiter or an
+// adapter of another implementation. Allow takes a token if one is free
+// now; Wait waits for one.
+type Limiter interface {
+	Allow() bool
+	Wait(ctx context.Context) error
+}
+
+var (
+	_ Limiter = (*RateLimiter)(nil)
+
+	_ Breaker = (*CircuitBreaker)(nil)
+	_ Breaker = NoopBreaker{}
+	_ Retrier = (*RetryPolicy)(nil)
+	_ Retrier = NoopRetrier{}
+
+	_ Policy = (*RetryPolicy)(nil)
+	_ Policy = (*CircuitBreaker)(nil)
+	_ Policy = (*Bulkhead)(nil)
+	_ Policy = (*Timeout)(nil)
+	_ Policy = (*RateLimiter)(nil)
+	_ Policy = (*Fallback)(nil)
+	_ Policy = (*Pipeline)(nil)
+	_ Policy = (*AdaptiveLimiter)(nil)
+	_ Policy = (*CoDelQueue)(nil)
+	_ Policy = (*Cooldown)(nil)
+	_ Policy = NoopBreaker{}
+	_ Policy = NoopRetrier{}
+	_ Policy = PolicyFunc(nil)
+)
+
+// NoopBreaker is a Breaker that is always Closed and calls fn directly.
+type NoopBreaker struct{}
+
+// Execute calls fn and returns its error.
+func (NoopBreaker) Execute(fn WorkFunc) error {
+	return fn()
+}
+
+// Do calls fn and returns its error.
+func (NoopBreaker) Do(ctx context.Context, fn WorkFuncCtx) error {
+	return fn(ctx)
+}
+
+// State always returns Closed.
+func (NoopBreaker) State() State {
+	return Closed
+}
+
+// NoopRetrier is a Retrier that calls fn exactly once.
+type NoopRetrier struct{}
+
+// Do calls fn once and returns its error.
+func (NoopRetrier) Do(ctx context.Context, fn WorkFuncCtx) error {
+	return fn(ctx)
+}
@@ -0,0 +1,80 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNoopBreaker(t *testing.T) {
+	t.Parallel()
+	var b Breaker = NoopBreaker{}
+
+	if err := b.Execute(func() error { return errTest }); !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("Expected state Closed, got %v", b.State())
+	}
+}
+
+func TestNoopRetrier(t *testing.T) {
+	t.Parallel()
+	var r Retrier = NoopRetrier{}
+
+	calls := 0
+	err := r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	})
+
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestCircuitBreaker_State(t *testing.T) {
+	t.Parallel()
+	var b Breaker = NewCircuitBreaker(1, 1, time.Minute)
+
+	_ = b.Execute(func() error { return errTest })
+	if b.State() != Open {
+		t.Fatalf("Expected state Open, got %v", b.State())
+	}
+}
+
+func TestPolicy_Interchangeable(t *testing.T) {
+	t.Parallel()
+	policies := []Policy{
+		NewRetryPolicy(1, time.Millisecond),
+		NewCircuitBreaker(5, 1, time.Minute),
+		NewBulkhead(1, 0, 0),
+		NewTimeout(time.Second),
+		NewRateLimiter(100, 1),
+		NewPipeline(),
+		NoopRetrier{},
+	}
+
+	for _, p := range policies {
+		if err := p.Do(context.Background(), func(context.Context) error { return errTest }); !errors.Is(err, errTest) {
+			t.Fatalf("%T: Expected error %v, got %v", p, errTest, err)
+		}
+	}
+}
+
+func TestPolicyFunc(t *testing.T) {
+	t.Parallel()
+	called := false
+	p := PolicyFunc(func(ctx context.Context, fn WorkFuncCtx) error {
+		called = true
+		return fn(ctx)
+	})
+
+	if err := p.Do(context.Background(), func(context.Context) error { return nil }); err != nil || !called {
+		t.Fatalf("Expected PolicyFunc to wrap fn, got called=%v err=%v", called, err)
+	}
+}
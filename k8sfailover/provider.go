@@ -0,0 +1,365 @@
+// Package k8sfailover discovers endpoints from Kubernetes EndpointSlices.
+//
+// It talks to the API server's REST interface directly rather than through
+// client-go, so importing it adds no dependencies. Inside a pod it uses the
+// service account credentials mounted by Kubernetes; the account needs
+// list and watch on endpointslices in the service's namespace.
+package k8sfailover
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// serviceAccountDir is where Kubernetes mounts the pod's credentials.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ErrNotInCluster is returned by NewProvider outside a pod when no API
+// server was configured with WithAPIServer.
+var ErrNotInCluster = errors.New("not running in a Kubernetes cluster")
+
+var _ failover.EndpointProvider = (*Provider)(nil)
+
+// Provider is a failover.EndpointProvider that watches the EndpointSlices
+// of a Service and yields the addresses of its ready endpoints, so clients
+// fail over between pods without a service mesh.
+type Provider struct {
+	namespace string
+	service   string
+	portName  string // Port to use from the slices, the first one when empty
+
+	apiServer string // Base URL of the API server
+	client    *http.Client
+	token     func() string // Bearer token, re-read so rotated tokens are picked up
+	retry     time.Duration // Delay before re-listing after a failed watch
+}
+
+// Option configures optional Provider behavior.
+type Option func(*Provider)
+
+// WithAPIServer sets the API server base URL, the client used to reach it
+// and the bearer token sent, instead of the in-cluster configuration.
+func WithAPIServer(apiServer string, client *http.Client, token string) Option {
+	return func(p *Provider) {
+		p.apiServer = apiServer
+		p.client = client
+		p.token = func() string { return token }
+	}
+}
+
+// WithPortName selects the named port of the slices. By default the first
+// port is used.
+func WithPortName(name string) Option {
+	return func(p *Provider) {
+		p.portName = name
+	}
+}
+
+// WithRetryDelay sets how long to wait before re-listing when a watch
+// fails. The default is one second.
+func WithRetryDelay(d time.Duration) Option {
+	return func(p *Provider) {
+		p.retry = d
+	}
+}
+
+// NewProvider creates a Provider for the Service named service in
+// namespace. Without WithAPIServer it uses the in-cluster configuration
+// and fails with ErrNotInCluster outside a pod.
+func NewProvider(namespace, service string, opts ...Option) (*Provider, error) {
+	p := &Provider{namespace: namespace, service: service, retry: time.Second}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.apiServer == "" {
+		if err := p.inCluster(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// inCluster configures the provider from the pod's environment.
+func (p *Provider) inCluster() error {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return ErrNotInCluster
+	}
+
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotInCluster, err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	p.apiServer = "https://" + net.JoinHostPort(host, port)
+	p.client = &http.Client{Transport: transport}
+	p.token = func() string {
+		token, _ := os.ReadFile(serviceAccountDir + "/token")
+		return string(token)
+	}
+
+	return nil
+}
+
+// endpointSlice holds the fields of a discovery.k8s.io/v1 EndpointSlice
+// the provider uses.
+type endpointSlice struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name *string `json:"name"`
+		Port *int32  `json:"port"`
+	} `json:"ports"`
+}
+
+type sliceList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []endpointSlice `json:"items"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// List returns the ready endpoints of the Service.
+func (p *Provider) List(ctx context.Context) ([]*failover.Endpoint, error) {
+	list, err := p.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bySlice := make(map[string]endpointSlice, len(list.Items))
+	for _, s := range list.Items {
+		bySlice[s.Metadata.Name] = s
+	}
+
+	return p.endpoints(bySlice), nil
+}
+
+// Watch sends the ready endpoints of the Service every time its
+// EndpointSlices change. A broken watch is resumed by listing again after
+// the retry delay. It fails if the first list does.
+func (p *Provider) Watch(ctx context.Context) (<-chan []*failover.Endpoint, error) {
+	list, err := p.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{p: p, slices: make(map[string]endpointSlice), out: make(chan []*failover.Endpoint, 1)}
+	w.reset(list)
+	w.send(ctx)
+
+	go w.run(ctx, list.Metadata.ResourceVersion)
+
+	return w.out, nil
+}
+
+// watcher tracks the slices of one Watch call.
+type watcher struct {
+	p      *Provider
+	slices map[string]endpointSlice
+	last   []*failover.Endpoint // Last set sent, nil before the first
+	out    chan []*failover.Endpoint
+}
+
+func (w *watcher) run(ctx context.Context, version string) {
+	defer close(w.out)
+
+	for {
+		err := w.watch(ctx, version)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			select {
+			case <-time.After(w.p.retry):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		list, err := w.p.list(ctx)
+		if err != nil {
+			continue
+		}
+		w.reset(list)
+		w.send(ctx)
+		version = list.Metadata.ResourceVersion
+	}
+}
+
+// watch streams changes from version until the stream ends.
+func (w *watcher) watch(ctx context.Context, version string) error {
+	resp, err := w.p.get(ctx, url.Values{"watch": {"true"}, "resourceVersion": {version}, "allowWatchBookmarks": {"true"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var event watchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return err
+		}
+
+		var slice endpointSlice
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			if err := json.Unmarshal(event.Object, &slice); err != nil {
+				return err
+			}
+			w.slices[slice.Metadata.Name] = slice
+		case "DELETED":
+			if err := json.Unmarshal(event.Object, &slice); err != nil {
+				return err
+			}
+			delete(w.slices, slice.Metadata.Name)
+		case "ERROR":
+			return fmt.Errorf("k8sfailover: watch error: %s", event.Object)
+		default:
+			continue // BOOKMARK
+		}
+
+		w.send(ctx)
+	}
+
+	return scanner.Err()
+}
+
+func (w *watcher) reset(list *sliceList) {
+	clear(w.slices)
+	for _, s := range list.Items {
+		w.slices[s.Metadata.Name] = s
+	}
+}
+
+// send delivers the current endpoints if they changed.
+func (w *watcher) send(ctx context.Context) {
+	endpoints := w.p.endpoints(w.slices)
+
+	if w.last != nil && slices.EqualFunc(w.last, endpoints, func(a, b *failover.Endpoint) bool { return a.Name == b.Name }) {
+		return
+	}
+	w.last = append(make([]*failover.Endpoint, 0, len(endpoints)), endpoints...)
+
+	select {
+	case w.out <- endpoints:
+	case <-ctx.Done():
+	}
+}
+
+// endpoints returns the ready addresses of slices, sorted by name.
+func (p *Provider) endpoints(bySlice map[string]endpointSlice) []*failover.Endpoint {
+	var endpoints []*failover.Endpoint
+	for _, s := range bySlice {
+		port, ok := p.port(s)
+		if !ok {
+			continue
+		}
+
+		for _, ep := range s.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, addr := range ep.Addresses {
+				hostPort := net.JoinHostPort(addr, strconv.Itoa(port))
+				endpoints = append(endpoints, &failover.Endpoint{Name: hostPort, Address: hostPort})
+			}
+		}
+	}
+
+	slices.SortFunc(endpoints, func(a, b *failover.Endpoint) int { return strings.Compare(a.Name, b.Name) })
+
+	return endpoints
+}
+
+// port returns the port of slice to use.
+func (p *Provider) port(s endpointSlice) (int, bool) {
+	for _, port := range s.Ports {
+		if port.Port == nil {
+			continue
+		}
+		if p.portName == "" || port.Name != nil && *port.Name == p.portName {
+			return int(*port.Port), true
+		}
+	}
+
+	return 0, false
+}
+
+func (p *Provider) list(ctx context.Context) (*sliceList, error) {
+	resp, err := p.get(ctx, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list sliceList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// get requests the Service's EndpointSlices with query.
+func (p *Provider) get(ctx context.Context, query url.Values) (*http.Response, error) {
+	query.Set("labelSelector", "kubernetes.io/service-name="+p.service)
+	u := fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s",
+		p.apiServer, url.PathEscape(p.namespace), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := p.token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("k8sfailover: list endpointslices: %s", resp.Status)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,147 @@
+package k8sfailover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// slice renders an EndpointSlice with one ready and one unready address.
+func slice(name, ready, unready string) string {
+	return fmt.Sprintf(`{"metadata":{"name":%q},"ports":[{"name":"http","port":8080},{"name":"admin","port":9090}],`+
+		`"endpoints":[{"addresses":[%q],"conditions":{"ready":true}},{"addresses":[%q],"conditions":{"ready":false}}]}`,
+		name, ready, unready)
+}
+
+// apiServer fakes the EndpointSlice list and watch endpoints; events sent on
+// the returned channel are streamed to watchers.
+func apiServer(t *testing.T, items ...string) (*httptest.Server, chan string) {
+	t.Helper()
+	events := make(chan string)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/discovery.k8s.io/v1/namespaces/prod/endpointslices" ||
+			r.URL.Query().Get("labelSelector") != "kubernetes.io/service-name=api" ||
+			r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Query().Get("watch") != "true" {
+			list := `{"metadata":{"resourceVersion":"1"},"items":[`
+			for i, item := range items {
+				if i > 0 {
+					list += ","
+				}
+				list += item
+			}
+			fmt.Fprint(w, list+"]}")
+			return
+		}
+
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case event := <-events:
+				fmt.Fprintln(w, event)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, events
+}
+
+// names returns the names of endpoints, in order.
+func names(endpoints []*failover.Endpoint) []string {
+	out := make([]string, len(endpoints))
+	for i, ep := range endpoints {
+		out[i] = ep.Name
+	}
+	return out
+}
+
+func TestProvider_List(t *testing.T) {
+	t.Parallel()
+	srv, _ := apiServer(t, slice("api-1", "10.0.0.2", "10.0.0.9"), slice("api-2", "10.0.0.1", "10.0.0.8"))
+
+	p, err := NewProvider("prod", "api", WithAPIServer(srv.URL, srv.Client(), "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := p.List(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if n := names(endpoints); len(n) != 2 || n[0] != "10.0.0.1:8080" || n[1] != "10.0.0.2:8080" {
+		t.Fatalf("Expected the ready addresses on the first port, got %v", n)
+	}
+}
+
+func TestProvider_PortName(t *testing.T) {
+	t.Parallel()
+	srv, _ := apiServer(t, slice("api-1", "10.0.0.1", "10.0.0.9"))
+
+	p, _ := NewProvider("prod", "api", WithAPIServer(srv.URL, srv.Client(), "secret"), WithPortName("admin"))
+	endpoints, _ := p.List(context.Background())
+	if n := names(endpoints); len(n) != 1 || n[0] != "10.0.0.1:9090" {
+		t.Fatalf("Expected [10.0.0.1:9090], got %v", n)
+	}
+}
+
+func TestProvider_Watch(t *testing.T) {
+	t.Parallel()
+	srv, events := apiServer(t, slice("api-1", "10.0.0.1", "10.0.0.9"))
+
+	p, _ := NewProvider("prod", "api", WithAPIServer(srv.URL, srv.Client(), "secret"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := p.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	next := func() []string {
+		t.Helper()
+		select {
+		case endpoints := <-updates:
+			return names(endpoints)
+		case <-time.After(time.Second):
+			t.Fatal("Expected an endpoint update")
+			return nil
+		}
+	}
+
+	if n := next(); len(n) != 1 {
+		t.Fatalf("Expected the initial endpoint, got %v", n)
+	}
+
+	events <- `{"type":"ADDED","object":` + slice("api-2", "10.0.0.2", "10.0.0.8") + `}`
+	if n := next(); len(n) != 2 || n[1] != "10.0.0.2:8080" {
+		t.Fatalf("Expected the added slice's endpoint, got %v", n)
+	}
+
+	events <- `{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"5"}}}`
+	events <- `{"type":"DELETED","object":` + slice("api-1", "10.0.0.1", "10.0.0.9") + `}`
+	if n := next(); len(n) != 1 || n[0] != "10.0.0.2:8080" {
+		t.Fatalf("Expected the deleted slice's endpoint gone, got %v", n)
+	}
+}
+
+func TestNewProvider_NotInCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	if _, err := NewProvider("prod", "api"); !errors.Is(err, ErrNotInCluster) {
+		t.Fatalf("Expected ErrNotInCluster, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package failover
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// keyedWaiter is a caller queued for a slot in a KeyedBulkhead.
+type keyedWaiter struct {
+	ready   chan struct{} // Closed when the slot is granted
+	granted bool
+}
+
+// tenant is the per-key state of a KeyedBulkhead.
+type tenant struct {
+	running int
+	waiters []*keyedWaiter
+}
+
+// KeyedBulkhead is a Bulkhead partitioned by key, typically a tenant or
+// customer. Each key may use at most perKey of the total slots, and when a
+// slot frees up it goes to the waiting keys in round-robin order, so one
+// noisy tenant cannot starve the others behind a shared limit.
+type KeyedBulkhead struct {
+	mu sync.Mutex // Protects the fields below
+
+	total    int // Slots shared by all keys
+	perKey   int // Slots a single key may hold
+	maxQueue int // Callers a single key may have waiting
+
+	running int
+	tenants map[string]*tenant
+	ring    []string // Keys with waiters, in admission order
+	next    int      // Position in ring of the next key to admit
+}
+
+// NewKeyedBulkhead creates a KeyedBulkhead with total slots, at most perKey
+// of which may be held by any one key, and up to maxQueue waiting callers
+// per key.
+func NewKeyedBulkhead(total, perKey, maxQueue int) *KeyedBulkhead {
+	return &KeyedBulkhead{
+		total:    total,
+		perKey:   perKey,
+		maxQueue: maxQueue,
+		tenants:  make(map[string]*tenant),
+	}
+}
+
+// Do executes fn once key is granted a slot. It returns ErrBulkheadFull if
+// key's queue is full and ctx.Err() if ctx is done while waiting.
+func (b *KeyedBulkhead) Do(ctx context.Context, key string, fn WorkFuncCtx) error {
+	if err := b.acquire(ctx, key); err != nil {
+		return err
+	}
+	defer b.release(key)
+
+	return fn(ctx)
+}
+
+// InFlight returns the number of executions key currently holds.
+func (b *KeyedBulkhead) InFlight(key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t, ok := b.tenants[key]; ok {
+		return t.running
+	}
+
+	return 0
+}
+
+func (b *KeyedBulkhead) acquire(ctx context.Context, key string) error {
+	b.mu.Lock()
+
+	t := b.tenant(key)
+	if len(t.waiters) == 0 && t.running < b.perKey && b.running < b.total {
+		t.running++
+		b.running++
+		b.mu.Unlock()
+		return nil
+	}
+
+	if len(t.waiters) >= b.maxQueue {
+		b.forget(key, t)
+		b.mu.Unlock()
+		return rejectWith(rejectedBulkheadFull)
+	}
+
+	w := &keyedWaiter{ready: make(chan struct{})}
+	t.waiters = append(t.waiters, w)
+	if len(t.waiters) == 1 {
+		b.ring = append(b.ring, key)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if w.granted {
+		// Lost the race with the grant; hand the slot on.
+		b.releaseLocked(key)
+		return ctx.Err()
+	}
+
+	t.waiters = slices.DeleteFunc(t.waiters, func(x *keyedWaiter) bool { return x == w })
+	if len(t.waiters) == 0 {
+		b.unring(key)
+	}
+	b.forget(key, t)
+
+	return ctx.Err()
+}
+
+func (b *KeyedBulkhead) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.releaseLocked(key)
+}
+
+func (b *KeyedBulkhead) releaseLocked(key string) {
+	t := b.tenants[key]
+	t.running--
+	b.running--
+	b.forget(key, t)
+	b.dispatch()
+}
+
+// dispatch grants free slots to waiting keys in round-robin order.
+func (b *KeyedBulkhead) dispatch() {
+	for b.running < b.total && len(b.ring) > 0 {
+		granted := false
+
+		for range len(b.ring) {
+			if b.next >= len(b.ring) {
+				b.next = 0
+			}
+
+			key := b.ring[b.next]
+			t := b.tenants[key]
+			if t.running >= b.perKey {
+				b.next++
+				continue
+			}
+
+			w := t.waiters[0]
+			t.waiters = t.waiters[1:]
+			t.running++
+			b.running++
+			w.granted = true
+			close(w.ready)
+
+			if len(t.waiters) == 0 {
+				b.unring(key) // next now points at the following key
+			} else {
+				b.next++
+			}
+
+			granted = true
+			break
+		}
+
+		if !granted {
+			return // every waiting key is at its per-key limit
+		}
+	}
+}
+
+// tenant returns the state for key, creating it if needed.
+func (b *KeyedBulkhead) tenant(key string) *tenant {
+	t, ok := b.tenants[key]
+	if !ok {
+		t = &tenant{}
+		b.tenants[key] = t
+	}
+
+	return t
+}
+
+// forget drops the state for key once it holds and awaits nothing.
+func (b *KeyedBulkhead) forget(key string, t *tenant) {
+	if t.running == 0 && len(t.waiters) == 0 {
+		delete(b.tenants, key)
+	}
+}
+
+// unring removes key from the round-robin ring.
+func (b *KeyedBulkhead) unring(key string) {
+	i := slices.Index(b.ring, key)
+	if i < 0 {
+		return
+	}
+
+	b.ring = slices.Delete(b.ring, i, i+1)
+	if i < b.next {
+		b.next--
+	}
+}
@@ -0,0 +1,113 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedBulkhead_PerKeyLimit(t *testing.T) {
+	t.Parallel()
+	b := NewKeyedBulkhead(10, 1, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = b.Do(context.Background(), "noisy", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	if err := b.Do(context.Background(), "noisy", func(context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull for key at its limit, got %v", err)
+	}
+	if err := b.Do(context.Background(), "quiet", func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected other key to be admitted, got %v", err)
+	}
+}
+
+func TestKeyedBulkhead_FairAdmission(t *testing.T) {
+	t.Parallel()
+	b := NewKeyedBulkhead(1, 1, 10)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = b.Do(ctx, "a", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(key string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = b.Do(ctx, key, func(context.Context) error {
+				mu.Lock()
+				order = append(order, key)
+				mu.Unlock()
+				return nil
+			})
+		}()
+		time.Sleep(5 * time.Millisecond) // fix the queueing order
+	}
+
+	// Tenant a queues three calls before b queues one.
+	enqueue("a")
+	enqueue("a")
+	enqueue("a")
+	enqueue("b")
+	close(release)
+	wg.Wait()
+
+	// b is admitted after a's first queued call instead of after all three.
+	want := []string{"a", "b", "a", "a"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected round-robin order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestKeyedBulkhead_CancelWhileQueued(t *testing.T) {
+	t.Parallel()
+	b := NewKeyedBulkhead(1, 1, 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = b.Do(context.Background(), "a", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Do(ctx, "a", func(context.Context) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	close(release)
+	if err := b.Do(context.Background(), "a", func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected slot to be free after the queued caller left, got %v", err)
+	}
+	if n := b.InFlight("a"); n != 0 {
+		t.Fatalf("Expected no executions in flight, got %d", n)
+	}
+}
@@ -0,0 +1,242 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded is returned when an adaptive limiter rejects a call
+// because its current concurrency limit is reached.
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// LimitAlgorithm decides a concurrency limit from the outcome of each call.
+// Implementations are called under the limiter's lock and need no locking.
+type LimitAlgorithm interface {
+	// Update returns the new limit after a call that took rtt with
+	// inFlight calls running, and failed with a timeout or overload error
+	// if dropped is true.
+	Update(limit int, rtt time.Duration, inFlight int, dropped bool) int
+}
+
+// AdaptiveLimiter limits concurrent executions to a limit that a
+// LimitAlgorithm adjusts as calls complete, so a service self-tunes its
+// in-flight limit to what the dependency can sustain.
+//
+// With WithPriorityHeadroom the limiter honors the Priority carried by the
+// context: BestEffort calls are shed before the limit is reached, and
+// Critical calls may go beyond it.
+type AdaptiveLimiter struct {
+	mu sync.Mutex // Protects limit and inFlight
+
+	algorithm LimitAlgorithm
+	minLimit  int
+	maxLimit  int
+	isDropped func(error) bool // Whether a failure signals overload
+	headroom  float64          // Fraction of the limit kept from BestEffort calls and lent to Critical ones
+
+	limit    int
+	inFlight int
+}
+
+// AdaptiveOption configures optional AdaptiveLimiter behavior.
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// WithDropClassifier sets which errors count as the dependency being
+// overloaded. By default any error other than context.Canceled does.
+func WithDropClassifier(isDropped func(error) bool) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.isDropped = isDropped
+	}
+}
+
+// WithPriorityHeadroom sheds BestEffort calls once the in-flight calls
+// reach (1-fraction) of the limit, and lets Critical calls run up to
+// (1+fraction) of it, so overload hits the least important traffic first.
+func WithPriorityHeadroom(fraction float64) AdaptiveOption {
+	return func(l *AdaptiveLimiter) {
+		l.headroom = fraction
+	}
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter starting at initialLimit and
+// kept within [minLimit, maxLimit]. A minLimit below 1 is taken as 1, since
+// at a limit of 0 no call completes to raise it again.
+func NewAdaptiveLimiter(algorithm LimitAlgorithm, initialLimit, minLimit, maxLimit int, opts ...AdaptiveOption) *AdaptiveLimiter {
+	minLimit = max(minLimit, 1)
+	l := &AdaptiveLimiter{
+		algorithm: algorithm,
+		minLimit:  minLimit,
+		maxLimit:  maxLimit,
+		isDropped: func(err error) bool { return !errors.Is(err, context.Canceled) },
+		limit:     min(max(initialLimit, minLimit), maxLimit),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Do executes fn if the current limit allows, and returns ErrLimitExceeded
+// otherwise.
+func (l *AdaptiveLimiter) Do(ctx context.Context, fn WorkFuncCtx) error {
+	if !l.acquire(PriorityFromContext(ctx)) {
+		return rejectWith(rejectedLimitExceeded)
+	}
+
+	start := time.Now()
+	err := fn(ctx)
+	l.release(time.Since(start), err != nil && l.isDropped(err))
+
+	return err
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+// InFlight returns the number of executions currently running.
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+func (l *AdaptiveLimiter) acquire(p Priority) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := float64(l.limit)
+	switch p {
+	case BestEffort:
+		limit *= 1 - l.headroom
+	case Critical:
+		limit *= 1 + l.headroom
+	}
+
+	if float64(l.inFlight) >= limit {
+		return false
+	}
+
+	l.inFlight++
+	return true
+}
+
+func (l *AdaptiveLimiter) release(rtt time.Duration, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.algorithm.Update(l.limit, rtt, l.inFlight, dropped)
+	l.limit = min(max(limit, l.minLimit), l.maxLimit)
+	l.inFlight--
+}
+
+// AIMD is an additive-increase/multiplicative-decrease LimitAlgorithm: the
+// limit grows by one after each successful call made while the limiter was
+// at least half utilized, and is multiplied by Backoff after a drop.
+type AIMD struct {
+	Backoff float64 // Multiplier applied on a drop, in (0, 1); 0.9 if unset
+}
+
+// Update implements LimitAlgorithm.
+func (a AIMD) Update(limit int, _ time.Duration, inFlight int, dropped bool) int {
+	if dropped {
+		backoff := a.Backoff
+		if backoff <= 0 || backoff >= 1 {
+			backoff = 0.9
+		}
+
+		return int(float64(limit) * backoff)
+	}
+
+	// Only grow when the limit is actually being exercised.
+	if inFlight*2 >= limit {
+		return limit + 1
+	}
+
+	return limit
+}
+
+// Gradient is a latency-based LimitAlgorithm in the style of Netflix's
+// concurrency-limits. It tracks the minimum observed round trip time as the
+// no-load latency and scales the limit by the ratio of that minimum to each
+// new sample, adding headroom of sqrt(limit) so the limit can keep probing
+// upwards while latency stays flat. It suits dependencies whose capacity
+// varies with load, where additive probing reacts too slowly.
+//
+// A Gradient keeps state and must be used by a single limiter, as a pointer.
+type Gradient struct {
+	Smoothing    float64 // Weight of each new estimate, in (0, 1]; 0.2 if unset
+	Tolerance    float64 // Latency inflation tolerated before shrinking; 1 if unset
+	MinRTTWindow int     // Samples after which the minimum is re-measured; 500 if unset
+
+	estimate float64
+	minRTT   time.Duration
+	samples  int
+}
+
+// Update implements LimitAlgorithm.
+func (g *Gradient) Update(limit int, rtt time.Duration, inFlight int, dropped bool) int {
+	g.track(rtt)
+
+	// Follow the limiter when it clamped the previous estimate.
+	if int(g.estimate) != limit {
+		g.estimate = float64(limit)
+	}
+
+	var next float64
+	switch {
+	case dropped:
+		next = g.estimate / 2
+	case float64(inFlight)*2 < g.estimate:
+		return limit // too lightly loaded for rtt to say anything about the limit
+	default:
+		gradient := min(max(g.tolerance()*float64(g.minRTT)/float64(rtt), 0.5), 1)
+		next = g.estimate*gradient + math.Sqrt(g.estimate)
+	}
+
+	smoothing := g.Smoothing
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+
+	g.estimate = (1-smoothing)*g.estimate + smoothing*next
+	return int(g.estimate)
+}
+
+// track updates the moving minimum round trip time. The minimum is reset to
+// the current sample every MinRTTWindow samples so it can rise again when
+// the dependency's no-load latency changes.
+func (g *Gradient) track(rtt time.Duration) {
+	window := g.MinRTTWindow
+	if window <= 0 {
+		window = 500
+	}
+
+	g.samples++
+	if g.samples >= window {
+		g.samples = 0
+		g.minRTT = rtt
+	}
+
+	if g.minRTT == 0 || rtt < g.minRTT {
+		g.minRTT = rtt
+	}
+}
+
+func (g *Gradient) tolerance() float64 {
+	if g.Tolerance <= 0 {
+		return 1
+	}
+
+	return g.Tolerance
+}
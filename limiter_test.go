@@ -0,0 +1,198 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter_RejectsOverLimit(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(AIMD{}, 1, 1, 10)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = l.Do(context.Background(), func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	err := l.Do(context.Background(), func(context.Context) error { return nil })
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestAdaptiveLimiter_AIMD(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(AIMD{Backoff: 0.5}, 4, 1, 5)
+	ctx := context.Background()
+
+	// A single call at limit 4 is not enough utilization to grow.
+	_ = l.Do(ctx, func(context.Context) error { return nil })
+	if l.Limit() != 4 {
+		t.Fatalf("Expected limit 4 with low utilization, got %d", l.Limit())
+	}
+
+	_ = l.Do(ctx, func(context.Context) error { return errTest })
+	if l.Limit() != 2 {
+		t.Fatalf("Expected limit halved to 2 after a drop, got %d", l.Limit())
+	}
+
+	// At limit 2 one in-flight call is half utilization, so it grows.
+	_ = l.Do(ctx, func(context.Context) error { return nil })
+	if l.Limit() != 3 {
+		t.Fatalf("Expected limit 3 after a success, got %d", l.Limit())
+	}
+}
+
+func TestAdaptiveLimiter_Bounds(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(AIMD{Backoff: 0.1}, 2, 2, 3)
+	ctx := context.Background()
+
+	_ = l.Do(ctx, func(context.Context) error { return errTest })
+	if l.Limit() != 2 {
+		t.Fatalf("Expected limit held at minimum 2, got %d", l.Limit())
+	}
+
+	for range 5 {
+		_ = l.Do(ctx, func(context.Context) error { return nil })
+	}
+	if l.Limit() != 3 {
+		t.Fatalf("Expected limit held at maximum 3, got %d", l.Limit())
+	}
+}
+
+func TestAdaptiveLimiter_ZeroMinLimit(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(AIMD{Backoff: 0.5}, 1, 0, 10)
+	ctx := context.Background()
+
+	// Halving a limit of 1 would leave 0, at which no call could run.
+	_ = l.Do(ctx, func(context.Context) error { return errTest })
+	if l.Limit() != 1 {
+		t.Fatalf("Expected limit held at 1, got %d", l.Limit())
+	}
+	if err := l.Do(ctx, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
+
+func TestAdaptiveLimiter_DropClassifier(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(AIMD{Backoff: 0.5}, 4, 1, 10,
+		WithDropClassifier(func(err error) bool { return errors.Is(err, ErrTimeout) }),
+	)
+	ctx := context.Background()
+
+	_ = l.Do(ctx, func(context.Context) error { return errTest })
+	if l.Limit() != 4 {
+		t.Fatalf("Expected unclassified error to leave the limit, got %d", l.Limit())
+	}
+
+	_ = l.Do(ctx, func(context.Context) error { return ErrTimeout })
+	if l.Limit() != 2 {
+		t.Fatalf("Expected timeout to halve the limit, got %d", l.Limit())
+	}
+}
+
+func TestGradient_GrowsWhileLatencyFlat(t *testing.T) {
+	t.Parallel()
+	g := &Gradient{Smoothing: 1}
+
+	// At the minimum latency the gradient is 1 and the limit grows by sqrt.
+	limit := g.Update(16, 10*time.Millisecond, 16, false)
+	if limit != 20 {
+		t.Fatalf("Expected limit 20, got %d", limit)
+	}
+}
+
+func TestGradient_ShrinksOnLatency(t *testing.T) {
+	t.Parallel()
+	g := &Gradient{Smoothing: 1}
+
+	limit := g.Update(100, 10*time.Millisecond, 100, false)
+	limit = g.Update(limit, 40*time.Millisecond, limit, false)
+
+	// Latency quadrupled, so the gradient bottoms out at 0.5.
+	if limit >= 100 {
+		t.Fatalf("Expected limit to shrink on inflated latency, got %d", limit)
+	}
+}
+
+func TestGradient_Drop(t *testing.T) {
+	t.Parallel()
+	g := &Gradient{Smoothing: 1}
+
+	if limit := g.Update(40, 10*time.Millisecond, 40, true); limit != 20 {
+		t.Fatalf("Expected limit halved to 20 on drop, got %d", limit)
+	}
+}
+
+func TestGradient_MinRTTWindow(t *testing.T) {
+	t.Parallel()
+	g := &Gradient{MinRTTWindow: 2}
+
+	g.track(5 * time.Millisecond)
+	g.track(50 * time.Millisecond)
+	if g.minRTT != 50*time.Millisecond {
+		t.Fatalf("Expected minimum re-measured to 50ms after the window, got %v", g.minRTT)
+	}
+}
+
+func TestAdaptiveLimiter_Gradient(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(&Gradient{}, 1, 1, 50)
+
+	for range 20 {
+		_ = l.Do(context.Background(), func(context.Context) error { return nil })
+	}
+
+	if l.Limit() <= 1 {
+		t.Fatalf("Expected limit to grow under flat latency, got %d", l.Limit())
+	}
+}
+
+func TestAdaptiveLimiter_PriorityHeadroom(t *testing.T) {
+	t.Parallel()
+	l := NewAdaptiveLimiter(AIMD{}, 4, 4, 4, WithPriorityHeadroom(0.5))
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+	hold := func(ctx context.Context) {
+		started := make(chan struct{})
+		go func() {
+			_ = l.Do(ctx, func(context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+	}
+
+	hold(ctx)
+	hold(ctx)
+
+	// Half of the limit of 4 is in use, which is all BestEffort may take.
+	if err := l.Do(WithPriority(ctx, BestEffort), func(context.Context) error { return nil }); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expected BestEffort to be shed, got %v", err)
+	}
+
+	hold(ctx)
+	hold(ctx)
+	if err := l.Do(ctx, func(context.Context) error { return nil }); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expected Normal to be rejected at the limit, got %v", err)
+	}
+	if err := l.Do(WithPriority(ctx, Critical), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected Critical to use the headroom, got %v", err)
+	}
+}
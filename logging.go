@@ -0,0 +1,209 @@
+package failover
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogEvent is a kind of record a Logger emits.
+type LogEvent string
+
+// The events a Logger logs.
+const (
+	LogRetry     LogEvent = "retry"     // A failed attempt is about to be retried
+	LogTrip      LogEvent = "trip"      // A breaker opened
+	LogRecovery  LogEvent = "recovery"  // A breaker closed again
+	LogRejection LogEvent = "rejection" // A policy turned a call away
+)
+
+// Logger emits structured records through log/slog when policies retry,
+// trip, recover and reject calls. Each record carries the policy name and,
+// depending on the event, the attempt number, the delay before the retry,
+// the error and the breaker states. Attach it with Policy, RetryFunc and
+// StateChangeFunc.
+//
+// During an outage the same record can repeat millions of times; use
+// WithLogSampling to keep the volume in check.
+type Logger struct {
+	logger  *slog.Logger
+	levels  map[LogEvent]slog.Level
+	sampler *logSampler // Nil to log every record
+}
+
+// logSampler decides which records of each event and policy are logged.
+type logSampler struct {
+	first      int
+	thereafter int
+	period     time.Duration
+
+	mu      sync.Mutex
+	periods map[sampleKey]*samplePeriod // Current period of each event and policy
+}
+
+// sampleKey identifies the records sampled together.
+type sampleKey struct {
+	event LogEvent
+	name  string
+}
+
+// samplePeriod counts the records of a sampleKey within one period.
+type samplePeriod struct {
+	start   time.Time
+	seen    int
+	dropped int
+}
+
+// LoggerOption configures optional Logger behavior.
+type LoggerOption func(*Logger)
+
+// WithLogLevel logs event at level. The defaults are Info for retries and
+// recoveries, Warn for trips and Debug for rejections, which can come in
+// floods while a breaker is open.
+func WithLogLevel(event LogEvent, level slog.Level) LoggerOption {
+	return func(l *Logger) {
+		l.levels[event] = level
+	}
+}
+
+// WithLogSampling limits the records of each event and policy to the first
+// first of every period, then one in thereafter (none if zero). At the end
+// of a period in which records were dropped, a summary record tells how
+// many, at the level of their event.
+func WithLogSampling(first, thereafter int, period time.Duration) LoggerOption {
+	return func(l *Logger) {
+		l.sampler = &logSampler{
+			first:      first,
+			thereafter: thereafter,
+			period:     period,
+			periods:    make(map[sampleKey]*samplePeriod),
+		}
+	}
+}
+
+// NewLogger creates a Logger writing to logger, or to slog.Default if nil.
+func NewLogger(logger *slog.Logger, opts ...LoggerOption) *Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	l := &Logger{
+		logger: logger,
+		levels: map[LogEvent]slog.Level{
+			LogRetry:     slog.LevelInfo,
+			LogTrip:      slog.LevelWarn,
+			LogRecovery:  slog.LevelInfo,
+			LogRejection: slog.LevelDebug,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Policy returns policy decorated to log the calls it rejects under name.
+func (l *Logger) Policy(name string, policy Policy) Policy {
+	return PolicyFunc(func(ctx context.Context, fn WorkFuncCtx) error {
+		err := policy.Do(ctx, fn)
+		if reason := RejectionReason(err); reason != "" {
+			l.log(ctx, LogRejection, "call rejected", name, slog.String("reason", reason), slog.Any("error", err))
+		}
+
+		return err
+	})
+}
+
+// RetryFunc returns a function for WithRetryFunc that logs every retry of
+// the policy name.
+func (l *Logger) RetryFunc(name string) RetryFunc {
+	return func(ctx context.Context, attempt int, err error, delay time.Duration) {
+		l.log(ctx, LogRetry, "retrying", name,
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+	}
+}
+
+// StateChangeFunc returns a function for WithStateChangeFunc that logs the
+// breaker name tripping open and closing again.
+func (l *Logger) StateChangeFunc(name string) func(from, to State) {
+	return func(from, to State) {
+		states := []slog.Attr{slog.String("from", from.String()), slog.String("to", to.String())}
+
+		switch to {
+		case Open:
+			l.log(context.Background(), LogTrip, "circuit breaker opened", name, states...)
+		case Closed:
+			l.log(context.Background(), LogRecovery, "circuit breaker closed", name, states...)
+		}
+	}
+}
+
+// log emits a record for event at its level.
+func (l *Logger) log(ctx context.Context, event LogEvent, msg, name string, attrs ...slog.Attr) {
+	level := l.levels[event]
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	if l.sampler != nil && !l.sample(event, name) {
+		return
+	}
+
+	attrs = append([]slog.Attr{slog.String("policy", name), slog.String("event", string(event))}, attrs...)
+	if op := OperationFromContext(ctx); op != "" {
+		attrs = append(attrs, slog.String("operation", op))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// sample reports whether a record of event for the policy name is logged.
+// The first record dropped in a period schedules the period's summary.
+func (l *Logger) sample(event LogEvent, name string) bool {
+	s := l.sampler
+	key := sampleKey{event, name}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.periods[key]
+	if p == nil || now.Sub(p.start) >= s.period {
+		p = &samplePeriod{start: now}
+		s.periods[key] = p
+	}
+
+	p.seen++
+	if p.seen <= s.first || s.thereafter > 0 && (p.seen-s.first)%s.thereafter == 0 {
+		return true
+	}
+
+	p.dropped++
+	if p.dropped == 1 {
+		time.AfterFunc(p.start.Add(s.period).Sub(now), func() { l.summarize(key, p) })
+	}
+
+	return false
+}
+
+// summarize logs how many records of key were dropped in period p.
+func (l *Logger) summarize(key sampleKey, p *samplePeriod) {
+	s := l.sampler
+
+	s.mu.Lock()
+	dropped := p.dropped
+	if s.periods[key] == p {
+		delete(s.periods, key) // over, the next record starts a new one
+	}
+	s.mu.Unlock()
+
+	l.logger.LogAttrs(context.Background(), l.levels[key.event], "log records suppressed",
+		slog.String("policy", key.name),
+		slog.String("event", string(key.event)),
+		slog.Int("suppressed", dropped),
+		slog.Duration("period", s.period))
+}
@@ -0,0 +1,148 @@
+package failover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logRecords decodes the JSON records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func newTestLogger(buf *bytes.Buffer, opts ...LoggerOption) *Logger {
+	return NewLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})), opts...)
+}
+
+func TestLogger_Retries(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+	r := NewRetryPolicy(2, time.Millisecond, WithRetryFunc(l.RetryFunc("db")))
+
+	r.Do(context.Background(), func(context.Context) error { return errTest })
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	rec := records[0]
+	if rec["policy"] != "db" || rec["event"] != "retry" || rec["attempt"] != 1.0 || rec["error"] != errTest.Error() || rec["level"] != "INFO" {
+		t.Fatalf("Expected an INFO retry record of db's attempt 1, got %v", rec)
+	}
+	if _, ok := rec["delay"]; !ok {
+		t.Fatalf("Expected the delay in the record, got %v", rec)
+	}
+}
+
+func TestLogger_TripsAndRecoveries(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+	cb := NewCircuitBreaker(1, 1, 20*time.Millisecond, WithStateChangeFunc(l.StateChangeFunc("db")))
+
+	cb.Execute(func() error { return errTest })
+	time.Sleep(30 * time.Millisecond)
+	cb.Execute(func() error { return nil })
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected a trip and a recovery record, got %d records", len(records))
+	}
+	if records[0]["event"] != "trip" || records[0]["level"] != "WARN" {
+		t.Fatalf("Expected a WARN trip record, got %v", records[0])
+	}
+	if records[1]["event"] != "recovery" || records[1]["from"] != "half-open" {
+		t.Fatalf("Expected a recovery record from half-open, got %v", records[1])
+	}
+}
+
+func TestLogger_RejectionsWithLevel(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, WithLogLevel(LogRejection, slog.LevelError))
+	policy := l.Policy("db", NewCircuitBreaker(1, 1, time.Minute))
+
+	policy.Do(context.Background(), func(context.Context) error { return errTest })
+	policy.Do(context.Background(), func(context.Context) error { return nil })
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("Expected only the rejection to be logged, got %d records", len(records))
+	}
+	if records[0]["event"] != "rejection" || records[0]["level"] != "ERROR" {
+		t.Fatalf("Expected an ERROR rejection record, got %v", records[0])
+	}
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	t.Parallel()
+	var buf syncBuffer
+	l := NewLogger(slog.New(slog.NewJSONHandler(&buf, nil)), WithLogSampling(2, 10, 50*time.Millisecond))
+	retry := l.RetryFunc("db")
+
+	for i := range 25 {
+		retry(context.Background(), i+1, errTest, 0)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	records := logRecords(t, buf.buffer())
+	// The first 2, then the 12th and 22nd, then the summary.
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 records, got %d", len(records))
+	}
+	if records[2]["attempt"] != 12.0 || records[3]["attempt"] != 22.0 {
+		t.Fatalf("Expected attempts 12 and 22 to be sampled, got %v and %v", records[2]["attempt"], records[3]["attempt"])
+	}
+	if summary := records[4]; summary["suppressed"] != 21.0 || summary["event"] != "retry" {
+		t.Fatalf("Expected a summary of 21 suppressed retries, got %v", summary)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of a logger.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// buffer returns a copy of what was written.
+func (b *syncBuffer) buffer() *bytes.Buffer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.NewBuffer(append([]byte(nil), b.buf.Bytes()...))
+}
+
+func TestLogger_Operation(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+	r := NewRetryPolicy(2, time.Millisecond, WithRetryFunc(l.RetryFunc("db")))
+
+	r.Do(WithOperation(context.Background(), "GetUser"), func(context.Context) error { return errTest })
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["operation"] != "GetUser" {
+		t.Fatalf("Expected a record for GetUser, got %v", records)
+	}
+}
@@ -0,0 +1,105 @@
+package failover
+
+import (
+	"context"
+	"time"
+)
+
+// Tag is a dimension of a measurement, such as the policy it is about.
+type Tag struct {
+	Key, Value string
+}
+
+// MetricsSink receives the measurements of a Metrics, to forward them to a
+// metrics backend. Implementations must be safe for concurrent use and
+// should not block.
+type MetricsSink interface {
+	// Count adds value to the counter name.
+	Count(name string, value int64, tags []Tag)
+	// Timing records a duration in the distribution name.
+	Timing(name string, d time.Duration, tags []Tag)
+	// Gauge sets the gauge name to value.
+	Gauge(name string, value float64, tags []Tag)
+}
+
+// Metrics records what policies do to a MetricsSink:
+//
+//   - failover.attempts counts the calls policies make to their function,
+//     tagged by outcome, and failover.attempt.duration times them.
+//   - failover.rejections counts the calls policies turned away, tagged by
+//     reason.
+//   - failover.retries counts retries.
+//   - failover.breaker.trips counts breakers tripping open, and
+//     failover.breaker.state is their state: 0 closed, 1 open, 2 half-open.
+//
+// Every measurement is tagged with the policy name, and also with the
+// operation when the call's context names one with WithOperation. Attach
+// it with Policy, RetryFunc and StateChangeFunc.
+type Metrics struct {
+	sink MetricsSink
+}
+
+// NewMetrics creates a Metrics recording to sink.
+func NewMetrics(sink MetricsSink) *Metrics {
+	return &Metrics{sink: sink}
+}
+
+// Policy returns policy decorated to record its attempts, their duration
+// and its rejections under name.
+func (m *Metrics) Policy(name string, policy Policy) Policy {
+	return PolicyFunc(func(ctx context.Context, fn WorkFuncCtx) error {
+		err := policy.Do(ctx, func(ctx context.Context) error {
+			start := time.Now()
+			err := fn(ctx)
+
+			outcome := "success"
+			if err != nil {
+				outcome = "failure"
+			}
+			tags := withOperation(ctx, []Tag{{"policy", name}, {"outcome", outcome}})
+			m.sink.Count("failover.attempts", 1, tags)
+			m.sink.Timing("failover.attempt.duration", time.Since(start), tags)
+
+			return err
+		})
+
+		if reason := RejectionReason(err); reason != "" {
+			m.sink.Count("failover.rejections", 1, withOperation(ctx, []Tag{{"policy", name}, {"reason", reason}}))
+		}
+
+		return err
+	})
+}
+
+// RetryFunc returns a function for WithRetryFunc that counts the retries of
+// the policy name.
+func (m *Metrics) RetryFunc(name string) RetryFunc {
+	tags := []Tag{{"policy", name}}
+
+	return func(ctx context.Context, _ int, _ error, _ time.Duration) {
+		m.sink.Count("failover.retries", 1, withOperation(ctx, tags))
+	}
+}
+
+// StateChangeFunc returns a function for WithStateChangeFunc that records
+// the state and trips of the breaker name.
+func (m *Metrics) StateChangeFunc(name string) func(from, to State) {
+	tags := []Tag{{"policy", name}}
+
+	return func(_, to State) {
+		m.sink.Gauge("failover.breaker.state", float64(to), tags)
+		if to == Open {
+			m.sink.Count("failover.breaker.trips", 1, tags)
+		}
+	}
+}
+
+// withOperation returns tags with the operation of ctx added, if it names
+// one.
+func withOperation(ctx context.Context, tags []Tag) []Tag {
+	if op := OperationFromContext(ctx); op != "" {
+		return append(tags[:len(tags):len(tags)], Tag{"operation", op})
+	}
+
+	return tags
+}
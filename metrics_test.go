@@ -0,0 +1,98 @@
+package failover
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink is a MetricsSink remembering every measurement as
+// "kind name tags".
+type recordingSink struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (s *recordingSink) record(kind, name string, tags []Tag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := kind + " " + name
+	for _, t := range tags {
+		record += " " + t.Key + "=" + t.Value
+	}
+	s.records = append(s.records, record)
+}
+
+func (s *recordingSink) Count(name string, _ int64, tags []Tag) { s.record("count", name, tags) }
+func (s *recordingSink) Timing(name string, _ time.Duration, tags []Tag) {
+	s.record("timing", name, tags)
+}
+func (s *recordingSink) Gauge(name string, _ float64, tags []Tag) { s.record("gauge", name, tags) }
+
+func (s *recordingSink) has(record string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Contains(s.records, record)
+}
+
+func TestMetrics_Policy(t *testing.T) {
+	t.Parallel()
+	sink := &recordingSink{}
+	m := NewMetrics(sink)
+	retry := NewRetryPolicy(2, time.Millisecond, WithRetryFunc(m.RetryFunc("db")))
+
+	m.Policy("db", retry).Do(context.Background(), func(context.Context) error { return errTest })
+
+	for _, want := range []string{
+		"count failover.attempts policy=db outcome=failure",
+		"timing failover.attempt.duration policy=db outcome=failure",
+		"count failover.retries policy=db",
+	} {
+		if !sink.has(want) {
+			t.Fatalf("Expected %q among %v", want, sink.records)
+		}
+	}
+}
+
+func TestMetrics_Breaker(t *testing.T) {
+	t.Parallel()
+	sink := &recordingSink{}
+	m := NewMetrics(sink)
+	cb := NewCircuitBreaker(1, 1, time.Minute, WithStateChangeFunc(m.StateChangeFunc("db")))
+	policy := m.Policy("db", cb)
+
+	policy.Do(context.Background(), func(context.Context) error { return errTest })
+	policy.Do(context.Background(), func(context.Context) error { return nil })
+
+	for _, want := range []string{
+		"count failover.breaker.trips policy=db",
+		"gauge failover.breaker.state policy=db",
+		"count failover.rejections policy=db reason=circuit_open",
+	} {
+		if !sink.has(want) {
+			t.Fatalf("Expected %q among %v", want, sink.records)
+		}
+	}
+}
+
+func TestMetrics_Operation(t *testing.T) {
+	t.Parallel()
+	sink := &recordingSink{}
+	m := NewMetrics(sink)
+	retry := NewRetryPolicy(2, time.Millisecond, WithRetryFunc(m.RetryFunc("db")))
+	ctx := WithOperation(context.Background(), "GetUser")
+
+	m.Policy("db", retry).Do(ctx, func(context.Context) error { return errTest })
+
+	for _, want := range []string{
+		"count failover.attempts policy=db outcome=failure operation=GetUser",
+		"count failover.retries policy=db operation=GetUser",
+	} {
+		if !sink.has(want) {
+			t.Fatalf("Expected %q among %v", want, sink.records)
+		}
+	}
+}
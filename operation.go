@@ -0,0 +1,60 @@
+package failover
+
+import (
+	"context"
+	"errors"
+)
+
+// operationKey is the context key for the name of an operation.
+type operationKey struct{}
+
+// WithOperation returns a copy of ctx naming the operation its calls are
+// for, such as "GetUser". The name goes wherever the call's context does:
+// Logger, Metrics and the hooks that take a context tag what they report
+// with it, dead letters record it, and a Pipeline wraps the call's error in
+// an OperationError. The telemetry of a breaker shared between call sites
+// can so be broken down by operation.
+func WithOperation(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, operationKey{}, name)
+}
+
+// OperationFromContext returns the name of the operation carried by ctx,
+// or "" if it carries none.
+func OperationFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(operationKey{}).(string)
+	return name
+}
+
+// OperationError is the failure of a call made for a named operation, as
+// returned by a Pipeline for a context set with WithOperation.
+type OperationError struct {
+	Operation string // Name of the operation
+	Err       error  // Error of the call
+}
+
+// Error implements error.
+func (e *OperationError) Error() string {
+	return e.Operation + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the call.
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// operationError returns err wrapped in an OperationError for the operation
+// of ctx, if ctx names one and err is not already wrapped for it, as by a
+// pipeline nested in another.
+func operationError(ctx context.Context, err error) error {
+	name := OperationFromContext(ctx)
+	if err == nil || name == "" {
+		return err
+	}
+
+	var oe *OperationError
+	if errors.As(err, &oe) && oe.Operation == name {
+		return err
+	}
+
+	return &OperationError{Operation: name, Err: err}
+}
@@ -0,0 +1,45 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperationFromContext(t *testing.T) {
+	t.Parallel()
+	if op := OperationFromContext(context.Background()); op != "" {
+		t.Fatalf("Expected no operation, got %q", op)
+	}
+
+	ctx := WithOperation(context.Background(), "GetUser")
+	if op := OperationFromContext(ctx); op != "GetUser" {
+		t.Fatalf("Expected GetUser, got %q", op)
+	}
+}
+
+func TestPipeline_OperationError(t *testing.T) {
+	t.Parallel()
+	inner := NewPipeline(WithRetry(NewRetryPolicy(2, time.Millisecond)))
+	outer := NewPipeline(WithPolicy(inner))
+	ctx := WithOperation(context.Background(), "GetUser")
+
+	err := outer.Execute(ctx, func(context.Context) error { return errTest })
+
+	var oe *OperationError
+	if !errors.As(err, &oe) || oe.Operation != "GetUser" {
+		t.Fatalf("Expected an OperationError for GetUser, got %v", err)
+	}
+	if errors.As(oe.Err, new(*OperationError)) {
+		t.Fatalf("Expected a single OperationError, got %v", err)
+	}
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected %v to be wrapped, got %v", errTest, err)
+	}
+
+	err = outer.Execute(context.Background(), func(context.Context) error { return errTest })
+	if errors.As(err, new(*OperationError)) {
+		t.Fatalf("Expected no OperationError without an operation, got %v", err)
+	}
+}
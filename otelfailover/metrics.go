@@ -0,0 +1,190 @@
+// Package otelfailover instruments the failover primitives with
+// OpenTelemetry.
+package otelfailover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// instrumentationName identifies the package to OpenTelemetry.
+const instrumentationName = "github.com/dadanrm/failover/otelfailover"
+
+// Attribute keys set on every measurement.
+const (
+	PolicyKey    = attribute.Key("failover.policy")    // Name the policy was instrumented under
+	OutcomeKey   = attribute.Key("failover.outcome")   // "success" or "failure", on attempts
+	ReasonKey    = attribute.Key("failover.reason")    // Why a call was rejected, on rejections
+	OperationKey = attribute.Key("failover.operation") // Operation named with failover.WithOperation, if any
+)
+
+// AttributesFunc returns extra attributes for a measurement of the policy
+// instrumented as name, made for a call with ctx that ended with err. State
+// changes are measured with a background context and a nil error.
+type AttributesFunc func(ctx context.Context, name string, err error) []attribute.KeyValue
+
+// Metrics records what failover policies do as OpenTelemetry metrics:
+//
+//   - failover.attempts counts the calls policies make to their function,
+//     by outcome, so a retried call counts once per attempt.
+//   - failover.attempt.duration is the latency of those attempts.
+//   - failover.rejections counts the calls policies turned away, such as
+//     with ErrCircuitOpen or ErrBulkheadFull, by reason.
+//   - failover.breaker.trips counts breakers tripping open.
+//   - failover.breaker.open.duration is how long breakers stayed open.
+//
+// The first three come from policies decorated with Policy, the breaker
+// metrics from breakers created with StateChangeFunc.
+type Metrics struct {
+	attempts        metric.Int64Counter
+	rejections      metric.Int64Counter
+	trips           metric.Int64Counter
+	attemptDuration metric.Float64Histogram
+	openDuration    metric.Float64Histogram
+
+	attrs     []attribute.KeyValue // Set on every measurement
+	attrsFunc AttributesFunc       // Optional, adds attributes per measurement
+}
+
+// config holds the options of NewMetrics.
+type config struct {
+	provider  metric.MeterProvider
+	attrs     []attribute.KeyValue
+	attrsFunc AttributesFunc
+}
+
+// Option configures optional Metrics behavior.
+type Option func(*config)
+
+// WithMeterProvider records through p instead of the global meter provider.
+func WithMeterProvider(p metric.MeterProvider) Option {
+	return func(c *config) {
+		c.provider = p
+	}
+}
+
+// WithAttributes sets attrs on every measurement.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = attrs
+	}
+}
+
+// WithAttributesFunc adds the attributes fn returns to every measurement.
+func WithAttributesFunc(fn AttributesFunc) Option {
+	return func(c *config) {
+		c.attrsFunc = fn
+	}
+}
+
+// NewMetrics creates the instruments of Metrics.
+func NewMetrics(opts ...Option) (*Metrics, error) {
+	c := config{provider: otel.GetMeterProvider()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.provider.Meter(instrumentationName)
+	m := &Metrics{attrs: c.attrs, attrsFunc: c.attrsFunc}
+
+	var errs [5]error
+	m.attempts, errs[0] = meter.Int64Counter("failover.attempts",
+		metric.WithDescription("Calls made by policies to their function."),
+		metric.WithUnit("{attempt}"))
+	m.rejections, errs[1] = meter.Int64Counter("failover.rejections",
+		metric.WithDescription("Calls turned away by policies without running them."),
+		metric.WithUnit("{call}"))
+	m.trips, errs[2] = meter.Int64Counter("failover.breaker.trips",
+		metric.WithDescription("Circuit breakers tripping open."),
+		metric.WithUnit("{trip}"))
+	m.attemptDuration, errs[3] = meter.Float64Histogram("failover.attempt.duration",
+		metric.WithDescription("Duration of the calls made by policies."),
+		metric.WithUnit("s"))
+	m.openDuration, errs[4] = meter.Float64Histogram("failover.breaker.open.duration",
+		metric.WithDescription("Time circuit breakers spent open."),
+		metric.WithUnit("s"))
+
+	if err := errors.Join(errs[:]...); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Policy returns policy decorated to record its attempts, their duration
+// and its rejections under name.
+func (m *Metrics) Policy(name string, policy failover.Policy) failover.Policy {
+	return failover.PolicyFunc(func(ctx context.Context, fn failover.WorkFuncCtx) error {
+		err := policy.Do(ctx, func(ctx context.Context) error {
+			start := time.Now()
+			err := fn(ctx)
+
+			outcome := "success"
+			if err != nil {
+				outcome = "failure"
+			}
+			set := m.attributes(ctx, name, err, OutcomeKey.String(outcome))
+			m.attempts.Add(ctx, 1, set)
+			m.attemptDuration.Record(ctx, time.Since(start).Seconds(), set)
+
+			return err
+		})
+
+		if reason := failover.RejectionReason(err); reason != "" {
+			m.rejections.Add(ctx, 1, m.attributes(ctx, name, err, ReasonKey.String(reason)))
+		}
+
+		return err
+	})
+}
+
+// StateChangeFunc returns a function for failover.WithStateChangeFunc that
+// records the trips of the breaker under name and how long it stays open.
+// A breaker's time open ends when the first call after its open timeout
+// moves it to HalfOpen.
+func (m *Metrics) StateChangeFunc(name string) func(from, to failover.State) {
+	var (
+		mu       sync.Mutex
+		openedAt time.Time
+	)
+
+	return func(from, to failover.State) {
+		ctx := context.Background()
+		set := m.attributes(ctx, name, nil)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if from == failover.Open && !openedAt.IsZero() {
+			m.openDuration.Record(ctx, time.Since(openedAt).Seconds(), set)
+			openedAt = time.Time{}
+		}
+		if to == failover.Open {
+			m.trips.Add(ctx, 1, set)
+			openedAt = time.Now()
+		}
+	}
+}
+
+// attributes returns the attributes of a measurement for the policy name.
+func (m *Metrics) attributes(ctx context.Context, name string, err error, extra ...attribute.KeyValue) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(m.attrs)+len(extra)+2)
+	attrs = append(attrs, PolicyKey.String(name))
+	if op := failover.OperationFromContext(ctx); op != "" {
+		attrs = append(attrs, OperationKey.String(op))
+	}
+	attrs = append(attrs, m.attrs...)
+	attrs = append(attrs, extra...)
+	if m.attrsFunc != nil {
+		attrs = append(attrs, m.attrsFunc(ctx, name, err)...)
+	}
+
+	return metric.WithAttributes(attrs...)
+}
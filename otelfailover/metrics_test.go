@@ -0,0 +1,171 @@
+package otelfailover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var errTest = errors.New("test error")
+
+// newTestMetrics returns Metrics recording into a manual reader.
+func newTestMetrics(t *testing.T, opts ...Option) (*Metrics, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	m, err := NewMetrics(append([]Option{WithMeterProvider(provider)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	return m, reader
+}
+
+// collect returns the data of the metric name.
+func collect(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	return nil
+}
+
+// count returns the value of the counter name for points with attr.
+func count(t *testing.T, reader *sdkmetric.ManualReader, name string, attr attribute.KeyValue) int64 {
+	t.Helper()
+	sum, ok := collect(t, reader, name).(metricdata.Sum[int64])
+	if !ok {
+		return 0
+	}
+
+	var total int64
+	for _, p := range sum.DataPoints {
+		if v, ok := p.Attributes.Value(attr.Key); ok && v == attr.Value {
+			total += p.Value
+		}
+	}
+	return total
+}
+
+// observations returns the number of values recorded in the histogram name.
+func observations(t *testing.T, reader *sdkmetric.ManualReader, name string) uint64 {
+	t.Helper()
+	hist, ok := collect(t, reader, name).(metricdata.Histogram[float64])
+	if !ok {
+		return 0
+	}
+
+	var total uint64
+	for _, p := range hist.DataPoints {
+		total += p.Count
+	}
+	return total
+}
+
+func TestMetrics_Policy(t *testing.T) {
+	t.Parallel()
+	m, reader := newTestMetrics(t)
+	policy := m.Policy("db", failover.NewRetryPolicy(3, time.Millisecond))
+
+	calls := 0
+	err := policy.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTest
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if n := count(t, reader, "failover.attempts", OutcomeKey.String("failure")); n != 2 {
+		t.Fatalf("Expected 2 failed attempts, got %d", n)
+	}
+	if n := count(t, reader, "failover.attempts", OutcomeKey.String("success")); n != 1 {
+		t.Fatalf("Expected 1 successful attempt, got %d", n)
+	}
+	if n := observations(t, reader, "failover.attempt.duration"); n != 3 {
+		t.Fatalf("Expected 3 attempt durations, got %d", n)
+	}
+}
+
+func TestMetrics_Rejections(t *testing.T) {
+	t.Parallel()
+	m, reader := newTestMetrics(t)
+	breaker := failover.NewCircuitBreaker(1, 1, time.Minute)
+	policy := m.Policy("db", breaker)
+
+	policy.Do(context.Background(), func(context.Context) error { return errTest })
+	err := policy.Do(context.Background(), func(context.Context) error { return nil })
+
+	if !errors.Is(err, failover.ErrCircuitOpen) {
+		t.Fatalf("Expected %v, got %v", failover.ErrCircuitOpen, err)
+	}
+	if n := count(t, reader, "failover.rejections", ReasonKey.String("circuit_open")); n != 1 {
+		t.Fatalf("Expected 1 rejection, got %d", n)
+	}
+}
+
+func TestMetrics_StateChangeFunc(t *testing.T) {
+	t.Parallel()
+	m, reader := newTestMetrics(t)
+	breaker := failover.NewCircuitBreaker(1, 1, 20*time.Millisecond,
+		failover.WithStateChangeFunc(m.StateChangeFunc("db")))
+
+	breaker.Execute(func() error { return errTest })
+	time.Sleep(30 * time.Millisecond)
+	breaker.Execute(func() error { return nil })
+
+	if n := count(t, reader, "failover.breaker.trips", PolicyKey.String("db")); n != 1 {
+		t.Fatalf("Expected 1 trip, got %d", n)
+	}
+	if n := observations(t, reader, "failover.breaker.open.duration"); n != 1 {
+		t.Fatalf("Expected 1 open duration, got %d", n)
+	}
+}
+
+func TestMetrics_Attributes(t *testing.T) {
+	t.Parallel()
+	m, reader := newTestMetrics(t,
+		WithAttributes(attribute.String("region", "eu")),
+		WithAttributesFunc(func(_ context.Context, _ string, err error) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.Bool("error", err != nil)}
+		}))
+
+	m.Policy("db", failover.NoopRetrier{}).Do(context.Background(), func(context.Context) error { return errTest })
+
+	if n := count(t, reader, "failover.attempts", attribute.String("region", "eu")); n != 1 {
+		t.Fatalf("Expected the static attribute on the attempt, got %d attempts with it", n)
+	}
+	if n := count(t, reader, "failover.attempts", attribute.Bool("error", true)); n != 1 {
+		t.Fatalf("Expected the attribute hook on the attempt, got %d attempts with it", n)
+	}
+}
+
+func TestMetrics_Operation(t *testing.T) {
+	t.Parallel()
+	m, reader := newTestMetrics(t)
+	policy := m.Policy("db", failover.NewRetryPolicy(1, time.Millisecond))
+
+	policy.Do(failover.WithOperation(context.Background(), "GetUser"), func(context.Context) error { return nil })
+
+	if n := count(t, reader, "failover.attempts", OperationKey.String("GetUser")); n != 1 {
+		t.Fatalf("Expected 1 attempt of GetUser, got %d", n)
+	}
+}
@@ -0,0 +1,137 @@
+package otelfailover
+
+import (
+	"context"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys set on spans and span events.
+const (
+	AttemptKey = attribute.Key("failover.attempt")       // Number of the attempt, from 1
+	BackoffKey = attribute.Key("failover.backoff")       // Wait before the next attempt, in seconds
+	StateKey   = attribute.Key("failover.breaker.state") // State of the breaker when the call ended
+)
+
+// Tracer records what failover policies do as OpenTelemetry spans:
+// policies decorated with Policy run inside a span of their own, with an
+// event for every attempt, and RetryFunc adds an event with the backoff
+// before each retry to the span of the call.
+type Tracer struct {
+	tracer    trace.Tracer
+	attrs     []attribute.KeyValue // Set on every span
+	attrsFunc AttributesFunc       // Optional, adds attributes per span
+}
+
+// tracerConfig holds the options of NewTracer.
+type tracerConfig struct {
+	provider  trace.TracerProvider
+	attrs     []attribute.KeyValue
+	attrsFunc AttributesFunc
+}
+
+// TracerOption configures optional Tracer behavior.
+type TracerOption func(*tracerConfig)
+
+// WithTracerProvider creates spans through p instead of the global tracer
+// provider.
+func WithTracerProvider(p trace.TracerProvider) TracerOption {
+	return func(c *tracerConfig) {
+		c.provider = p
+	}
+}
+
+// WithSpanAttributes sets attrs on every span.
+func WithSpanAttributes(attrs ...attribute.KeyValue) TracerOption {
+	return func(c *tracerConfig) {
+		c.attrs = attrs
+	}
+}
+
+// WithSpanAttributesFunc adds the attributes fn returns to every span, when
+// it ends.
+func WithSpanAttributesFunc(fn AttributesFunc) TracerOption {
+	return func(c *tracerConfig) {
+		c.attrsFunc = fn
+	}
+}
+
+// NewTracer creates a Tracer.
+func NewTracer(opts ...TracerOption) *Tracer {
+	c := tracerConfig{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &Tracer{
+		tracer:    c.provider.Tracer(instrumentationName),
+		attrs:     c.attrs,
+		attrsFunc: c.attrsFunc,
+	}
+}
+
+// Policy returns policy decorated to run every call in a span named name.
+// Each attempt adds a failover.attempt event with its number and error. A
+// call the policy turns away is marked with its reason, and if policy is a
+// breaker, the span records the breaker's state when the call ends. Failed
+// calls set the span status to Error.
+func (t *Tracer) Policy(name string, policy failover.Policy) failover.Policy {
+	breaker, _ := policy.(interface{ State() failover.State })
+
+	return failover.PolicyFunc(func(ctx context.Context, fn failover.WorkFuncCtx) error {
+		attrs := append([]attribute.KeyValue{PolicyKey.String(name)}, t.attrs...)
+		if op := failover.OperationFromContext(ctx); op != "" {
+			attrs = append(attrs, OperationKey.String(op))
+		}
+		ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+		defer span.End()
+
+		attempt := 0
+		err := policy.Do(ctx, func(ctx context.Context) error {
+			attempt++
+			err := fn(ctx)
+
+			eventAttrs := []attribute.KeyValue{AttemptKey.Int(attempt)}
+			if err != nil {
+				eventAttrs = append(eventAttrs, attribute.String("error", err.Error()))
+			}
+			span.AddEvent("failover.attempt", trace.WithAttributes(eventAttrs...))
+
+			return err
+		})
+
+		if reason := failover.RejectionReason(err); reason != "" {
+			span.SetAttributes(ReasonKey.String(reason))
+		}
+		if breaker != nil {
+			span.SetAttributes(StateKey.String(breaker.State().String()))
+		}
+		if t.attrsFunc != nil {
+			span.SetAttributes(t.attrsFunc(ctx, name, err)...)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	})
+}
+
+// RetryFunc returns a function for failover.WithRetryFunc that adds a
+// failover.retry event, with the failed attempt's number and error and the
+// backoff before the next, to the span of the call being retried.
+func (t *Tracer) RetryFunc() failover.RetryFunc {
+	return func(ctx context.Context, attempt int, err error, delay time.Duration) {
+		trace.SpanFromContext(ctx).AddEvent("failover.retry", trace.WithAttributes(
+			AttemptKey.Int(attempt),
+			BackoffKey.Float64(delay.Seconds()),
+			attribute.String("error", err.Error()),
+		))
+	}
+}
@@ -0,0 +1,117 @@
+package otelfailover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestTracer returns a Tracer recording into a span recorder.
+func newTestTracer(opts ...TracerOption) (*Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewTracer(append([]TracerOption{WithTracerProvider(provider)}, opts...)...), recorder
+}
+
+// attr returns the value of key among attrs.
+func attr(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracer_RetryEvents(t *testing.T) {
+	t.Parallel()
+	tracer, recorder := newTestTracer()
+	retry := failover.NewRetryPolicy(3, time.Millisecond, failover.WithRetryFunc(tracer.RetryFunc()))
+	policy := tracer.Policy("db.query", retry)
+
+	calls := 0
+	policy.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTest
+		}
+		return nil
+	})
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	var attempts, retries int
+	for _, e := range spans[0].Events() {
+		switch e.Name {
+		case "failover.attempt":
+			attempts++
+		case "failover.retry":
+			retries++
+			if v, ok := attr(e.Attributes, BackoffKey); !ok || v.AsFloat64() <= 0 {
+				t.Fatalf("Expected a backoff on the retry event, got %v", e.Attributes)
+			}
+		}
+	}
+	if attempts != 3 || retries != 2 {
+		t.Fatalf("Expected 3 attempt and 2 retry events, got %d and %d", attempts, retries)
+	}
+	if code := spans[0].Status().Code; code != codes.Unset {
+		t.Fatalf("Expected the successful call to leave the status unset, got %v", code)
+	}
+}
+
+func TestTracer_BreakerRejection(t *testing.T) {
+	t.Parallel()
+	tracer, recorder := newTestTracer()
+	policy := tracer.Policy("db.query", failover.NewCircuitBreaker(1, 1, time.Minute))
+
+	policy.Do(context.Background(), func(context.Context) error { return errTest })
+	policy.Do(context.Background(), func(context.Context) error { return nil })
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	rejected := spans[1]
+	if v, _ := attr(rejected.Attributes(), ReasonKey); v.AsString() != "circuit_open" {
+		t.Fatalf("Expected reason circuit_open, got %q", v.AsString())
+	}
+	if v, _ := attr(rejected.Attributes(), StateKey); v.AsString() != "open" {
+		t.Fatalf("Expected breaker state open, got %q", v.AsString())
+	}
+	if code := rejected.Status().Code; code != codes.Error {
+		t.Fatalf("Expected status %v, got %v", codes.Error, code)
+	}
+	if n := len(rejected.Events()); n != 1 || rejected.Events()[0].Name != "exception" {
+		t.Fatalf("Expected only the recorded error as event, got %d events", n)
+	}
+}
+
+func TestTracer_Attributes(t *testing.T) {
+	t.Parallel()
+	tracer, recorder := newTestTracer(
+		WithSpanAttributes(attribute.String("region", "eu")),
+		WithSpanAttributesFunc(func(context.Context, string, error) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("tenant", "acme")}
+		}))
+
+	tracer.Policy("db.query", failover.NoopRetrier{}).Do(context.Background(), func(context.Context) error { return nil })
+
+	attrs := recorder.Ended()[0].Attributes()
+	if v, _ := attr(attrs, "region"); v.AsString() != "eu" {
+		t.Fatalf("Expected region eu, got %q", v.AsString())
+	}
+	if v, _ := attr(attrs, "tenant"); v.AsString() != "acme" {
+		t.Fatalf("Expected tenant acme, got %q", v.AsString())
+	}
+}
@@ -0,0 +1,259 @@
+package failover
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// outlierMinHosts is how many endpoints need enough volume in an interval
+// before success rates are compared; with fewer the mean is meaningless.
+const outlierMinHosts = 3
+
+// outlierStats is what an OutlierDetector tracks per endpoint.
+type outlierStats struct {
+	consecutive  int // Consecutive failures
+	successes    int // Outcomes in the current interval
+	failures     int
+	ejections    int // Ejection multiplier, grows on ejection and decays while healthy
+	ejectedUntil time.Time
+}
+
+// OutlierDetector ejects misbehaving endpoints from a Balancer in the style
+// of Envoy's outlier detection, independently of any per-endpoint breaker.
+// An endpoint is ejected after a run of consecutive failures, or when its
+// success rate in an interval falls far below that of its peers. Each
+// ejection lasts the base ejection time multiplied by how many times the
+// endpoint was recently ejected, and the multiplier decays by one for every
+// interval the endpoint spends admitted, so repeat offenders are ejected
+// for longer.
+type OutlierDetector struct {
+	consecutive   int           // Consecutive failures that eject, zero to disable
+	baseEjection  time.Duration // Ejection time per ejection
+	maxEjectedPct int           // Cap on the share of endpoints ejected at once
+	minRequests   int           // Volume an endpoint needs for its success rate to count
+	stdevFactor   float64       // Distance below the mean, in standard deviations, that ejects; zero to disable
+	interval      time.Duration // How often success rates are analyzed
+	isFailure     func(error) bool
+
+	mu    sync.Mutex // Protects stats
+	stats map[*Endpoint]*outlierStats
+}
+
+// OutlierOption configures optional OutlierDetector behavior.
+type OutlierOption func(*OutlierDetector)
+
+// WithConsecutiveErrors ejects an endpoint after n consecutive failures.
+// The default is 5; zero disables it.
+func WithConsecutiveErrors(n int) OutlierOption {
+	return func(o *OutlierDetector) {
+		o.consecutive = n
+	}
+}
+
+// WithBaseEjectionTime sets how long a first ejection lasts. The default is
+// 30 seconds.
+func WithBaseEjectionTime(d time.Duration) OutlierOption {
+	return func(o *OutlierDetector) {
+		o.baseEjection = d
+	}
+}
+
+// WithMaxEjectionPercent caps the share of endpoints ejected at once. One
+// endpoint can always be ejected. The default is 10.
+func WithMaxEjectionPercent(pct int) OutlierOption {
+	return func(o *OutlierDetector) {
+		o.maxEjectedPct = pct
+	}
+}
+
+// WithSuccessRateEjection ejects, at every analysis interval, endpoints whose
+// success rate is more than stdevFactor standard deviations below the mean
+// of their peers. Only endpoints with at least minRequests calls in the
+// interval are considered, and only when at least three of them qualify.
+func WithSuccessRateEjection(minRequests int, stdevFactor float64) OutlierOption {
+	return func(o *OutlierDetector) {
+		o.minRequests = minRequests
+		o.stdevFactor = stdevFactor
+	}
+}
+
+// WithOutlierClassifier sets which errors count as failures, e.g. only
+// 5xx responses. By default every non-nil error does.
+func WithOutlierClassifier(isFailure func(error) bool) OutlierOption {
+	return func(o *OutlierDetector) {
+		o.isFailure = isFailure
+	}
+}
+
+// NewOutlierDetector creates an OutlierDetector that analyzes success rates
+// every interval once Run is called.
+func NewOutlierDetector(interval time.Duration, opts ...OutlierOption) *OutlierDetector {
+	o := &OutlierDetector{
+		consecutive:   5,
+		baseEjection:  30 * time.Second,
+		maxEjectedPct: 10,
+		interval:      interval,
+		isFailure:     func(err error) bool { return err != nil },
+		stats:         make(map[*Endpoint]*outlierStats),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Record folds the outcome of a call to ep into its statistics, ejecting
+// the endpoint if it reached the consecutive failure threshold.
+func (o *OutlierDetector) Record(ep *Endpoint, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.statsFor(ep)
+	if err == nil || !o.isFailure(err) {
+		s.successes++
+		s.consecutive = 0
+		return
+	}
+
+	s.failures++
+	s.consecutive++
+	if o.consecutive > 0 && s.consecutive >= o.consecutive {
+		o.eject(s, time.Now())
+	}
+}
+
+// Ejected reports whether ep is currently ejected.
+func (o *OutlierDetector) Ejected(ep *Endpoint) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s, ok := o.stats[ep]
+	return ok && time.Now().Before(s.ejectedUntil)
+}
+
+// SetEndpoints drops the statistics of endpoints not in endpoints, so that
+// removed endpoints neither leak nor count towards the ejection cap. An
+// endpoint replaced by one with the same name and address keeps its
+// statistics. A Balancer calls it from its own SetEndpoints.
+func (o *OutlierDetector) SetEndpoints(endpoints []*Endpoint) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := make(map[*Endpoint]*outlierStats, len(endpoints))
+	for ep, s := range o.stats {
+		for _, next := range endpoints {
+			if next == ep || next.Name == ep.Name && next.Address == ep.Address {
+				stats[next] = s
+				break
+			}
+		}
+	}
+	o.stats = stats
+}
+
+// Run analyzes success rates every interval until ctx is done, then
+// returns ctx.Err().
+func (o *OutlierDetector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			o.Analyze()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Analyze ends the current interval: it ejects success rate outliers,
+// decays the ejection multiplier of healthy endpoints and resets the
+// interval counts. Run calls it on every interval.
+func (o *OutlierDetector) Analyze() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	for _, ep := range o.rateOutliers() {
+		o.eject(o.stats[ep], now)
+	}
+
+	for _, s := range o.stats {
+		if !now.Before(s.ejectedUntil) && s.ejections > 0 {
+			s.ejections--
+		}
+		s.successes, s.failures = 0, 0
+	}
+}
+
+// rateOutliers returns the endpoints whose success rate in the interval is
+// too far below the mean.
+func (o *OutlierDetector) rateOutliers() []*Endpoint {
+	if o.stdevFactor == 0 {
+		return nil
+	}
+
+	rates := make(map[*Endpoint]float64)
+	var sum float64
+	for ep, s := range o.stats {
+		if total := s.successes + s.failures; total > 0 && total >= o.minRequests {
+			rates[ep] = float64(s.successes) / float64(total)
+			sum += rates[ep]
+		}
+	}
+	if len(rates) < outlierMinHosts {
+		return nil
+	}
+
+	mean := sum / float64(len(rates))
+	var variance float64
+	for _, r := range rates {
+		variance += (r - mean) * (r - mean)
+	}
+	threshold := mean - o.stdevFactor*math.Sqrt(variance/float64(len(rates)))
+
+	var outliers []*Endpoint
+	for ep, r := range rates {
+		if r < threshold {
+			outliers = append(outliers, ep)
+		}
+	}
+
+	return outliers
+}
+
+// eject ejects the endpoint of s unless that would exceed the ejection cap.
+func (o *OutlierDetector) eject(s *outlierStats, now time.Time) {
+	if now.Before(s.ejectedUntil) {
+		return // already ejected
+	}
+
+	ejected := 0
+	for _, other := range o.stats {
+		if now.Before(other.ejectedUntil) {
+			ejected++
+		}
+	}
+	if ejected > 0 && (ejected+1)*100 > o.maxEjectedPct*len(o.stats) {
+		return
+	}
+
+	s.ejections++
+	s.consecutive = 0
+	s.ejectedUntil = now.Add(o.baseEjection * time.Duration(s.ejections))
+}
+
+func (o *OutlierDetector) statsFor(ep *Endpoint) *outlierStats {
+	s, ok := o.stats[ep]
+	if !ok {
+		s = &outlierStats{}
+		o.stats[ep] = s
+	}
+
+	return s
+}
@@ -0,0 +1,163 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutlierDetector_ConsecutiveErrors(t *testing.T) {
+	t.Parallel()
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(3), WithMaxEjectionPercent(100))
+	ep := &Endpoint{Name: "a"}
+
+	o.Record(ep, errTest)
+	o.Record(ep, errTest)
+	o.Record(ep, nil) // resets the run
+	o.Record(ep, errTest)
+	o.Record(ep, errTest)
+	if o.Ejected(ep) {
+		t.Fatal("Expected endpoint not to be ejected before 3 consecutive errors")
+	}
+
+	o.Record(ep, errTest)
+	if !o.Ejected(ep) {
+		t.Fatal("Expected endpoint to be ejected after 3 consecutive errors")
+	}
+}
+
+func TestOutlierDetector_GrowingEjection(t *testing.T) {
+	t.Parallel()
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(1), WithBaseEjectionTime(20*time.Millisecond), WithMaxEjectionPercent(100))
+	ep := &Endpoint{Name: "a"}
+
+	o.Record(ep, errTest)
+	time.Sleep(25 * time.Millisecond)
+	if o.Ejected(ep) {
+		t.Fatal("Expected first ejection to last the base ejection time")
+	}
+
+	// A second ejection, without an interval admitted in between, lasts twice as long.
+	o.Record(ep, errTest)
+	time.Sleep(25 * time.Millisecond)
+	if !o.Ejected(ep) {
+		t.Fatal("Expected second ejection to last longer")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if o.Ejected(ep) {
+		t.Fatal("Expected endpoint to be re-admitted after twice the base time")
+	}
+
+	// Intervals spent admitted decay the multiplier back.
+	o.Analyze()
+	o.Analyze()
+	o.Record(ep, errTest)
+	time.Sleep(25 * time.Millisecond)
+	if o.Ejected(ep) {
+		t.Fatal("Expected the multiplier to have decayed to one")
+	}
+}
+
+func TestOutlierDetector_SuccessRate(t *testing.T) {
+	t.Parallel()
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(0), WithSuccessRateEjection(10, 1), WithMaxEjectionPercent(100))
+
+	endpoints := []*Endpoint{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "bad"}}
+	for _, ep := range endpoints {
+		for i := range 20 {
+			var err error
+			if ep.Name == "bad" && i%2 == 0 {
+				err = errTest
+			}
+			o.Record(ep, err)
+		}
+	}
+	o.Analyze()
+
+	for _, ep := range endpoints {
+		if ejected := o.Ejected(ep); ejected != (ep.Name == "bad") {
+			t.Fatalf("Expected only bad to be ejected, %s ejected=%v", ep.Name, ejected)
+		}
+	}
+}
+
+func TestOutlierDetector_MaxEjectionPercent(t *testing.T) {
+	t.Parallel()
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(1), WithMaxEjectionPercent(50))
+	endpoints := []*Endpoint{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	for _, ep := range endpoints {
+		o.Record(ep, nil)
+	}
+
+	for _, ep := range endpoints {
+		o.Record(ep, errTest)
+	}
+
+	ejected := 0
+	for _, ep := range endpoints {
+		if o.Ejected(ep) {
+			ejected++
+		}
+	}
+	if ejected != 2 {
+		t.Fatalf("Expected 2 of 4 endpoints ejected, got %d", ejected)
+	}
+}
+
+func TestOutlierDetector_Classifier(t *testing.T) {
+	t.Parallel()
+	errClient := errors.New("bad request")
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(1), WithMaxEjectionPercent(100),
+		WithOutlierClassifier(func(err error) bool { return !errors.Is(err, errClient) }))
+	ep := &Endpoint{Name: "a"}
+
+	o.Record(ep, errClient)
+	if o.Ejected(ep) {
+		t.Fatal("Expected errors the classifier ignores not to eject")
+	}
+}
+
+func TestBalancer_OutlierDetection(t *testing.T) {
+	t.Parallel()
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(2), WithMaxEjectionPercent(100))
+	b := NewBalancer([]*Endpoint{{Name: "a"}, {Name: "b"}}, WithOutlierDetection(o))
+	ctx := context.Background()
+
+	fn := func(_ context.Context, ep *Endpoint) error {
+		if ep.Name == "a" {
+			return errTest
+		}
+		return nil
+	}
+	for range 4 {
+		b.Do(ctx, fn)
+	}
+
+	counts := make(map[string]int)
+	for range 10 {
+		b.Do(ctx, countCalls(counts))
+	}
+	if counts["a"] != 0 {
+		t.Fatalf("Expected ejected endpoint to get no calls, got %v", counts)
+	}
+}
+
+func TestBalancer_SetEndpointsForgetsOutliers(t *testing.T) {
+	t.Parallel()
+	o := NewOutlierDetector(time.Hour, WithConsecutiveErrors(1), WithMaxEjectionPercent(100))
+	a, b := &Endpoint{Name: "a"}, &Endpoint{Name: "b"}
+	bal := NewBalancer([]*Endpoint{a, b}, WithOutlierDetection(o))
+
+	o.Record(a, errTest)
+	o.Record(b, nil)
+
+	// a comes back with a new weight, which replaces its *Endpoint.
+	bal.SetEndpoints([]*Endpoint{{Name: "a", Weight: 2}})
+	if n := len(o.stats); n != 1 {
+		t.Fatalf("Expected statistics for 1 endpoint, got %d", n)
+	}
+	if !o.Ejected(bal.Endpoints()[0]) {
+		t.Fatal("Expected the replaced endpoint to stay ejected")
+	}
+}
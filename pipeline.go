@@ -0,0 +1,190 @@
+package failover
+
+import (
+	"context"
+	"time"
+)
+
+// Pipeline composes resilience policies around an operation in a fixed
+// order, from outermost to innermost:
+//
+//	Fallback → Retry → CircuitBreaker → Timeout → Bulkhead → custom → fn
+//
+// so a fallback sees the final outcome after retries, every retry attempt
+// goes through the breaker, and each attempt gets its own timeout and
+// bulkhead slot. Policies that are not configured are skipped.
+type Pipeline struct {
+	fallback *Fallback
+	retry    Retrier
+	breaker  Breaker
+	timeout  *Timeout
+	bulkhead *Bulkhead
+	custom   []Policy // Innermost policies, outermost first
+}
+
+// PipelineOption adds a policy to a Pipeline.
+type PipelineOption func(*Pipeline)
+
+// WithFallback sets the fallback that handles the pipeline's final failure.
+func WithFallback(f *Fallback) PipelineOption {
+	return func(p *Pipeline) {
+		p.fallback = f
+	}
+}
+
+// WithRetry sets the retrier that repeats failed attempts.
+func WithRetry(r Retrier) PipelineOption {
+	return func(p *Pipeline) {
+		p.retry = r
+	}
+}
+
+// WithBreaker sets the circuit breaker each attempt goes through.
+func WithBreaker(b Breaker) PipelineOption {
+	return func(p *Pipeline) {
+		p.breaker = b
+	}
+}
+
+// WithTimeout bounds each attempt to d.
+func WithTimeout(d time.Duration, opts ...TimeoutOption) PipelineOption {
+	return func(p *Pipeline) {
+		p.timeout = NewTimeout(d, opts...)
+	}
+}
+
+// WithBulkhead sets the bulkhead each attempt must get a slot from.
+func WithBulkhead(b *Bulkhead) PipelineOption {
+	return func(p *Pipeline) {
+		p.bulkhead = b
+	}
+}
+
+// WithPolicy adds an arbitrary Policy, such as a RateLimiter, innermost
+// in the pipeline. Policies added this way wrap fn in the order given, the
+// first being the outermost.
+func WithPolicy(policy Policy) PipelineOption {
+	return func(p *Pipeline) {
+		p.custom = append(p.custom, policy)
+	}
+}
+
+// NewPipeline creates a Pipeline from the given policies.
+func NewPipeline(opts ...PipelineOption) *Pipeline {
+	p := &Pipeline{}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// CallOption overrides a setting of a Pipeline for a single call to
+// Execute, leaving the shared policies unchanged.
+type CallOption func(*callSettings)
+
+// callSettings are the overrides of a single call.
+type callSettings struct {
+	attempts int           // Replaces the retry policy's attempts, if positive
+	timeout  time.Duration // Replaces the timeout, if positive
+}
+
+// OverrideMaxAttempts makes the call up to n attempts, such as 1 for a call
+// that must not be repeated. It applies to a *RetryPolicy set with
+// WithRetry; other Retriers are used as they are.
+func OverrideMaxAttempts(n int) CallOption {
+	return func(s *callSettings) {
+		s.attempts = n
+	}
+}
+
+// OverrideTimeout gives each attempt of the call d to finish instead of the
+// pipeline's timeout, adding a timeout if the pipeline has none.
+func OverrideTimeout(d time.Duration) CallOption {
+	return func(s *callSettings) {
+		s.timeout = d
+	}
+}
+
+// Execute runs fn through the pipeline's policies, with any overrides in
+// opts applied to this call only.
+func (p *Pipeline) Execute(ctx context.Context, fn WorkFuncCtx, opts ...CallOption) error {
+	var call callSettings
+	if len(opts) > 0 {
+		call = overrides(opts)
+	}
+
+	for i := len(p.custom) - 1; i >= 0; i-- {
+		fn = wrapPolicy(p.custom[i], fn)
+	}
+	if p.bulkhead != nil {
+		fn = wrapPolicy(p.bulkhead, fn)
+	}
+	if timeout := p.timeout; timeout != nil || call.timeout > 0 {
+		if call.timeout > 0 {
+			timeout = &Timeout{timeout: call.timeout, wait: timeout != nil && timeout.wait}
+		}
+		fn = wrapPolicy(timeout, fn)
+	}
+	if p.breaker != nil {
+		fn = wrapBreaker(p.breaker, fn)
+	}
+	if r, ok := p.retry.(*RetryPolicy); ok && call.attempts > 0 {
+		next := fn
+		fn = func(ctx context.Context) error { return r.doAttempts(ctx, next, call.attempts) }
+	} else if p.retry != nil {
+		fn = wrapPolicy(p.retry, fn)
+	}
+	if p.fallback != nil {
+		fn = wrapPolicy(p.fallback, fn)
+	}
+
+	return operationError(ctx, fn(ctx))
+}
+
+// overrides returns the settings opts override. Execute only calls it for
+// calls that have some, so that the others do not allocate them.
+func overrides(opts []CallOption) callSettings {
+	var s callSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	return s
+}
+
+// Do is Execute, making a Pipeline usable as a Policy in another pipeline.
+func (p *Pipeline) Do(ctx context.Context, fn WorkFuncCtx) error {
+	return p.Execute(ctx, fn)
+}
+
+// Do runs fn through a pipeline of the given policies, for one-off call
+// sites that want them composed without keeping a Pipeline:
+//
+//	err := failover.Do(ctx, fetch, failover.WithBreaker(cb), failover.WithTimeout(2*time.Second))
+//
+// The policies are stateful, so pass the same breaker, retry policy or
+// bulkhead on every call; those created inline start afresh each time.
+func Do(ctx context.Context, fn WorkFuncCtx, opts ...PipelineOption) error {
+	return NewPipeline(opts...).Execute(ctx, fn)
+}
+
+func wrapPolicy(policy Policy, next WorkFuncCtx) WorkFuncCtx {
+	return func(ctx context.Context) error { return policy.Do(ctx, next) }
+}
+
+func wrapBreaker(b Breaker, next WorkFuncCtx) WorkFuncCtx {
+	return func(ctx context.Context) error { return runBreaker(ctx, b, next) }
+}
+
+// runBreaker runs fn through b. A breaker that is also a Policy, such as
+// a *CircuitBreaker or a decorator of one, runs it through Do, so that
+// the failures it keeps carry the operation of ctx; others use Execute.
+func runBreaker(ctx context.Context, b Breaker, fn WorkFuncCtx) error {
+	if p, ok := b.(Policy); ok {
+		return p.Do(ctx, fn)
+	}
+
+	return b.Execute(func() error { return fn(ctx) })
+}
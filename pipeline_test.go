@@ -0,0 +1,212 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipeline_Empty(t *testing.T) {
+	t.Parallel()
+	p := NewPipeline()
+
+	if err := p.Execute(context.Background(), func(context.Context) error { return errTest }); !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+}
+
+func TestPipeline_RetryThroughBreaker(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(2, 1, time.Minute)
+	p := NewPipeline(
+		WithRetry(NewRetryPolicy(5, time.Millisecond)),
+		WithBreaker(cb),
+	)
+
+	calls := 0
+	err := p.Execute(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	})
+
+	// Two failures trip the breaker; the remaining attempts are rejected.
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls to reach fn, got %d", calls)
+	}
+}
+
+// policyBreaker is a Breaker decorator that counts the calls made through
+// its Do.
+type policyBreaker struct {
+	Breaker
+	calls atomic.Int32
+}
+
+func (b *policyBreaker) Do(ctx context.Context, fn WorkFuncCtx) error {
+	b.calls.Add(1)
+	return b.Execute(func() error { return fn(ctx) })
+}
+
+func TestPipeline_BreakerDo(t *testing.T) {
+	t.Parallel()
+	b := &policyBreaker{Breaker: NewCircuitBreaker(1, 1, time.Minute)}
+	p := NewPipeline(WithBreaker(b))
+
+	if err := p.Execute(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if n := b.calls.Load(); n != 1 {
+		t.Fatalf("Expected the breaker's Do to be called once, got %d", n)
+	}
+}
+
+func TestPipeline_FallbackIsOutermost(t *testing.T) {
+	t.Parallel()
+	attempts := 0
+	var final error
+
+	p := NewPipeline(
+		WithBreaker(NewCircuitBreaker(10, 1, time.Minute)),
+		WithRetry(NewRetryPolicy(3, time.Millisecond)),
+		WithFallback(NewFallback(func(_ context.Context, err error) error {
+			final = err
+			return nil
+		})),
+	)
+
+	err := p.Execute(context.Background(), func(context.Context) error {
+		attempts++
+		return errTest
+	})
+
+	if err != nil {
+		t.Fatalf("Expected fallback to mask the error, got %v", err)
+	}
+	if attempts != 3 || !errors.Is(final, errTest) {
+		t.Fatalf("Expected fallback after 3 attempts, got %d attempts and %v", attempts, final)
+	}
+}
+
+func TestPipeline_TimeoutPerAttempt(t *testing.T) {
+	t.Parallel()
+	p := NewPipeline(
+		WithRetry(NewRetryPolicy(2, time.Millisecond)),
+		WithTimeout(10*time.Millisecond),
+		WithBulkhead(NewBulkhead(1, 0, 0)),
+	)
+
+	var attempts atomic.Int32
+	err := p.Execute(context.Background(), func(ctx context.Context) error {
+		attempts.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	if attempts.Load() != 2 {
+		t.Fatalf("Expected each of 2 attempts to time out, got %d", attempts.Load())
+	}
+}
+
+func TestPipeline_WithPolicy(t *testing.T) {
+	t.Parallel()
+	var order []string
+	trace := func(name string) Policy {
+		return PolicyFunc(func(ctx context.Context, fn WorkFuncCtx) error {
+			order = append(order, name)
+			return fn(ctx)
+		})
+	}
+
+	p := NewPipeline(
+		WithPolicy(trace("first")),
+		WithRetry(trace("retry")),
+		WithPolicy(trace("second")),
+	)
+	_ = p.Execute(context.Background(), func(context.Context) error { return nil })
+
+	want := []string{"retry", "first", "second"}
+	if len(order) != len(want) {
+		t.Fatalf("Expected order %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+
+	calls := 0
+	err := Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	}, WithRetry(NewRetryPolicy(3, time.Millisecond)), WithBreaker(cb), WithTimeout(time.Second))
+
+	if !errors.Is(err, ErrCircuitOpen) || calls != 1 {
+		t.Fatalf("Expected the breaker to stop the retries after 1 call, got %v after %d", err, calls)
+	}
+
+	// The breaker passed in keeps its state across calls.
+	err = Do(context.Background(), func(context.Context) error { return nil }, WithBreaker(cb))
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestPipeline_OverrideMaxAttempts(t *testing.T) {
+	t.Parallel()
+	retry := NewRetryPolicy(3, time.Millisecond)
+	p := NewPipeline(WithRetry(retry))
+
+	calls := 0
+	fail := func(context.Context) error {
+		calls++
+		return errTest
+	}
+
+	p.Execute(context.Background(), fail, OverrideMaxAttempts(1))
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+
+	calls = 0
+	p.Execute(context.Background(), fail)
+	if calls != 3 {
+		t.Fatalf("Expected the shared policy to keep 3 attempts, got %d", calls)
+	}
+	if got := retry.Stats().Calls; got != 2 {
+		t.Fatalf("Expected both calls in the stats, got %d", got)
+	}
+}
+
+func TestPipeline_OverrideTimeout(t *testing.T) {
+	t.Parallel()
+	p := NewPipeline(WithTimeout(time.Minute))
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	var te *TimeoutError
+	err := p.Execute(context.Background(), slow, OverrideTimeout(5*time.Millisecond))
+	if !errors.As(err, &te) || te.Timeout != 5*time.Millisecond {
+		t.Fatalf("Expected a 5ms TimeoutError, got %v", err)
+	}
+
+	// A pipeline without a timeout gets one.
+	err = NewPipeline().Execute(context.Background(), slow, OverrideTimeout(5*time.Millisecond))
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Get once the pool is closed.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// poolConfig holds the settings of a Pool, kept apart from the generic
+// type so that PoolOption is not generic.
+type poolConfig struct {
+	maxIdle     int           // Idle connections kept per endpoint
+	maxActive   int           // Connections handed out at once, zero for no limit
+	maxLifetime time.Duration // Age after which a connection is closed, zero for no limit
+	health      *HealthChecker
+}
+
+// PoolOption configures optional Pool behavior.
+type PoolOption func(*poolConfig)
+
+// WithMaxIdle sets how many idle connections are kept per endpoint. The
+// default is 2.
+func WithMaxIdle(n int) PoolOption {
+	return func(c *poolConfig) {
+		c.maxIdle = n
+	}
+}
+
+// WithMaxActive bounds how many connections are handed out at once; Get
+// waits for one to be released beyond that.
+func WithMaxActive(n int) PoolOption {
+	return func(c *poolConfig) {
+		c.maxActive = n
+	}
+}
+
+// WithMaxLifetime closes connections once they are older than d, instead
+// of handing them out or keeping them idle.
+func WithMaxLifetime(d time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.maxLifetime = d
+	}
+}
+
+// WithPoolHealth skips endpoints h reports as Down, and closes the idle
+// connections to an endpoint as soon as it goes Down.
+func WithPoolHealth(h *HealthChecker) PoolOption {
+	return func(c *poolConfig) {
+		c.health = h
+	}
+}
+
+// PooledConn is a connection handed out by a Pool. It must be given back
+// with Release.
+type PooledConn[C io.Closer] struct {
+	Conn     C
+	Endpoint *Endpoint
+
+	pool    *Pool[C]
+	created time.Time
+}
+
+// Release gives the connection back to the pool. A non-nil err reports
+// that the connection failed while in use: it is closed rather than
+// reused, and the failure counts against the endpoint's breaker.
+func (pc *PooledConn[C]) Release(err error) {
+	pc.pool.release(pc, err)
+}
+
+// Pool is a connection pool for raw connections that the standard library
+// does not pool, such as TCP connections or driver sessions. Connections
+// are dialed to the highest-priority available endpoint, going down the
+// list when an endpoint's breaker is open, its dial fails, or its health
+// check reports it Down; idle connections to an endpoint that goes Down
+// are evicted.
+type Pool[C io.Closer] struct {
+	dial   func(ctx context.Context, ep *Endpoint) (C, error)
+	config poolConfig
+	active chan struct{} // One token per handed out connection, nil for no limit
+
+	mu        sync.Mutex // Protects endpoints, idle and closed
+	endpoints []*Endpoint
+	idle      map[*Endpoint][]*PooledConn[C]
+	closed    bool
+}
+
+// NewPool creates a Pool dialing connections to endpoints, in priority
+// order, with dial.
+func NewPool[C io.Closer](endpoints []*Endpoint, dial func(ctx context.Context, ep *Endpoint) (C, error), opts ...PoolOption) *Pool[C] {
+	p := &Pool[C]{
+		dial:      dial,
+		config:    poolConfig{maxIdle: 2},
+		endpoints: endpoints,
+		idle:      make(map[*Endpoint][]*PooledConn[C]),
+	}
+
+	for _, opt := range opts {
+		opt(&p.config)
+	}
+
+	if p.config.maxActive > 0 {
+		p.active = make(chan struct{}, p.config.maxActive)
+	}
+	if p.config.health != nil {
+		p.config.health.Subscribe(func(c HealthChange) {
+			if c.To == Down {
+				p.evict(c.Target)
+			}
+		})
+	}
+
+	return p
+}
+
+// Get returns an idle connection to the highest-priority available
+// endpoint, or dials a new one. It returns ErrNoEndpoint, or the last dial
+// error, when no endpoint could provide a connection.
+func (p *Pool[C]) Get(ctx context.Context) (*PooledConn[C], error) {
+	if p.active != nil {
+		select {
+		case p.active <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	pc, err := p.get(ctx)
+	if err != nil && p.active != nil {
+		<-p.active
+	}
+
+	return pc, err
+}
+
+func (p *Pool[C]) get(ctx context.Context) (*PooledConn[C], error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	endpoints := p.endpoints
+	p.mu.Unlock()
+
+	err := ErrNoEndpoint
+	for _, ep := range endpoints {
+		if !p.usable(ep) {
+			continue
+		}
+
+		if pc := p.takeIdle(ep); pc != nil {
+			return pc, nil
+		}
+
+		var conn C
+		dialErr := ep.call(ctx, func(ctx context.Context, ep *Endpoint) error {
+			var err error
+			conn, err = p.dial(ctx, ep)
+			return err
+		})
+		if dialErr != nil {
+			err = dialErr
+			continue
+		}
+
+		return &PooledConn[C]{Conn: conn, Endpoint: ep, pool: p, created: time.Now()}, nil
+	}
+
+	return nil, err
+}
+
+// takeIdle returns the most recently used live idle connection to ep.
+func (p *Pool[C]) takeIdle(ep *Endpoint) *PooledConn[C] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for idle := p.idle[ep]; len(idle) > 0; idle = p.idle[ep] {
+		pc := idle[len(idle)-1]
+		p.idle[ep] = idle[:len(idle)-1]
+
+		if !p.expired(pc) {
+			return pc
+		}
+		pc.Conn.Close()
+	}
+
+	return nil
+}
+
+func (p *Pool[C]) release(pc *PooledConn[C], err error) {
+	if p.active != nil {
+		defer func() { <-p.active }()
+	}
+
+	if err != nil {
+		recordFailure(pc.Endpoint.Breaker, err)
+		pc.Conn.Close()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || p.expired(pc) || !p.healthy(pc.Endpoint) || len(p.idle[pc.Endpoint]) >= p.config.maxIdle {
+		pc.Conn.Close()
+		return
+	}
+
+	p.idle[pc.Endpoint] = append(p.idle[pc.Endpoint], pc)
+}
+
+// recordFailure counts err against b. A breaker with RecordFailure, such
+// as a *CircuitBreaker, records it directly; others get a call that fails
+// with err, which they may reject while open.
+func recordFailure(b Breaker, err error) {
+	switch b := b.(type) {
+	case nil:
+	case interface{ RecordFailure(err error) }:
+		b.RecordFailure(err)
+	default:
+		b.Execute(func() error { return err })
+	}
+}
+
+// Idle returns the number of idle connections kept for ep.
+func (p *Pool[C]) Idle(ep *Endpoint) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.idle[ep])
+}
+
+// SetEndpoints replaces the pool's endpoints, closing the idle connections
+// to the ones removed.
+func (p *Pool[C]) SetEndpoints(endpoints []*Endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.endpoints = mergeEndpoints(p.endpoints, endpoints)
+	for ep, idle := range p.idle {
+		if !slices.Contains(p.endpoints, ep) {
+			closeAll(idle)
+			delete(p.idle, ep)
+		}
+	}
+}
+
+// Close closes the idle connections and makes Get fail. Connections in use
+// are closed when released.
+func (p *Pool[C]) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for ep, idle := range p.idle {
+		closeAll(idle)
+		delete(p.idle, ep)
+	}
+
+	return nil
+}
+
+// evict closes the idle connections to the endpoint named name.
+func (p *Pool[C]) evict(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for ep, idle := range p.idle {
+		if ep.Name == name {
+			closeAll(idle)
+			delete(p.idle, ep)
+		}
+	}
+}
+
+// usable reports whether connections may be taken from or dialed to ep.
+func (p *Pool[C]) usable(ep *Endpoint) bool {
+	return p.healthy(ep) && (ep.Breaker == nil || ep.Breaker.State() != Open)
+}
+
+func (p *Pool[C]) healthy(ep *Endpoint) bool {
+	return p.config.health == nil || p.config.health.Status(ep.Name) != Down
+}
+
+func (p *Pool[C]) expired(pc *PooledConn[C]) bool {
+	return p.config.maxLifetime > 0 && time.Since(pc.created) >= p.config.maxLifetime
+}
+
+func closeAll[C io.Closer](conns []*PooledConn[C]) {
+	for _, pc := range conns {
+		pc.Conn.Close()
+	}
+}
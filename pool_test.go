@@ -0,0 +1,209 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeConn is a connection that records whether it was closed.
+type fakeConn struct {
+	endpoint string
+	closed   atomic.Bool
+}
+
+func (c *fakeConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// dialer returns a dial func that fails for the endpoints named in failing
+// and counts the dials.
+func dialer(dials *atomic.Int32, failing ...string) func(context.Context, *Endpoint) (*fakeConn, error) {
+	return func(_ context.Context, ep *Endpoint) (*fakeConn, error) {
+		dials.Add(1)
+		for _, name := range failing {
+			if ep.Name == name {
+				return nil, errTest
+			}
+		}
+		return &fakeConn{endpoint: ep.Name}, nil
+	}
+}
+
+func TestPool_ReusesIdle(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	p := NewPool([]*Endpoint{{Name: "a"}}, dialer(&dials))
+	ctx := context.Background()
+
+	pc, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	conn := pc.Conn
+	pc.Release(nil)
+
+	pc, _ = p.Get(ctx)
+	if pc.Conn != conn || dials.Load() != 1 {
+		t.Fatalf("Expected the idle connection to be reused, got %d dials", dials.Load())
+	}
+
+	// A connection that failed in use is closed, not reused.
+	pc.Release(errTest)
+	if !conn.closed.Load() || p.Idle(pc.Endpoint) != 0 {
+		t.Fatal("Expected a failed connection to be closed")
+	}
+}
+
+func TestPool_MaxIdle(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	ep := &Endpoint{Name: "a"}
+	p := NewPool([]*Endpoint{ep}, dialer(&dials), WithMaxIdle(1))
+	ctx := context.Background()
+
+	first, _ := p.Get(ctx)
+	second, _ := p.Get(ctx)
+	first.Release(nil)
+	second.Release(nil)
+
+	if p.Idle(ep) != 1 || !second.Conn.closed.Load() {
+		t.Fatalf("Expected 1 idle connection and the extra one closed, got %d idle", p.Idle(ep))
+	}
+}
+
+func TestPool_MaxActive(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	p := NewPool([]*Endpoint{{Name: "a"}}, dialer(&dials), WithMaxActive(1))
+
+	pc, _ := p.Get(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected Get to wait for a connection, got %v", err)
+	}
+
+	pc.Release(nil)
+	if _, err := p.Get(context.Background()); err != nil {
+		t.Fatalf("Expected a connection once one was released, got %v", err)
+	}
+}
+
+func TestPool_MaxLifetime(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	p := NewPool([]*Endpoint{{Name: "a"}}, dialer(&dials), WithMaxLifetime(10*time.Millisecond))
+	ctx := context.Background()
+
+	pc, _ := p.Get(ctx)
+	old := pc.Conn
+	pc.Release(nil)
+	time.Sleep(15 * time.Millisecond)
+
+	pc, _ = p.Get(ctx)
+	if pc.Conn == old || !old.closed.Load() {
+		t.Fatal("Expected an expired connection to be closed and replaced")
+	}
+}
+
+func TestPool_Failover(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	p := NewPool([]*Endpoint{{Name: "primary", Breaker: cb}, {Name: "secondary"}}, dialer(&dials, "primary"))
+	ctx := context.Background()
+
+	pc, err := p.Get(ctx)
+	if err != nil || pc.Endpoint.Name != "secondary" {
+		t.Fatalf("Expected a connection to secondary when primary fails to dial, got %v", err)
+	}
+	if cb.State() != Open {
+		t.Fatalf("Expected the dial failure to trip the primary breaker, got %v", cb.State())
+	}
+
+	dials.Store(0)
+	p.Get(ctx)
+	if dials.Load() != 1 {
+		t.Fatalf("Expected the open primary to be skipped, got %d dials", dials.Load())
+	}
+}
+
+func TestPool_ReleaseFailure(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	p := NewPool([]*Endpoint{{Name: "a", Breaker: cb}}, dialer(&dials))
+	ctx := context.Background()
+
+	first, _ := p.Get(ctx)
+	second, _ := p.Get(ctx)
+	first.Release(errTest)
+	second.Release(errTest)
+
+	// The second failure happened on a connection handed out before the
+	// breaker opened, so it is counted rather than rejected.
+	if c := cb.Counts(); c.TotalFailures != 2 || c.Rejections != 0 {
+		t.Fatalf("Expected 2 failures and no rejections, got %+v", c)
+	}
+}
+
+func TestPool_HealthEviction(t *testing.T) {
+	t.Parallel()
+	var healthy atomic.Bool
+	healthy.Store(true)
+	h := NewHealthChecker(time.Hour)
+	h.Add("a", func(context.Context) error {
+		if healthy.Load() {
+			return nil
+		}
+		return errTest
+	})
+
+	var dials atomic.Int32
+	ep := &Endpoint{Name: "a"}
+	p := NewPool([]*Endpoint{ep, {Name: "b"}}, dialer(&dials), WithPoolHealth(h))
+	ctx := context.Background()
+
+	pc, _ := p.Get(ctx)
+	pc.Release(nil)
+
+	healthy.Store(false)
+	h.Check(ctx, "a")
+	if p.Idle(ep) != 0 || !pc.Conn.closed.Load() {
+		t.Fatal("Expected idle connections to an endpoint going Down to be evicted")
+	}
+
+	pc, _ = p.Get(ctx)
+	if pc.Endpoint.Name != "b" {
+		t.Fatalf("Expected a connection to b, got %s", pc.Endpoint.Name)
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	t.Parallel()
+	var dials atomic.Int32
+	p := NewPool([]*Endpoint{{Name: "a"}}, dialer(&dials))
+	ctx := context.Background()
+
+	idle, _ := p.Get(ctx)
+	inUse, _ := p.Get(ctx)
+	idle.Release(nil)
+
+	p.Close()
+	if !idle.Conn.closed.Load() {
+		t.Fatal("Expected Close to close idle connections")
+	}
+	if _, err := p.Get(ctx); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Expected ErrPoolClosed, got %v", err)
+	}
+
+	inUse.Release(nil)
+	if !inUse.Conn.closed.Load() {
+		t.Fatal("Expected a connection released after Close to be closed")
+	}
+}
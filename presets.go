@@ -0,0 +1,66 @@
+package failover
+
+import "time"
+
+// Presets builds pipelines tuned for common kinds of calls, so that a team
+// can start from settings that suit the call rather than guess thresholds:
+//
+//	reads := failover.Presets.UserFacingRead()
+//	err := reads.Do(ctx, fetchProfile)
+//
+// Each method builds new policies on every call. opts are applied after the
+// preset's own, so they can add a fallback or bulkhead, or replace one of
+// the preset's policies.
+var Presets PresetSet
+
+// PresetSet is the type of Presets.
+type PresetSet struct{}
+
+// FastFail is for calls on a hot path that should fail at once rather than
+// wait: no retries, a 1s timeout, and a breaker that opens after 5
+// consecutive failures for 10s.
+func (PresetSet) FastFail(opts ...PipelineOption) *Pipeline {
+	return NewPipeline(append([]PipelineOption{
+		WithBreaker(NewCircuitBreaker(5, 1, 10*time.Second)),
+		WithTimeout(time.Second),
+	}, opts...)...)
+}
+
+// UserFacingRead is for reads a user is waiting on: 3 attempts 50ms to
+// 500ms apart, each given at most half of the time left and 2s, that stop
+// at once on an open breaker; the breaker opens for 15s when half of at
+// least 20 calls within 10s fail.
+func (PresetSet) UserFacingRead(opts ...PipelineOption) *Pipeline {
+	retry := NewRetryPolicy(3, 50*time.Millisecond,
+		WithBackoff(ExponentialBackoff{Initial: 50 * time.Millisecond, Max: 500 * time.Millisecond, Jitter: 0.2}),
+		WithDeadlineSplit(0.5),
+		WithCircuitOpenAction(FailOnOpen),
+	)
+	breaker := NewCircuitBreaker(0, 2, 15*time.Second,
+		WithFailureRate(0.5, 10*time.Second),
+		WithMinimumRequests(20),
+	)
+
+	return NewPipeline(append([]PipelineOption{
+		WithRetry(retry),
+		WithBreaker(breaker),
+		WithTimeout(2 * time.Second),
+	}, opts...)...)
+}
+
+// BackgroundJob is for work no one waits on, which should rather get done
+// late than not at all: 8 attempts 1s to 1m apart that wait out an open
+// breaker, a 30s timeout per attempt, and a breaker that opens after 10
+// consecutive failures for a minute.
+func (PresetSet) BackgroundJob(opts ...PipelineOption) *Pipeline {
+	retry := NewRetryPolicy(8, time.Second,
+		WithBackoff(ExponentialBackoff{Initial: time.Second, Max: time.Minute, Jitter: 0.5}),
+		WithCircuitOpenAction(WaitOnOpen),
+	)
+
+	return NewPipeline(append([]PipelineOption{
+		WithRetry(retry),
+		WithBreaker(NewCircuitBreaker(10, 3, time.Minute)),
+		WithTimeout(30 * time.Second),
+	}, opts...)...)
+}
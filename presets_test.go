@@ -0,0 +1,62 @@
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPresets_FastFail(t *testing.T) {
+	t.Parallel()
+	p := Presets.FastFail()
+
+	if p.retry != nil {
+		t.Fatalf("Expected no retries, got %v", p.retry)
+	}
+	if p.breaker == nil || p.timeout == nil || p.timeout.timeout != time.Second {
+		t.Fatalf("Expected a breaker and a 1s timeout, got %v and %v", p.breaker, p.timeout)
+	}
+
+	calls := 0
+	p.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	})
+	if calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestPresets_UserFacingRead(t *testing.T) {
+	t.Parallel()
+	p := Presets.UserFacingRead()
+
+	retry, ok := p.retry.(*RetryPolicy)
+	if !ok || retry.settings.Load().attempts != 3 || retry.onOpen != FailOnOpen {
+		t.Fatalf("Expected 3 attempts failing on open, got %v", p.retry)
+	}
+	if cb, ok := p.breaker.(*CircuitBreaker); !ok || cb.failureRate != 0.5 {
+		t.Fatalf("Expected a rate breaker, got %v", p.breaker)
+	}
+}
+
+func TestPresets_BackgroundJob(t *testing.T) {
+	t.Parallel()
+	p := Presets.BackgroundJob()
+
+	if retry, ok := p.retry.(*RetryPolicy); !ok || retry.onOpen != WaitOnOpen {
+		t.Fatalf("Expected retries waiting on open, got %v", p.retry)
+	}
+}
+
+func TestPresets_Options(t *testing.T) {
+	t.Parallel()
+	p := Presets.FastFail(WithTimeout(5*time.Millisecond), WithFallback(NewFallback(func(context.Context, error) error { return nil })))
+
+	if p.timeout.timeout != 5*time.Millisecond {
+		t.Fatalf("Expected the timeout to be replaced, got %v", p.timeout.timeout)
+	}
+	if err := p.Do(context.Background(), func(context.Context) error { return errTest }); err != nil {
+		t.Fatalf("Expected the fallback to handle the failure, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package failover
+
+import "context"
+
+// Priority classifies requests for load shedding. Under overload
+// BestEffort requests are rejected first and Critical requests last.
+type Priority int
+
+const (
+	// Normal is the priority of requests that do not set one.
+	Normal Priority = iota
+	// Critical requests keep flowing for as long as possible.
+	Critical
+	// BestEffort requests are the first to be shed.
+	BestEffort
+)
+
+// priorityKey is the context key for a request's Priority.
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx carrying priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the Priority carried by ctx, or Normal if it
+// carries none.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+
+	return Normal
+}
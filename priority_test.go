@@ -0,0 +1,18 @@
+package failover
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPriorityFromContext(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	if p := PriorityFromContext(ctx); p != Normal {
+		t.Fatalf("Expected default priority Normal, got %v", p)
+	}
+	if p := PriorityFromContext(WithPriority(ctx, BestEffort)); p != BestEffort {
+		t.Fatalf("Expected priority BestEffort, got %v", p)
+	}
+}
@@ -0,0 +1,245 @@
+package failover
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrUnhealthy is returned by the built-in probes when the target answered
+// but not the way a healthy one would.
+var ErrUnhealthy = errors.New("target is unhealthy")
+
+// maxProbeBody caps how much of a response body an HTTP probe reads.
+const maxProbeBody = 64 << 10
+
+// httpProbe holds the settings of an HTTPProbe.
+type httpProbe struct {
+	url      string
+	method   string
+	statuses []int  // Accepted status codes, any 2xx when empty
+	body     []byte // Substring the body must contain, nil to skip the check
+	timeout  time.Duration
+	client   *http.Client
+	header   http.Header
+}
+
+// HTTPProbeOption configures an HTTPProbe.
+type HTTPProbeOption func(*httpProbe)
+
+// WithExpectedStatus sets the status codes that count as healthy, instead
+// of any 2xx.
+func WithExpectedStatus(codes ...int) HTTPProbeOption {
+	return func(p *httpProbe) {
+		p.statuses = codes
+	}
+}
+
+// WithBodyContains requires the response body to contain s.
+func WithBodyContains(s string) HTTPProbeOption {
+	return func(p *httpProbe) {
+		p.body = []byte(s)
+	}
+}
+
+// WithHTTPTimeout bounds each probe request to d. The default is five
+// seconds.
+func WithHTTPTimeout(d time.Duration) HTTPProbeOption {
+	return func(p *httpProbe) {
+		p.timeout = d
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to reach https targets,
+// e.g. to trust a private CA or present a client certificate.
+func WithTLSConfig(cfg *tls.Config) HTTPProbeOption {
+	return func(p *httpProbe) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = cfg
+		p.client = &http.Client{Transport: transport}
+	}
+}
+
+// WithProbeHeader adds a header to every probe request.
+func WithProbeHeader(key, value string) HTTPProbeOption {
+	return func(p *httpProbe) {
+		p.header.Add(key, value)
+	}
+}
+
+// WithProbeMethod sets the request method, GET by default.
+func WithProbeMethod(method string) HTTPProbeOption {
+	return func(p *httpProbe) {
+		p.method = method
+	}
+}
+
+// HTTPProbe returns a Probe that requests url and checks the response
+// status and, optionally, its body. Failures that come from the response
+// wrap ErrUnhealthy; transport errors are returned as is.
+func HTTPProbe(url string, opts ...HTTPProbeOption) Probe {
+	p := &httpProbe{
+		url:     url,
+		method:  http.MethodGet,
+		timeout: 5 * time.Second,
+		client:  http.DefaultClient,
+		header:  make(http.Header),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p.check
+}
+
+func (p *httpProbe) check(ctx context.Context) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.timeout, errDeadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, p.method, p.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = p.header.Clone()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !p.statusOK(resp.StatusCode) {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeBody))
+		return fmt.Errorf("%w: status %d", ErrUnhealthy, resp.StatusCode)
+	}
+
+	if p.body == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeBody))
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(body, p.body) {
+		return fmt.Errorf("%w: body does not contain %q", ErrUnhealthy, p.body)
+	}
+
+	return nil
+}
+
+func (p *httpProbe) statusOK(code int) bool {
+	if len(p.statuses) == 0 {
+		return code >= 200 && code < 300
+	}
+
+	return slices.Contains(p.statuses, code)
+}
+
+// TCPProbe returns a Probe that succeeds when a TCP connection to addr
+// (host:port) can be established within timeout.
+func TCPProbe(addr string, timeout time.Duration) Probe {
+	return func(ctx context.Context) error {
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
+
+// tlsProbe holds the settings of a TLSProbe.
+type tlsProbe struct {
+	addr        string
+	config      *tls.Config
+	timeout     time.Duration
+	commonName  string        // Expected leaf certificate subject CN, empty to skip
+	dnsName     string        // Name the leaf certificate must be valid for, empty to skip
+	minValidity time.Duration // How long the leaf certificate must remain valid
+}
+
+// TLSProbeOption configures a TLSProbe.
+type TLSProbeOption func(*tlsProbe)
+
+// WithCertCommonName requires the leaf certificate's subject common name
+// to be cn.
+func WithCertCommonName(cn string) TLSProbeOption {
+	return func(p *tlsProbe) {
+		p.commonName = cn
+	}
+}
+
+// WithCertDNSName requires the leaf certificate to be valid for name.
+func WithCertDNSName(name string) TLSProbeOption {
+	return func(p *tlsProbe) {
+		p.dnsName = name
+	}
+}
+
+// WithMinCertValidity fails the probe when the leaf certificate expires
+// within d, so rotations that did not happen surface before clients break.
+func WithMinCertValidity(d time.Duration) TLSProbeOption {
+	return func(p *tlsProbe) {
+		p.minValidity = d
+	}
+}
+
+// TLSProbe returns a Probe that connects to addr and completes a TLS
+// handshake using cfg (nil for the defaults) within timeout, then checks
+// the leaf certificate against the expected attributes. Certificate
+// mismatches wrap ErrUnhealthy.
+func TLSProbe(addr string, cfg *tls.Config, timeout time.Duration, opts ...TLSProbeOption) Probe {
+	p := &tlsProbe{addr: addr, config: cfg, timeout: timeout}
+	if p.config == nil {
+		p.config = &tls.Config{}
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p.check
+}
+
+func (p *tlsProbe) check(ctx context.Context) error {
+	dialer := tls.Dialer{NetDialer: &net.Dialer{Timeout: p.timeout}, Config: p.config}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("%w: no peer certificate", ErrUnhealthy)
+	}
+	leaf := certs[0]
+
+	if p.commonName != "" && leaf.Subject.CommonName != p.commonName {
+		return fmt.Errorf("%w: certificate common name %q", ErrUnhealthy, leaf.Subject.CommonName)
+	}
+	if p.dnsName != "" {
+		if err := leaf.VerifyHostname(p.dnsName); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnhealthy, err)
+		}
+	}
+	if p.minValidity > 0 && time.Until(leaf.NotAfter) < p.minValidity {
+		return fmt.Errorf("%w: certificate expires at %v", ErrUnhealthy, leaf.NotAfter)
+	}
+
+	return nil
+}
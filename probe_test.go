@@ -0,0 +1,147 @@
+package failover
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPProbe_Status(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := HTTPProbe(srv.URL + "/up")(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if err := HTTPProbe(srv.URL + "/down")(ctx); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy, got %v", err)
+	}
+	if err := HTTPProbe(srv.URL+"/down", WithExpectedStatus(http.StatusServiceUnavailable))(ctx); err != nil {
+		t.Fatalf("Expected 503 to be accepted, got %v", err)
+	}
+}
+
+func TestHTTPProbe_Body(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := HTTPProbe(srv.URL, WithBodyContains(`"ok"`))(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if err := HTTPProbe(srv.URL, WithBodyContains("degraded"))(ctx); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy, got %v", err)
+	}
+}
+
+func TestHTTPProbe_HeaderAndMethod(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.Header.Get("X-Probe") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	probe := HTTPProbe(srv.URL, WithProbeMethod(http.MethodHead), WithProbeHeader("X-Probe", "1"))
+	if err := probe(context.Background()); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
+
+func TestHTTPProbe_Timeout(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	err := HTTPProbe(srv.URL, WithHTTPTimeout(20*time.Millisecond))(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected the probe's own deadline to show as ErrTimeout, got %v", err)
+	}
+}
+
+func TestHTTPProbe_TLS(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := HTTPProbe(srv.URL)(ctx); err == nil {
+		t.Fatal("Expected an untrusted certificate to fail the probe")
+	}
+
+	cfg := &tls.Config{RootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+	if err := HTTPProbe(srv.URL, WithTLSConfig(cfg))(ctx); err != nil {
+		t.Fatalf("Expected nil error with the server CA trusted, got %v", err)
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ctx := context.Background()
+
+	if err := TCPProbe(addr, time.Second)(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	ln.Close()
+	if err := TCPProbe(addr, time.Second)(ctx); err == nil {
+		t.Fatal("Expected a closed port to fail the probe")
+	}
+}
+
+func TestTLSProbe(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+	ctx := context.Background()
+
+	if err := TLSProbe(addr, nil, time.Second)(ctx); err == nil {
+		t.Fatal("Expected an untrusted certificate to fail the probe")
+	}
+
+	// httptest certificates are issued for example.com and expire in 2084.
+	cfg := &tls.Config{RootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs, ServerName: "example.com"}
+	if err := TLSProbe(addr, cfg, time.Second, WithCertDNSName("example.com"), WithMinCertValidity(24*time.Hour))(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if err := TLSProbe(addr, cfg, time.Second, WithCertDNSName("other.test"))(ctx); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy for a name mismatch, got %v", err)
+	}
+	if err := TLSProbe(addr, cfg, time.Second, WithCertCommonName("db.internal"))(ctx); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy for a common name mismatch, got %v", err)
+	}
+	if err := TLSProbe(addr, cfg, time.Second, WithMinCertValidity(100*365*24*time.Hour))(ctx); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("Expected ErrUnhealthy for a certificate expiring too soon, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package failover
+
+import (
+	"context"
+	"sync"
+)
+
+// EndpointProvider is a source of endpoints, such as a static list, DNS or
+// a service catalog.
+type EndpointProvider interface {
+	// List returns the current endpoints.
+	List(ctx context.Context) ([]*Endpoint, error)
+	// Watch returns a channel that receives the full endpoint set every time
+	// it changes, starting with the current one. The channel is closed once
+	// ctx is done.
+	Watch(ctx context.Context) (<-chan []*Endpoint, error)
+}
+
+// EndpointSetter is implemented by consumers of endpoints that can be
+// updated at runtime, such as FailoverGroup and Balancer.
+type EndpointSetter interface {
+	SetEndpoints(endpoints []*Endpoint)
+}
+
+var (
+	_ EndpointProvider = (*StaticProvider)(nil)
+	_ EndpointProvider = (*DynamicProvider)(nil)
+	_ EndpointSetter   = (*FailoverGroup)(nil)
+	_ EndpointSetter   = (*Balancer)(nil)
+)
+
+// WatchEndpoints feeds every endpoint set from p into dst until ctx is done
+// or the provider stops, and returns the reason.
+func WatchEndpoints(ctx context.Context, p EndpointProvider, dst EndpointSetter) error {
+	updates, err := p.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	for endpoints := range updates {
+		dst.SetEndpoints(endpoints)
+	}
+
+	return ctx.Err()
+}
+
+// StaticProvider is an EndpointProvider for a fixed list of endpoints.
+type StaticProvider struct {
+	endpoints []*Endpoint
+}
+
+// NewStaticProvider creates a StaticProvider for endpoints.
+func NewStaticProvider(endpoints ...*Endpoint) *StaticProvider {
+	return &StaticProvider{endpoints: endpoints}
+}
+
+// List returns the endpoints.
+func (p *StaticProvider) List(context.Context) ([]*Endpoint, error) {
+	return append([]*Endpoint(nil), p.endpoints...), nil
+}
+
+// Watch sends the endpoints once, and closes the channel once ctx is done.
+func (p *StaticProvider) Watch(ctx context.Context) (<-chan []*Endpoint, error) {
+	ch := make(chan []*Endpoint, 1)
+	ch <- append([]*Endpoint(nil), p.endpoints...)
+
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// DynamicProvider is an EndpointProvider whose endpoints are set by the
+// application, e.g. from its own discovery mechanism or in tests.
+type DynamicProvider struct {
+	mu        sync.Mutex // Protects endpoints and watchers
+	endpoints []*Endpoint
+	watchers  map[chan []*Endpoint]struct{}
+}
+
+// NewDynamicProvider creates a DynamicProvider with an initial set of
+// endpoints.
+func NewDynamicProvider(endpoints ...*Endpoint) *DynamicProvider {
+	return &DynamicProvider{
+		endpoints: endpoints,
+		watchers:  make(map[chan []*Endpoint]struct{}),
+	}
+}
+
+// Set replaces the endpoints and notifies the watchers. A watcher that has
+// not consumed the previous set only receives the latest one.
+func (p *DynamicProvider) Set(endpoints ...*Endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.endpoints = endpoints
+	for ch := range p.watchers {
+		select {
+		case <-ch: // drop the stale set
+		default:
+		}
+		ch <- append([]*Endpoint(nil), endpoints...)
+	}
+}
+
+// List returns the current endpoints.
+func (p *DynamicProvider) List(context.Context) ([]*Endpoint, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*Endpoint(nil), p.endpoints...), nil
+}
+
+// Watch returns a channel receiving the current endpoints and every set
+// passed to Set afterwards.
+func (p *DynamicProvider) Watch(ctx context.Context) (<-chan []*Endpoint, error) {
+	ch := make(chan []*Endpoint, 1)
+
+	p.mu.Lock()
+	ch <- append([]*Endpoint(nil), p.endpoints...)
+	p.watchers[ch] = struct{}{}
+	p.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		p.mu.Lock()
+		delete(p.watchers, ch)
+		close(ch)
+		p.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// mergeEndpoints returns next, reusing the endpoints of current that have
+// the same name and address, so their breakers and bulkheads carry over
+// when a provider sends a fresh copy of an unchanged endpoint. An endpoint
+// whose weight changed is taken from next with the breaker and bulkhead
+// of the current one, rather than changed in place under its readers.
+func mergeEndpoints(current, next []*Endpoint) []*Endpoint {
+	merged := make([]*Endpoint, len(next))
+	for i, ep := range next {
+		merged[i] = ep
+		for _, old := range current {
+			if old.Name != ep.Name || old.Address != ep.Address {
+				continue
+			}
+
+			if old.Weight == ep.Weight {
+				merged[i] = old
+			} else {
+				updated := *ep
+				updated.Breaker, updated.Bulkhead = old.Breaker, old.Bulkhead
+				merged[i] = &updated
+			}
+			break
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,124 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// names returns the names of endpoints, in order.
+func names(endpoints []*Endpoint) []string {
+	out := make([]string, len(endpoints))
+	for i, ep := range endpoints {
+		out[i] = ep.Name
+	}
+	return out
+}
+
+func TestStaticProvider(t *testing.T) {
+	t.Parallel()
+	p := NewStaticProvider(&Endpoint{Name: "a"}, &Endpoint{Name: "b"})
+
+	endpoints, err := p.List(context.Background())
+	if err != nil || len(endpoints) != 2 {
+		t.Fatalf("Expected 2 endpoints, got %v (%v)", names(endpoints), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, _ := p.Watch(ctx)
+	if got := <-updates; len(got) != 2 {
+		t.Fatalf("Expected the initial set on Watch, got %v", names(got))
+	}
+
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Fatal("Expected the channel to be closed once ctx is done")
+	}
+}
+
+func TestDynamicProvider_Watch(t *testing.T) {
+	t.Parallel()
+	p := NewDynamicProvider(&Endpoint{Name: "a"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, _ := p.Watch(ctx)
+	<-updates
+
+	p.Set(&Endpoint{Name: "b"})
+	p.Set(&Endpoint{Name: "c"}, &Endpoint{Name: "d"})
+
+	// A slow watcher only sees the latest set.
+	got := <-updates
+	if n := names(got); len(n) != 2 || n[0] != "c" || n[1] != "d" {
+		t.Fatalf("Expected [c d], got %v", n)
+	}
+
+	endpoints, _ := p.List(ctx)
+	if len(endpoints) != 2 {
+		t.Fatalf("Expected List to return the latest set, got %v", names(endpoints))
+	}
+}
+
+func TestWatchEndpoints_Balancer(t *testing.T) {
+	t.Parallel()
+	a := &Endpoint{Name: "a", Address: "10.0.0.1"}
+	p := NewDynamicProvider(a)
+	b := NewBalancer(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- WatchEndpoints(ctx, p, b) }()
+
+	waitFor := func(want int) []*Endpoint {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if eps := b.Endpoints(); len(eps) == want {
+				return eps
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Expected %d endpoints, got %v", want, names(b.Endpoints()))
+		return nil
+	}
+	waitFor(1)
+
+	// A fresh copy of an unchanged endpoint keeps the existing one.
+	p.Set(&Endpoint{Name: "a", Address: "10.0.0.1"}, &Endpoint{Name: "b", Address: "10.0.0.2"})
+	if eps := waitFor(2); eps[0] != a {
+		t.Fatal("Expected the unchanged endpoint to be reused")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMergeEndpoints_Weight(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	a := &Endpoint{Name: "a", Address: "10.0.0.1", Weight: 1, Breaker: cb}
+
+	merged := mergeEndpoints([]*Endpoint{a}, []*Endpoint{{Name: "a", Address: "10.0.0.1", Weight: 5}})
+	if got := merged[0]; got.Weight != 5 || got.Breaker != cb {
+		t.Fatalf("Expected the new weight with the same breaker, got %+v", got)
+	}
+	if a.Weight != 1 {
+		t.Fatalf("Expected the current endpoint left unchanged, got weight %d", a.Weight)
+	}
+}
+
+func TestFailoverGroup_SetEndpoints(t *testing.T) {
+	t.Parallel()
+	g := NewFailoverGroup([]*Endpoint{{Name: "old"}})
+	g.SetEndpoints([]*Endpoint{{Name: "primary"}, {Name: "secondary"}})
+
+	var got []string
+	g.Do(context.Background(), record(&got))
+	if len(got) != 1 || got[0] != "primary" {
+		t.Fatalf("Expected call routed to the new primary, got %v", got)
+	}
+}
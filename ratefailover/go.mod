@@ -0,0 +1,10 @@
+module github.com/dadanrm/failover/ratefailover
+
+go 1.24.7
+
+require (
+	github.com/dadanrm/failover v0.0.0
+	golang.org/x/time v0.14.0
+)
+
+replace github.com/dadanrm/failover => ../
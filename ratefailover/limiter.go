@@ -0,0 +1,97 @@
+// Package ratefailover adapts the token bucket of golang.org/x/time/rate
+// to the failover package, so that a *rate.Limiter already in use can pace
+// calls wherever a failover.Limiter or failover.Policy is expected, in
+// place of a failover.RateLimiter.
+package ratefailover
+
+import (
+	"context"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"golang.org/x/time/rate"
+)
+
+var (
+	_ failover.Limiter = (*Limiter)(nil)
+	_ failover.Policy  = (*Limiter)(nil)
+)
+
+// Limiter is a failover.Limiter and failover.Policy backed by a
+// *rate.Limiter. It behaves like a failover.RateLimiter: Execute rejects
+// calls beyond the rate with failover.ErrRateLimited, while Do waits for a
+// token, failing at once with failover.ErrDeadlineUnreachable if the
+// context's deadline would pass first.
+type Limiter struct {
+	limiter *rate.Limiter
+}
+
+// NewLimiter creates a Limiter taking its tokens from l, which may still be
+// used directly alongside it.
+func NewLimiter(l *rate.Limiter) *Limiter {
+	return &Limiter{limiter: l}
+}
+
+// Allow reports whether a call may happen now, consuming a token if so.
+func (l *Limiter) Allow() bool {
+	return l.limiter.Allow()
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	r := l.limiter.Reserve()
+	if !r.OK() {
+		return failover.ErrRateLimited // a burst of zero never has a token
+	}
+
+	delay := r.Delay()
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return failover.ErrDeadlineUnreachable
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// EstimatedWait returns how long a Wait call made now would block.
+func (l *Limiter) EstimatedWait() time.Duration {
+	r := l.limiter.Reserve()
+	defer r.Cancel()
+
+	if !r.OK() {
+		return 0
+	}
+	return r.Delay()
+}
+
+// Execute calls fn if a token is available and returns
+// failover.ErrRateLimited otherwise.
+func (l *Limiter) Execute(fn failover.WorkFunc) error {
+	if !l.Allow() {
+		return failover.ErrRateLimited
+	}
+
+	return fn()
+}
+
+// Do waits for a token and then executes fn.
+func (l *Limiter) Do(ctx context.Context, fn failover.WorkFuncCtx) error {
+	if err := l.Wait(ctx); err != nil {
+		return err
+	}
+
+	return fn(ctx)
+}
@@ -0,0 +1,63 @@
+package ratefailover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiter_Execute(t *testing.T) {
+	t.Parallel()
+	l := NewLimiter(rate.NewLimiter(rate.Every(time.Hour), 1))
+
+	if err := l.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if err := l.Execute(func() error { return nil }); !errors.Is(err, failover.ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestLimiter_Do(t *testing.T) {
+	t.Parallel()
+	l := NewLimiter(rate.NewLimiter(rate.Every(20*time.Millisecond), 1))
+	l.Allow()
+
+	start := time.Now()
+	if err := l.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("Expected Do to wait for a token, took %v", elapsed)
+	}
+}
+
+func TestLimiter_DeadlineUnreachable(t *testing.T) {
+	t.Parallel()
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	l := NewLimiter(limiter)
+	l.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := l.Wait(ctx); !errors.Is(err, failover.ErrDeadlineUnreachable) {
+		t.Fatalf("Expected ErrDeadlineUnreachable, got %v", err)
+	}
+	if wait := l.EstimatedWait(); wait < 59*time.Minute {
+		t.Fatalf("Expected the canceled reservation to leave an hour's wait, got %v", wait)
+	}
+}
+
+func TestLimiter_Pipeline(t *testing.T) {
+	t.Parallel()
+	p := failover.NewPipeline(failover.WithPolicy(NewLimiter(rate.NewLimiter(rate.Inf, 0))))
+
+	if err := p.Do(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
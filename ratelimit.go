@@ -0,0 +1,222 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a call is rejected because the rate
+// limiter has no token available.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimiter paces calls. By default it is a token bucket: tokens are
+// added at a fixed rate up to a burst capacity and each call consumes one.
+// WithLeakyBucket turns it into a leaky bucket that spaces calls evenly.
+type RateLimiter struct {
+	mu sync.Mutex // Protects the bucket fields
+
+	rate  float64 // Tokens added per second
+	burst float64 // Bucket capacity
+	leaky bool    // Space calls evenly instead of allowing bursts
+
+	tokens float64
+	last   time.Time // When tokens was last brought up to date
+	next   time.Time // Earliest start of the next call, leaky bucket only
+}
+
+// LimiterOption configures optional RateLimiter behavior.
+type LimiterOption func(*RateLimiter)
+
+// WithLeakyBucket makes the limiter strictly smooth the outbound rate: calls
+// are spaced at least 1/rate apart and the burst is ignored. Use it for
+// dependencies with hard per-second quotas that reject bursts.
+func WithLeakyBucket() LimiterOption {
+	return func(rl *RateLimiter) {
+		rl.leaky = true
+	}
+}
+
+// NewRateLimiter creates a RateLimiter allowing rate calls per second with
+// bursts of up to burst calls. The bucket starts full. It panics if rate is
+// not positive, as the limiter could then never refill.
+func NewRateLimiter(rate float64, burst int, opts ...LimiterOption) *RateLimiter {
+	checkRate(rate)
+	rl := &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(rl)
+	}
+
+	return rl
+}
+
+// SetRateLimit changes the rate and burst of the limiter. Calls already
+// waiting keep the delay they were given; tokens above the new burst are
+// dropped. Like NewRateLimiter, it panics if rate is not positive.
+func (rl *RateLimiter) SetRateLimit(rate float64, burst int) {
+	checkRate(rate)
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.advance(time.Now())
+	rl.rate = rate
+	rl.burst = float64(burst)
+	rl.tokens = min(rl.tokens, rl.burst)
+}
+
+func checkRate(rate float64) {
+	if !(rate > 0) {
+		panic(fmt.Sprintf("failover: rate limiter rate must be positive, got %v", rate))
+	}
+}
+
+// Allow reports whether a call may happen now, consuming a token if so.
+func (rl *RateLimiter) Allow() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if rl.leaky {
+		if now.Before(rl.next) {
+			return false
+		}
+
+		rl.next = now.Add(rl.interval())
+		return true
+	}
+
+	rl.advance(now)
+	if rl.tokens < 1 {
+		return false
+	}
+
+	rl.tokens--
+	return true
+}
+
+// Wait blocks until a token is available or ctx is done. If ctx has a
+// deadline sooner than the token would become available, Wait returns
+// ErrDeadlineUnreachable at once without consuming a token.
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	delay, slot := rl.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		rl.cancel(slot)
+		return rejectWith(rejectedRateLimiterLate)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		rl.cancel(slot)
+		return ctx.Err()
+	}
+}
+
+// EstimatedWait returns how long a Wait call made now would block.
+func (rl *RateLimiter) EstimatedWait() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if rl.leaky {
+		return max(rl.next.Sub(now), 0)
+	}
+
+	rl.advance(now)
+	if rl.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - rl.tokens) / rl.rate * float64(time.Second))
+}
+
+// Execute calls fn if a token is available and returns ErrRateLimited
+// otherwise.
+func (rl *RateLimiter) Execute(fn WorkFunc) error {
+	if !rl.Allow() {
+		return rejectWith(rejectedRateLimited)
+	}
+
+	return fn()
+}
+
+// Do waits for a token and then executes fn.
+func (rl *RateLimiter) Do(ctx context.Context, fn WorkFuncCtx) error {
+	if err := rl.Wait(ctx); err != nil {
+		return err
+	}
+
+	return fn(ctx)
+}
+
+// reserve takes a token, borrowing against future refills if necessary, and
+// returns how long the caller must wait before using it. For a leaky bucket
+// it also returns the start of the reserved slot, to be passed to cancel.
+func (rl *RateLimiter) reserve(now time.Time) (time.Duration, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.leaky {
+		start := rl.next
+		if start.Before(now) {
+			start = now
+		}
+
+		rl.next = start.Add(rl.interval())
+		return start.Sub(now), start
+	}
+
+	rl.advance(now)
+	rl.tokens--
+	if rl.tokens >= 0 {
+		return 0, now
+	}
+
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second)), now
+}
+
+// cancel returns a token taken by reserve that will not be used. A leaky
+// bucket only gives the slot back if no later reservation follows it;
+// otherwise rewinding next would let the following caller share a slot.
+func (rl *RateLimiter) cancel(slot time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.leaky {
+		if rl.next.Equal(slot.Add(rl.interval())) {
+			rl.next = slot
+		}
+		return
+	}
+
+	rl.tokens = min(rl.tokens+1, rl.burst)
+}
+
+// interval is the spacing between calls at the configured rate.
+func (rl *RateLimiter) interval() time.Duration {
+	return time.Duration(float64(time.Second) / rl.rate)
+}
+
+// advance refills the bucket for the time elapsed since the last update.
+func (rl *RateLimiter) advance(now time.Time) {
+	if elapsed := now.Sub(rl.last); elapsed > 0 {
+		rl.tokens = min(rl.tokens+elapsed.Seconds()*rl.rate, rl.burst)
+		rl.last = now
+	}
+}
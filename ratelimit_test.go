@@ -0,0 +1,218 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Burst(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(1, 3)
+
+	for i := range 3 {
+		if !rl.Allow() {
+			t.Fatalf("Expected call %d within burst to be allowed", i+1)
+		}
+	}
+	if rl.Allow() {
+		t.Fatal("Expected call beyond burst to be rejected")
+	}
+}
+
+func TestRateLimiter_Execute(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(1, 1)
+
+	if err := rl.Execute(func() error { return errTest }); !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+	if err := rl.Execute(func() error { return nil }); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestRateLimiter_WaitPaces(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(50, 1) // one token every 20ms
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 3 {
+		if err := rl.Wait(ctx); err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+
+	// The first token is free, the next two wait ~20ms each.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("Expected Wait to pace calls, took only %v", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitCanceled(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(1, 1)
+	rl.Allow()
+
+	// Cancel rather than set a deadline, which Wait would reject up front.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if err := rl.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// The canceled reservation is returned, so the bucket is not left in debt.
+	rl.mu.Lock()
+	tokens := rl.tokens
+	rl.mu.Unlock()
+	if tokens < 0 {
+		t.Fatalf("Expected canceled reservation to be returned, got %v tokens", tokens)
+	}
+}
+
+func TestRateLimiter_LeakyBucketNoBurst(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(10, 5, WithLeakyBucket())
+
+	if !rl.Allow() {
+		t.Fatal("Expected first call to be allowed")
+	}
+	// Despite the burst of 5, the next call must wait a full interval.
+	if rl.Allow() {
+		t.Fatal("Expected leaky bucket to reject a burst")
+	}
+}
+
+func TestRateLimiter_LeakyBucketSpacing(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(50, 10, WithLeakyBucket()) // one call every 20ms
+	ctx := context.Background()
+
+	last := time.Now()
+	for i := range 3 {
+		if err := rl.Wait(ctx); err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+
+		now := time.Now()
+		if gap := now.Sub(last); i > 0 && gap < 15*time.Millisecond {
+			t.Fatalf("Expected calls spaced ~20ms apart, got %v", gap)
+		}
+		last = now
+	}
+}
+
+func TestRateLimiter_LeakyBucketCancel(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(10, 1, WithLeakyBucket()) // one call every 100ms
+	now := time.Now()
+	interval := 100 * time.Millisecond
+
+	_, first := rl.reserve(now)
+	_, second := rl.reserve(now)
+
+	// Cancelling a slot with a later reservation behind it must not rewind
+	// next, or the following caller would share the second slot.
+	rl.cancel(first)
+	if delay, slot := rl.reserve(now); delay != 2*interval || !slot.Equal(second.Add(interval)) {
+		t.Fatalf("Expected third call to wait %v, got %v", 2*interval, delay)
+	}
+
+	// Cancelling the most recent slot gives it back to the next caller.
+	_, fourth := rl.reserve(now)
+	rl.cancel(fourth)
+	if delay, slot := rl.reserve(now); delay != 3*interval || !slot.Equal(fourth) {
+		t.Fatalf("Expected reused slot to wait %v, got %v", 3*interval, delay)
+	}
+
+	// Once the tail is cancelled, the slot before it is the tail again.
+	rl.cancel(fourth)
+	rl.cancel(second.Add(interval))
+	if delay, _ := rl.reserve(now); delay != 2*interval {
+		t.Fatalf("Expected rewound slot to wait %v, got %v", 2*interval, delay)
+	}
+}
+
+func TestRateLimiter_Do(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(1, 1)
+	rl.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	called := false
+	err := rl.Do(ctx, func(context.Context) error {
+		called = true
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) || called {
+		t.Fatalf("Expected Do to give up waiting without calling fn, got %v (called=%v)", err, called)
+	}
+}
+
+func TestRateLimiter_DeadlineUnreachable(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(1, 1)
+	rl.Allow()
+
+	if wait := rl.EstimatedWait(); wait < 900*time.Millisecond {
+		t.Fatalf("Expected estimated wait close to 1s, got %v", wait)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := rl.Wait(ctx); !errors.Is(err, ErrDeadlineUnreachable) {
+		t.Fatalf("Expected ErrDeadlineUnreachable, got %v", err)
+	}
+	if time.Since(start) > 20*time.Millisecond {
+		t.Fatal("Expected Wait to give up without waiting")
+	}
+}
+
+func TestRateLimiter_SetRateLimit(t *testing.T) {
+	t.Parallel()
+	rl := NewRateLimiter(1, 3)
+
+	rl.SetRateLimit(1000, 1)
+	if !rl.Allow() {
+		t.Fatal("Expected the first call to be allowed")
+	}
+	if rl.Allow() {
+		t.Fatal("Expected tokens above the new burst to be dropped")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if !rl.Allow() {
+		t.Fatal("Expected the new rate to refill the bucket")
+	}
+}
+
+func TestNewRateLimiter_InvalidRate(t *testing.T) {
+	t.Parallel()
+
+	for _, rate := range []float64{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected a panic for rate %v", rate)
+				}
+			}()
+			NewRateLimiter(rate, 1)
+		}()
+	}
+
+	rl := NewRateLimiter(1, 1)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected SetRateLimit to panic for rate 0")
+		}
+	}()
+	rl.SetRateLimit(0, 1)
+}
@@ -0,0 +1,255 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReconnectingConn is a net.Conn that re-dials with backoff when a read or
+// write fails, so long-lived protocol clients survive dropped connections
+// without handling them at every call site.
+//
+// A failed Read is retried on the new connection; a failed Write is
+// repeated in full, as a partial write can not be resumed on a different
+// connection. State the server keeps per connection, such as a login or
+// subscriptions, is restored by the hook set with WithReconnectFunc.
+// Errors from deadlines set with SetDeadline are returned as is, without
+// reconnecting.
+type ReconnectingConn struct {
+	dial        func(ctx context.Context) (net.Conn, error)
+	backoff     Backoff
+	maxAttempts int // Dials per reconnect, zero for no limit
+	onReconnect func(ctx context.Context, conn net.Conn) error
+
+	ctx    context.Context // Done once the conn is closed
+	cancel context.CancelFunc
+
+	mu            sync.Mutex // Protects the fields below; held while reconnecting
+	conn          net.Conn
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// ReconnectOption configures optional ReconnectingConn behavior.
+type ReconnectOption func(*ReconnectingConn)
+
+// WithReconnectBackoff sets the wait between dials while reconnecting. The
+// default doubles from 100ms up to 30s, with jitter.
+func WithReconnectBackoff(b Backoff) ReconnectOption {
+	return func(r *ReconnectingConn) {
+		r.backoff = b
+	}
+}
+
+// WithMaxReconnectAttempts gives up reconnecting after n failed dials in a
+// row, failing the read or write that needed the connection. By default a
+// ReconnectingConn keeps trying until it is closed.
+func WithMaxReconnectAttempts(n int) ReconnectOption {
+	return func(r *ReconnectingConn) {
+		r.maxAttempts = n
+	}
+}
+
+// WithReconnectFunc calls fn on every new connection before it is used,
+// for instance to authenticate again or renew subscriptions. An error from
+// fn discards the connection and counts as a failed dial.
+func WithReconnectFunc(fn func(ctx context.Context, conn net.Conn) error) ReconnectOption {
+	return func(r *ReconnectingConn) {
+		r.onReconnect = fn
+	}
+}
+
+// NewReconnectingConn dials a first connection with dial and returns it
+// wrapped in a ReconnectingConn. The hook set with WithReconnectFunc is not
+// called for this first connection.
+func NewReconnectingConn(ctx context.Context, dial func(ctx context.Context) (net.Conn, error), opts ...ReconnectOption) (*ReconnectingConn, error) {
+	r := &ReconnectingConn{
+		dial: dial,
+		backoff: ExponentialBackoff{
+			Initial: 100 * time.Millisecond,
+			Max:     30 * time.Second,
+			Jitter:  0.2,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn = conn
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r, nil
+}
+
+// Read implements net.Conn.
+func (r *ReconnectingConn) Read(p []byte) (int, error) {
+	for {
+		conn := r.current()
+		n, err := conn.Read(p)
+		if n > 0 || !r.broken(err) {
+			return n, err
+		}
+
+		if err := r.reconnect(conn); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write implements net.Conn.
+func (r *ReconnectingConn) Write(p []byte) (int, error) {
+	for {
+		conn := r.current()
+		n, err := conn.Write(p)
+		if !r.broken(err) {
+			return n, err
+		}
+
+		if err := r.reconnect(conn); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Close closes the connection and stops any reconnect in progress.
+func (r *ReconnectingConn) Close() error {
+	r.cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.conn.Close()
+}
+
+// LocalAddr implements net.Conn, for the current connection.
+func (r *ReconnectingConn) LocalAddr() net.Addr {
+	return r.current().LocalAddr()
+}
+
+// RemoteAddr implements net.Conn, for the current connection.
+func (r *ReconnectingConn) RemoteAddr() net.Addr {
+	return r.current().RemoteAddr()
+}
+
+// SetDeadline implements net.Conn. Deadlines carry over to new
+// connections.
+func (r *ReconnectingConn) SetDeadline(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.readDeadline, r.writeDeadline = t, t
+	return r.conn.SetDeadline(t)
+}
+
+// SetReadDeadline implements net.Conn.
+func (r *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.readDeadline = t
+	return r.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements net.Conn.
+func (r *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.writeDeadline = t
+	return r.conn.SetWriteDeadline(t)
+}
+
+func (r *ReconnectingConn) current() net.Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.conn
+}
+
+// broken reports whether err means the connection must be replaced.
+func (r *ReconnectingConn) broken(err error) bool {
+	if err == nil || r.ctx.Err() != nil {
+		return false
+	}
+
+	var ne net.Error
+	return !errors.As(err, &ne) || !ne.Timeout()
+}
+
+// reconnect replaces old with a new connection, unless another caller
+// already did.
+func (r *ReconnectingConn) reconnect(old net.Conn) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != old {
+		return nil
+	}
+	old.Close()
+
+	for attempt := 1; ; attempt++ {
+		conn, err := r.redial()
+		if err == nil {
+			r.conn = conn
+			return nil
+		}
+		if r.ctx.Err() != nil {
+			return net.ErrClosed
+		}
+		if r.maxAttempts > 0 && attempt >= r.maxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(r.backoff.Delay(attempt))
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+			return net.ErrClosed
+		}
+	}
+}
+
+// redial dials and prepares one new connection.
+func (r *ReconnectingConn) redial() (net.Conn, error) {
+	conn, err := r.dial(r.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.onReconnect != nil {
+		if err := r.onReconnect(r.ctx, conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if err := r.applyDeadlines(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// applyDeadlines sets the current deadlines on conn.
+func (r *ReconnectingConn) applyDeadlines(conn net.Conn) error {
+	if !r.readDeadline.IsZero() {
+		if err := conn.SetReadDeadline(r.readDeadline); err != nil {
+			return err
+		}
+	}
+	if !r.writeDeadline.IsZero() {
+		return conn.SetWriteDeadline(r.writeDeadline)
+	}
+
+	return nil
+}
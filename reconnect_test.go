@@ -0,0 +1,189 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeServer hands out connections whose server ends the test controls.
+type pipeServer struct {
+	mu      sync.Mutex
+	servers []net.Conn
+	fail    int // Dials to fail before the next success
+}
+
+func (s *pipeServer) dial(context.Context) (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail > 0 {
+		s.fail--
+		return nil, errTest
+	}
+	client, server := net.Pipe()
+	s.servers = append(s.servers, server)
+	return client, nil
+}
+
+// server returns the server end of the i-th connection.
+func (s *pipeServer) server(i int) net.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.servers[i]
+}
+
+func (s *pipeServer) dials() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.servers)
+}
+
+func TestReconnectingConn_ReadReconnects(t *testing.T) {
+	t.Parallel()
+	srv := &pipeServer{}
+	var hooks int
+	conn, err := NewReconnectingConn(context.Background(), srv.dial,
+		WithReconnectBackoff(ConstantBackoff(time.Millisecond)),
+		WithReconnectFunc(func(context.Context, net.Conn) error {
+			hooks++
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	// The first connection drops; the second one answers.
+	srv.mu.Lock()
+	srv.fail = 2
+	srv.mu.Unlock()
+	srv.server(0).Close()
+	go func() {
+		for srv.dials() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		srv.server(1).Write([]byte("hello"))
+	}()
+
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("Expected to read from the new connection, got %q", buf)
+	}
+	if hooks != 1 {
+		t.Fatalf("Expected the reconnect hook to run once, got %d", hooks)
+	}
+}
+
+func TestReconnectingConn_WriteRepeated(t *testing.T) {
+	t.Parallel()
+	srv := &pipeServer{}
+	conn, err := NewReconnectingConn(context.Background(), srv.dial,
+		WithReconnectBackoff(ConstantBackoff(time.Millisecond)))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	srv.server(0).Close()
+	got := make(chan string, 1)
+	go func() {
+		for srv.dials() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		buf := make([]byte, 4)
+		io.ReadFull(srv.server(1), buf)
+		got <- string(buf)
+	}()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if msg := <-got; msg != "ping" {
+		t.Fatalf("Expected the write repeated on the new connection, got %q", msg)
+	}
+}
+
+func TestReconnectingConn_MaxAttempts(t *testing.T) {
+	t.Parallel()
+	srv := &pipeServer{}
+	conn, err := NewReconnectingConn(context.Background(), srv.dial,
+		WithReconnectBackoff(ConstantBackoff(time.Millisecond)),
+		WithMaxReconnectAttempts(2),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	srv.mu.Lock()
+	srv.fail = 5
+	srv.mu.Unlock()
+	srv.server(0).Close()
+
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, errTest) {
+		t.Fatalf("Expected the dial error once attempts ran out, got %v", err)
+	}
+}
+
+func TestReconnectingConn_DeadlineNotReconnected(t *testing.T) {
+	t.Parallel()
+	srv := &pipeServer{}
+	conn, err := NewReconnectingConn(context.Background(), srv.dial)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if srv.dials() != 1 {
+		t.Fatalf("Expected no reconnect on a timeout, got %d dials", srv.dials())
+	}
+}
+
+func TestReconnectingConn_CloseStopsReconnect(t *testing.T) {
+	t.Parallel()
+	srv := &pipeServer{}
+	conn, err := NewReconnectingConn(context.Background(), srv.dial,
+		WithReconnectBackoff(ConstantBackoff(time.Hour)))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	srv.mu.Lock()
+	srv.fail = 1
+	srv.mu.Unlock()
+	srv.server(0).Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	conn.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Expected net.ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Close to stop the reconnect")
+	}
+}
@@ -0,0 +1,216 @@
+package failover
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Reconnector keeps a long-lived connection of type C, such as a WebSocket,
+// open: it dials it, checks it is alive and dials a new one with backoff
+// whenever the current one fails. Callers get the current connection from
+// Conn, and report a connection they found broken with Reconnect.
+//
+// Dialing is left to the caller's functions, so it works with any
+// WebSocket or streaming client library.
+type Reconnector[C comparable] struct {
+	dial  func(ctx context.Context) (C, error)
+	close func(conn C) error
+
+	backoff       Backoff
+	ping          func(ctx context.Context, conn C) error // Optional liveness check
+	pingInterval  time.Duration
+	onReconnected func(ctx context.Context, conn C) error
+
+	mu     sync.Mutex // Protects the fields below
+	conn   C
+	ok     bool          // Whether conn is connected
+	ready  chan struct{} // Closed once connected
+	broken chan struct{} // Closed when the current connection is reported broken
+}
+
+// ReconnectorOption configures optional Reconnector behavior.
+type ReconnectorOption[C comparable] func(*Reconnector[C])
+
+// WithPing checks the connection every interval with fn, which should send
+// a ping and wait for the reply. The connection is replaced when fn fails
+// or does not return within interval.
+func WithPing[C comparable](interval time.Duration, fn func(ctx context.Context, conn C) error) ReconnectorOption[C] {
+	return func(r *Reconnector[C]) {
+		r.ping = fn
+		r.pingInterval = interval
+	}
+}
+
+// WithReconnectedFunc calls fn on every new connection before it is handed
+// out, the first one included, for instance to restore subscriptions. An
+// error from fn closes the connection and counts as a failed dial.
+func WithReconnectedFunc[C comparable](fn func(ctx context.Context, conn C) error) ReconnectorOption[C] {
+	return func(r *Reconnector[C]) {
+		r.onReconnected = fn
+	}
+}
+
+// WithRedialBackoff sets the wait between failed dials. The default
+// doubles from 100ms up to 30s, with jitter.
+func WithRedialBackoff[C comparable](b Backoff) ReconnectorOption[C] {
+	return func(r *Reconnector[C]) {
+		r.backoff = b
+	}
+}
+
+// NewReconnector creates a Reconnector opening connections with dial and
+// closing them with closeConn. Nothing is dialed before Run.
+func NewReconnector[C comparable](dial func(ctx context.Context) (C, error), closeConn func(conn C) error, opts ...ReconnectorOption[C]) *Reconnector[C] {
+	r := &Reconnector[C]{
+		dial:  dial,
+		close: closeConn,
+		backoff: ExponentialBackoff{
+			Initial: 100 * time.Millisecond,
+			Max:     30 * time.Second,
+			Jitter:  0.2,
+		},
+		ready: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run keeps a connection open until ctx is done, then closes it and
+// returns ctx.Err().
+func (r *Reconnector[C]) Run(ctx context.Context) error {
+	for failures := 0; ; {
+		conn, err := r.connect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			failures++
+			timer := time.NewTimer(r.backoff.Delay(failures))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+			continue
+		}
+		failures = 0
+
+		broken := r.set(conn)
+		r.supervise(ctx, conn, broken)
+		r.unset()
+		_ = r.close(conn)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Conn returns the current connection, waiting for one if the Reconnector
+// is between connections.
+func (r *Reconnector[C]) Conn(ctx context.Context) (C, error) {
+	for {
+		r.mu.Lock()
+		conn, ok, ready := r.conn, r.ok, r.ready
+		r.mu.Unlock()
+
+		if ok {
+			return conn, nil
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			var zero C
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// Reconnect reports conn as broken, typically after a read from it failed,
+// so that it is replaced. It does nothing if conn was already replaced.
+func (r *Reconnector[C]) Reconnect(conn C) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ok && r.conn == conn {
+		close(r.broken)
+		r.ok = false
+		r.ready = make(chan struct{})
+	}
+}
+
+// connect dials a connection and runs the reconnected hook on it.
+func (r *Reconnector[C]) connect(ctx context.Context) (C, error) {
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return conn, err
+	}
+
+	if r.onReconnected != nil {
+		if err := r.onReconnected(ctx, conn); err != nil {
+			_ = r.close(conn)
+			return conn, err
+		}
+	}
+
+	return conn, nil
+}
+
+// set makes conn the current connection and returns the channel closed
+// when it is reported broken.
+func (r *Reconnector[C]) set(conn C) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.conn, r.ok = conn, true
+	r.broken = make(chan struct{})
+	close(r.ready)
+	return r.broken
+}
+
+// unset marks the Reconnector as between connections, if Reconnect did
+// not already.
+func (r *Reconnector[C]) unset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ok {
+		r.ok = false
+		r.ready = make(chan struct{})
+	}
+}
+
+// supervise returns when conn is reported broken, fails its ping, or ctx
+// is done.
+func (r *Reconnector[C]) supervise(ctx context.Context, conn C, broken <-chan struct{}) {
+	var tick <-chan time.Time
+	if r.ping != nil {
+		ticker := time.NewTicker(r.pingInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			pingCtx, cancel := context.WithTimeoutCause(ctx, r.pingInterval, errDeadline)
+			err := r.ping(pingCtx, conn)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-broken:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSocket is a connection of a fakeSocketServer.
+type fakeSocket struct {
+	id     int
+	dead   atomic.Bool
+	closed atomic.Bool
+}
+
+// fakeSocketServer dials fakeSockets, failing while down is set.
+type fakeSocketServer struct {
+	mu      sync.Mutex
+	down    bool
+	sockets []*fakeSocket
+}
+
+func (s *fakeSocketServer) dial(context.Context) (*fakeSocket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		return nil, errTest
+	}
+	sock := &fakeSocket{id: len(s.sockets)}
+	s.sockets = append(s.sockets, sock)
+	return sock, nil
+}
+
+func (s *fakeSocketServer) close(sock *fakeSocket) error {
+	sock.closed.Store(true)
+	return nil
+}
+
+func (s *fakeSocketServer) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// runReconnector runs r until the test ends.
+func runReconnector[C comparable](t *testing.T, r *Reconnector[C]) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// connWithin returns the current connection, failing the test if there is
+// none within a second.
+func connWithin[C comparable](t *testing.T, r *Reconnector[C]) C {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	conn, err := r.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Expected a connection, got %v", err)
+	}
+	return conn
+}
+
+func TestReconnector_Reconnect(t *testing.T) {
+	t.Parallel()
+	srv := &fakeSocketServer{}
+	var restored atomic.Int32
+	r := NewReconnector(srv.dial, srv.close,
+		WithRedialBackoff[*fakeSocket](ConstantBackoff(time.Millisecond)),
+		WithReconnectedFunc(func(context.Context, *fakeSocket) error {
+			restored.Add(1)
+			return nil
+		}),
+	)
+	runReconnector(t, r)
+
+	first := connWithin(t, r)
+	srv.setDown(true)
+	r.Reconnect(first)
+	r.Reconnect(first) // reported twice, replaced once
+
+	time.Sleep(10 * time.Millisecond)
+	srv.setDown(false)
+
+	second := connWithin(t, r)
+	if second == first || !first.closed.Load() {
+		t.Fatal("Expected the broken connection to be closed and replaced")
+	}
+	if got := restored.Load(); got != 2 {
+		t.Fatalf("Expected the reconnected hook on both connections, got %d", got)
+	}
+}
+
+func TestReconnector_Ping(t *testing.T) {
+	t.Parallel()
+	srv := &fakeSocketServer{}
+	r := NewReconnector(srv.dial, srv.close,
+		WithPing(5*time.Millisecond, func(_ context.Context, sock *fakeSocket) error {
+			if sock.dead.Load() {
+				return errors.New("no pong")
+			}
+			return nil
+		}),
+	)
+	runReconnector(t, r)
+
+	first := connWithin(t, r)
+	first.dead.Store(true)
+
+	deadline := time.Now().Add(time.Second)
+	for connWithin(t, r) == first {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the failed ping to replace the connection")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReconnector_ConnWaits(t *testing.T) {
+	t.Parallel()
+	srv := &fakeSocketServer{down: true}
+	r := NewReconnector(srv.dial, srv.close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.Conn(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestReconnector_RunClosesOnExit(t *testing.T) {
+	t.Parallel()
+	srv := &fakeSocketServer{}
+	r := NewReconnector(srv.dial, srv.close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	sock := connWithin(t, r)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if !sock.closed.Load() {
+		t.Fatal("Expected Run to close the connection")
+	}
+}
@@ -0,0 +1,221 @@
+package failover
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Registry holds breakers, retry policies and other policies by name, so
+// that they can be looked up, inspected and reported on in one place, such
+// as with PublishExpvar. Each kind has names of its own; bulkheads,
+// timeouts and pipelines are registered as policies. It is safe for
+// concurrent use, and a nil *Registry stands for DefaultRegistry.
+type Registry struct {
+	mu       sync.RWMutex // Protects breakers, retries and policies
+	breakers map[string]Breaker
+	retries  map[string]*RetryPolicy
+	policies map[string]Policy
+}
+
+// DefaultRegistry is the registry used where none is given, so that
+// middleware, config loaders, metrics and the admin handler all address the
+// same policies without passing a registry around.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		breakers: make(map[string]Breaker),
+		retries:  make(map[string]*RetryPolicy),
+		policies: make(map[string]Policy),
+	}
+}
+
+// AddBreaker registers b under name, replacing any breaker of that name.
+func (r *Registry) AddBreaker(name string, b Breaker) {
+	r = r.orDefault()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.breakers[name] = b
+}
+
+// AddRetry registers p under name, replacing any retry policy of that
+// name.
+func (r *Registry) AddRetry(name string, p *RetryPolicy) {
+	r = r.orDefault()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retries[name] = p
+}
+
+// AddPolicy registers p under name, replacing any policy of that name.
+func (r *Registry) AddPolicy(name string, p Policy) {
+	r = r.orDefault()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.policies[name] = p
+}
+
+// Remove unregisters the breaker, retry policy and policy of name.
+func (r *Registry) Remove(name string) {
+	r = r.orDefault()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.breakers, name)
+	delete(r.retries, name)
+	delete(r.policies, name)
+}
+
+// Replace sets the breaker, retry policy and policy of each of names to
+// those src has under the name, removing the kinds src lacks, in one step:
+// concurrent lookups see either the old entries or the new ones, never a
+// name with nothing registered in between.
+func (r *Registry) Replace(names []string, src *Registry) {
+	breakers, retries, policies := src.Breakers(), src.Retries(), src.Policies()
+
+	r = r.orDefault()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		replace(r.breakers, breakers, name)
+		replace(r.retries, retries, name)
+		replace(r.policies, policies, name)
+	}
+}
+
+// replace sets m[name] to src[name], or deletes it if src has none.
+func replace[T any](m, src map[string]T, name string) {
+	if v, ok := src[name]; ok {
+		m[name] = v
+	} else {
+		delete(m, name)
+	}
+}
+
+// Policy returns the policy registered under name.
+func (r *Registry) Policy(name string) (Policy, bool) {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.policies[name]
+	return p, ok
+}
+
+// Breaker returns the breaker registered under name.
+func (r *Registry) Breaker(name string) (Breaker, bool) {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.breakers[name]
+	return b, ok
+}
+
+// Retry returns the retry policy registered under name.
+func (r *Registry) Retry(name string) (*RetryPolicy, bool) {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.retries[name]
+	return p, ok
+}
+
+// List returns the names registered for any kind, sorted.
+func (r *Registry) List() []string {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	names = slices.AppendSeq(names, maps.Keys(r.breakers))
+	names = slices.AppendSeq(names, maps.Keys(r.retries))
+	names = slices.AppendSeq(names, maps.Keys(r.policies))
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+// Breakers returns the registered breakers by name.
+func (r *Registry) Breakers() map[string]Breaker {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.breakers)
+}
+
+// Retries returns the registered retry policies by name.
+func (r *Registry) Retries() map[string]*RetryPolicy {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.retries)
+}
+
+// Policies returns the registered policies by name.
+func (r *Registry) Policies() map[string]Policy {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.policies)
+}
+
+// orDefault returns r, or DefaultRegistry if r is nil.
+func (r *Registry) orDefault() *Registry {
+	if r == nil {
+		return DefaultRegistry
+	}
+
+	return r
+}
+
+// Get returns what is registered under name in r as a T, looking at the
+// breaker, the retry policy and the policy of that name in turn, such as
+// Get[*CircuitBreaker](nil, "db") for the breaker of DefaultRegistry.
+func Get[T any](r *Registry, name string) (T, bool) {
+	r = r.orDefault()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found []any
+	if b, ok := r.breakers[name]; ok {
+		found = append(found, b)
+	}
+	if p, ok := r.retries[name]; ok {
+		found = append(found, p)
+	}
+	if p, ok := r.policies[name]; ok {
+		found = append(found, p)
+	}
+
+	for _, v := range found {
+		if t, ok := v.(T); ok {
+			return t, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// MustGet is Get for policies that must be registered, such as those a
+// program sets up at start. It panics if there is no T under name.
+func MustGet[T any](r *Registry, name string) T {
+	t, ok := Get[T](r, name)
+	if !ok {
+		panic(fmt.Sprintf("failover: no %T registered as %q", t, name))
+	}
+
+	return t
+}
@@ -0,0 +1,149 @@
+package failover
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	retry := NewRetryPolicy(3, time.Millisecond)
+
+	r.AddBreaker("db", cb)
+	r.AddRetry("db", retry)
+	r.AddPolicy("db", NoopRetrier{})
+
+	if b := r.Breakers()["db"]; b != cb {
+		t.Fatalf("Expected the registered breaker, got %v", b)
+	}
+	if p := r.Retries()["db"]; p != retry {
+		t.Fatalf("Expected the registered retry policy, got %v", p)
+	}
+
+	if p, ok := r.Policy("db"); !ok || p != (NoopRetrier{}) {
+		t.Fatalf("Expected the registered policy, got %v", p)
+	}
+
+	r.Remove("db")
+	if n := len(r.Breakers()) + len(r.Retries()) + len(r.Policies()); n != 0 {
+		t.Fatalf("Expected an empty registry, got %d policies", n)
+	}
+}
+
+func TestRegistry_Replace(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	r.AddBreaker("db", NoopBreaker{})
+	r.AddRetry("db", NewRetryPolicy(2, time.Millisecond))
+	r.AddPolicy("cache", NoopRetrier{})
+	r.AddPolicy("other", NoopRetrier{})
+
+	src := NewRegistry()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	src.AddBreaker("db", cb)
+	src.AddPolicy("queue", NoopRetrier{})
+	r.Replace([]string{"db", "cache", "queue"}, src)
+
+	if b, _ := r.Breaker("db"); b != cb {
+		t.Fatalf("Expected the new breaker, got %v", b)
+	}
+	if _, ok := r.Retry("db"); ok {
+		t.Fatal("Expected the retry policy src lacks to be removed")
+	}
+	if names := r.List(); len(names) != 3 || names[0] != "db" || names[1] != "other" || names[2] != "queue" {
+		t.Fatalf("Expected db, other and queue, got %v", names)
+	}
+}
+
+func TestRegistry_ReturnsCopies(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	r.AddBreaker("db", NoopBreaker{})
+
+	delete(r.Breakers(), "db")
+
+	if _, ok := r.Breakers()["db"]; !ok {
+		t.Fatal("Expected changes to the returned map not to affect the registry")
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	retry := NewRetryPolicy(3, time.Millisecond)
+	r.AddBreaker("db", cb)
+	r.AddRetry("db", retry)
+	r.AddPolicy("cache", NoopRetrier{})
+
+	if b, ok := r.Breaker("db"); !ok || b != cb {
+		t.Fatalf("Expected the registered breaker, got %v", b)
+	}
+	if p, ok := r.Retry("db"); !ok || p != retry {
+		t.Fatalf("Expected the registered retry policy, got %v", p)
+	}
+	if _, ok := r.Retry("cache"); ok {
+		t.Fatal("Expected no retry policy for cache")
+	}
+
+	names := r.List()
+	if len(names) != 2 || names[0] != "cache" || names[1] != "db" {
+		t.Fatalf("Expected [cache db], got %v", names)
+	}
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	cb := NewCircuitBreaker(1, 1, time.Minute)
+	retry := NewRetryPolicy(3, time.Millisecond)
+	r.AddBreaker("db", cb)
+	r.AddRetry("db", retry)
+
+	if got, ok := Get[*CircuitBreaker](r, "db"); !ok || got != cb {
+		t.Fatalf("Expected the breaker, got %v", got)
+	}
+	if got, ok := Get[*RetryPolicy](r, "db"); !ok || got != retry {
+		t.Fatalf("Expected the retry policy, got %v", got)
+	}
+	if got, ok := Get[Policy](r, "db"); !ok || got != Policy(cb) {
+		t.Fatalf("Expected the breaker as the first Policy, got %v", got)
+	}
+	if _, ok := Get[*Bulkhead](r, "db"); ok {
+		t.Fatal("Expected no bulkhead")
+	}
+	if _, ok := Get[*RetryPolicy](r, "missing"); ok {
+		t.Fatal("Expected nothing under an unknown name")
+	}
+}
+
+func TestMustGet(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	r.AddPolicy("pool", NewBulkhead(1, 0, 0))
+
+	MustGet[*Bulkhead](r, "pool")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for an unknown name")
+		}
+	}()
+	MustGet[*Bulkhead](r, "missing")
+}
+
+func TestRegistry_NilIsDefault(t *testing.T) {
+	// Not parallel: uses DefaultRegistry.
+	var r *Registry
+	r.AddPolicy("registry-nil-test", NoopRetrier{})
+	defer DefaultRegistry.Remove("registry-nil-test")
+
+	if _, ok := DefaultRegistry.Policy("registry-nil-test"); !ok {
+		t.Fatal("Expected a nil registry to register in DefaultRegistry")
+	}
+	if _, ok := Get[NoopRetrier](nil, "registry-nil-test"); !ok {
+		t.Fatal("Expected Get on nil to look in DefaultRegistry")
+	}
+}
@@ -0,0 +1,267 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// maxResolverCache is the number of answers a Resolver caches at most.
+const maxResolverCache = 1024
+
+// NameResolver is the part of net.Resolver that a Resolver offers and
+// queries its upstreams through. Both *net.Resolver and *Resolver
+// implement it.
+type NameResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+var _ NameResolver = (*net.Resolver)(nil)
+var _ NameResolver = (*Resolver)(nil)
+
+// UpstreamResolver returns a net.Resolver that sends every query to the DNS
+// server at address, such as "1.1.1.1:53", for use as a Resolver upstream.
+func UpstreamResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// Resolver is a drop-in for the lookup methods of net.Resolver that queries
+// a list of upstream resolvers in order of preference. Each upstream has
+// its own breaker and a timeout per query, so a primary that fails or is
+// slow to answer is passed over for the secondaries.
+//
+// Answers are cached, and so are names that do not exist; a "no such host"
+// answer is authoritative and is not retried on the other upstreams.
+type Resolver struct {
+	upstreams   []NameResolver
+	breakers    []Breaker
+	timeout     time.Duration // Per upstream query, zero for none
+	ttl         time.Duration // How long answers are cached, zero to disable
+	negativeTTL time.Duration // How long missing names are cached, zero to disable
+
+	mu    sync.Mutex // Protects cache
+	cache map[string]resolverEntry
+}
+
+// resolverEntry is a cached answer, or a cached "no such host" error.
+type resolverEntry struct {
+	answer  any
+	err     error
+	expires time.Time
+}
+
+// ResolverOption configures optional Resolver behavior.
+type ResolverOption func(*Resolver)
+
+// WithLookupTimeout sets how long each upstream has to answer a query
+// before the next one is asked. The default is 2 seconds.
+func WithLookupTimeout(d time.Duration) ResolverOption {
+	return func(r *Resolver) {
+		r.timeout = d
+	}
+}
+
+// WithResolverBreakers guards each upstream with the breaker fn creates for
+// it. Upstreams whose breaker is open are skipped. The default breaker opens
+// after 5 failures in a row and stays open for 30 seconds; nil disables
+// breakers.
+func WithResolverBreakers(fn func() Breaker) ResolverOption {
+	return func(r *Resolver) {
+		for i := range r.breakers {
+			if fn == nil {
+				r.breakers[i] = NoopBreaker{}
+			} else {
+				r.breakers[i] = fn()
+			}
+		}
+	}
+}
+
+// WithResolverCache sets how long answers and missing names are cached. The
+// defaults are 30 and 5 seconds; zero disables either cache.
+func WithResolverCache(ttl, negativeTTL time.Duration) ResolverOption {
+	return func(r *Resolver) {
+		r.ttl = ttl
+		r.negativeTTL = negativeTTL
+	}
+}
+
+// NewResolver creates a Resolver over upstreams, in order of preference.
+func NewResolver(upstreams []NameResolver, opts ...ResolverOption) *Resolver {
+	r := &Resolver{
+		upstreams:   upstreams,
+		breakers:    make([]Breaker, len(upstreams)),
+		timeout:     2 * time.Second,
+		ttl:         30 * time.Second,
+		negativeTTL: 5 * time.Second,
+		cache:       make(map[string]resolverEntry),
+	}
+	for i := range r.breakers {
+		r.breakers[i] = NewCircuitBreaker(5, 1, 30*time.Second)
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// LookupHost looks up the addresses of host, like net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return lookup(ctx, r, "host\x00"+host, func(ctx context.Context, u NameResolver) ([]string, error) {
+		return u.LookupHost(ctx, host)
+	})
+}
+
+// LookupIPAddr looks up the IP addresses of host, like
+// net.Resolver.LookupIPAddr.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return lookup(ctx, r, "ip\x00"+host, func(ctx context.Context, u NameResolver) ([]net.IPAddr, error) {
+		return u.LookupIPAddr(ctx, host)
+	})
+}
+
+// LookupNetIP looks up the IP addresses of host on network "ip", "ip4" or
+// "ip6", like net.Resolver.LookupNetIP.
+func (r *Resolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	return lookup(ctx, r, "netip\x00"+network+"\x00"+host, func(ctx context.Context, u NameResolver) ([]netip.Addr, error) {
+		return u.LookupNetIP(ctx, network, host)
+	})
+}
+
+// LookupSRV looks up the SRV records of a service, like
+// net.Resolver.LookupSRV.
+func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	type answer struct {
+		cname string
+		srvs  []*net.SRV
+	}
+
+	a, err := lookup(ctx, r, "srv\x00"+service+"\x00"+proto+"\x00"+name, func(ctx context.Context, u NameResolver) (answer, error) {
+		cname, srvs, err := u.LookupSRV(ctx, service, proto, name)
+		return answer{cname, srvs}, err
+	})
+
+	return a.cname, a.srvs, err
+}
+
+// lookup answers the query key from the cache of r, or from the first
+// upstream that can answer it. It returns ErrNoEndpoint, joined with the
+// error of every upstream asked, if none could.
+func lookup[T any](ctx context.Context, r *Resolver, key string, query func(ctx context.Context, u NameResolver) (T, error)) (T, error) {
+	var zero T
+	if e, ok := r.cached(key); ok {
+		if e.err != nil {
+			return zero, e.err
+		}
+		return e.answer.(T), nil
+	}
+
+	var errs []error
+	for i, u := range r.upstreams {
+		if r.breakers[i].State() == Open {
+			continue
+		}
+
+		var answer T
+		var notFound error
+		err := runBreaker(ctx, r.breakers[i], func(ctx context.Context) error {
+			qctx := ctx
+			if r.timeout > 0 {
+				var cancel context.CancelFunc
+				qctx, cancel = context.WithTimeoutCause(ctx, r.timeout, errDeadline)
+				defer cancel()
+			}
+
+			var err error
+			answer, err = query(qctx, u)
+
+			// A missing name is an answer, not a failure of the upstream.
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				notFound = err
+				return nil
+			}
+			// An abandoned query says nothing about the upstream.
+			if err != nil && ctx.Err() != nil {
+				return nil
+			}
+			return err
+		})
+
+		if ctx.Err() != nil && notFound == nil {
+			return zero, ctx.Err()
+		}
+
+		if notFound != nil {
+			r.store(key, resolverEntry{err: notFound}, r.negativeTTL)
+			return zero, notFound
+		}
+		if err == nil {
+			r.store(key, resolverEntry{answer: answer}, r.ttl)
+			return answer, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return zero, ErrNoEndpoint
+	}
+	return zero, fmt.Errorf("%w: %w", ErrNoEndpoint, errors.Join(errs...))
+}
+
+// cached returns the unexpired cache entry for key.
+func (r *Resolver) cached(key string) (resolverEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.cache[key]
+	if !ok || time.Now().After(e.expires) {
+		return resolverEntry{}, false
+	}
+
+	return e, true
+}
+
+// store caches e under key for ttl, making room if the cache is full.
+func (r *Resolver) store(key string, e resolverEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if len(r.cache) >= maxResolverCache {
+		for k, old := range r.cache {
+			if now.After(old.expires) {
+				delete(r.cache, k)
+			}
+		}
+	}
+	if len(r.cache) >= maxResolverCache {
+		for k := range r.cache {
+			delete(r.cache, k) // any entry will do
+			break
+		}
+	}
+
+	e.expires = now.Add(ttl)
+	r.cache[key] = e
+}
@@ -0,0 +1,150 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeUpstream is a NameResolver that answers every host with addr, fails
+// with err, or hangs until the query times out.
+type fakeUpstream struct {
+	addr    string
+	err     error
+	hang    bool
+	queries atomic.Int32
+}
+
+func (u *fakeUpstream) LookupNetIP(ctx context.Context, _, host string) ([]netip.Addr, error) {
+	u.queries.Add(1)
+	if u.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if u.err != nil {
+		return nil, u.err
+	}
+	return []netip.Addr{netip.MustParseAddr(u.addr)}, nil
+}
+
+func (u *fakeUpstream) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := u.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	return []string{addrs[0].String()}, nil
+}
+
+func (u *fakeUpstream) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := u.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	return []net.IPAddr{{IP: addrs[0].AsSlice()}}, nil
+}
+
+func (u *fakeUpstream) LookupSRV(context.Context, string, string, string) (string, []*net.SRV, error) {
+	return "", nil, errors.New("not supported")
+}
+
+func TestResolver_FallsBackToSecondary(t *testing.T) {
+	t.Parallel()
+	primary := &fakeUpstream{err: errTest}
+	secondary := &fakeUpstream{addr: "10.0.0.2"}
+	r := NewResolver([]NameResolver{primary, secondary}, WithResolverCache(0, 0))
+
+	addrs, err := r.LookupHost(context.Background(), "db.internal")
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "10.0.0.2" {
+		t.Fatalf("Expected the secondary's answer, got %v", addrs)
+	}
+}
+
+func TestResolver_SlowPrimary(t *testing.T) {
+	t.Parallel()
+	primary := &fakeUpstream{hang: true}
+	secondary := &fakeUpstream{addr: "10.0.0.2"}
+	r := NewResolver([]NameResolver{primary, secondary}, WithLookupTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	addrs, err := r.LookupNetIP(context.Background(), "ip", "db.internal")
+
+	if err != nil || len(addrs) != 1 {
+		t.Fatalf("Expected the secondary's answer, got %v (%v)", addrs, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected the primary to time out quickly, took %v", elapsed)
+	}
+}
+
+func TestResolver_BreakerSkipsPrimary(t *testing.T) {
+	t.Parallel()
+	primary := &fakeUpstream{err: errTest}
+	secondary := &fakeUpstream{addr: "10.0.0.2"}
+	r := NewResolver([]NameResolver{primary, secondary},
+		WithResolverCache(0, 0),
+		WithResolverBreakers(func() Breaker { return NewCircuitBreaker(2, 1, time.Minute) }))
+
+	for range 5 {
+		r.LookupHost(context.Background(), "db.internal")
+	}
+
+	if n := primary.queries.Load(); n != 2 {
+		t.Fatalf("Expected the open breaker to stop queries to the primary after 2, got %d", n)
+	}
+}
+
+func TestResolver_AllFail(t *testing.T) {
+	t.Parallel()
+	r := NewResolver([]NameResolver{&fakeUpstream{err: errTest}, &fakeUpstream{err: errTest}})
+
+	_, err := r.LookupIPAddr(context.Background(), "db.internal")
+
+	if !errors.Is(err, ErrNoEndpoint) || !errors.Is(err, errTest) {
+		t.Fatalf("Expected %v joined with %v, got %v", ErrNoEndpoint, errTest, err)
+	}
+}
+
+func TestResolver_Cache(t *testing.T) {
+	t.Parallel()
+	upstream := &fakeUpstream{addr: "10.0.0.1"}
+	r := NewResolver([]NameResolver{upstream}, WithResolverCache(time.Minute, 0))
+
+	r.LookupHost(context.Background(), "db.internal")
+	addrs, err := r.LookupHost(context.Background(), "db.internal")
+
+	if err != nil || len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+		t.Fatalf("Expected the cached answer, got %v (%v)", addrs, err)
+	}
+	if n := upstream.queries.Load(); n != 1 {
+		t.Fatalf("Expected 1 upstream query, got %d", n)
+	}
+}
+
+func TestResolver_NegativeCache(t *testing.T) {
+	t.Parallel()
+	notFound := &net.DNSError{Err: "no such host", Name: "gone.internal", IsNotFound: true}
+	primary := &fakeUpstream{err: notFound}
+	secondary := &fakeUpstream{addr: "10.0.0.2"}
+	r := NewResolver([]NameResolver{primary, secondary}, WithResolverCache(0, time.Minute))
+
+	for range 2 {
+		if _, err := r.LookupHost(context.Background(), "gone.internal"); !errors.Is(err, notFound) {
+			t.Fatalf("Expected %v, got %v", notFound, err)
+		}
+	}
+
+	if n := primary.queries.Load(); n != 1 {
+		t.Fatalf("Expected the missing name to be cached after 1 query, got %d", n)
+	}
+	if n := secondary.queries.Load(); n != 0 {
+		t.Fatalf("Expected the secondary not to be asked about a missing name, got %d queries", n)
+	}
+}
@@ -0,0 +1,455 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAttemptDeadline is the cause, read with context.Cause, of the context
+// of an attempt whose share of the deadline set with WithDeadlineSplit runs
+// out, as opposed to the deadline of the call itself. It is also
+// context.DeadlineExceeded, so existing checks still match.
+var ErrAttemptDeadline = fmt.Errorf("attempt's share of the deadline ran out: %w", context.DeadlineExceeded)
+
+// RetryAfterError is a failure that says how long to wait before trying
+// again, such as an HTTP response with a Retry-After header. RetryPolicy
+// and RetryQueue wait at least Delay before the next attempt, whatever
+// their backoff suggests.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+// Error implements error.
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %v)", e.Err, e.Delay)
+}
+
+// Unwrap returns the underlying error.
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// retryDelay returns the wait before retry number attempt after err: the
+// backoff delay, stretched to any delay err asks for.
+func retryDelay(b Backoff, attempt int, err error) time.Duration {
+	delay := b.Delay(attempt)
+	if ra := retryAfter(err); ra != nil && ra.Delay > delay {
+		delay = ra.Delay
+	}
+
+	return delay
+}
+
+// retryAfter returns the RetryAfterError in err's tree, if any. It only
+// calls errors.As, which allocates, for errors that wrap others.
+func retryAfter(err error) *RetryAfterError {
+	switch e := err.(type) {
+	case *RetryAfterError:
+		return e
+	case interface{ Unwrap() error }, interface{ Unwrap() []error }:
+		var ra *RetryAfterError
+		if errors.As(err, &ra) {
+			return ra
+		}
+	}
+
+	return nil
+}
+
+// RetryPolicy is a reusable retry configuration. It is safe for
+// concurrent use.
+type RetryPolicy struct {
+	settings   atomic.Pointer[retrySettings] // Replaced whole by the setters
+	deadLetter DeadLetter                    // Receives calls that used up their attempts, if set
+	retryIf    func(error) bool              // Reports whether an error is worth retrying, nil for all
+	onRetry    RetryFunc                     // Called before each retry, if set
+	split      float64                       // Share of the time left given to each attempt but the last, zero for all
+	limiter    Limiter                       // Paces every attempt, if set
+	onOpen     CircuitOpenAction             // What to do when an attempt is rejected by an open breaker
+	recent     *failureLog                   // Last failed attempts, nil unless WithRetryRecentFailures
+	clock      Clock                         // Source of the time and of the waits between attempts
+
+	calls    atomic.Uint64 // Calls made, for Stats
+	tries    atomic.Uint64 // Attempts made, for Stats
+	failures atomic.Uint64 // Calls failed, for Stats
+}
+
+// retrySettings are the parts of a RetryPolicy that can be changed while it
+// is in use. Each call runs with the settings it started with.
+type retrySettings struct {
+	attempts int
+	backoff  Backoff
+}
+
+// RetryStats are the calls a RetryPolicy has made since it was created.
+type RetryStats struct {
+	Calls    uint64 `json:"calls"`    // Calls to Do and DoTransfer
+	Attempts uint64 `json:"attempts"` // Calls to their functions, including retries
+	Failures uint64 `json:"failures"` // Calls that ended with an error
+}
+
+// RetryFunc is told about a retry before it is made: attempt is the number
+// of the attempt that failed with err, from 1, and delay the wait before
+// the next one.
+type RetryFunc func(ctx context.Context, attempt int, err error, delay time.Duration)
+
+// CircuitOpenAction is what a RetryPolicy does when an attempt is rejected
+// by an open breaker.
+type CircuitOpenAction int
+
+const (
+	// BackoffOnOpen retries like after any other failure. It is the
+	// default.
+	BackoffOnOpen CircuitOpenAction = iota
+	// FailOnOpen returns the rejection at once, without spending the
+	// attempts left waiting against an open breaker.
+	FailOnOpen
+	// WaitOnOpen waits until the breaker lets a trial call through, as
+	// told by the CircuitOpenError of the rejection, before the next
+	// attempt. A breaker forced open tells no time, so its rejections are
+	// retried like other failures.
+	WaitOnOpen
+)
+
+// RetryOption configures optional RetryPolicy behavior.
+type RetryOption func(*RetryPolicy)
+
+// WithBackoff replaces the default doubling delay between attempts.
+func WithBackoff(b Backoff) RetryOption {
+	return func(r *RetryPolicy) {
+		r.settings.Load().backoff = b // not shared until NewRetryPolicy returns
+	}
+}
+
+// WithDeadLetter sends calls that fail every attempt to dl, along with the
+// attempt history and any payload set with WithDeadLetterPayload. Calls
+// that stop early, because the context is done, the error is not retryable
+// or a breaker is open with FailOnOpen, are not sent.
+func WithDeadLetter(dl DeadLetter) RetryOption {
+	return func(r *RetryPolicy) {
+		r.deadLetter = dl
+	}
+}
+
+// WithRetryIf only retries errors for which fn returns true; any other
+// error ends the call at once, as if the attempts were used up.
+func WithRetryIf(fn func(error) bool) RetryOption {
+	return func(r *RetryPolicy) {
+		r.retryIf = fn
+	}
+}
+
+// WithRetryFunc calls fn before each retry, such as to log or trace it.
+func WithRetryFunc(fn RetryFunc) RetryOption {
+	return func(r *RetryPolicy) {
+		r.onRetry = fn
+	}
+}
+
+// WithDeadlineSplit gives each attempt of Do but the last its own deadline,
+// share of the time left before the deadline of the call's context when it
+// starts; the last attempt gets all that is left. With a share of 0.6 the
+// first attempt gets 60% of the budget, the second 60% of the rest and so
+// on, so that a single slow attempt cannot use up the whole budget. An
+// attempt cut off by its share sees ErrAttemptDeadline as the cause of its
+// context. Calls whose context has no deadline are not affected.
+func WithDeadlineSplit(share float64) RetryOption {
+	return func(r *RetryPolicy) {
+		r.split = share
+	}
+}
+
+// WithRetryLimiter makes every attempt, the first included, wait for l to
+// allow it, so that the calls to a dependency stay within its rate however
+// many callers are retrying at once. A wait that fails, such as because ctx
+// is done first, ends the call with its error.
+func WithRetryLimiter(l Limiter) RetryOption {
+	return func(r *RetryPolicy) {
+		r.limiter = l
+	}
+}
+
+// WithCircuitOpenAction sets what the policy does when an attempt is
+// rejected with ErrCircuitOpen. The default is BackoffOnOpen.
+func WithCircuitOpenAction(a CircuitOpenAction) RetryOption {
+	return func(r *RetryPolicy) {
+		r.onOpen = a
+	}
+}
+
+// WithRetryRecentFailures keeps the last n failed attempts of Do, with when
+// they happened, their operation, error, number and latency, for
+// RecentFailures and the admin handler.
+func WithRetryRecentFailures(n int) RetryOption {
+	return func(r *RetryPolicy) {
+		r.recent = newFailureLog(n)
+	}
+}
+
+// WithRetryClock makes the policy wait between attempts with c instead of
+// the system clock, such as to simulate the waits.
+func WithRetryClock(c Clock) RetryOption {
+	return func(r *RetryPolicy) {
+		r.clock = c
+	}
+}
+
+// NewRetryPolicy creates a RetryPolicy that makes up to attempts calls,
+// doubling the delay between them starting from initialDelay.
+func NewRetryPolicy(attempts int, initialDelay time.Duration, opts ...RetryOption) *RetryPolicy {
+	r := &RetryPolicy{clock: systemClock{}}
+	r.settings.Store(&retrySettings{
+		attempts: attempts,
+		backoff:  ExponentialBackoff{Initial: initialDelay},
+	})
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Do executes fn, retrying it on failure until it succeeds, the attempts
+// are used up, or ctx is done. Once the attempts are used up it returns a
+// MaxAttemptsError, and if the deadline of ctx would pass during the wait
+// before the next attempt it returns a BudgetExhaustedError at once; either
+// wraps the error of the last attempt. An error not worth retrying is
+// returned as is.
+func (r *RetryPolicy) Do(ctx context.Context, fn WorkFuncCtx) error {
+	return r.run(ctx, fn, r.settings.Load())
+}
+
+// doAttempts is Do making up to attempts calls instead of the configured
+// number, for a single call.
+func (r *RetryPolicy) doAttempts(ctx context.Context, fn WorkFuncCtx, attempts int) error {
+	settings := *r.settings.Load()
+	settings.attempts = attempts
+	return r.run(ctx, fn, &settings)
+}
+
+// run is Do with the given settings.
+func (r *RetryPolicy) run(ctx context.Context, fn WorkFuncCtx, settings *retrySettings) error {
+	r.calls.Add(1)
+	err := r.do(ctx, fn, settings)
+	if err != nil {
+		r.failures.Add(1)
+	}
+
+	return err
+}
+
+// SetMaxAttempts changes how many calls the policy makes, from the next
+// call on.
+func (r *RetryPolicy) SetMaxAttempts(attempts int) {
+	r.update(func(s *retrySettings) { s.attempts = attempts })
+}
+
+// SetBackoff changes the delay between attempts, from the next call on.
+func (r *RetryPolicy) SetBackoff(b Backoff) {
+	r.update(func(s *retrySettings) { s.backoff = b })
+}
+
+// update replaces the settings with a copy changed by fn.
+func (r *RetryPolicy) update(fn func(*retrySettings)) {
+	for {
+		old := r.settings.Load()
+		s := *old
+		fn(&s)
+		if r.settings.CompareAndSwap(old, &s) {
+			return
+		}
+	}
+}
+
+// RecentFailures returns the failed attempts kept by
+// WithRetryRecentFailures, oldest first.
+func (r *RetryPolicy) RecentFailures() []FailureRecord {
+	return r.recent.list()
+}
+
+// Stats returns the calls the policy has made.
+func (r *RetryPolicy) Stats() RetryStats {
+	return RetryStats{
+		Calls:    r.calls.Load(),
+		Attempts: r.tries.Load(),
+		Failures: r.failures.Load(),
+	}
+}
+
+// do is run without the bookkeeping of Stats.
+func (r *RetryPolicy) do(ctx context.Context, fn WorkFuncCtx, settings *retrySettings) error {
+	var err error
+	var history []AttemptRecord
+
+	var timer *time.Timer // Reused between attempts
+	defer stopTimer(&timer)
+
+	for i := range settings.attempts {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		default:
+			// context is not done, proceed.
+		}
+
+		if r.limiter != nil {
+			if err := r.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		var start time.Time
+		if r.recent != nil {
+			start = r.clock.Now()
+		}
+
+		r.tries.Add(1)
+		err = r.attempt(ctx, fn, i == settings.attempts-1)
+
+		if err == nil {
+			return nil // success
+		}
+
+		if r.recent != nil {
+			now := r.clock.Now()
+			r.recent.add(FailureRecord{
+				Time:      now,
+				Operation: OperationFromContext(ctx),
+				Error:     err.Error(),
+				Attempt:   i + 1,
+				Latency:   now.Sub(start),
+			})
+		}
+
+		if r.deadLetter != nil {
+			history = append(history, AttemptRecord{Attempt: i + 1, Time: r.clock.Now(), Error: err.Error()})
+		}
+
+		// not worth another
+		if r.retryIf != nil && !r.retryIf(err) || r.onOpen == FailOnOpen && errors.Is(err, ErrCircuitOpen) {
+			break
+		}
+
+		// last attempt
+		if i == settings.attempts-1 {
+			r.sendDeadLetter(ctx, err, history)
+			return &MaxAttemptsError{Attempts: settings.attempts, LastErr: err}
+		}
+
+		delay := r.openDelay(err, retryDelay(settings.backoff, i+1, err))
+		if err := budgetExhausted(ctx, r.clock, i+1, err, delay); err != nil {
+			return err
+		}
+		if r.onRetry != nil {
+			r.onRetry(ctx, i+1, err, delay)
+		}
+
+		if err := r.wait(ctx, &timer, delay); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// attempt calls fn, under its share of the deadline of ctx unless it is the
+// last attempt.
+func (r *RetryPolicy) attempt(ctx context.Context, fn WorkFuncCtx, last bool) error {
+	if r.split <= 0 || r.split >= 1 || last {
+		return fn(ctx)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return fn(ctx)
+	}
+
+	now := r.clock.Now()
+	share := time.Duration(float64(deadline.Sub(now)) * r.split)
+	attemptCtx, cancel := context.WithDeadlineCause(ctx, now.Add(share), ErrAttemptDeadline)
+	defer cancel()
+
+	return fn(attemptCtx)
+}
+
+// openDelay returns the wait before the attempt after one that failed with
+// err: delay, stretched to the time the breaker that rejected the attempt
+// lets a trial call through if the policy waits for it.
+func (r *RetryPolicy) openDelay(err error, delay time.Duration) time.Duration {
+	if r.onOpen != WaitOnOpen {
+		return delay
+	}
+
+	var open *CircuitOpenError
+	if !errors.As(err, &open) || open.RetryAt.IsZero() {
+		return delay
+	}
+
+	return max(delay, open.RetryAt.Sub(r.clock.Now()))
+}
+
+// sendDeadLetter sends a call that failed with err after the attempts of
+// history to the dead letter destination, if any.
+func (r *RetryPolicy) sendDeadLetter(ctx context.Context, err error, history []AttemptRecord) {
+	if r.deadLetter == nil {
+		return
+	}
+
+	_ = r.deadLetter.Send(ctx, DeadLetterRecord{
+		Operation: OperationFromContext(ctx),
+		Payload:   deadLetterPayload(ctx),
+		Error:     err.Error(),
+		Attempts:  history,
+		Time:      r.clock.Now(),
+	})
+}
+
+// budgetExhausted returns a BudgetExhaustedError if the deadline of ctx
+// would pass before the wait of delay ahead of the next attempt is over.
+func budgetExhausted(ctx context.Context, clock Clock, attempts int, err error, delay time.Duration) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	if remaining := deadline.Sub(clock.Now()); remaining < delay {
+		return &BudgetExhaustedError{Attempts: attempts, LastErr: err, Remaining: max(remaining, 0), Delay: delay}
+	}
+
+	return nil
+}
+
+// wait waits for delay to pass or ctx to be done, and returns the context's
+// error in the latter case. With the system clock it reuses *timer, so that
+// a call allocates one timer however many times it retries.
+func (r *RetryPolicy) wait(ctx context.Context, timer **time.Timer, delay time.Duration) error {
+	var after <-chan time.Time
+	if _, ok := r.clock.(systemClock); !ok {
+		after = r.clock.After(delay)
+	} else if *timer == nil {
+		*timer = time.NewTimer(delay)
+		after = (*timer).C
+	} else {
+		(*timer).Reset(delay)
+		after = (*timer).C
+	}
+
+	select {
+	case <-after:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopTimer stops the timer *timer, if any.
+func stopTimer(timer **time.Timer) {
+	if *timer != nil {
+		(*timer).Stop()
+	}
+}
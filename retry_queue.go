@@ -0,0 +1,372 @@
+package failover
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueueItem is an operation persisted by a RetryQueue.
+type QueueItem struct {
+	ID          string          `json:"id"`
+	Handler     string          `json:"handler"` // Name the handler was registered under
+	Payload     []byte          `json:"payload"`
+	Attempts    int             `json:"attempts"` // Failed executions so far
+	LastError   string          `json:"last_error,omitempty"`
+	History     []AttemptRecord `json:"history,omitempty"`
+	Created     time.Time       `json:"created"`
+	NextAttempt time.Time       `json:"next_attempt"`
+}
+
+// QueueStore persists the items of a RetryQueue. Implementations must be
+// safe for concurrent use.
+type QueueStore interface {
+	// Put inserts item, or replaces the item with the same ID.
+	Put(ctx context.Context, item QueueItem) error
+	// Delete removes the item with the given ID, if any.
+	Delete(ctx context.Context, id string) error
+	// List returns every stored item.
+	List(ctx context.Context) ([]QueueItem, error)
+}
+
+// QueueHandler executes the operation described by payload.
+type QueueHandler func(ctx context.Context, payload []byte) error
+
+// RetryQueue is a durable queue of fire-and-forget operations that must
+// eventually succeed. Operations are recorded as a handler name and a
+// payload in a QueueStore, so they survive process restarts, and are
+// re-executed with backoff until they succeed.
+type RetryQueue struct {
+	store        QueueStore
+	backoff      Backoff
+	maxAttempts  int // Executions before an item is dropped, zero for no limit
+	pollInterval time.Duration
+	deadLetter   DeadLetter // Receives dropped items, if set
+
+	mu       sync.RWMutex // Protects handlers
+	handlers map[string]QueueHandler
+}
+
+// QueueOption configures optional RetryQueue behavior.
+type QueueOption func(*RetryQueue)
+
+// WithQueueBackoff sets the delay before each re-execution. The default
+// starts at one second and doubles up to ten minutes.
+func WithQueueBackoff(b Backoff) QueueOption {
+	return func(q *RetryQueue) {
+		q.backoff = b
+	}
+}
+
+// WithQueueMaxAttempts drops an item after n failed executions. By default
+// items are retried until they succeed.
+func WithQueueMaxAttempts(n int) QueueOption {
+	return func(q *RetryQueue) {
+		q.maxAttempts = n
+	}
+}
+
+// WithQueueDeadLetter sends items dropped after the maximum attempts to dl.
+// If sending fails the item stays in the queue and is offered again on the
+// next pass.
+func WithQueueDeadLetter(dl DeadLetter) QueueOption {
+	return func(q *RetryQueue) {
+		q.deadLetter = dl
+	}
+}
+
+// WithPollInterval sets how often Run looks for due items. The default is
+// one second.
+func WithPollInterval(d time.Duration) QueueOption {
+	return func(q *RetryQueue) {
+		q.pollInterval = d
+	}
+}
+
+// NewRetryQueue creates a RetryQueue persisting its items in store.
+func NewRetryQueue(store QueueStore, opts ...QueueOption) *RetryQueue {
+	q := &RetryQueue{
+		store:        store,
+		backoff:      ExponentialBackoff{Initial: time.Second, Max: 10 * time.Minute},
+		pollInterval: time.Second,
+		handlers:     make(map[string]QueueHandler),
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// Register makes handler available under name. Handlers must be registered
+// again after a restart before Run picks up persisted items for them.
+func (q *RetryQueue) Register(name string, handler QueueHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.handlers[name] = handler
+}
+
+// Enqueue persists an operation for the named handler and returns its ID.
+// The operation is executed by the next pass of Run.
+func (q *RetryQueue) Enqueue(ctx context.Context, handler string, payload []byte) (string, error) {
+	id, err := newQueueID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	item := QueueItem{
+		ID:          id,
+		Handler:     handler,
+		Payload:     payload,
+		Created:     now,
+		NextAttempt: now,
+	}
+
+	if err := q.store.Put(ctx, item); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Run executes due items every poll interval until ctx is done, and then
+// returns ctx.Err().
+func (q *RetryQueue) Run(ctx context.Context) error {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Failed items are rescheduled by RunOnce and an unavailable store
+		// is simply tried again on the next tick.
+		_ = q.RunOnce(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// RunOnce executes every item that is due, oldest first. Items whose
+// handler is not registered are left in the store.
+func (q *RetryQueue) RunOnce(ctx context.Context) error {
+	items, err := q.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(items, func(a, b QueueItem) int { return a.NextAttempt.Compare(b.NextAttempt) })
+
+	var errs []error
+	now := time.Now()
+	for _, item := range items {
+		if item.NextAttempt.After(now) {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := q.execute(ctx, item); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// execute runs a due item and records the outcome in the store.
+func (q *RetryQueue) execute(ctx context.Context, item QueueItem) error {
+	q.mu.RLock()
+	handler, ok := q.handlers[item.Handler]
+	q.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	err := handler(ctx, item.Payload)
+	if err == nil {
+		return q.store.Delete(ctx, item.ID)
+	}
+
+	now := time.Now()
+	item.Attempts++
+	item.LastError = err.Error()
+	item.History = append(item.History, AttemptRecord{Attempt: item.Attempts, Time: now, Error: err.Error()})
+
+	if q.maxAttempts > 0 && item.Attempts >= q.maxAttempts {
+		if q.deadLetter != nil {
+			record := DeadLetterRecord{
+				Name:     item.Handler,
+				Payload:  item.Payload,
+				Error:    err.Error(),
+				Attempts: item.History,
+				Time:     now,
+			}
+			if err := q.deadLetter.Send(ctx, record); err != nil {
+				return errors.Join(err, q.store.Put(ctx, item))
+			}
+		}
+
+		return q.store.Delete(ctx, item.ID)
+	}
+
+	item.NextAttempt = now.Add(retryDelay(q.backoff, item.Attempts, err))
+	return q.store.Put(ctx, item)
+}
+
+func newQueueID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
+
+// MemoryQueueStore is a QueueStore that keeps items in memory. It does not
+// survive restarts and is meant for tests and ephemeral workers.
+type MemoryQueueStore struct {
+	mu    sync.Mutex // Protects items
+	items map[string]QueueItem
+}
+
+// NewMemoryQueueStore creates an empty MemoryQueueStore.
+func NewMemoryQueueStore() *MemoryQueueStore {
+	return &MemoryQueueStore{items: make(map[string]QueueItem)}
+}
+
+// Put implements QueueStore.
+func (s *MemoryQueueStore) Put(_ context.Context, item QueueItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[item.ID] = item
+	return nil
+}
+
+// Delete implements QueueStore.
+func (s *MemoryQueueStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, id)
+	return nil
+}
+
+// List implements QueueStore.
+func (s *MemoryQueueStore) List(context.Context) ([]QueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]QueueItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// FileQueueStore is a QueueStore that keeps each item as a JSON file in a
+// directory. Writes go through a temporary file and a rename, so an item
+// is never left half written by a crash.
+type FileQueueStore struct {
+	dir string
+}
+
+// NewFileQueueStore creates a FileQueueStore in dir, creating it if needed.
+func NewFileQueueStore(dir string) (*FileQueueStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileQueueStore{dir: dir}, nil
+}
+
+// Put implements QueueStore.
+func (s *FileQueueStore) Put(_ context.Context, item QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(item.ID))
+}
+
+// Delete implements QueueStore.
+func (s *FileQueueStore) Delete(_ context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// List implements QueueStore.
+func (s *FileQueueStore) List(context.Context) ([]QueueItem, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []QueueItem
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue // deleted since ReadDir
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var item QueueItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("queue item %s: %w", name, err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (s *FileQueueStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
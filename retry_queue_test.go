@@ -0,0 +1,182 @@
+package failover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetryQueue_RetriesUntilSuccess(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryQueueStore()
+	q := NewRetryQueue(store, WithQueueBackoff(ConstantBackoff(0)))
+	ctx := context.Background()
+
+	var got []string
+	q.Register("send", func(_ context.Context, payload []byte) error {
+		got = append(got, string(payload))
+		if len(got) < 3 {
+			return errTest
+		}
+		return nil
+	})
+
+	if _, err := q.Enqueue(ctx, "send", []byte("hello")); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	for range 3 {
+		_ = q.RunOnce(ctx)
+	}
+
+	if len(got) != 3 || got[2] != "hello" {
+		t.Fatalf("Expected 3 executions with the payload, got %v", got)
+	}
+	if items, _ := store.List(ctx); len(items) != 0 {
+		t.Fatalf("Expected item removed after success, got %v", items)
+	}
+}
+
+func TestRetryQueue_Backoff(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryQueueStore()
+	q := NewRetryQueue(store, WithQueueBackoff(ConstantBackoff(time.Hour)))
+	ctx := context.Background()
+
+	calls := 0
+	q.Register("op", func(context.Context, []byte) error {
+		calls++
+		return errTest
+	})
+	_, _ = q.Enqueue(ctx, "op", nil)
+
+	_ = q.RunOnce(ctx)
+	_ = q.RunOnce(ctx) // not due again for an hour
+
+	items, _ := store.List(ctx)
+	if calls != 1 || len(items) != 1 {
+		t.Fatalf("Expected 1 call and the item kept, got %d calls and %d items", calls, len(items))
+	}
+	if items[0].Attempts != 1 || items[0].LastError != errTest.Error() {
+		t.Fatalf("Expected the failure recorded on the item, got %+v", items[0])
+	}
+}
+
+func TestRetryQueue_MaxAttempts(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryQueueStore()
+	q := NewRetryQueue(store, WithQueueBackoff(ConstantBackoff(0)), WithQueueMaxAttempts(2))
+	ctx := context.Background()
+
+	q.Register("op", func(context.Context, []byte) error { return errTest })
+	_, _ = q.Enqueue(ctx, "op", nil)
+
+	_ = q.RunOnce(ctx)
+	_ = q.RunOnce(ctx)
+
+	if items, _ := store.List(ctx); len(items) != 0 {
+		t.Fatalf("Expected item dropped after 2 attempts, got %v", items)
+	}
+}
+
+func TestRetryQueue_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := NewFileQueueStore(dir)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	_, _ = NewRetryQueue(store).Enqueue(ctx, "op", []byte("payload"))
+
+	// A fresh queue over the same directory picks the item up.
+	store, _ = NewFileQueueStore(dir)
+	q := NewRetryQueue(store)
+
+	var got string
+	q.Register("op", func(_ context.Context, payload []byte) error {
+		got = string(payload)
+		return nil
+	})
+	if err := q.RunOnce(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if got != "payload" {
+		t.Fatalf("Expected persisted payload, got %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Expected directory empty after success, got %d entries", len(entries))
+	}
+}
+
+func TestRetryQueue_UnregisteredHandler(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryQueueStore()
+	q := NewRetryQueue(store)
+	ctx := context.Background()
+
+	_, _ = q.Enqueue(ctx, "later", nil)
+	if err := q.RunOnce(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if items, _ := store.List(ctx); len(items) != 1 || items[0].Attempts != 0 {
+		t.Fatalf("Expected item kept untouched, got %v", items)
+	}
+}
+
+func TestFileQueueStore_IgnoresTempFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, _ := NewFileQueueStore(dir)
+
+	_ = os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte("{"), 0o644)
+
+	items, err := store.List(context.Background())
+	if err != nil || len(items) != 0 {
+		t.Fatalf("Expected no items and no error, got %v, %v", items, err)
+	}
+}
+
+func TestRetryQueue_Run(t *testing.T) {
+	t.Parallel()
+	q := NewRetryQueue(NewMemoryQueueStore(), WithPollInterval(5*time.Millisecond))
+
+	done := make(chan struct{})
+	q.Register("op", func(context.Context, []byte) error {
+		close(done)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = q.Run(ctx) }()
+
+	_, _ = q.Enqueue(ctx, "op", nil)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to execute the enqueued item")
+	}
+}
+
+func TestRetryQueue_RetryAfter(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryQueueStore()
+	q := NewRetryQueue(store, WithQueueBackoff(ConstantBackoff(0)))
+	ctx := context.Background()
+
+	q.Register("op", func(context.Context, []byte) error {
+		return &RetryAfterError{Err: errTest, Delay: time.Hour}
+	})
+	_, _ = q.Enqueue(ctx, "op", nil)
+	_ = q.RunOnce(ctx)
+
+	items, _ := store.List(ctx)
+	if len(items) != 1 || time.Until(items[0].NextAttempt) < 59*time.Minute {
+		t.Fatalf("Expected the next attempt an hour out, got %+v", items)
+	}
+}
@@ -0,0 +1,388 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_PassesContext(t *testing.T) {
+	t.Parallel()
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	attempts := 0
+	err := NewRetryPolicy(3, time.Millisecond).Do(ctx, func(ctx context.Context) error {
+		attempts++
+		if ctx.Value(ctxKey{}) != "value" {
+			t.Errorf("Expected the caller's context to be passed through")
+		}
+		if attempts < 2 {
+			return errTest
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryPolicy_Reusable(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(2, time.Millisecond)
+
+	for range 2 {
+		attempts := 0
+		err := r.Do(context.Background(), func(context.Context) error {
+			attempts++
+			return errTest
+		})
+
+		if !errors.Is(err, errTest) {
+			t.Fatalf("Expected error %v, got %v", errTest, err)
+		}
+		if attempts != 2 {
+			t.Fatalf("Expected 2 attempts on each use, got %d", attempts)
+		}
+	}
+}
+
+func TestRetryPolicy_WithBackoff(t *testing.T) {
+	t.Parallel()
+	var delays []int
+	b := BackoffFunc(func(attempt int) time.Duration {
+		delays = append(delays, attempt)
+		return time.Millisecond
+	})
+
+	_ = NewRetryPolicy(3, time.Hour, WithBackoff(b)).Do(context.Background(), func(context.Context) error {
+		return errTest
+	})
+
+	if len(delays) != 2 || delays[0] != 1 || delays[1] != 2 {
+		t.Fatalf("Expected backoff consulted for retries 1 and 2, got %v", delays)
+	}
+}
+
+func TestRetryPolicy_WithRetryIf(t *testing.T) {
+	t.Parallel()
+	errPermanent := errors.New("permanent")
+	r := NewRetryPolicy(5, time.Millisecond, WithRetryIf(func(err error) bool {
+		return !errors.Is(err, errPermanent)
+	}))
+
+	attempts := 0
+	err := r.Do(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts == 2 {
+			return errPermanent
+		}
+		return errTest
+	})
+
+	if !errors.Is(err, errPermanent) {
+		t.Fatalf("Expected error %v, got %v", errPermanent, err)
+	}
+	if attempts != 2 {
+		t.Fatalf("Expected retries to stop at the permanent error, got %d attempts", attempts)
+	}
+}
+
+func TestRetryPolicy_WithRetryFunc(t *testing.T) {
+	t.Parallel()
+	var retries []int
+	var delays []time.Duration
+	r := NewRetryPolicy(3, 2*time.Millisecond, WithRetryFunc(func(_ context.Context, attempt int, err error, delay time.Duration) {
+		if !errors.Is(err, errTest) {
+			t.Errorf("Expected error %v, got %v", errTest, err)
+		}
+		retries = append(retries, attempt)
+		delays = append(delays, delay)
+	}))
+
+	r.Do(context.Background(), func(context.Context) error { return errTest })
+
+	// No retry follows the last attempt.
+	if len(retries) != 2 || retries[0] != 1 || retries[1] != 2 {
+		t.Fatalf("Expected retries after attempts [1 2], got %v", retries)
+	}
+	if delays[0] != 2*time.Millisecond || delays[1] != 4*time.Millisecond {
+		t.Fatalf("Expected delays [2ms 4ms], got %v", delays)
+	}
+}
+
+func TestRetryPolicy_Stats(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(3, time.Millisecond)
+
+	r.Do(context.Background(), func(context.Context) error { return errTest })
+	attempts := 0
+	r.Do(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errTest
+		}
+		return nil
+	})
+
+	want := RetryStats{Calls: 2, Attempts: 5, Failures: 1}
+	if s := r.Stats(); s != want {
+		t.Fatalf("Expected stats %+v, got %+v", want, s)
+	}
+}
+
+func TestRetryPolicy_RetryAfter(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(2, time.Millisecond)
+
+	start := time.Now()
+	attempts := 0
+	err := r.Do(context.Background(), func(context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return &RetryAfterError{Err: errTest, Delay: 30 * time.Millisecond}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("Expected the retry to wait for the requested delay, took only %v", elapsed)
+	}
+}
+
+func TestRetryAfterError_Unwrap(t *testing.T) {
+	t.Parallel()
+	var err error = &RetryAfterError{Err: errTest, Delay: time.Second}
+
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected error to wrap %v, got %v", errTest, err)
+	}
+}
+
+func TestRetryPolicy_SetMaxAttempts(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(5, time.Millisecond)
+
+	r.SetMaxAttempts(2)
+	r.SetBackoff(ConstantBackoff(0))
+
+	calls := 0
+	r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return errTest
+	})
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+}
+
+// --- Benchmarks ---
+
+func BenchmarkRetryPolicy_Do(b *testing.B) {
+	r := NewRetryPolicy(3, 0)
+	ctx := context.Background()
+	fn := func(context.Context) error { return nil }
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = r.Do(ctx, fn)
+	}
+}
+
+func BenchmarkRetryPolicy_DoRetried(b *testing.B) {
+	r := NewRetryPolicy(10, 0, WithBackoff(ConstantBackoff(time.Nanosecond)))
+	ctx := context.Background()
+	fn := func(context.Context) error { return errTest }
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = r.Do(ctx, fn)
+	}
+}
+
+func TestRetryDelay_WrappedRetryAfter(t *testing.T) {
+	t.Parallel()
+	ra := &RetryAfterError{Err: errTest, Delay: time.Second}
+
+	for _, err := range []error{ra, fmt.Errorf("call: %w", ra), errors.Join(errTest, ra)} {
+		if d := retryDelay(ConstantBackoff(time.Millisecond), 1, err); d != time.Second {
+			t.Fatalf("Expected the delay of %v, got %v", err, d)
+		}
+	}
+	if d := retryDelay(ConstantBackoff(time.Millisecond), 1, errTest); d != time.Millisecond {
+		t.Fatalf("Expected the backoff delay, got %v", d)
+	}
+}
+
+func TestRetryPolicy_WithDeadlineSplit(t *testing.T) {
+	t.Parallel()
+	retry := NewRetryPolicy(3, time.Millisecond, WithDeadlineSplit(0.5))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	parent, _ := ctx.Deadline()
+
+	var budgets []time.Duration
+	var causes []error
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		budgets = append(budgets, time.Until(deadline))
+		if deadline.Equal(parent) {
+			return nil // the last attempt
+		}
+		<-ctx.Done()
+		causes = append(causes, context.Cause(ctx))
+		return ctx.Err()
+	})
+
+	if err != nil {
+		t.Fatalf("Expected the last attempt to succeed, got %v", err)
+	}
+	if len(budgets) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(budgets))
+	}
+	if budgets[0] > 100*time.Millisecond || budgets[1] > 50*time.Millisecond {
+		t.Errorf("Expected at most 100ms then 50ms, got %v", budgets[:2])
+	}
+	for _, cause := range causes {
+		if !errors.Is(cause, ErrAttemptDeadline) || !errors.Is(cause, context.DeadlineExceeded) {
+			t.Errorf("Expected ErrAttemptDeadline, got %v", cause)
+		}
+	}
+}
+
+func TestRetryPolicy_WithDeadlineSplitNoDeadline(t *testing.T) {
+	t.Parallel()
+	retry := NewRetryPolicy(2, time.Millisecond, WithDeadlineSplit(0.5))
+
+	err := retry.Do(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("Expected no deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+}
+
+// countingLimiter is a Limiter counting the waits, refusing all once
+// refuse is set.
+type countingLimiter struct {
+	waits  int
+	refuse bool
+}
+
+func (l *countingLimiter) Allow() bool { return !l.refuse }
+
+func (l *countingLimiter) Wait(context.Context) error {
+	l.waits++
+	if l.refuse {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+func TestRetryPolicy_WithRetryLimiter(t *testing.T) {
+	t.Parallel()
+	limiter := &countingLimiter{}
+	retry := NewRetryPolicy(3, time.Millisecond, WithRetryLimiter(limiter))
+
+	retry.Do(context.Background(), func(context.Context) error { return errTest })
+
+	if limiter.waits != 3 {
+		t.Fatalf("Expected a wait per attempt, got %d", limiter.waits)
+	}
+}
+
+func TestRetryPolicy_WithRetryLimiterRefuses(t *testing.T) {
+	t.Parallel()
+	limiter := &countingLimiter{refuse: true}
+	retry := NewRetryPolicy(3, time.Millisecond, WithRetryLimiter(limiter))
+
+	calls := 0
+	err := retry.Do(context.Background(), func(context.Context) error {
+		calls++
+		return nil
+	})
+
+	if !errors.Is(err, ErrRateLimited) || calls != 0 {
+		t.Fatalf("Expected ErrRateLimited without a call, got %v after %d calls", err, calls)
+	}
+}
+
+func TestRetryPolicy_FailOnOpen(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	retry := NewRetryPolicy(3, time.Millisecond, WithCircuitOpenAction(FailOnOpen))
+
+	calls := 0
+	err := retry.Do(context.Background(), func(context.Context) error {
+		return cb.Execute(func() error {
+			calls++
+			return errTest
+		})
+	})
+
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || errors.As(err, new(*MaxAttemptsError)) {
+		t.Fatalf("Expected the rejection as is, got %v", err)
+	}
+	if got := retry.Stats().Attempts; got != 2 || calls != 1 {
+		t.Fatalf("Expected 2 attempts and 1 call, got %d and %d", got, calls)
+	}
+}
+
+func TestRetryPolicy_WaitOnOpen(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, 50*time.Millisecond)
+	retry := NewRetryPolicy(3, time.Millisecond, WithCircuitOpenAction(WaitOnOpen))
+
+	calls := 0
+	start := time.Now()
+	err := retry.Do(context.Background(), func(context.Context) error {
+		return cb.Execute(func() error {
+			calls++
+			if calls == 1 {
+				return errTest
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		t.Fatalf("Expected the trial call to succeed, got %v", err)
+	}
+	// The failure, the rejection, then the trial call.
+	if got := retry.Stats().Attempts; got != 3 || calls != 2 {
+		t.Fatalf("Expected 3 attempts and 2 calls, got %d and %d", got, calls)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Expected to wait out the open timeout, took %v", elapsed)
+	}
+}
+
+func TestRetryPolicy_WaitOnOpenBudget(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(1, 1, time.Hour)
+	retry := NewRetryPolicy(3, time.Millisecond, WithCircuitOpenAction(WaitOnOpen))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := retry.Do(ctx, func(context.Context) error {
+		return cb.Execute(func() error { return errTest })
+	})
+
+	var budget *BudgetExhaustedError
+	if !errors.As(err, &budget) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected BudgetExhaustedError over the rejection, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobCanceled is the result of a scheduled job canceled before it
+	// started.
+	ErrJobCanceled = errors.New("job canceled")
+	// ErrSchedulerClosed is returned when scheduling on a Scheduler that
+	// is shutting down, and is the result of pending jobs it dropped.
+	ErrSchedulerClosed = errors.New("scheduler is shut down")
+)
+
+// jobState tracks the lifecycle of a Job.
+type jobState int
+
+const (
+	jobPending jobState = iota
+	jobRunning
+	jobFinished
+)
+
+// Job is a unit of work scheduled on a Scheduler.
+type Job struct {
+	s     *Scheduler
+	fn    WorkFuncCtx
+	retry Retrier
+	timer *time.Timer
+
+	state jobState      // Protected by the scheduler's lock
+	done  chan struct{} // Closed once err is set
+	err   error
+}
+
+// JobOption configures optional Job behavior.
+type JobOption func(*Job)
+
+// WithJobRetry runs the job through r, so a failed execution is retried
+// according to the job's own policy.
+func WithJobRetry(r Retrier) JobOption {
+	return func(j *Job) {
+		j.retry = r
+	}
+}
+
+// Cancel prevents the job from running and reports whether it did so. It
+// returns false if the job already started or finished.
+func (j *Job) Cancel() bool {
+	return j.s.finish(j, ErrJobCanceled)
+}
+
+// Done returns a channel that is closed when the job has finished, was
+// canceled, or was dropped by Shutdown.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Err returns the job's result once Done is closed.
+func (j *Job) Err() error {
+	<-j.done
+	return j.err
+}
+
+// Scheduler runs work at a future time or after a delay, for flows like
+// "retry this in ten minutes" that do not fit a blocking retry loop.
+type Scheduler struct {
+	mu      sync.Mutex // Protects pending and closed
+	pending map[*Job]struct{}
+	closed  bool
+
+	running sync.WaitGroup
+	ctx     context.Context // Passed to jobs, canceled with ErrSchedulerClosed if Shutdown gives up
+	cancel  context.CancelCauseFunc
+}
+
+// NewScheduler creates a running Scheduler.
+func NewScheduler() *Scheduler {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	return &Scheduler{
+		pending: make(map[*Job]struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// At schedules fn to run at t.
+func (s *Scheduler) At(t time.Time, fn WorkFuncCtx, opts ...JobOption) (*Job, error) {
+	return s.After(time.Until(t), fn, opts...)
+}
+
+// After schedules fn to run once d has elapsed.
+func (s *Scheduler) After(d time.Duration, fn WorkFuncCtx, opts ...JobOption) (*Job, error) {
+	j := &Job{s: s, fn: fn, retry: NoopRetrier{}, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSchedulerClosed
+	}
+
+	s.pending[j] = struct{}{}
+	j.timer = time.AfterFunc(d, func() { s.run(j) })
+	return j, nil
+}
+
+// Shutdown stops accepting jobs, drops the ones that have not started, and
+// waits for running jobs to finish. If ctx is done first, running jobs
+// have their context canceled, with ErrSchedulerClosed as the cause, and
+// Shutdown returns ctx.Err().
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	pending := make([]*Job, 0, len(s.pending))
+	for j := range s.pending {
+		pending = append(pending, j)
+	}
+	s.mu.Unlock()
+
+	for _, j := range pending {
+		s.finish(j, ErrSchedulerClosed)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.cancel(ErrSchedulerClosed)
+		return nil
+	case <-ctx.Done():
+		s.cancel(ErrSchedulerClosed)
+		return ctx.Err()
+	}
+}
+
+// run executes a job whose time has come.
+func (s *Scheduler) run(j *Job) {
+	s.mu.Lock()
+	if j.state != jobPending {
+		s.mu.Unlock()
+		return
+	}
+
+	j.state = jobRunning
+	delete(s.pending, j)
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	defer s.running.Done()
+
+	err := j.retry.Do(s.ctx, j.fn)
+
+	s.mu.Lock()
+	j.state = jobFinished
+	s.mu.Unlock()
+
+	j.err = err
+	close(j.done)
+}
+
+// finish ends a pending job with err and reports whether it was pending.
+func (s *Scheduler) finish(j *Job, err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j.state != jobPending {
+		return false
+	}
+
+	j.timer.Stop()
+	j.state = jobFinished
+	delete(s.pending, j)
+	j.err = err
+	close(j.done)
+	return true
+}
@@ -0,0 +1,155 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_After(t *testing.T) {
+	t.Parallel()
+	s := NewScheduler()
+	defer s.Shutdown(context.Background())
+
+	start := time.Now()
+	j, err := s.After(20*time.Millisecond, func(context.Context) error { return errTest })
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if err := j.Err(); !errors.Is(err, errTest) {
+		t.Fatalf("Expected job result %v, got %v", errTest, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Expected job to run after 20ms, ran after %v", elapsed)
+	}
+}
+
+func TestScheduler_At(t *testing.T) {
+	t.Parallel()
+	s := NewScheduler()
+	defer s.Shutdown(context.Background())
+
+	at := time.Now().Add(10 * time.Millisecond)
+	var ranAt time.Time
+	j, _ := s.At(at, func(context.Context) error {
+		ranAt = time.Now()
+		return nil
+	})
+
+	if err := j.Err(); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ranAt.Before(at) {
+		t.Fatalf("Expected job to run at %v, ran at %v", at, ranAt)
+	}
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	t.Parallel()
+	s := NewScheduler()
+	defer s.Shutdown(context.Background())
+
+	var ran atomic.Bool
+	j, _ := s.After(20*time.Millisecond, func(context.Context) error {
+		ran.Store(true)
+		return nil
+	})
+
+	if !j.Cancel() {
+		t.Fatal("Expected pending job to be canceled")
+	}
+	if err := j.Err(); !errors.Is(err, ErrJobCanceled) {
+		t.Fatalf("Expected ErrJobCanceled, got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if ran.Load() {
+		t.Fatal("Expected canceled job not to run")
+	}
+	if j.Cancel() {
+		t.Fatal("Expected second Cancel to report false")
+	}
+}
+
+func TestScheduler_JobRetry(t *testing.T) {
+	t.Parallel()
+	s := NewScheduler()
+	defer s.Shutdown(context.Background())
+
+	attempts := 0
+	j, _ := s.After(0, func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTest
+		}
+		return nil
+	}, WithJobRetry(NewRetryPolicy(3, time.Millisecond)))
+
+	if err := j.Err(); err != nil {
+		t.Fatalf("Expected job to succeed on retry, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestScheduler_ShutdownDrains(t *testing.T) {
+	t.Parallel()
+	s := NewScheduler()
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	running, _ := s.After(0, func(context.Context) error {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+	pending, _ := s.After(time.Hour, func(context.Context) error { return nil })
+	<-started
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if !finished.Load() || running.Err() != nil {
+		t.Fatal("Expected the running job to finish before Shutdown returned")
+	}
+	if err := pending.Err(); !errors.Is(err, ErrSchedulerClosed) {
+		t.Fatalf("Expected pending job dropped with ErrSchedulerClosed, got %v", err)
+	}
+	if _, err := s.After(0, func(context.Context) error { return nil }); !errors.Is(err, ErrSchedulerClosed) {
+		t.Fatalf("Expected ErrSchedulerClosed after shutdown, got %v", err)
+	}
+}
+
+func TestScheduler_ShutdownTimeout(t *testing.T) {
+	t.Parallel()
+	s := NewScheduler()
+
+	started := make(chan struct{})
+	var cause error
+	j, _ := s.After(0, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if err := j.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected running job's context canceled, got %v", err)
+	}
+	if !errors.Is(cause, ErrSchedulerClosed) {
+		t.Fatalf("Expected ErrSchedulerClosed as the cause, got %v", cause)
+	}
+}
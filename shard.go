@@ -0,0 +1,72 @@
+package failover
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// cacheLine is the size of the padding that keeps shards written by
+// different CPUs on different cache lines.
+const cacheLine = 64
+
+// shardCount returns n rounded up to a power of two, or the number of CPUs
+// rounded up when n is not positive.
+func shardCount(n int) int {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n <= 1 {
+		return 1
+	}
+
+	return 1 << bits.Len(uint(n-1))
+}
+
+// pickShard returns a shard index below n, a power of two. The runtime's
+// random source is per thread, so concurrent callers spread across the
+// shards without sharing any state to pick one.
+func pickShard(n int) int {
+	if n == 1 {
+		return 0
+	}
+
+	return int(rand.Uint32() & uint32(n-1))
+}
+
+// countShard is one CPU's share of a breaker's totals.
+type countShard struct {
+	successes  atomic.Uint64
+	failures   atomic.Uint64
+	rejections atomic.Uint64
+	_          [cacheLine - 24]byte
+}
+
+// shardedCounts spreads a breaker's totals over shards, so that calls on
+// different CPUs do not contend for one cache line. Reads add up the
+// shards, and so may miss calls still being counted.
+type shardedCounts struct {
+	shards []countShard
+}
+
+func newShardedCounts(n int) *shardedCounts {
+	return &shardedCounts{shards: make([]countShard, n)}
+}
+
+// shard returns the shard the calling goroutine counts in.
+func (c *shardedCounts) shard() *countShard {
+	return &c.shards[pickShard(len(c.shards))]
+}
+
+// load returns the totals of all the shards.
+func (c *shardedCounts) load() (successes, failures, rejections uint64) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		successes += s.successes.Load()
+		failures += s.failures.Load()
+		rejections += s.rejections.Load()
+	}
+
+	return successes, failures, rejections
+}
@@ -0,0 +1,42 @@
+package failover
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestShardCount(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct{ n, want int }{{1, 1}, {2, 2}, {3, 4}, {8, 8}, {9, 16}} {
+		if got := shardCount(tt.n); got != tt.want {
+			t.Errorf("Expected %d shards for %d, got %d", tt.want, tt.n, got)
+		}
+	}
+
+	if got := shardCount(0); got < runtime.GOMAXPROCS(0) {
+		t.Errorf("Expected at least one shard per CPU, got %d", got)
+	}
+}
+
+func TestShardedCounts(t *testing.T) {
+	t.Parallel()
+	c := newShardedCounts(8)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				c.shard().successes.Add(1)
+				c.shard().failures.Add(2)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes, failures, rejections := c.load(); successes != 800 || failures != 1600 || rejections != 0 {
+		t.Fatalf("Expected 800 successes and 1600 failures, got %d, %d and %d", successes, failures, rejections)
+	}
+}
@@ -0,0 +1,51 @@
+package failover
+
+import "time"
+
+// BreakerSnapshot is a view of a CircuitBreaker at one time. Its state,
+// override and consecutive failures are read together in one atomic load;
+// its totals may miss calls still finishing.
+type BreakerSnapshot struct {
+	State    State
+	Override BreakerOverride
+	Flapping bool
+	Counts   Counts
+	Time     time.Time // When it was taken
+}
+
+// WithSnapshotInterval makes Snapshot publish the snapshot it takes and
+// hand the published one to every caller for up to d. Scrapers and admin
+// pages polling many breakers then read one pointer per breaker, instead
+// of the counters the request path writes.
+func WithSnapshotInterval(d time.Duration) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.snapshotInterval = d
+	}
+}
+
+// Snapshot returns the state and counts of the breaker. It never waits for
+// the request path. With WithSnapshotInterval, it returns the published
+// snapshot while it is recent enough.
+func (cb *CircuitBreaker) Snapshot() BreakerSnapshot {
+	now := cb.clock.Now()
+	published := cb.snapshot.Load()
+	if published != nil && now.Sub(published.Time) < cb.snapshotInterval {
+		return *published
+	}
+
+	w := cb.state.v.Load()
+	s := &BreakerSnapshot{
+		State:    cb.reported(w),
+		Override: wordOverride(w),
+		Flapping: cb.Flapping(),
+		Counts:   cb.counts(w),
+		Time:     now,
+	}
+	if cb.snapshotInterval > 0 {
+		// A concurrent caller may have published first; its snapshot is as
+		// recent as this one.
+		cb.snapshot.CompareAndSwap(published, s)
+	}
+
+	return *s
+}
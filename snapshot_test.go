@@ -0,0 +1,49 @@
+package failover
+
+import (
+	"testing"
+	"time"
+)
+
+// stepClock is a Clock whose time only moves when told to.
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time                         { return c.now }
+func (c *stepClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func TestCircuitBreaker_Snapshot(t *testing.T) {
+	t.Parallel()
+	cb := NewCircuitBreaker(2, 1, time.Minute)
+
+	cb.Execute(func() error { return nil })
+	cb.Execute(func() error { return errTest })
+	cb.Execute(func() error { return errTest })
+	cb.ForceOpen()
+
+	snap := cb.Snapshot()
+	if snap.State != Open || snap.Override != ForcedOpen {
+		t.Fatalf("Expected Open and ForcedOpen, got %v and %v", snap.State, snap.Override)
+	}
+	want := Counts{Requests: 3, TotalSuccesses: 1, TotalFailures: 2}
+	if snap.Counts != want {
+		t.Fatalf("Expected counts %+v, got %+v", want, snap.Counts)
+	}
+}
+
+func TestCircuitBreaker_SnapshotInterval(t *testing.T) {
+	t.Parallel()
+	clock := &stepClock{now: time.Unix(1000, 0)}
+	cb := NewCircuitBreaker(5, 1, time.Minute, WithBreakerClock(clock), WithSnapshotInterval(time.Second))
+
+	first := cb.Snapshot()
+	cb.Execute(func() error { return nil })
+
+	if snap := cb.Snapshot(); snap != first {
+		t.Fatalf("Expected the published snapshot %+v, got %+v", first, snap)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if snap := cb.Snapshot(); snap.Counts.TotalSuccesses != 1 || !snap.Time.Equal(clock.now) {
+		t.Fatalf("Expected a new snapshot with 1 success, got %+v", snap)
+	}
+}
@@ -0,0 +1,168 @@
+package sqlfailover
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// ErrReplicaLagging is reported by the health check of a replica whose lag
+// exceeds the limit set with WithMaxLag.
+var ErrReplicaLagging = errors.New("sqlfailover: replica lag exceeds the limit")
+
+// LagFunc returns how far replica db is behind its primary.
+type LagFunc func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+// LagQuery returns a LagFunc running query, which must return a single
+// number of seconds. For a Postgres streaming replica:
+//
+//	SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+//
+// and for MySQL, the Seconds_Behind_Source column of the replica status is
+// the usual source.
+func LagQuery(query string) LagFunc {
+	return func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+		var seconds sql.NullFloat64
+		if err := db.QueryRowContext(ctx, query).Scan(&seconds); err != nil {
+			return 0, err
+		}
+		if !seconds.Valid {
+			return 0, errors.New("sqlfailover: replica reports no lag")
+		}
+
+		return time.Duration(math.Round(seconds.Float64 * float64(time.Second))), nil
+	}
+}
+
+// Cluster routes queries between a primary and its read replicas: writes go
+// to the primary and reads are balanced over the replicas, leaving out the
+// ones that are unreachable or, with WithMaxLag, too far behind.
+//
+// Replicas are checked by Run; until a replica's first check it is
+// considered available.
+type Cluster struct {
+	primary  *sql.DB
+	replicas map[*failover.Endpoint]*sql.DB
+	health   *failover.HealthChecker
+	balancer *failover.Balancer
+
+	maxLag   time.Duration // Lag beyond which a replica is left out, zero for no limit
+	lag      LagFunc
+	fallback bool // Whether reads go to the primary when no replica can take them
+	picker   failover.Picker
+}
+
+// ClusterOption configures optional Cluster behavior.
+type ClusterOption func(*Cluster)
+
+// WithMaxLag leaves out of reads the replicas that lag, as reported by fn,
+// exceeds max, until they catch up again.
+func WithMaxLag(max time.Duration, fn LagFunc) ClusterOption {
+	return func(c *Cluster) {
+		c.maxLag = max
+		c.lag = fn
+	}
+}
+
+// WithoutPrimaryReads makes reads fail with failover.ErrNoEndpoint when no
+// replica can take them, instead of going to the primary.
+func WithoutPrimaryReads() ClusterOption {
+	return func(c *Cluster) {
+		c.fallback = false
+	}
+}
+
+// WithReplicaPicker sets how reads are spread over the replicas; the
+// default is failover.WeightedRoundRobin.
+func WithReplicaPicker(p failover.Picker) ClusterOption {
+	return func(c *Cluster) {
+		c.picker = p
+	}
+}
+
+// NewCluster creates a Cluster over primary and replicas, checking the
+// replicas every interval once Run is called.
+func NewCluster(primary *sql.DB, replicas []*sql.DB, interval time.Duration, opts ...ClusterOption) *Cluster {
+	c := &Cluster{
+		primary:  primary,
+		replicas: make(map[*failover.Endpoint]*sql.DB, len(replicas)),
+		health:   failover.NewHealthChecker(interval),
+		fallback: true,
+		picker:   failover.WeightedRoundRobin(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	endpoints := make([]*failover.Endpoint, len(replicas))
+	for i, db := range replicas {
+		ep := &failover.Endpoint{Name: fmt.Sprintf("replica-%d", i)}
+		endpoints[i] = ep
+		c.replicas[ep] = db
+		c.health.Add(ep.Name, c.probe(db))
+	}
+	c.balancer = failover.NewBalancer(endpoints, failover.WithPicker(c.picker), failover.WithHealthExclusion(c.health))
+
+	return c
+}
+
+// Primary returns the primary, for writes and reads that must see them.
+func (c *Cluster) Primary() *sql.DB {
+	return c.primary
+}
+
+// Write runs fn against the primary.
+func (c *Cluster) Write(ctx context.Context, fn func(ctx context.Context, db *sql.DB) error) error {
+	return fn(ctx, c.primary)
+}
+
+// Read runs fn against an available replica, or the primary if there is
+// none, unless WithoutPrimaryReads is set.
+func (c *Cluster) Read(ctx context.Context, fn func(ctx context.Context, db *sql.DB) error) error {
+	err := c.balancer.Do(ctx, func(ctx context.Context, ep *failover.Endpoint) error {
+		return fn(ctx, c.replicas[ep])
+	})
+	if errors.Is(err, failover.ErrNoEndpoint) && c.fallback {
+		return fn(ctx, c.primary)
+	}
+
+	return err
+}
+
+// Health returns the checker probing the replicas, named replica-0,
+// replica-1... in the order they were given.
+func (c *Cluster) Health() *failover.HealthChecker {
+	return c.health
+}
+
+// Run checks the replicas on the interval until ctx is done, then returns
+// ctx.Err().
+func (c *Cluster) Run(ctx context.Context) error {
+	return c.health.Run(ctx)
+}
+
+// probe returns the health check of replica db: reachable and, with a lag
+// limit, caught up.
+func (c *Cluster) probe(db *sql.DB) failover.Probe {
+	return func(ctx context.Context) error {
+		if c.lag == nil {
+			return db.PingContext(ctx)
+		}
+
+		lag, err := c.lag(ctx, db)
+		if err != nil {
+			return err
+		}
+		if lag > c.maxLag {
+			return fmt.Errorf("%w: %v behind", ErrReplicaLagging, lag)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package sqlfailover
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+// openCluster returns pools on the primary and two replicas of cl.
+func openCluster(t *testing.T, cl *cluster) (primary *sql.DB, replicas []*sql.DB) {
+	t.Helper()
+	for _, dsn := range []string{"primary", "replica-a", "replica-b"} {
+		db := sql.OpenDB(dsnConnector{driver: cl, dsn: dsn})
+		t.Cleanup(func() { db.Close() })
+		if dsn == "primary" {
+			primary = db
+		} else {
+			replicas = append(replicas, db)
+		}
+	}
+	return primary, replicas
+}
+
+// where returns a function recording the pool each call ran on.
+func where(got *[]*sql.DB) func(context.Context, *sql.DB) error {
+	return func(_ context.Context, db *sql.DB) error {
+		*got = append(*got, db)
+		return nil
+	}
+}
+
+func TestCluster_Routing(t *testing.T) {
+	t.Parallel()
+	primary, replicas := openCluster(t, newCluster())
+	c := NewCluster(primary, replicas, time.Hour)
+	ctx := context.Background()
+
+	var got []*sql.DB
+	c.Write(ctx, where(&got))
+	if len(got) != 1 || got[0] != primary {
+		t.Fatal("Expected the write to go to the primary")
+	}
+
+	got = nil
+	for range 4 {
+		c.Read(ctx, where(&got))
+	}
+	counts := map[*sql.DB]int{}
+	for _, db := range got {
+		counts[db]++
+	}
+	if counts[replicas[0]] != 2 || counts[replicas[1]] != 2 {
+		t.Fatalf("Expected reads split over the replicas, got %v", counts)
+	}
+}
+
+func TestCluster_ExcludesLaggingReplica(t *testing.T) {
+	t.Parallel()
+	cl := newCluster()
+	primary, replicas := openCluster(t, cl)
+	c := NewCluster(primary, replicas, time.Hour, WithMaxLag(5*time.Second, LagQuery("SELECT lag")))
+	ctx := context.Background()
+
+	cl.setLag("replica-a", 30)
+	cl.setLag("replica-b", 0.5)
+	if err := c.Health().Check(ctx, "replica-0"); !errors.Is(err, ErrReplicaLagging) {
+		t.Fatalf("Expected ErrReplicaLagging, got %v", err)
+	}
+	if err := c.Health().Check(ctx, "replica-1"); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	var got []*sql.DB
+	for range 3 {
+		c.Read(ctx, where(&got))
+	}
+	for _, db := range got {
+		if db != replicas[1] {
+			t.Fatal("Expected reads to leave out the lagging replica")
+		}
+	}
+
+	// Once it catches up, the replica takes reads again.
+	cl.setLag("replica-a", 1)
+	c.Health().Check(ctx, "replica-0")
+	got = nil
+	for range 2 {
+		c.Read(ctx, where(&got))
+	}
+	if got[0] == got[1] {
+		t.Fatal("Expected reads split over both replicas again")
+	}
+}
+
+func TestCluster_PrimaryFallback(t *testing.T) {
+	t.Parallel()
+	cl := newCluster()
+	primary, replicas := openCluster(t, cl)
+	ctx := context.Background()
+
+	cl.setLag("replica-a", 30)
+	cl.setLag("replica-b", 30)
+
+	c := NewCluster(primary, replicas, time.Hour, WithMaxLag(time.Second, LagQuery("SELECT lag")))
+	c.Health().Check(ctx, "replica-0")
+	c.Health().Check(ctx, "replica-1")
+
+	var got []*sql.DB
+	if err := c.Read(ctx, where(&got)); err != nil || got[0] != primary {
+		t.Fatalf("Expected the read to fall back to the primary, got %v", err)
+	}
+
+	strict := NewCluster(primary, replicas, time.Hour, WithMaxLag(time.Second, LagQuery("SELECT lag")), WithoutPrimaryReads())
+	strict.Health().Check(ctx, "replica-0")
+	strict.Health().Check(ctx, "replica-1")
+	if err := strict.Read(ctx, where(&got)); !errors.Is(err, failover.ErrNoEndpoint) {
+		t.Fatalf("Expected ErrNoEndpoint, got %v", err)
+	}
+}
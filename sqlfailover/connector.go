@@ -0,0 +1,259 @@
+package sqlfailover
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ driver.Connector = (*Connector)(nil)
+
+// CheckFunc verifies that a freshly opened connection is to a usable
+// primary, for instance by querying pg_is_in_recovery() on Postgres.
+type CheckFunc func(ctx context.Context, conn driver.Conn) error
+
+// Connector is a driver.Connector over several data sources, in priority
+// order: a primary followed by its standbys. New connections go to the
+// highest-priority source passing its health checks; when it becomes
+// unreachable, they move on to the next one.
+//
+// Connections already in the sql.DB pool stay where they are until they
+// fail or expire, so set a connection lifetime with sql.DB.SetConnMaxLifetime
+// for the pool to follow a failover promptly.
+type Connector struct {
+	driver  driver.Driver
+	sources map[*failover.Endpoint]dataSource
+	group   *failover.FailoverGroup
+	health  *failover.HealthChecker
+
+	interval   time.Duration // Between health checks, zero for none
+	check      CheckFunc
+	newBreaker func() failover.Breaker
+	failback   failover.FailbackPolicy
+
+	stop     context.CancelFunc // Stops the health checks, nil without them
+	stopOnce sync.Once
+	done     chan struct{} // Closed when the health checks have stopped
+}
+
+// Option configures optional Connector behavior.
+type Option func(*Connector)
+
+// WithHealthInterval sets how often every data source is checked. The
+// default is 5 seconds; zero disables the checks, leaving failover to the
+// breakers alone.
+func WithHealthInterval(d time.Duration) Option {
+	return func(c *Connector) {
+		c.interval = d
+	}
+}
+
+// WithCheck runs fn on every connection made by a health check, and on
+// every new connection handed to the pool. The default pings the
+// connection if the driver supports it.
+func WithCheck(fn CheckFunc) Option {
+	return func(c *Connector) {
+		c.check = fn
+	}
+}
+
+// WithBreaker creates the breaker guarding each data source with fn. The
+// default opens after 2 consecutive connection failures for 10 seconds.
+func WithBreaker(fn func() failover.Breaker) Option {
+	return func(c *Connector) {
+		c.newBreaker = fn
+	}
+}
+
+// WithFailback sets when connections move back to a recovered
+// higher-priority source. The default is failover.ImmediateFailback, which
+// relies on the check telling a demoted primary apart from a promoted one.
+func WithFailback(p failover.FailbackPolicy) Option {
+	return func(c *Connector) {
+		c.failback = p
+	}
+}
+
+// NewConnector creates a Connector opening connections with drv to the
+// data sources named by dsns, in priority order, and starts checking their
+// health. Close the Connector, or the sql.DB opened with it, to stop the
+// checks.
+func NewConnector(drv driver.Driver, dsns []string, opts ...Option) (*Connector, error) {
+	if len(dsns) == 0 {
+		return nil, errors.New("sqlfailover: no data source")
+	}
+
+	c := &Connector{
+		driver:   drv,
+		sources:  make(map[*failover.Endpoint]dataSource, len(dsns)),
+		interval: 5 * time.Second,
+		check:    ping,
+		newBreaker: func() failover.Breaker {
+			return failover.NewCircuitBreaker(2, 1, 10*time.Second)
+		},
+		failback: failover.ImmediateFailback(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	endpoints := make([]*failover.Endpoint, len(dsns))
+	if c.interval > 0 {
+		c.health = failover.NewHealthChecker(c.interval)
+	}
+	for i, dsn := range dsns {
+		source, err := open(drv, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("sqlfailover: data source %d: %w", i, err)
+		}
+
+		// The name, not the DSN, identifies the source, which keeps any
+		// credentials in the DSN out of health reports.
+		ep := &failover.Endpoint{Name: fmt.Sprintf("source-%d", i), Breaker: c.newBreaker()}
+		endpoints[i] = ep
+		c.sources[ep] = dataSource{index: i, connector: source}
+		if c.health != nil {
+			c.health.Add(ep.Name, c.probe(source))
+		}
+	}
+
+	groupOpts := []failover.FailoverGroupOption{
+		failover.WithFailback(c.failback),
+		failover.WithFailoverOn(func(error) bool { return true }),
+	}
+	if c.health != nil {
+		groupOpts = append(groupOpts, failover.WithHealthChecker(c.health))
+	}
+	c.group = failover.NewFailoverGroup(endpoints, groupOpts...)
+
+	if c.health != nil {
+		ctx, stop := context.WithCancel(context.Background())
+		c.stop = stop
+		c.done = make(chan struct{})
+		go func() {
+			defer close(c.done)
+			_ = c.health.Run(ctx)
+		}()
+	}
+
+	return c, nil
+}
+
+// Connect implements driver.Connector, opening a connection to the
+// highest-priority available data source.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	err := c.group.Do(ctx, func(ctx context.Context, ep *failover.Endpoint) error {
+		var err error
+		conn, err = c.connect(ctx, c.sources[ep].connector)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Driver implements driver.Connector.
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Active returns the index in the DSN list of the data source that took
+// the last connection, or -1 before the first one.
+func (c *Connector) Active() int {
+	ep := c.group.Active()
+	if ep == nil {
+		return -1
+	}
+
+	return c.sources[ep].index
+}
+
+// Health returns the checker probing the data sources, named source-0,
+// source-1... in DSN order, or nil if checks are disabled.
+func (c *Connector) Health() *failover.HealthChecker {
+	return c.health
+}
+
+// Close stops the health checks. sql.DB calls it when closed.
+func (c *Connector) Close() error {
+	if c.stop != nil {
+		c.stopOnce.Do(c.stop)
+		<-c.done
+	}
+	return nil
+}
+
+// connect opens a connection to source and checks it.
+func (c *Connector) connect(ctx context.Context, source driver.Connector) (driver.Conn, error) {
+	conn, err := source.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.check(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// probe returns the health check of source: open a connection, check it
+// and close it.
+func (c *Connector) probe(source driver.Connector) failover.Probe {
+	return func(ctx context.Context) error {
+		conn, err := c.connect(ctx, source)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
+
+// dataSource is one of the DSNs of a Connector.
+type dataSource struct {
+	index     int // Position in the DSN list
+	connector driver.Connector
+}
+
+// open returns the connector of drv for dsn.
+func open(drv driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+
+	return dsnConnector{driver: drv, dsn: dsn}, nil
+}
+
+// dsnConnector is the driver.Connector of a driver without DriverContext.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (d dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return d.driver.Open(d.dsn)
+}
+
+func (d dsnConnector) Driver() driver.Driver {
+	return d.driver
+}
+
+// ping is the default CheckFunc.
+func ping(ctx context.Context, conn driver.Conn) error {
+	if p, ok := conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
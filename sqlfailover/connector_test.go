@@ -0,0 +1,203 @@
+package sqlfailover
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var errDown = errors.New("connection refused")
+
+// cluster is a fake driver whose data sources can be taken down and
+// brought back, and which records where each connection went.
+type cluster struct {
+	mu      sync.Mutex
+	down    map[string]bool
+	opened  []string
+	replica map[string]bool    // Sources that refuse writes
+	lag     map[string]float64 // Replication lag of each source, in seconds
+}
+
+func newCluster() *cluster {
+	return &cluster{down: make(map[string]bool), replica: make(map[string]bool), lag: make(map[string]float64)}
+}
+
+// setLag sets the lag reported by dsn.
+func (c *cluster) setLag(dsn string, seconds float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lag[dsn] = seconds
+}
+
+func (c *cluster) set(dsn string, down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down[dsn] = down
+}
+
+func (c *cluster) Open(dsn string) (driver.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down[dsn] {
+		return nil, errDown
+	}
+	c.opened = append(c.opened, dsn)
+	return &clusterConn{cluster: c, dsn: dsn}, nil
+}
+
+// last returns the data source of the latest connection.
+func (c *cluster) last() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opened[len(c.opened)-1]
+}
+
+// clusterConn is a connection of the fake cluster. It supports Ping and
+// the lag query "SELECT lag".
+type clusterConn struct {
+	cluster *cluster
+	dsn     string
+}
+
+func (c *clusterConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *clusterConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c *clusterConn) Close() error                        { return nil }
+
+func (c *clusterConn) Ping(context.Context) error {
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+
+	if c.cluster.down[c.dsn] {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func TestConnector_FailsOver(t *testing.T) {
+	t.Parallel()
+	cl := newCluster()
+	c, err := NewConnector(cl, []string{"primary", "standby"}, WithHealthInterval(0))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, err := c.Connect(ctx); err != nil || cl.last() != "primary" || c.Active() != 0 {
+		t.Fatalf("Expected a connection to primary, got %s (%v)", cl.last(), err)
+	}
+
+	cl.set("primary", true)
+	if _, err := c.Connect(ctx); err != nil || cl.last() != "standby" || c.Active() != 1 {
+		t.Fatalf("Expected a connection to standby, got %s (%v)", cl.last(), err)
+	}
+
+	cl.set("standby", true)
+	if _, err := c.Connect(ctx); !errors.Is(err, failover.ErrNoEndpoint) || !errors.Is(err, errDown) {
+		t.Fatalf("Expected ErrNoEndpoint wrapping the connection error, got %v", err)
+	}
+}
+
+func TestConnector_HealthChecks(t *testing.T) {
+	t.Parallel()
+	cl := newCluster()
+	cl.replica["primary"] = true // the old primary was demoted
+	c, err := NewConnector(cl, []string{"primary", "standby"},
+		WithHealthInterval(5*time.Millisecond),
+		WithCheck(func(_ context.Context, conn driver.Conn) error {
+			if cl.replica[conn.(*clusterConn).dsn] {
+				return errors.New("read-only")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer c.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for c.Health().Status("source-0") != failover.Down {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the demoted primary to be marked Down")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := c.Connect(context.Background()); err != nil || c.Active() != 1 {
+		t.Fatalf("Expected the connection to go to the standby, got %d (%v)", c.Active(), err)
+	}
+}
+
+func TestConnector_OpenDB(t *testing.T) {
+	t.Parallel()
+	cl := newCluster()
+	cl.set("primary", true)
+	c, err := NewConnector(cl, []string{"primary", "standby"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	db := sql.OpenDB(c)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if cl.last() != "standby" {
+		t.Fatalf("Expected the pool to connect to standby, got %s", cl.last())
+	}
+
+	// Closing the pool stops the health checks through Connector.Close.
+	done := make(chan struct{})
+	go func() {
+		db.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected db.Close to return")
+	}
+}
+
+func TestNewConnector_NoSource(t *testing.T) {
+	t.Parallel()
+	if _, err := NewConnector(newCluster(), nil); err == nil {
+		t.Fatal("Expected an error without data sources")
+	}
+}
+
+func (c *clusterConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query != "SELECT lag" {
+		return nil, errors.New("unexpected query " + query)
+	}
+
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	return &floatRows{value: c.cluster.lag[c.dsn]}, nil
+}
+
+// floatRows is a single row holding a float.
+type floatRows struct {
+	value float64
+	read  bool
+}
+
+func (r *floatRows) Columns() []string { return []string{"lag"} }
+func (r *floatRows) Close() error      { return nil }
+
+func (r *floatRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.value
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package sqlfailover integrates the failover primitives with
+// database/sql.
+package sqlfailover
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/dadanrm/failover"
+)
+
+var _ failover.LeaseLock = (*AdvisoryLock)(nil)
+
+// AdvisoryLock is a failover.LeaseLock backed by a Postgres session-level
+// advisory lock. The lock lives as long as the database session that took
+// it, so it is held on a dedicated connection; if the holder dies, Postgres
+// releases the lock when the session ends, and the TTL passed to TryAcquire
+// is not used.
+type AdvisoryLock struct {
+	db  *sql.DB
+	key int64
+
+	mu   sync.Mutex // Protects conn
+	conn *sql.Conn  // Session holding the lock, nil when not held
+}
+
+// NewAdvisoryLock creates the AdvisoryLock through which this instance
+// competes for the advisory lock key in db.
+func NewAdvisoryLock(db *sql.DB, key int64) *AdvisoryLock {
+	return &AdvisoryLock{db: db, key: key}
+}
+
+// TryAcquire takes the advisory lock if it is free. While held, it checks
+// that the session holding it is still alive.
+func (l *AdvisoryLock) TryAcquire(ctx context.Context, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err != nil {
+			discard(l.conn)
+			l.conn = nil
+			return false, nil // the session, and the lock with it, is gone
+		}
+		return true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var held bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&held); err != nil || !held {
+		conn.Close()
+		return false, err
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Release unlocks the advisory lock and returns its session to the pool.
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if err != nil {
+		// The session may still hold the lock: end it rather than return it
+		// to the pool, so that Postgres releases the lock.
+		discard(l.conn)
+	} else {
+		l.conn.Close()
+	}
+	l.conn = nil
+	return err
+}
+
+// discard closes the session of conn instead of returning it to the pool.
+func discard(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
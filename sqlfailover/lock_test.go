@@ -0,0 +1,165 @@
+package sqlfailover
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockServer is the shared state of a fake Postgres: which session holds
+// each advisory lock.
+type lockServer struct {
+	mu      sync.Mutex
+	holders map[int64]*fakeConn
+}
+
+func (s *lockServer) Open(string) (driver.Conn, error) {
+	return &fakeConn{server: s}, nil
+}
+
+// fakeConn is a session of the fake Postgres, understanding only the
+// advisory lock functions.
+type fakeConn struct {
+	server *lockServer
+	dead   bool
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+// Close ends the session, releasing its locks as Postgres does.
+func (c *fakeConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	for key, holder := range c.server.holders {
+		if holder == c {
+			delete(c.server.holders, key)
+		}
+	}
+	return nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	if c.dead {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	key := args[0].Value.(int64)
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	held := false
+	switch query {
+	case "SELECT pg_try_advisory_lock($1)":
+		if holder, ok := c.server.holders[key]; !ok || holder == c {
+			c.server.holders[key] = c
+			held = true
+		}
+	case "SELECT pg_advisory_unlock($1)":
+		if c.server.holders[key] == c {
+			delete(c.server.holders, key)
+			held = true
+		}
+	default:
+		return nil, errors.New("unexpected query " + query)
+	}
+
+	return &boolRows{value: held}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return driver.RowsAffected(0), nil
+}
+
+// boolRows is a single row holding a boolean.
+type boolRows struct {
+	value bool
+	read  bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"result"} }
+func (r *boolRows) Close() error      { return nil }
+
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.value
+	return nil
+}
+
+// openFake returns two independent pools on one fake Postgres.
+func openFake(t *testing.T) (*lockServer, *sql.DB, *sql.DB) {
+	t.Helper()
+	server := &lockServer{holders: make(map[int64]*fakeConn)}
+	a, b := sql.OpenDB(connector{server}), sql.OpenDB(connector{server})
+	t.Cleanup(func() { a.Close(); b.Close() })
+	return server, a, b
+}
+
+type connector struct{ server *lockServer }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.server.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.server }
+
+func TestAdvisoryLock(t *testing.T) {
+	t.Parallel()
+	_, dbA, dbB := openFake(t)
+	ctx := context.Background()
+
+	a, b := NewAdvisoryLock(dbA, 42), NewAdvisoryLock(dbB, 42)
+
+	if held, err := a.TryAcquire(ctx, time.Second); !held || err != nil {
+		t.Fatalf("Expected a to take the free lock, got %v (%v)", held, err)
+	}
+	if held, _ := b.TryAcquire(ctx, time.Second); held {
+		t.Fatal("Expected b not to take a held lock")
+	}
+	if held, _ := a.TryAcquire(ctx, time.Second); !held {
+		t.Fatal("Expected a to keep its lock")
+	}
+
+	if err := a.Release(ctx); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if held, _ := b.TryAcquire(ctx, time.Second); !held {
+		t.Fatal("Expected b to take the released lock")
+	}
+}
+
+func TestAdvisoryLock_SessionLost(t *testing.T) {
+	t.Parallel()
+	server, dbA, dbB := openFake(t)
+	ctx := context.Background()
+
+	a, b := NewAdvisoryLock(dbA, 7), NewAdvisoryLock(dbB, 7)
+	a.TryAcquire(ctx, time.Second)
+
+	// The holder's session dies: Postgres frees the lock and a notices.
+	server.mu.Lock()
+	holder := server.holders[7]
+	holder.dead = true
+	server.mu.Unlock()
+
+	if held, _ := a.TryAcquire(ctx, time.Second); held {
+		t.Fatal("Expected a to notice its session is gone")
+	}
+	if held, _ := b.TryAcquire(ctx, time.Second); !held {
+		t.Fatal("Expected b to take the lock of the dead session")
+	}
+}
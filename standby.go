@@ -0,0 +1,255 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLeaseLost is the cause, as told by context.Cause, of the context of
+// a Standby's work canceled because the instance lost the lease.
+var ErrLeaseLost = errors.New("lease lost")
+
+// LeaseLock is a distributed lock held under a lease that expires unless
+// renewed, such as an etcd lease, a Redis key with a TTL or a Postgres
+// advisory lock. Each instance competing for the lock has its own
+// LeaseLock.
+type LeaseLock interface {
+	// TryAcquire takes the lock for ttl if it is free, or renews it if this
+	// instance already holds it, and reports whether this instance holds it
+	// afterwards.
+	TryAcquire(ctx context.Context, ttl time.Duration) (bool, error)
+	// Release gives the lock up if this instance holds it.
+	Release(ctx context.Context) error
+}
+
+// Standby runs work on exactly one of a set of instances, in active/standby
+// fashion: the instance holding the lock runs it, while the others keep
+// trying to acquire the lock and take over when the holder dies or its
+// lease lapses.
+type Standby struct {
+	lock  LeaseLock
+	work  WorkFuncCtx
+	ttl   time.Duration // Lease duration
+	renew time.Duration // How often the lease is renewed, or acquisition retried
+	clock Clock
+
+	onPromote func()
+	onDemote  func()
+
+	leader atomic.Bool
+}
+
+// StandbyOption configures optional Standby behavior.
+type StandbyOption func(*Standby)
+
+// WithLeaseTTL sets how long the lease lasts without renewal, which bounds
+// how long a dead holder blocks a takeover. The default is 15 seconds.
+func WithLeaseTTL(d time.Duration) StandbyOption {
+	return func(s *Standby) {
+		s.ttl = d
+	}
+}
+
+// WithRenewInterval sets how often the holder renews its lease and
+// standbys retry to acquire it. The default is a third of the lease TTL.
+func WithRenewInterval(d time.Duration) StandbyOption {
+	return func(s *Standby) {
+		s.renew = d
+	}
+}
+
+// WithStandbyClock makes the standby tell the time and wait with c instead
+// of the system clock, such as to simulate it.
+func WithStandbyClock(c Clock) StandbyOption {
+	return func(s *Standby) {
+		s.clock = c
+	}
+}
+
+// WithPromoteFunc sets a callback fired when this instance acquires the
+// lock, before the work starts.
+func WithPromoteFunc(fn func()) StandbyOption {
+	return func(s *Standby) {
+		s.onPromote = fn
+	}
+}
+
+// WithDemoteFunc sets a callback fired when this instance loses or gives up
+// the lock, after the work has returned.
+func WithDemoteFunc(fn func()) StandbyOption {
+	return func(s *Standby) {
+		s.onDemote = fn
+	}
+}
+
+// NewStandby creates a Standby running work while lock is held.
+func NewStandby(lock LeaseLock, work WorkFuncCtx, opts ...StandbyOption) *Standby {
+	s := &Standby{lock: lock, work: work, ttl: 15 * time.Second, clock: systemClock{}}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.renew <= 0 {
+		s.renew = s.ttl / 3
+	}
+
+	return s
+}
+
+// Leader reports whether this instance currently holds the lock.
+func (s *Standby) Leader() bool {
+	return s.leader.Load()
+}
+
+// Run competes for the lock until ctx is done, then returns ctx.Err().
+// While it holds the lock it runs work with a context that is canceled as
+// soon as the lease cannot be renewed, and at the latest a renew interval
+// before the lease would expire, so that the work stops before another
+// instance can take over. When work returns on its own the lock is
+// released and this instance goes back to standby.
+func (s *Standby) Run(ctx context.Context) error {
+	for {
+		sent := s.clock.Now()
+		if held, err := s.lock.TryAcquire(ctx, s.ttl); err == nil && held {
+			s.lead(ctx, sent)
+		}
+
+		select {
+		case <-s.clock.After(s.renew):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// leaseEnd returns when work must stop under a lease requested at sent:
+// ahead of its expiry by a renew interval, or by half the lease if that is
+// shorter, to leave room for clock drift and the time the store took.
+func (s *Standby) leaseEnd(sent time.Time) time.Time {
+	return sent.Add(s.ttl - min(s.renew, s.ttl/2))
+}
+
+// lead runs the work while renewing the lease acquired with a request sent
+// at sent, until the work returns, the lease is lost or ctx is done.
+func (s *Standby) lead(ctx context.Context, sent time.Time) {
+	s.leader.Store(true)
+	if s.onPromote != nil {
+		s.onPromote()
+	}
+
+	workCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.work(workCtx)
+	}()
+
+	// A renewal that errors is retried until the lease is about to expire,
+	// since a store blip should not demote a healthy holder.
+	renew := s.clock.After(s.renew)
+	expire := s.clock.After(s.leaseEnd(sent).Sub(s.clock.Now()))
+loop:
+	for {
+		select {
+		case <-renew:
+			sent := s.clock.Now()
+			held, err := s.lock.TryAcquire(ctx, s.ttl)
+			if err == nil && !held {
+				cancel(ErrLeaseLost)
+				break loop
+			}
+			if err == nil {
+				expire = s.clock.After(s.leaseEnd(sent).Sub(s.clock.Now()))
+			}
+			renew = s.clock.After(s.renew)
+		case <-expire:
+			cancel(ErrLeaseLost)
+			break loop
+		case <-done:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	cancel(nil)
+	<-done
+
+	// Release with a fresh context: ctx may be done already.
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), s.renew)
+	s.lock.Release(releaseCtx)
+	cancelRelease()
+
+	s.leader.Store(false)
+	if s.onDemote != nil {
+		s.onDemote()
+	}
+}
+
+// MemoryLease is a lease shared by the LeaseLocks it hands out, for
+// competing instances within one process, mostly useful in tests.
+type MemoryLease struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+}
+
+// NewMemoryLease creates a free MemoryLease.
+func NewMemoryLease() *MemoryLease {
+	return &MemoryLease{}
+}
+
+// Lock returns the LeaseLock through which holder competes for the lease.
+func (l *MemoryLease) Lock(holder string) LeaseLock {
+	return memoryLock{lease: l, holder: holder}
+}
+
+// Holder returns who holds the lease, or the empty string if it is free.
+func (l *MemoryLease) Holder() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Now().After(l.expires) {
+		return ""
+	}
+
+	return l.holder
+}
+
+// memoryLock is one holder's view of a MemoryLease.
+type memoryLock struct {
+	lease  *MemoryLease
+	holder string
+}
+
+func (m memoryLock) TryAcquire(_ context.Context, ttl time.Duration) (bool, error) {
+	l := m.lease
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.holder != m.holder && now.Before(l.expires) {
+		return false, nil
+	}
+
+	l.holder = m.holder
+	l.expires = now.Add(ttl)
+	return true, nil
+}
+
+func (m memoryLock) Release(context.Context) error {
+	l := m.lease
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.holder == m.holder {
+		l.holder = ""
+		l.expires = time.Time{}
+	}
+
+	return nil
+}
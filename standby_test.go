@@ -0,0 +1,262 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runStandby runs a Standby for holder on lease whose work blocks until
+// its context is done, and returns it with a stop function.
+func runStandby(lease *MemoryLease, holder string, opts ...StandbyOption) (*Standby, context.CancelFunc) {
+	opts = append([]StandbyOption{WithLeaseTTL(30 * time.Millisecond), WithRenewInterval(5 * time.Millisecond)}, opts...)
+	s := NewStandby(lease.Lock(holder), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	return s, func() {
+		cancel()
+		<-done
+	}
+}
+
+// eventually fails the test if cond does not hold within a second.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStandby_SingleLeader(t *testing.T) {
+	t.Parallel()
+	lease := NewMemoryLease()
+
+	a, stopA := runStandby(lease, "a")
+	eventually(t, a.Leader, "Expected the first instance to become leader")
+
+	b, stopB := runStandby(lease, "b")
+	defer stopB()
+	time.Sleep(50 * time.Millisecond)
+	if b.Leader() {
+		t.Fatal("Expected the standby not to lead while the holder renews")
+	}
+
+	// Stopping the holder releases the lock and the standby takes over.
+	stopA()
+	if a.Leader() {
+		t.Fatal("Expected the stopped instance not to be leader")
+	}
+	eventually(t, b.Leader, "Expected the standby to take over")
+	if lease.Holder() != "b" {
+		t.Fatalf("Expected lease held by b, got %q", lease.Holder())
+	}
+}
+
+func TestStandby_Callbacks(t *testing.T) {
+	t.Parallel()
+	lease := NewMemoryLease()
+
+	var promoted, demoted atomic.Int32
+	_, stop := runStandby(lease, "a",
+		WithPromoteFunc(func() { promoted.Add(1) }),
+		WithDemoteFunc(func() { demoted.Add(1) }),
+	)
+	eventually(t, func() bool { return promoted.Load() == 1 }, "Expected the promote callback to fire")
+
+	stop()
+	if demoted.Load() != 1 {
+		t.Fatalf("Expected demote callback once, got %d", demoted.Load())
+	}
+}
+
+// flakyLock fails renewals once broken.
+type flakyLock struct {
+	LeaseLock
+	broken atomic.Bool
+}
+
+func (f *flakyLock) TryAcquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	if f.broken.Load() {
+		return false, errTest
+	}
+	return f.LeaseLock.TryAcquire(ctx, ttl)
+}
+
+func TestStandby_LeaseLost(t *testing.T) {
+	t.Parallel()
+	lock := &flakyLock{LeaseLock: NewMemoryLease().Lock("a")}
+
+	workErr, workCause := make(chan error, 1), make(chan error, 1)
+	s := NewStandby(lock, func(ctx context.Context) error {
+		<-ctx.Done()
+		workErr <- ctx.Err()
+		workCause <- context.Cause(ctx)
+		return nil
+	}, WithLeaseTTL(30*time.Millisecond), WithRenewInterval(5*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	eventually(t, s.Leader, "Expected the instance to become leader")
+
+	// Renewals failing past the TTL cancel the work.
+	lock.broken.Store(true)
+	select {
+	case err := <-workErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected work context canceled, got %v", err)
+		}
+		if cause := <-workCause; !errors.Is(cause, ErrLeaseLost) {
+			t.Fatalf("Expected ErrLeaseLost as the cause, got %v", cause)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected work to be stopped once the lease lapsed")
+	}
+	eventually(t, func() bool { return !s.Leader() }, "Expected the instance to be demoted")
+}
+
+// timerClock is a Clock whose time only moves with Advance, which fires the
+// waits it passes.
+type timerClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []timerWait
+}
+
+type timerWait struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func (c *timerClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *timerClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, timerWait{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *timerClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	c.waiters = slices.DeleteFunc(c.waiters, func(w timerWait) bool {
+		if w.at.After(c.now) {
+			return false
+		}
+		w.ch <- c.now
+		return true
+	})
+}
+
+func (c *timerClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// clockLock is a LeaseLock whose lease runs on clock, failing renewals
+// once broken.
+type clockLock struct {
+	clock   *timerClock
+	broken  atomic.Bool
+	expires atomic.Int64 // Unix nanoseconds
+}
+
+func (l *clockLock) TryAcquire(_ context.Context, ttl time.Duration) (bool, error) {
+	if l.broken.Load() {
+		return false, errTest
+	}
+	l.expires.Store(l.clock.Now().Add(ttl).UnixNano())
+	return true, nil
+}
+
+func (l *clockLock) Release(context.Context) error { return nil }
+
+func TestStandby_StopsBeforeLeaseExpires(t *testing.T) {
+	t.Parallel()
+	clock := &timerClock{now: time.Unix(0, 0)}
+	lock := &clockLock{clock: clock}
+
+	stopped := make(chan time.Time, 1)
+	s := NewStandby(lock, func(ctx context.Context) error {
+		<-ctx.Done()
+		if errors.Is(context.Cause(ctx), ErrLeaseLost) {
+			stopped <- clock.Now()
+		}
+		return nil
+	}, WithLeaseTTL(30*time.Second), WithRenewInterval(10*time.Second), WithStandbyClock(clock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	eventually(t, func() bool { return s.Leader() && clock.pending() == 2 }, "Expected the instance to become leader")
+
+	// Renewals keep failing: the work must stop before the lease expires
+	// in the store, 30s after it was requested.
+	lock.broken.Store(true)
+	clock.Advance(10 * time.Second)
+	eventually(t, func() bool { return clock.pending() == 2 }, "Expected the failed renewal to be retried")
+	clock.Advance(10 * time.Second)
+
+	select {
+	case at := <-stopped:
+		if expires := time.Unix(0, lock.expires.Load()); !at.Before(expires) {
+			t.Fatalf("Expected the work stopped before the lease expired at %v, got %v", expires, at)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the work to stop ahead of the lease expiry")
+	}
+}
+
+func TestStandby_WorkReturns(t *testing.T) {
+	t.Parallel()
+	lease := NewMemoryLease()
+
+	var runs atomic.Int32
+	s := NewStandby(lease.Lock("a"), func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}, WithLeaseTTL(30*time.Millisecond), WithRenewInterval(5*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	// Finished work releases the lock, and is run again on the next term.
+	if runs.Load() < 2 {
+		t.Fatalf("Expected work to run again after returning, got %d runs", runs.Load())
+	}
+	if lease.Holder() != "" {
+		t.Fatalf("Expected the lock released, held by %q", lease.Holder())
+	}
+}
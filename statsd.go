@@ -0,0 +1,103 @@
+package failover
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ MetricsSink = (*StatsDSink)(nil)
+
+// StatsDSink is a MetricsSink that sends measurements over UDP to a StatsD
+// server or to the Datadog agent, one datagram per measurement. Sending is
+// fire-and-forget: measurements that can not be sent are lost rather than
+// slow the caller down.
+type StatsDSink struct {
+	conn      net.Conn
+	prefix    string // Prepended to every metric name
+	dogstatsd bool   // Whether to send tags in the DogStatsD format
+	tags      []Tag  // Added to every measurement
+}
+
+// StatsDOption configures optional StatsDSink behavior.
+type StatsDOption func(*StatsDSink)
+
+// WithStatsDPrefix prepends prefix to every metric name, such as
+// "checkout." for checkout.failover.attempts.
+func WithStatsDPrefix(prefix string) StatsDOption {
+	return func(s *StatsDSink) {
+		s.prefix = prefix
+	}
+}
+
+// WithDogStatsD sends tags, and tags on every measurement, in the DogStatsD
+// format understood by the Datadog agent. Plain StatsD has no tags, so
+// without it they are dropped.
+func WithDogStatsD(tags ...Tag) StatsDOption {
+	return func(s *StatsDSink) {
+		s.dogstatsd = true
+		s.tags = tags
+	}
+}
+
+// NewStatsDSink creates a StatsDSink sending to the server at address, such
+// as "127.0.0.1:8125".
+func NewStatsDSink(address string, opts ...StatsDOption) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StatsDSink{conn: conn}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Count implements MetricsSink.
+func (s *StatsDSink) Count(name string, value int64, tags []Tag) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing implements MetricsSink, in milliseconds.
+func (s *StatsDSink) Timing(name string, d time.Duration, tags []Tag) {
+	s.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+// Gauge implements MetricsSink.
+func (s *StatsDSink) Gauge(name string, value float64, tags []Tag) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Close closes the connection to the server.
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// send writes the datagram of one measurement.
+func (s *StatsDSink) send(name, value, kind string, tags []Tag) {
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+
+	if s.dogstatsd && len(s.tags)+len(tags) > 0 {
+		b.WriteString("|#")
+		for i, t := range append(s.tags[:len(s.tags):len(s.tags)], tags...) {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(t.Key)
+			b.WriteByte(':')
+			b.WriteString(t.Value)
+		}
+	}
+
+	_, _ = s.conn.Write([]byte(b.String()))
+}
@@ -0,0 +1,66 @@
+package failover
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// statsdServer listens for datagrams and returns its address and a function
+// reading the next one.
+func statsdServer(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() string {
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Expected a datagram, got %v", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestStatsDSink(t *testing.T) {
+	t.Parallel()
+	addr, next := statsdServer(t)
+	sink, err := NewStatsDSink(addr, WithStatsDPrefix("app."))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer sink.Close()
+
+	tags := []Tag{{"policy", "db"}}
+	sink.Count("failover.attempts", 1, tags)
+	sink.Timing("failover.attempt.duration", 1500*time.Microsecond, tags)
+	sink.Gauge("failover.breaker.state", 1, tags)
+
+	// Plain StatsD drops the tags.
+	for _, want := range []string{"app.failover.attempts:1|c", "app.failover.attempt.duration:1.5|ms", "app.failover.breaker.state:1|g"} {
+		if got := next(); got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestStatsDSink_DogStatsD(t *testing.T) {
+	t.Parallel()
+	addr, next := statsdServer(t)
+	sink, err := NewStatsDSink(addr, WithDogStatsD(Tag{"env", "prod"}))
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	defer sink.Close()
+
+	sink.Count("failover.rejections", 1, []Tag{{"policy", "db"}, {"reason", "circuit_open"}})
+
+	if got, want := next(), "failover.rejections:1|c|#env:prod,policy:db,reason:circuit_open"; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+}
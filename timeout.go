@@ -0,0 +1,85 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned when an operation does not finish within the
+// Timeout policy's deadline. The operation may still be running. It is
+// also the cause, as told by context.Cause, of every context the package
+// cancels at a deadline of its own, such as that of a probe.
+var ErrTimeout = errors.New("operation timed out")
+
+// errDeadline is the cause of the contexts the package cancels at its own
+// deadlines, other than the Timeout policy's. It is also a
+// context.DeadlineExceeded, as callers such as net/http return the cause in
+// place of the context's error.
+var errDeadline = fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded)
+
+// Timeout bounds how long an operation may run.
+//
+// The operation receives a context that is canceled at the deadline. By
+// default Do returns ErrTimeout as soon as the deadline passes and leaves
+// the operation to observe its context in its own goroutine; with
+// WithWaitForCompletion Do instead waits for it to return first.
+type Timeout struct {
+	timeout time.Duration
+	wait    bool // Wait for the operation to return after the deadline
+}
+
+// TimeoutOption configures optional Timeout behavior.
+type TimeoutOption func(*Timeout)
+
+// WithWaitForCompletion makes Do wait for a timed out operation to return
+// before reporting ErrTimeout, so no work outlives the call.
+func WithWaitForCompletion() TimeoutOption {
+	return func(t *Timeout) {
+		t.wait = true
+	}
+}
+
+// NewTimeout creates a Timeout policy with the given deadline.
+func NewTimeout(timeout time.Duration, opts ...TimeoutOption) *Timeout {
+	t := &Timeout{timeout: timeout}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Do executes fn with a deadline. It returns ErrTimeout if the deadline
+// passes first, and the parent context's error if that is done first.
+func (t *Timeout) Do(ctx context.Context, fn WorkFuncCtx) error {
+	// The error doubles as the cause, so only this deadline, and not an
+	// ErrTimeout cause inherited from the parent, is reported as ours.
+	timedOut := &TimeoutError{Timeout: t.timeout}
+	ctx, cancel := context.WithTimeoutCause(ctx, t.timeout, timedOut)
+	defer cancel()
+
+	done := make(chan error, 1) // buffered so an abandoned fn can still finish
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// the deadline or the parent context ended first.
+	}
+
+	if t.wait {
+		<-done
+	}
+
+	if context.Cause(ctx) == error(timedOut) {
+		return timedOut
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,126 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeout_Success(t *testing.T) {
+	t.Parallel()
+	to := NewTimeout(100 * time.Millisecond)
+
+	err := to.Do(context.Background(), func(context.Context) error { return errTest })
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected error %v, got %v", errTest, err)
+	}
+}
+
+func TestTimeout_Abandon(t *testing.T) {
+	t.Parallel()
+	to := NewTimeout(10 * time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	err := to.Do(context.Background(), func(context.Context) error {
+		<-release // ignores its context
+		return nil
+	})
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("Expected Do to return at the deadline, took %v", elapsed)
+	}
+}
+
+func TestTimeout_WaitForCompletion(t *testing.T) {
+	t.Parallel()
+	to := NewTimeout(10*time.Millisecond, WithWaitForCompletion())
+
+	var finished atomic.Bool
+	err := to.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // cleanup after cancellation
+		finished.Store(true)
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	if !finished.Load() {
+		t.Fatal("Expected Do to wait for the operation to return")
+	}
+}
+
+func TestTimeout_ParentCanceled(t *testing.T) {
+	t.Parallel()
+	to := NewTimeout(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := to.Do(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// The caller's own deadline is not reported as the policy timing out.
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestTimeout_ParentTimedOut(t *testing.T) {
+	t.Parallel()
+	to := NewTimeout(time.Second)
+
+	// A parent canceled with ErrTimeout, as by an outer Timeout, is not
+	// this policy's deadline.
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 10*time.Millisecond, ErrTimeout)
+	defer cancel()
+
+	err := to.Do(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected the parent's error, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestTimeout_ComposesWithRetry(t *testing.T) {
+	t.Parallel()
+	to := NewTimeout(10 * time.Millisecond)
+	attempts := 0
+
+	err := NewRetryPolicy(3, time.Millisecond).Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		n := attempts
+		return to.Do(ctx, func(ctx context.Context) error {
+			if n < 3 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+}
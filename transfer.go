@@ -0,0 +1,96 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TransferFunc moves the data of a large upload or download from offset
+// onwards. It calls commit with each new offset up to which the data is
+// durably transferred, such as after every acknowledged chunk, so that a
+// retry can resume from there. commit may be called from any goroutine.
+type TransferFunc func(ctx context.Context, offset int64, commit func(offset int64)) error
+
+// DoTransfer runs fn from offset, retrying it from the last committed
+// offset when it fails, and returns the offset reached. Only attempts that
+// commit no progress count against the policy's attempts, so a transfer
+// over a flaky link completes as long as each attempt moves it forward.
+// Errors are reported as by Do, a MaxAttemptsError counting the attempts
+// since the last progress.
+//
+// The policy's dead letter, if any, is not used.
+func (r *RetryPolicy) DoTransfer(ctx context.Context, offset int64, fn TransferFunc) (int64, error) {
+	r.calls.Add(1)
+	end, err := r.doTransfer(ctx, offset, fn)
+	if err != nil {
+		r.failures.Add(1)
+	}
+
+	return end, err
+}
+
+// doTransfer is DoTransfer without the bookkeeping of Stats.
+func (r *RetryPolicy) doTransfer(ctx context.Context, offset int64, fn TransferFunc) (int64, error) {
+	var mu sync.Mutex // Protects offset
+	commit := func(n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		offset = max(offset, n)
+	}
+	committed := func() int64 {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return offset
+	}
+
+	var timer *time.Timer // Reused between attempts
+	defer stopTimer(&timer)
+
+	settings := r.settings.Load()
+	for failures := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return committed(), err
+		}
+
+		if r.limiter != nil {
+			if err := r.limiter.Wait(ctx); err != nil {
+				return committed(), err
+			}
+		}
+
+		start := committed()
+		r.tries.Add(1)
+		err := fn(ctx, start, commit)
+		if err == nil {
+			return committed(), nil
+		}
+
+		if committed() > start {
+			failures = 0
+		}
+		failures++
+
+		if r.retryIf != nil && !r.retryIf(err) || r.onOpen == FailOnOpen && errors.Is(err, ErrCircuitOpen) {
+			return committed(), err
+		}
+		if failures >= settings.attempts {
+			return committed(), &MaxAttemptsError{Attempts: failures, LastErr: err}
+		}
+
+		delay := r.openDelay(err, retryDelay(settings.backoff, failures, err))
+		if err := budgetExhausted(ctx, r.clock, failures, err, delay); err != nil {
+			return committed(), err
+		}
+		if r.onRetry != nil {
+			r.onRetry(ctx, failures, err, delay)
+		}
+
+		if err := r.wait(ctx, &timer, delay); err != nil {
+			return committed(), err
+		}
+	}
+}
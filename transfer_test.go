@@ -0,0 +1,87 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_DoTransferResumes(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(2, time.Millisecond)
+	var starts []int64
+
+	// Each attempt moves 10 bytes forward before the link drops, 40 in all.
+	end, err := r.DoTransfer(context.Background(), 0, func(_ context.Context, offset int64, commit func(int64)) error {
+		starts = append(starts, offset)
+		commit(offset + 10)
+		if offset+10 < 40 {
+			return errTest
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if end != 40 {
+		t.Fatalf("Expected offset 40, got %d", end)
+	}
+	// Four attempts despite a limit of 2, as each made progress.
+	want := []int64{0, 10, 20, 30}
+	if len(starts) != len(want) || starts[1] != 10 || starts[3] != 30 {
+		t.Fatalf("Expected attempts to resume at %v, got %v", want, starts)
+	}
+}
+
+func TestRetryPolicy_DoTransferStalls(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(3, time.Millisecond)
+	calls := 0
+
+	end, err := r.DoTransfer(context.Background(), 100, func(context.Context, int64, func(int64)) error {
+		calls++
+		return errTest
+	})
+
+	if !errors.Is(err, errTest) || end != 100 {
+		t.Fatalf("Expected error %v at offset 100, got %v at %d", errTest, err, end)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 attempts without progress, got %d", calls)
+	}
+}
+
+func TestRetryPolicy_DoTransferCommitNeverGoesBack(t *testing.T) {
+	t.Parallel()
+	r := NewRetryPolicy(1, time.Millisecond)
+
+	end, _ := r.DoTransfer(context.Background(), 0, func(_ context.Context, _ int64, commit func(int64)) error {
+		commit(50)
+		commit(20) // a late acknowledgment of an earlier chunk
+		return nil
+	})
+
+	if end != 50 {
+		t.Fatalf("Expected offset 50, got %d", end)
+	}
+}
+
+func TestRetryPolicy_DoTransferLimiter(t *testing.T) {
+	t.Parallel()
+	limiter := &countingLimiter{}
+	r := NewRetryPolicy(2, time.Millisecond, WithRetryLimiter(limiter))
+
+	r.DoTransfer(context.Background(), 0, func(_ context.Context, offset int64, commit func(int64)) error {
+		commit(offset + 10)
+		if offset+10 < 30 {
+			return errTest
+		}
+		return nil
+	})
+
+	if limiter.waits != 3 {
+		t.Fatalf("Expected a wait per attempt, got %d", limiter.waits)
+	}
+}
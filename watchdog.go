@@ -0,0 +1,122 @@
+package failover
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Watchdog supervises a long-running worker that is expected to signal
+// liveness periodically, either by calling Beat or through a probe that the
+// watchdog runs itself. It fires a callback when beats stop arriving within
+// the timeout and another when they resume.
+type Watchdog struct {
+	timeout time.Duration // How long without a beat before the worker is considered stalled
+
+	probe         WorkFuncCtx // Optional liveness check, a nil result counts as a beat
+	probeInterval time.Duration
+
+	onMissed  func(silence time.Duration)
+	onResumed func()
+
+	last    atomic.Int64 // Last beat, in Unix nanoseconds
+	stalled atomic.Bool
+}
+
+// WatchdogOption configures optional Watchdog behavior.
+type WatchdogOption func(*Watchdog)
+
+// WithProbe makes the watchdog run fn every interval while it runs; each
+// call that returns nil counts as a beat.
+func WithProbe(interval time.Duration, fn WorkFuncCtx) WatchdogOption {
+	return func(w *Watchdog) {
+		w.probe = fn
+		w.probeInterval = interval
+	}
+}
+
+// WithMissedFunc sets the callback fired once when beats stop arriving,
+// with how long it has been since the last one.
+func WithMissedFunc(fn func(silence time.Duration)) WatchdogOption {
+	return func(w *Watchdog) {
+		w.onMissed = fn
+	}
+}
+
+// WithResumedFunc sets the callback fired when a beat arrives after the
+// missed callback.
+func WithResumedFunc(fn func()) WatchdogOption {
+	return func(w *Watchdog) {
+		w.onResumed = fn
+	}
+}
+
+// NewWatchdog creates a Watchdog that considers the worker stalled when no
+// beat arrived for timeout. The clock starts at creation.
+func NewWatchdog(timeout time.Duration, opts ...WatchdogOption) *Watchdog {
+	w := &Watchdog{timeout: timeout}
+	w.last.Store(time.Now().UnixNano())
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Beat records that the worker is alive.
+func (w *Watchdog) Beat() {
+	w.last.Store(time.Now().UnixNano())
+
+	if w.stalled.CompareAndSwap(true, false) && w.onResumed != nil {
+		w.onResumed()
+	}
+}
+
+// Alive reports whether a beat arrived within the timeout.
+func (w *Watchdog) Alive() bool {
+	return w.silence() <= w.timeout
+}
+
+// Run supervises the worker until ctx is done, checking for missed beats
+// and running the probe if one is configured. It returns ctx.Err().
+func (w *Watchdog) Run(ctx context.Context) error {
+	check := time.NewTicker(max(w.timeout/4, time.Millisecond))
+	defer check.Stop()
+
+	var probe <-chan time.Time
+	if w.probe != nil {
+		ticker := time.NewTicker(w.probeInterval)
+		defer ticker.Stop()
+		probe = ticker.C
+	}
+
+	for {
+		select {
+		case <-check.C:
+			w.check()
+		case <-probe:
+			if w.probe(ctx) == nil {
+				w.Beat()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// check fires onMissed the first time the silence exceeds the timeout.
+func (w *Watchdog) check() {
+	silence := w.silence()
+	if silence <= w.timeout {
+		return
+	}
+
+	if w.stalled.CompareAndSwap(false, true) && w.onMissed != nil {
+		w.onMissed(silence)
+	}
+}
+
+func (w *Watchdog) silence() time.Duration {
+	return time.Since(time.Unix(0, w.last.Load()))
+}
@@ -0,0 +1,108 @@
+package failover
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchdog_MissedAndResumed(t *testing.T) {
+	t.Parallel()
+
+	missed := make(chan time.Duration, 1)
+	resumed := make(chan struct{}, 1)
+	w := NewWatchdog(20*time.Millisecond,
+		WithMissedFunc(func(silence time.Duration) { missed <- silence }),
+		WithResumedFunc(func() { resumed <- struct{}{} }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case silence := <-missed:
+		if silence < 20*time.Millisecond {
+			t.Fatalf("Expected silence of at least 20ms, got %v", silence)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the missed callback to fire")
+	}
+	if w.Alive() {
+		t.Fatal("Expected watchdog to report the worker stalled")
+	}
+
+	w.Beat()
+	select {
+	case <-resumed:
+	default:
+		t.Fatal("Expected the resumed callback to fire on the next beat")
+	}
+	if !w.Alive() {
+		t.Fatal("Expected watchdog to report the worker alive")
+	}
+}
+
+func TestWatchdog_BeatsKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	var missed atomic.Bool
+	w := NewWatchdog(30*time.Millisecond, WithMissedFunc(func(time.Duration) { missed.Store(true) }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go w.Run(ctx)
+
+	for ctx.Err() == nil {
+		w.Beat()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if missed.Load() {
+		t.Fatal("Expected regular beats not to trigger the missed callback")
+	}
+}
+
+func TestWatchdog_Probe(t *testing.T) {
+	t.Parallel()
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	missed := make(chan struct{}, 1)
+	resumed := make(chan struct{}, 1)
+
+	w := NewWatchdog(20*time.Millisecond,
+		WithProbe(5*time.Millisecond, func(context.Context) error {
+			if healthy.Load() {
+				return nil
+			}
+			return errTest
+		}),
+		WithMissedFunc(func(time.Duration) { missed <- struct{}{} }),
+		WithResumedFunc(func() { resumed <- struct{}{} }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	time.Sleep(40 * time.Millisecond)
+	if !w.Alive() {
+		t.Fatal("Expected a passing probe to keep the worker alive")
+	}
+
+	healthy.Store(false)
+	select {
+	case <-missed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the missed callback once the probe fails")
+	}
+
+	healthy.Store(true)
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the resumed callback once the probe passes again")
+	}
+}
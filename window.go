@@ -0,0 +1,99 @@
+package failover
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// windowBuckets is the number of buckets a rolling window is split into.
+const windowBuckets = 10
+
+// bucket holds the outcomes recorded during one slice of a rolling window.
+type bucket struct {
+	start     atomic.Int64 // Bucket start, in nanoseconds since the Unix epoch
+	successes atomic.Int64
+	failures  atomic.Int64
+}
+
+// windowShard is one set of buckets of a rolling window, padded so that
+// shards written by different CPUs do not share a cache line.
+type windowShard struct {
+	buckets [windowBuckets]bucket
+	_       [cacheLine]byte
+}
+
+// rollingWindow counts successes and failures over a sliding time window.
+// It is safe for concurrent use without locking; a call racing with a
+// bucket being recycled for a new lap may have its outcome dropped, which
+// only matters at the edge of the window. A window split into shards
+// keeps an independent set of buckets per shard and adds them up when
+// read.
+type rollingWindow struct {
+	size   time.Duration
+	width  int64 // Width of a single bucket in nanoseconds
+	shards []windowShard
+}
+
+func newRollingWindow(size time.Duration) *rollingWindow {
+	width := int64(size) / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+
+	return &rollingWindow{size: size, width: width, shards: make([]windowShard, 1)}
+}
+
+// split spreads the window over n shards, a power of two. It must be
+// called before the window is used.
+func (w *rollingWindow) split(n int) {
+	w.shards = make([]windowShard, n)
+}
+
+// current returns the bucket for now, of the calling goroutine's shard,
+// clearing it if it belongs to an earlier lap of the window.
+func (w *rollingWindow) current(now time.Time) *bucket {
+	start := now.UnixNano() / w.width * w.width
+	shard := &w.shards[pickShard(len(w.shards))]
+	b := &shard.buckets[(start/w.width)%windowBuckets]
+	if old := b.start.Load(); old != start && b.start.CompareAndSwap(old, start) {
+		b.successes.Store(0)
+		b.failures.Store(0)
+	}
+
+	return b
+}
+
+func (w *rollingWindow) success(now time.Time) {
+	w.current(now).successes.Add(1)
+}
+
+func (w *rollingWindow) failure(now time.Time) {
+	w.current(now).failures.Add(1)
+}
+
+// counts returns the successes and failures recorded within the window.
+func (w *rollingWindow) counts(now time.Time) (successes, failures int) {
+	oldest := now.UnixNano() - int64(w.size)
+	for i := range w.shards {
+		for j := range w.shards[i].buckets {
+			b := &w.shards[i].buckets[j]
+			if b.start.Load() > oldest {
+				successes += int(b.successes.Load())
+				failures += int(b.failures.Load())
+			}
+		}
+	}
+
+	return successes, failures
+}
+
+func (w *rollingWindow) reset() {
+	for i := range w.shards {
+		for j := range w.shards[i].buckets {
+			b := &w.shards[i].buckets[j]
+			b.start.Store(0)
+			b.successes.Store(0)
+			b.failures.Store(0)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package failover
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollingWindow_Counts(t *testing.T) {
+	t.Parallel()
+	w := newRollingWindow(10 * time.Second)
+	now := time.Unix(1000, 0)
+
+	w.success(now)
+	w.failure(now.Add(1 * time.Second))
+	w.failure(now.Add(2 * time.Second))
+
+	successes, failures := w.counts(now.Add(2 * time.Second))
+	if successes != 1 || failures != 2 {
+		t.Fatalf("Expected 1 success and 2 failures, got %d and %d", successes, failures)
+	}
+}
+
+func TestRollingWindow_Expiry(t *testing.T) {
+	t.Parallel()
+	w := newRollingWindow(10 * time.Second)
+	now := time.Unix(1000, 0)
+
+	w.failure(now)
+	w.success(now.Add(5 * time.Second))
+
+	// After the window has slid past the first failure only the success remains.
+	successes, failures := w.counts(now.Add(12 * time.Second))
+	if successes != 1 || failures != 0 {
+		t.Fatalf("Expected 1 success and 0 failures, got %d and %d", successes, failures)
+	}
+
+	// A bucket reused on a later lap must not carry stale counts.
+	w.success(now.Add(20 * time.Second))
+	successes, failures = w.counts(now.Add(20 * time.Second))
+	if successes != 1 || failures != 0 {
+		t.Fatalf("Expected 1 success and 0 failures after lap, got %d and %d", successes, failures)
+	}
+}
+
+func TestRollingWindow_Reset(t *testing.T) {
+	t.Parallel()
+	w := newRollingWindow(10 * time.Second)
+	now := time.Unix(1000, 0)
+
+	w.failure(now)
+	w.reset()
+
+	if successes, failures := w.counts(now); successes != 0 || failures != 0 {
+		t.Fatalf("Expected empty window after reset, got %d and %d", successes, failures)
+	}
+}
+
+func TestRollingWindow_Split(t *testing.T) {
+	t.Parallel()
+	w := newRollingWindow(10 * time.Second)
+	w.split(4)
+	now := time.Unix(1000, 0)
+
+	for range 20 {
+		w.success(now)
+		w.failure(now)
+	}
+
+	if successes, failures := w.counts(now); successes != 20 || failures != 20 {
+		t.Fatalf("Expected the shards to add up to 20 and 20, got %d and %d", successes, failures)
+	}
+
+	w.reset()
+	if successes, failures := w.counts(now); successes != 0 || failures != 0 {
+		t.Fatalf("Expected empty window after reset, got %d and %d", successes, failures)
+	}
+}
@@ -0,0 +1,50 @@
+package failover
+
+import (
+	"context"
+	"sync"
+)
+
+// Wrap returns fn with policy built in, so that call sites invoke a plain
+// function and the resilience behavior is defined once, where fn is set
+// up:
+//
+//	getUser := failover.Wrap(pipeline, client.GetUser)
+//	user, err := getUser(ctx)
+//
+// The value returned is that of the attempt that succeeded; on failure it
+// is the zero T. Attempts that end after the policy gave up on them, such
+// as past a Timeout, are discarded.
+func Wrap[T any](policy Policy, fn func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		var (
+			mu     sync.Mutex // Protects result and done
+			result T
+			done   bool // Set once the policy returns
+		)
+
+		err := policy.Do(ctx, func(ctx context.Context) error {
+			v, err := fn(ctx)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !done {
+				result = v
+			}
+			return nil
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		done = true
+
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,56 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	get := Wrap(NewRetryPolicy(3, time.Millisecond), func(context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return -1, errTest
+		}
+		return 42, nil
+	})
+
+	v, err := get(context.Background())
+	if err != nil || v != 42 {
+		t.Fatalf("Expected 42, got %d (%v)", v, err)
+	}
+}
+
+func TestWrap_Failure(t *testing.T) {
+	t.Parallel()
+	get := Wrap(NewRetryPolicy(2, time.Millisecond), func(context.Context) (int, error) {
+		return -1, errTest
+	})
+
+	v, err := get(context.Background())
+	if !errors.Is(err, errTest) || v != 0 {
+		t.Fatalf("Expected the zero value and %v, got %d (%v)", errTest, v, err)
+	}
+}
+
+func TestWrap_AbandonedAttempt(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	get := Wrap(NewTimeout(5*time.Millisecond), func(context.Context) (int, error) {
+		defer close(finished)
+		<-release
+		return 42, nil
+	})
+
+	v, err := get(context.Background())
+	close(release)
+	<-finished
+
+	if !errors.Is(err, ErrTimeout) || v != 0 {
+		t.Fatalf("Expected the zero value and ErrTimeout, got %d (%v)", v, err)
+	}
+}